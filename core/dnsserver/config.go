@@ -62,6 +62,13 @@ type Config struct {
 	// TSIG secrets, [name]key.
 	TsigSecret map[string]string
 
+	// TSIG algorithms, [name]algorithm, for the keys in TsigSecret that define one.
+	TsigAlgorithm map[string]string
+
+	// UpdateZones are the zones in which a plugin handles dynamic updates (RFC 2136), these are
+	// set by that plugin. Updates for other zones are answered with NOTIMP.
+	UpdateZones []string
+
 	// Plugin stack.
 	Plugin []plugin.Plugin
 
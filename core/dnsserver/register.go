@@ -150,6 +150,8 @@ func (h *dnsContext) MakeServers() ([]caddy.Server, error) {
 		c.WriteTimeout = c.firstConfigInBlock.WriteTimeout
 		c.IdleTimeout = c.firstConfigInBlock.IdleTimeout
 		c.TsigSecret = c.firstConfigInBlock.TsigSecret
+		c.TsigAlgorithm = c.firstConfigInBlock.TsigAlgorithm
+		c.UpdateZones = c.firstConfigInBlock.UpdateZones
 	}
 
 	// we must map (group) each config to a bind address
@@ -49,6 +49,7 @@ type Server struct {
 	writeTimeout time.Duration        // Write timeout for TCP
 
 	tsigSecret map[string]string
+	msgAccept  dns.MsgAcceptFunc // accepts dynamic updates when a site handles them
 }
 
 // MetadataCollector is a plugin that can retrieve metadata functions from all metadata providing plugins
@@ -67,6 +68,7 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		readTimeout:  3 * time.Second,
 		writeTimeout: 5 * time.Second,
 		tsigSecret:   make(map[string]string),
+		msgAccept:    dns.DefaultMsgAcceptFunc,
 	}
 
 	// We have to bound our wg with one increment
@@ -102,6 +104,9 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 		for key, secret := range site.TsigSecret {
 			s.tsigSecret[key] = secret
 		}
+		if len(site.UpdateZones) > 0 {
+			s.msgAccept = MsgAcceptFunc
+		}
 
 		// compile custom plugin for everything
 		var stack plugin.Handler
@@ -140,6 +145,21 @@ func NewServer(addr string, group []*Config) (*Server, error) {
 	return s, nil
 }
 
+// MsgAcceptFunc is dns.DefaultMsgAcceptFunc, but it also accepts dynamic updates (RFC 2136). These
+// can carry any number of RRs in the prerequisite and update sections. It is only used by servers
+// where a plugin handles updates, see Config.UpdateZones. ServeDNS only passes the updates for
+// those zones to the plugins.
+func MsgAcceptFunc(dh dns.Header) dns.MsgAcceptAction {
+	opcode := int(dh.Bits>>11) & 0xF
+	if isResponse := dh.Bits&(1<<15) != 0; !isResponse && opcode == dns.OpcodeUpdate {
+		if dh.Qdcount != 1 {
+			return dns.MsgReject
+		}
+		return dns.MsgAccept
+	}
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
 // Compile-time check to ensure Server implements the caddy.GracefulServer interface
 var _ caddy.GracefulServer = &Server{}
 
@@ -157,6 +177,7 @@ func (s *Server) Serve(l net.Listener) error {
 		IdleTimeout: func() time.Duration {
 			return s.idleTimeout
 		},
+		MsgAcceptFunc: s.msgAccept,
 		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 			ctx := context.WithValue(context.Background(), Key{}, s)
 			ctx = context.WithValue(ctx, LoopKey{}, 0)
@@ -176,7 +197,7 @@ func (s *Server) ServePacket(p net.PacketConn) error {
 		ctx := context.WithValue(context.Background(), Key{}, s)
 		ctx = context.WithValue(ctx, LoopKey{}, 0)
 		s.ServeDNS(ctx, w, r)
-	}), TsigSecret: s.tsigSecret}
+	}), TsigSecret: s.tsigSecret, MsgAcceptFunc: s.msgAccept}
 	s.m.Unlock()
 
 	return s.server[udp].ActivateAndServe()
@@ -312,6 +333,10 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 						// if there was a view defined for this Config, set the view name in the context
 						ctx = context.WithValue(ctx, ViewKey{}, h.ViewName)
 					}
+					if !handlesOpcode(h, r) {
+						errorFunc(s.Addr, w, r, dns.RcodeNotImplemented)
+						return
+					}
 					if r.Question[0].Qtype != dns.TypeDS {
 						rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
 						if !plugin.ClientWrite(rcode) {
@@ -361,6 +386,10 @@ func (s *Server) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 					// if there was a view defined for this Config, set the view name in the context
 					ctx = context.WithValue(ctx, ViewKey{}, h.ViewName)
 				}
+				if !handlesOpcode(h, r) {
+					errorFunc(s.Addr, w, r, dns.RcodeNotImplemented)
+					return
+				}
 				rcode, _ := h.pluginChain.ServeDNS(ctx, w, r)
 				if !plugin.ClientWrite(rcode) {
 					errorFunc(s.Addr, w, r, rcode)
@@ -406,6 +435,20 @@ func (s *Server) Tracer() ot.Tracer {
 	return s.trace.Tracer()
 }
 
+// handlesOpcode returns true if the plugins of h handle the opcode of r. Queries and notifies are
+// passed to all plugins, dynamic updates only to those of the zones in h.UpdateZones. Plugins that
+// serve queries would otherwise answer an update as if it were a query, and the client would see
+// that as a successful update.
+func handlesOpcode(h *Config, r *dns.Msg) bool {
+	switch r.Opcode {
+	case dns.OpcodeQuery, dns.OpcodeNotify:
+		return true
+	case dns.OpcodeUpdate:
+		return plugin.Zones(h.UpdateZones).Matches(r.Question[0].Name) != ""
+	}
+	return false
+}
+
 // errorFunc responds to an DNS request with an error.
 func errorFunc(server string, w dns.ResponseWriter, r *dns.Msg, rc int) {
 	state := request.Request{W: w, Req: r}
@@ -20,6 +20,10 @@ type ServerTLS struct {
 	tlsConfig *tls.Config
 }
 
+// TLSConnectionStateKey is the context key for the *tls.ConnectionState of the connection the current
+// processed request was received on (if current processed request was done over DoT).
+type TLSConnectionStateKey struct{}
+
 // NewServerTLS returns a new CoreDNS TLS server and compiles all plugin in to it.
 func NewServerTLS(addr string, group []*Config) (*ServerTLS, error) {
 	s, err := NewServer(addr, group)
@@ -62,6 +66,10 @@ func (s *ServerTLS) Serve(l net.Listener) error {
 		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
 			ctx := context.WithValue(context.Background(), Key{}, s.Server)
 			ctx = context.WithValue(ctx, LoopKey{}, 0)
+			// Plugins may wrap w, the connection state is handed to them in the context.
+			if cs, ok := w.(dns.ConnectionStater); ok {
+				ctx = context.WithValue(ctx, TLSConnectionStateKey{}, cs.ConnectionState())
+			}
 			s.ServeDNS(ctx, w, r)
 		})}
 
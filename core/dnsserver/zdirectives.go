@@ -23,6 +23,7 @@ var Directives = []string{
 	"debug",
 	"trace",
 	"ready",
+	"admin",
 	"health",
 	"pprof",
 	"prometheus",
@@ -31,11 +32,15 @@ var Directives = []string{
 	"dnstap",
 	"local",
 	"dns64",
+	"cookie",
 	"acl",
+	"ratelimit",
+	"rrl",
 	"any",
 	"chaos",
 	"loadbalance",
 	"tsig",
+	"rpz",
 	"cache",
 	"rewrite",
 	"header",
@@ -49,12 +54,16 @@ var Directives = []string{
 	"azure",
 	"clouddns",
 	"k8s_external",
+	"k8s_crd",
 	"kubernetes",
 	"file",
 	"auto",
 	"secondary",
 	"etcd",
+	"consul",
 	"loop",
+	"validate",
+	"recursive",
 	"forward",
 	"grpc",
 	"erratic",
@@ -0,0 +1,138 @@
+// Package admin implements an HTTP endpoint to change the records of the zones served by other
+// plugins, such as route53, azure and clouddns. The changes are made by the plugin serving the zone.
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// Updater is implemented by plugins whose zones can be changed with the admin endpoint.
+type Updater interface {
+	// Update applies changes to zone in the plugin's backend, and then to the plugin's copy of the
+	// zone, so the changes are served once it returns. If the zone is stored in several hosted zones
+	// in the backend, hostedZone selects one of them, it may be empty if there is only one.
+	// ErrNotAuthoritative is returned when the plugin doesn't serve zone. Errors caused by the
+	// changes or the hosted zone wrap ErrInvalid.
+	Update(ctx context.Context, zone, hostedZone string, changes []Change) error
+}
+
+var (
+	// ErrNotAuthoritative is returned by Update when the plugin doesn't serve the zone.
+	ErrNotAuthoritative = errors.New("not authoritative for zone")
+	// ErrInvalid is wrapped by the errors returned by Update for changes that can't be made.
+	ErrInvalid = errors.New("invalid change")
+)
+
+// Action is the action of a Change.
+type Action string
+
+const (
+	// Upsert creates the RRset, or replaces it if it exists.
+	Upsert Action = "upsert"
+	// Delete deletes the RRset.
+	Delete Action = "delete"
+)
+
+// Change is a change to the RRset with Name and Type.
+type Change struct {
+	Action Action
+	Name   string // Fully qualified and lower case.
+	Type   uint16
+	TTL    uint32
+	RRs    []dns.RR // The RRset for an Upsert, empty for a Delete.
+}
+
+// RRset returns the RRs of type typ at name in z, the zone with origin zone.
+func RRset(zone string, z *file.Zone, name string, typ uint16) []dns.RR {
+	if name == zone {
+		switch typ {
+		case dns.TypeSOA:
+			if z.Apex.SOA == nil {
+				return nil
+			}
+			return []dns.RR{z.Apex.SOA}
+		case dns.TypeNS:
+			return z.Apex.NS
+		}
+	}
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return nil
+	}
+	return e.Type(typ)
+}
+
+// Apply returns a copy of z, the zone with origin zone, with changes applied. The copy can be swapped in
+// for z, z itself is not modified.
+func Apply(zone string, z *file.Zone, changes []Change) *file.Zone {
+	type rrset struct {
+		name string
+		typ  uint16
+	}
+	changed := map[rrset]struct{}{}
+	for _, c := range changes {
+		changed[rrset{c.Name, c.Type}] = struct{}{}
+	}
+	keep := func(rr dns.RR) bool {
+		_, ok := changed[rrset{strings.ToLower(rr.Header().Name), rr.Header().Rrtype}]
+		return !ok
+	}
+
+	z1 := file.NewZone(zone, "")
+	z1.Upstream = z.Upstream
+	if z.Apex.SOA != nil {
+		z1.Insert(z.Apex.SOA)
+	}
+	for _, rr := range z.Apex.NS {
+		if keep(rr) {
+			z1.Insert(rr)
+		}
+	}
+	z.Tree.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			if keep(rr) {
+				z1.Insert(rr)
+			}
+		}
+		return nil
+	})
+	for _, c := range changes {
+		if c.Action != Upsert {
+			continue
+		}
+		for _, rr := range c.RRs {
+			z1.Insert(dns.Copy(rr))
+		}
+	}
+	return z1
+}
+
+// Rdata returns the rdata of rr in presentation format.
+func Rdata(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// HostedZone returns the index of hostedZone in ids, the identifiers of the hosted zones that store zone.
+// If hostedZone is empty, ids must have a single element.
+func HostedZone(zone, hostedZone string, ids []string) (int, error) {
+	if hostedZone == "" {
+		if len(ids) == 1 {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%w: %s is stored in %d hosted zones, select one of %v", ErrInvalid, zone, len(ids), ids)
+	}
+	for i, id := range ids {
+		if id == hostedZone {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: hosted zone %q of %s not found", ErrInvalid, hostedZone, zone)
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func testZone() *file.Zone {
+	z := file.NewZone("example.org.", "")
+	for _, rr := range []dns.RR{
+		test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 300 2419200 300"),
+		test.NS("example.org. 300 IN NS ns.example.org."),
+		test.A("ns.example.org. 300 IN A 10.0.0.53"),
+		test.A("www.example.org. 300 IN A 10.0.0.1"),
+		test.A("www.example.org. 300 IN A 10.0.0.2"),
+		test.TXT(`www.example.org. 300 IN TXT "www"`),
+	} {
+		z.Insert(rr)
+	}
+	return z
+}
+
+func TestApply(t *testing.T) {
+	z := testZone()
+	z1 := Apply("example.org.", z, []Change{
+		{Action: Upsert, Name: "www.example.org.", Type: dns.TypeA, TTL: 60, RRs: []dns.RR{test.A("www.example.org. 60 IN A 10.0.0.3")}},
+		{Action: Delete, Name: "www.example.org.", Type: dns.TypeTXT},
+		{Action: Upsert, Name: "mail.example.org.", Type: dns.TypeMX, TTL: 60, RRs: []dns.RR{test.MX("mail.example.org. 60 IN MX 10 mx.example.org.")}},
+	})
+
+	tests := []struct {
+		name string
+		typ  uint16
+		want []string
+	}{
+		{"example.org.", dns.TypeSOA, nil},
+		{"example.org.", dns.TypeNS, []string{"ns.example.org."}},
+		{"ns.example.org.", dns.TypeA, []string{"10.0.0.53"}},
+		{"www.example.org.", dns.TypeA, []string{"10.0.0.3"}},
+		{"www.example.org.", dns.TypeTXT, nil},
+		{"mail.example.org.", dns.TypeMX, []string{"10 mx.example.org."}},
+	}
+	for i, tc := range tests {
+		rrs := RRset("example.org.", z1, tc.name, tc.typ)
+		if tc.typ == dns.TypeSOA {
+			if len(rrs) != 1 {
+				t.Errorf("Test %d: Expected SOA, got %v", i, rrs)
+			}
+			continue
+		}
+		if len(rrs) != len(tc.want) {
+			t.Errorf("Test %d: Expected %d %s records at %s, got %v", i, len(tc.want), dns.TypeToString[tc.typ], tc.name, rrs)
+			continue
+		}
+		for j, rr := range rrs {
+			if got := Rdata(rr); got != tc.want[j] {
+				t.Errorf("Test %d: Expected %s, got %s", i, tc.want[j], got)
+			}
+		}
+	}
+
+	// The original zone isn't modified.
+	if rrs := RRset("example.org.", z, "www.example.org.", dns.TypeA); len(rrs) != 2 {
+		t.Errorf("Expected the original zone to be unmodified, got %v", rrs)
+	}
+}
+
+func TestHostedZone(t *testing.T) {
+	tests := []struct {
+		hostedZone string
+		ids        []string
+		want       int
+		wantErr    bool
+	}{
+		{"", []string{"a"}, 0, false},
+		{"", []string{"a", "b"}, 0, true},
+		{"b", []string{"a", "b"}, 1, false},
+		{"c", []string{"a", "b"}, 0, true},
+	}
+	for i, tc := range tests {
+		got, err := HostedZone("example.org.", tc.hostedZone, tc.ids)
+		if tc.wantErr {
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("Test %d: Expected ErrInvalid, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+		if got != tc.want {
+			t.Errorf("Test %d: Expected %d, got %d", i, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package admin
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/reuseport"
+	"github.com/coredns/coredns/plugin/pkg/uniq"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	defaultTTL = 300
+	maxBody    = 1 << 20
+)
+
+var (
+	log      = clog.NewWithPlugin("admin")
+	updaters = &list{}
+	uniqAddr = uniq.New()
+)
+
+type admin struct {
+	Addr      string
+	token     []byte
+	tlsConfig *tls.Config
+
+	sync.Mutex
+	ln   net.Listener
+	done bool
+}
+
+// changeRequest is the body of a request to change the records of a zone.
+type changeRequest struct {
+	HostedZone string `json:"hosted_zone"`
+	Changes    []struct {
+		Action  string   `json:"action"`
+		Name    string   `json:"name"`
+		Type    string   `json:"type"`
+		TTL     uint32   `json:"ttl"`
+		Records []string `json:"records"`
+	} `json:"changes"`
+}
+
+func (a *admin) onStartup() error {
+	ln, err := reuseport.Listen("tcp", a.Addr)
+	if err != nil {
+		return err
+	}
+	if a.tlsConfig != nil {
+		ln = tls.NewListener(ln, a.tlsConfig)
+	}
+
+	a.Lock()
+	a.ln = ln
+	a.done = true
+	a.Unlock()
+
+	mux := http.NewServeMux()
+	mux.Handle("/zones/", a)
+	go func() { http.Serve(ln, mux) }()
+
+	return nil
+}
+
+func (a *admin) onFinalShutdown() error {
+	a.Lock()
+	defer a.Unlock()
+	if !a.done {
+		return nil
+	}
+
+	uniqAddr.Unset(a.Addr)
+
+	a.ln.Close()
+	a.done = false
+	return nil
+}
+
+// ServeHTTP handles a POST to /zones/ZONE, which applies the changes in the body to ZONE.
+func (a *admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || !updaters.Authorized([]byte(token)) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	zone := strings.TrimPrefix(r.URL.Path, "/zones/")
+	if _, ok := dns.IsDomainName(zone); !ok || zone == "" {
+		http.Error(w, fmt.Sprintf("invalid zone %q", zone), http.StatusBadRequest)
+		return
+	}
+	zone = dns.CanonicalName(zone)
+
+	req := changeRequest{}
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %s", err), http.StatusBadRequest)
+		return
+	}
+	changes, err := parseChanges(zone, req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	name, err := updaters.Update(r.Context(), []byte(token), zone, req.HostedZone, changes)
+	switch {
+	case errors.Is(err, ErrNotAuthoritative):
+		http.Error(w, fmt.Sprintf("zone %s not found", zone), http.StatusNotFound)
+		return
+	case errors.Is(err, ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Errorf("Failed to update %s with %s: %s", zone, name, err)
+		http.Error(w, fmt.Sprintf("%s: %s", name, err), http.StatusBadGateway)
+		return
+	}
+
+	log.Infof("Applied %d change(s) to %s with %s, requested by %s", len(changes), zone, name, r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseChanges validates the changes in req for zone, and returns them.
+func parseChanges(zone string, req changeRequest) ([]Change, error) {
+	if len(req.Changes) == 0 {
+		return nil, errors.New("no changes")
+	}
+
+	type rrset struct {
+		name string
+		typ  uint16
+	}
+	seen := map[rrset]struct{}{}
+	changes := make([]Change, 0, len(req.Changes))
+	for _, c := range req.Changes {
+		name := dns.CanonicalName(c.Name)
+		if _, ok := dns.IsDomainName(name); !ok || c.Name == "" {
+			return nil, fmt.Errorf("invalid name %q", c.Name)
+		}
+		if !dns.IsSubDomain(zone, name) {
+			return nil, fmt.Errorf("name %s is not in zone %s", name, zone)
+		}
+		typ, ok := dns.StringToType[strings.ToUpper(c.Type)]
+		if !ok {
+			return nil, fmt.Errorf("invalid type %q", c.Type)
+		}
+		if typ == dns.TypeSOA || (typ == dns.TypeNS && name == zone) {
+			return nil, fmt.Errorf("the %s records at the apex can't be changed", dns.TypeToString[typ])
+		}
+		if _, ok := seen[rrset{name, typ}]; ok {
+			return nil, fmt.Errorf("more than one change for %s %s", name, dns.TypeToString[typ])
+		}
+		seen[rrset{name, typ}] = struct{}{}
+
+		change := Change{Action: Action(strings.ToLower(c.Action)), Name: name, Type: typ, TTL: c.TTL}
+		if change.TTL == 0 {
+			change.TTL = defaultTTL
+		}
+		switch change.Action {
+		case Upsert:
+			if len(c.Records) == 0 {
+				return nil, fmt.Errorf("no records for %s %s", name, dns.TypeToString[typ])
+			}
+			for _, r := range c.Records {
+				rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, change.TTL, dns.TypeToString[typ], r))
+				if err != nil || rr == nil {
+					return nil, fmt.Errorf("invalid %s record %q", dns.TypeToString[typ], r)
+				}
+				change.RRs = append(change.RRs, rr)
+			}
+		case Delete:
+			if len(c.Records) != 0 {
+				return nil, fmt.Errorf("records given to delete %s %s, only whole RRsets are deleted", name, dns.TypeToString[typ])
+			}
+		default:
+			return nil, fmt.Errorf("invalid action %q", c.Action)
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
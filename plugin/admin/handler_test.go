@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+type fakeUpdater struct {
+	zone    string
+	changes []Change
+	err     error
+}
+
+func (f *fakeUpdater) Update(_ context.Context, zone, _ string, changes []Change) error {
+	if zone != f.zone {
+		return ErrNotAuthoritative
+	}
+	f.changes = changes
+	return f.err
+}
+
+func TestParseChanges(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{`{"changes": [{"action": "upsert", "name": "www.example.org", "type": "A", "records": ["10.0.0.1", "10.0.0.2"]}]}`, false},
+		{`{"changes": [{"action": "DELETE", "name": "www.example.org.", "type": "txt"}]}`, false},
+		{`{"changes": [{"action": "upsert", "name": "sub.example.org.", "type": "NS", "records": ["ns.example.net."]}]}`, false},
+		{`{"changes": []}`, true},
+		{`{"changes": [{"action": "upsert", "name": "www.example.net.", "type": "A", "records": ["10.0.0.1"]}]}`, true},
+		{`{"changes": [{"action": "upsert", "name": "example.org.", "type": "NS", "records": ["ns.example.net."]}]}`, true},
+		{`{"changes": [{"action": "upsert", "name": "example.org.", "type": "SOA", "records": ["ns. h. 1 2 3 4 5"]}]}`, true},
+		{`{"changes": [{"action": "upsert", "name": "www.example.org.", "type": "A"}]}`, true},
+		{`{"changes": [{"action": "upsert", "name": "www.example.org.", "type": "A", "records": ["not-an-ip"]}]}`, true},
+		{`{"changes": [{"action": "upsert", "name": "www.example.org.", "type": "BLAH", "records": ["10.0.0.1"]}]}`, true},
+		{`{"changes": [{"action": "delete", "name": "www.example.org.", "type": "A", "records": ["10.0.0.1"]}]}`, true},
+		{`{"changes": [{"action": "create", "name": "www.example.org.", "type": "A", "records": ["10.0.0.1"]}]}`, true},
+		{`{"changes": [{"action": "delete", "name": "www.example.org.", "type": "A"}, {"action": "delete", "name": "WWW.example.org.", "type": "A"}]}`, true},
+	}
+	for i, tc := range tests {
+		req := changeRequest{}
+		if err := json.Unmarshal([]byte(tc.body), &req); err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		_, err := parseChanges("example.org.", req)
+		if tc.wantErr && err == nil {
+			t.Errorf("Test %d: Expected error, got none", i)
+		}
+		if !tc.wantErr && err != nil {
+			t.Errorf("Test %d: Expected no error, got %v", i, err)
+		}
+	}
+
+	changes, _ := parseChanges("example.org.", changeRequest{Changes: []struct {
+		Action  string   `json:"action"`
+		Name    string   `json:"name"`
+		Type    string   `json:"type"`
+		TTL     uint32   `json:"ttl"`
+		Records []string `json:"records"`
+	}{{Action: "upsert", Name: "WWW.example.org", Type: "a", Records: []string{"10.0.0.1"}}}})
+	if len(changes) != 1 {
+		t.Fatalf("Expected 1 change, got %d", len(changes))
+	}
+	if c := changes[0]; c.Name != "www.example.org." || c.Type != dns.TypeA || c.TTL != defaultTTL || len(c.RRs) != 1 || c.RRs[0].Header().Ttl != defaultTTL {
+		t.Errorf("Unexpected change: %+v", c)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	u := &fakeUpdater{zone: "example.org."}
+	updaters.Append(u, "fake", []byte("s3cret"))
+	defer updaters.Reset()
+
+	a := &admin{}
+	body := `{"changes": [{"action": "upsert", "name": "www.example.org.", "type": "A", "ttl": 60, "records": ["10.0.0.1"]}]}`
+	tests := []struct {
+		method, path, token, body string
+		want                      int
+	}{
+		{http.MethodPost, "/zones/example.org.", "s3cret", body, http.StatusNoContent},
+		{http.MethodPost, "/zones/EXAMPLE.org", "s3cret", body, http.StatusNoContent},
+		{http.MethodPost, "/zones/example.org.", "", body, http.StatusUnauthorized},
+		{http.MethodPost, "/zones/example.org.", "wrong", body, http.StatusUnauthorized},
+		{http.MethodGet, "/zones/example.org.", "s3cret", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/zones/example.net.", "s3cret", strings.Replace(body, "org", "net", 1), http.StatusNotFound},
+		{http.MethodPost, "/zones/", "s3cret", body, http.StatusBadRequest},
+		{http.MethodPost, "/zones/example.org.", "s3cret", `{"changes": [], "extra": 1}`, http.StatusBadRequest},
+		{http.MethodPost, "/zones/example.org.", "s3cret", `{"changes": [{"action": "upsert", "name": "www.example.net.", "type": "A", "records": ["10.0.0.1"]}]}`, http.StatusBadRequest},
+	}
+	for i, tc := range tests {
+		r := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.token != "" {
+			r.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		w := httptest.NewRecorder()
+		a.ServeHTTP(w, r)
+		if w.Code != tc.want {
+			t.Errorf("Test %d: Expected status %d, got %d: %s", i, tc.want, w.Code, w.Body.String())
+		}
+	}
+	if len(u.changes) != 1 || u.changes[0].Name != "www.example.org." || u.changes[0].TTL != 60 {
+		t.Errorf("Unexpected changes: %+v", u.changes)
+	}
+
+	// Errors of the updater.
+	u.err = ErrInvalid
+	r := httptest.NewRequest(http.MethodPost, "/zones/example.org.", strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer s3cret")
+	w := httptest.NewRecorder()
+	a.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
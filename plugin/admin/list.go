@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"sync"
+)
+
+// list holds the plugins that can be updated, from all server blocks that enable admin.
+type list struct {
+	sync.RWMutex
+	us []entry
+}
+
+type entry struct {
+	u     Updater
+	name  string
+	token []byte // token of the server block the plugin is in.
+}
+
+// Reset resets l.
+func (l *list) Reset() {
+	l.Lock()
+	defer l.Unlock()
+	l.us = nil
+}
+
+// Append adds plugin u, with name, that can be updated by requests with token.
+func (l *list) Append(u Updater, name string, token []byte) {
+	l.Lock()
+	defer l.Unlock()
+	l.us = append(l.us, entry{u: u, name: name, token: token})
+}
+
+// Authorized returns true if token may update any of the plugins in l.
+func (l *list) Authorized(token []byte) bool {
+	l.RLock()
+	defer l.RUnlock()
+	for _, e := range l.us {
+		if subtle.ConstantTimeCompare(e.token, token) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// Update applies changes to zone with the first plugin that serves zone and may be updated with
+// token. It returns the name of that plugin.
+func (l *list) Update(ctx context.Context, token []byte, zone, hostedZone string, changes []Change) (string, error) {
+	l.RLock()
+	us := l.us
+	l.RUnlock()
+
+	for _, e := range us {
+		if subtle.ConstantTimeCompare(e.token, token) != 1 {
+			continue
+		}
+		err := e.u.Update(ctx, zone, hostedZone, changes)
+		if errors.Is(err, ErrNotAuthoritative) {
+			continue
+		}
+		return e.name, err
+	}
+	return "", ErrNotAuthoritative
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net"
+	"os"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
+)
+
+func init() { plugin.Register("admin", setup) }
+
+func setup(c *caddy.Controller) error {
+	a, err := parse(c)
+	if err != nil {
+		return plugin.Error("admin", err)
+	}
+
+	uniqAddr.Set(a.Addr, a.onStartup)
+	c.OnStartup(func() error { uniqAddr.Set(a.Addr, a.onStartup); return nil })
+	c.OnRestartFailed(func() error { uniqAddr.Set(a.Addr, a.onStartup); return nil })
+
+	c.OnStartup(func() error { return uniqAddr.ForEach() })
+	c.OnRestartFailed(func() error { return uniqAddr.ForEach() })
+
+	register := func() error {
+		for _, p := range dnsserver.GetConfig(c).Handlers() {
+			if u, ok := p.(Updater); ok {
+				updaters.Append(u, p.Name(), a.token)
+			}
+		}
+		return nil
+	}
+	c.OnStartup(register)
+	c.OnRestartFailed(register)
+
+	reset := func() error { updaters.Reset(); return nil }
+	c.OnRestart(reset)
+	c.OnRestart(a.onFinalShutdown)
+	c.OnFinalShutdown(reset)
+	c.OnFinalShutdown(a.onFinalShutdown)
+
+	// Don't do AddPlugin, as admin is not *really* a plugin just a separate webserver running.
+	return nil
+}
+
+func parse(c *caddy.Controller) (*admin, error) {
+	a := &admin{Addr: "localhost:8282"}
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		args := c.RemainingArgs()
+		switch len(args) {
+		case 0:
+		case 1:
+			a.Addr = args[0]
+			if _, _, e := net.SplitHostPort(a.Addr); e != nil {
+				return nil, e
+			}
+		default:
+			return nil, c.ArgErr()
+		}
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "token_file":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				token, err := os.ReadFile(args[0])
+				if err != nil {
+					return nil, err
+				}
+				a.token = bytes.TrimSpace(token)
+				if len(a.token) == 0 {
+					return nil, c.Errf("token file %q is empty", args[0])
+				}
+			case "tls":
+				args := c.RemainingArgs()
+				if len(args) < 2 || len(args) > 3 {
+					return nil, c.ArgErr()
+				}
+				tlsConfig, err := pkgtls.NewTLSConfigFromArgs(args...)
+				if err != nil {
+					return nil, err
+				}
+				if len(args) == 3 {
+					// Clients must present a certificate signed by the CA.
+					tlsConfig.ClientCAs = tlsConfig.RootCAs
+					tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+				a.tlsConfig = tlsConfig
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	if a.token == nil {
+		return nil, c.Err("a token_file is required")
+	}
+	return a, nil
+}
@@ -0,0 +1,77 @@
+package admin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupAdmin(t *testing.T) {
+	dir := t.TempDir()
+	token := filepath.Join(dir, "token")
+	if err := os.WriteFile(token, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		addr      string
+	}{
+		{`admin {
+			token_file ` + token + `
+		}`, false, "localhost:8282"},
+		{`admin localhost:1234 {
+			token_file ` + token + `
+		}`, false, "localhost:1234"},
+		{`admin`, true, ""},
+		{`admin bla {
+			token_file ` + token + `
+		}`, true, ""},
+		{`admin localhost:1234 b`, true, ""},
+		{`admin {
+			token_file ` + empty + `
+		}`, true, ""},
+		{`admin {
+			token_file ` + filepath.Join(dir, "missing") + `
+		}`, true, ""},
+		{`admin {
+			token_file
+		}`, true, ""},
+		{`admin {
+			token_file ` + token + `
+			tls cert.pem
+		}`, true, ""},
+		{`admin {
+			token_file ` + token + `
+			blah
+		}`, true, ""},
+	}
+
+	for i, test := range tests {
+		a, err := parse(caddy.NewTestController("dns", test.input))
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			}
+			continue
+		}
+		if a.Addr != test.addr {
+			t.Errorf("Test %d: Expected address %s, got %s", i, test.addr, a.Addr)
+		}
+		if string(a.token) != "s3cret" {
+			t.Errorf("Test %d: Expected token s3cret, got %q", i, a.token)
+		}
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/coredns/coredns/plugin/metrics"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/plugin/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -34,6 +35,7 @@ type (
 
 		ReloadInterval time.Duration
 		upstream       *upstream.Upstream // Upstream for looking up names during the resolution process.
+		update         *file.UpdatePolicy // Dynamic update policy for all loaded zones.
 	}
 )
 
@@ -67,6 +69,17 @@ func (a Auto) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeRefused, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		rcode, changed := z.DynamicUpdate(state, tsig.KeyName(ctx))
+		m := new(dns.Msg)
+		m.SetRcode(r, rcode)
+		w.WriteMsg(m)
+		if changed && a.transfer != nil {
+			go a.transfer.Notify(zone)
+		}
+		return dns.RcodeSuccess, nil
+	}
+
 	answer, ns, extra, result := z.Lookup(ctx, state, qname)
 
 	m := new(dns.Msg)
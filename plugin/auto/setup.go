@@ -10,6 +10,7 @@ import (
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
 	"github.com/coredns/coredns/plugin/metrics"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
@@ -158,12 +159,38 @@ func autoParse(c *caddy.Controller) (Auto, error) {
 				// remove soon
 				c.RemainingArgs() // eat remaining args
 
+			case "update":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return a, c.ArgErr()
+				}
+				if a.loader.update == nil {
+					a.loader.update = &file.UpdatePolicy{}
+				}
+				if err := a.loader.update.AddSources(args); err != nil {
+					return a, c.Err(err.Error())
+				}
+
+			case "update_key":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return a, c.ArgErr()
+				}
+				if a.loader.update == nil {
+					a.loader.update = &file.UpdatePolicy{}
+				}
+				a.loader.update.AddKeys(args)
+
 			default:
 				return Auto{}, c.Errf("unknown property '%s'", c.Val())
 			}
 		}
 	}
 
+	if a.loader.update != nil {
+		config.UpdateZones = append(config.UpdateZones, a.Zones.origins...)
+	}
+
 	if a.loader.ReloadInterval == nilInterval {
 		a.loader.ReloadInterval = 60 * time.Second
 	}
@@ -48,7 +48,22 @@ func TestAutoParse(t *testing.T) {
 			}`,
 			false, "/tmp", "bliep", `(.*)`, 10 * time.Second,
 		},
+		{
+			`auto {
+				directory /tmp
+				update 10.0.0.0/8
+				update_key key.example.org.
+			}`,
+			false, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// errors
+		{
+			`auto {
+				directory /tmp
+				update 10.0.0.0/33
+			}`,
+			true, "/tmp", "${1}", `db\.(.*)`, 60 * time.Second,
+		},
 		// NO_RELOAD has been deprecated.
 		{
 			`auto {
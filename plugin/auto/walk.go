@@ -55,6 +55,7 @@ func (a Auto) Walk() error {
 
 		zo.ReloadInterval = a.loader.ReloadInterval
 		zo.Upstream = a.loader.upstream
+		zo.UpdatePolicy = a.loader.update
 
 		a.Zones.Add(zo, origin, a.transfer)
 
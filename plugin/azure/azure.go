@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
@@ -23,6 +24,8 @@ type zone struct {
 	z       *file.Zone
 	zone    string
 	private bool
+
+	versions map[string]version // The version of each record set by its id, as last listed.
 }
 
 type zones map[string][]*zone
@@ -32,6 +35,8 @@ type Azure struct {
 	zoneNames     []string
 	publicClient  publicdns.RecordSetsClient
 	privateClient privatedns.RecordSetsClient
+	publicWriter  recordSetWriter
+	privateWriter recordSetWriter
 	upstream      *upstream.Upstream
 	zMu           sync.RWMutex
 	zones         zones
@@ -44,6 +49,7 @@ type Azure struct {
 func New(ctx context.Context, publicClient publicdns.RecordSetsClient, privateClient privatedns.RecordSetsClient, keys map[string][]string, accessMap map[string]string) (*Azure, error) {
 	zones := make(map[string][]*zone, len(keys))
 	names := make([]string, len(keys))
+	up := upstream.New()
 	var private bool
 
 	for resourceGroup, znames := range keys {
@@ -65,16 +71,20 @@ func New(ctx context.Context, publicClient publicdns.RecordSetsClient, privateCl
 			if _, ok := zones[fqdn]; !ok {
 				names = append(names, fqdn)
 			}
-			zones[fqdn] = append(zones[fqdn], &zone{id: resourceGroup, zone: name, private: private, z: file.NewZone(fqdn, "")})
+			z := file.NewZone(fqdn, "")
+			z.Upstream = up
+			zones[fqdn] = append(zones[fqdn], &zone{id: resourceGroup, zone: name, private: private, z: z})
 		}
 	}
 
 	return &Azure{
 		publicClient:  publicClient,
 		privateClient: privateClient,
+		publicWriter:  publicWriter{publicClient},
+		privateWriter: privateWriter{privateClient},
 		zones:         zones,
 		zoneNames:     names,
-		upstream:      upstream.New(),
+		upstream:      up,
 	}, nil
 }
 
@@ -108,25 +118,35 @@ func (h *Azure) updateZones(ctx context.Context) error {
 	var publicSet publicdns.RecordSetListResultPage
 	var privateSet privatedns.RecordSetListResultPage
 	errs := make([]string, 0)
-	for zName, z := range h.zones {
-		for i, hostedZone := range z {
-			newZ := file.NewZone(zName, "")
+	for _, z := range h.zones {
+		for _, hostedZone := range z {
+			start := time.Now()
+			var sets []listedSet
 			if hostedZone.private {
 				for privateSet, err = h.privateClient.List(ctx, hostedZone.id, hostedZone.zone, nil, ""); privateSet.NotDone(); err = privateSet.NextWithContext(ctx) {
-					updateZoneFromPrivateResourceSet(privateSet, newZ)
+					for _, result := range *(privateSet.Response().Value) {
+						result := result
+						sets = append(sets, newListedSet(result.ID, result.Etag, result.Type, result.RecordSetProperties.Fqdn, func() []dns.RR { return privateRRs(result) }))
+					}
 				}
 			} else {
 				for publicSet, err = h.publicClient.ListByDNSZone(ctx, hostedZone.id, hostedZone.zone, nil, ""); publicSet.NotDone(); err = publicSet.NextWithContext(ctx) {
-					updateZoneFromPublicResourceSet(publicSet, newZ)
+					for _, result := range *(publicSet.Response().Value) {
+						result := result
+						sets = append(sets, newListedSet(result.ID, result.Etag, result.Type, result.RecordSetProperties.Fqdn, func() []dns.RR { return publicRRs(result) }))
+					}
 				}
 			}
 			if err != nil {
 				errs = append(errs, fmt.Sprintf("failed to list resource records for %v from azure: %v", hostedZone.zone, err))
+				continue
 			}
-			newZ.Upstream = h.upstream
-			h.zMu.Lock()
-			(*z[i]).z = newZ
-			h.zMu.Unlock()
+
+			changed, records := h.applySets(hostedZone, sets)
+			zName := dns.Fqdn(hostedZone.zone)
+			refreshDuration.WithLabelValues(zName, hostedZone.id).Observe(time.Since(start).Seconds())
+			recordCount.WithLabelValues(zName, hostedZone.id).Set(float64(records))
+			rrsetChangeCount.WithLabelValues(zName, hostedZone.id).Add(float64(changed))
 		}
 	}
 
@@ -136,167 +156,241 @@ func (h *Azure) updateZones(ctx context.Context) error {
 	return nil
 }
 
-func updateZoneFromPublicResourceSet(recordSet publicdns.RecordSetListResultPage, newZ *file.Zone) {
-	for _, result := range *(recordSet.Response().Value) {
-		resultFqdn := *(result.RecordSetProperties.Fqdn)
-		resultTTL := uint32(*(result.RecordSetProperties.TTL))
-		if result.RecordSetProperties.ARecords != nil {
-			for _, A := range *(result.RecordSetProperties.ARecords) {
-				a := &dns.A{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: resultTTL},
-					A: net.ParseIP(*(A.Ipv4Address))}
-				newZ.Insert(a)
-			}
-		}
+// listedSet is a record set as listed from azure.
+type listedSet struct {
+	id   string
+	etag string
+	name string
+	typ  uint16
+	rrs  func() []dns.RR // Converts the record set, only called when it changed.
+}
 
-		if result.RecordSetProperties.AaaaRecords != nil {
-			for _, AAAA := range *(result.RecordSetProperties.AaaaRecords) {
-				aaaa := &dns.AAAA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: resultTTL},
-					AAAA: net.ParseIP(*(AAAA.Ipv6Address))}
-				newZ.Insert(aaaa)
-			}
+// version is the version of a record set as last listed from azure.
+type version struct {
+	etag    string
+	name    string
+	typ     uint16
+	records int
+}
+
+func newListedSet(id, etag, typ, fqdn *string, rrs func() []dns.RR) listedSet {
+	s := listedSet{id: stringValue(id), etag: stringValue(etag), name: dns.CanonicalName(stringValue(fqdn)), rrs: rrs}
+	// The type is the last element of the resource type, i.e. Microsoft.Network/dnszones/A.
+	t := stringValue(typ)
+	s.typ = dns.StringToType[t[strings.LastIndex(t, "/")+1:]]
+	if s.id == "" {
+		s.id = s.name + "/" + t
+	}
+	return s
+}
+
+// applySets applies the record sets listed for hostedZone to its zone. Only the record sets whose etag changed
+// since the previous listing are converted, and only the changed RRsets are replaced. It returns the number of
+// RRsets changed and the number of records in the zone.
+func (h *Azure) applySets(hostedZone *zone, sets []listedSet) (changed, records int) {
+	type rrsetKey struct {
+		name string
+		typ  uint16
+	}
+	changes := map[rrsetKey][]dns.RR{}
+	versions := make(map[string]version, len(sets))
+	for _, s := range sets {
+		if v, ok := hostedZone.versions[s.id]; ok && s.etag != "" && v.etag == s.etag {
+			versions[s.id] = v
+			records += v.records
+			continue
+		}
+		rrs := s.rrs()
+		changes[rrsetKey{s.name, s.typ}] = rrs
+		versions[s.id] = version{etag: s.etag, name: s.name, typ: s.typ, records: len(rrs)}
+		records += len(rrs)
+	}
+	for id, v := range hostedZone.versions {
+		if _, ok := versions[id]; !ok {
+			changes[rrsetKey{v.name, v.typ}] = nil
 		}
+	}
 
-		if result.RecordSetProperties.MxRecords != nil {
-			for _, MX := range *(result.RecordSetProperties.MxRecords) {
-				mx := &dns.MX{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: resultTTL},
-					Preference: uint16(*(MX.Preference)),
-					Mx:         dns.Fqdn(*(MX.Exchange))}
-				newZ.Insert(mx)
-			}
+	h.zMu.Lock()
+	for key, rrs := range changes {
+		hostedZone.z.SetRRset(key.name, key.typ, rrs)
+	}
+	h.zMu.Unlock()
+	hostedZone.versions = versions
+	return len(changes), records
+}
+
+// stringValue returns the string s points to, or the empty string.
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// publicRRs returns the RRs in the public record set result.
+func publicRRs(result publicdns.RecordSet) []dns.RR {
+	var rrs []dns.RR
+	resultFqdn := *(result.RecordSetProperties.Fqdn)
+	resultTTL := uint32(*(result.RecordSetProperties.TTL))
+	if result.RecordSetProperties.ARecords != nil {
+		for _, A := range *(result.RecordSetProperties.ARecords) {
+			a := &dns.A{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: resultTTL},
+				A: net.ParseIP(*(A.Ipv4Address))}
+			rrs = append(rrs, a)
 		}
+	}
 
-		if result.RecordSetProperties.PtrRecords != nil {
-			for _, PTR := range *(result.RecordSetProperties.PtrRecords) {
-				ptr := &dns.PTR{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: resultTTL},
-					Ptr: dns.Fqdn(*(PTR.Ptrdname))}
-				newZ.Insert(ptr)
-			}
+	if result.RecordSetProperties.AaaaRecords != nil {
+		for _, AAAA := range *(result.RecordSetProperties.AaaaRecords) {
+			aaaa := &dns.AAAA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: resultTTL},
+				AAAA: net.ParseIP(*(AAAA.Ipv6Address))}
+			rrs = append(rrs, aaaa)
 		}
+	}
 
-		if result.RecordSetProperties.SrvRecords != nil {
-			for _, SRV := range *(result.RecordSetProperties.SrvRecords) {
-				srv := &dns.SRV{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: resultTTL},
-					Priority: uint16(*(SRV.Priority)),
-					Weight:   uint16(*(SRV.Weight)),
-					Port:     uint16(*(SRV.Port)),
-					Target:   dns.Fqdn(*(SRV.Target))}
-				newZ.Insert(srv)
-			}
+	if result.RecordSetProperties.MxRecords != nil {
+		for _, MX := range *(result.RecordSetProperties.MxRecords) {
+			mx := &dns.MX{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: resultTTL},
+				Preference: uint16(*(MX.Preference)),
+				Mx:         dns.Fqdn(*(MX.Exchange))}
+			rrs = append(rrs, mx)
 		}
+	}
 
-		if result.RecordSetProperties.TxtRecords != nil {
-			for _, TXT := range *(result.RecordSetProperties.TxtRecords) {
-				txt := &dns.TXT{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: resultTTL},
-					Txt: *(TXT.Value)}
-				newZ.Insert(txt)
-			}
+	if result.RecordSetProperties.PtrRecords != nil {
+		for _, PTR := range *(result.RecordSetProperties.PtrRecords) {
+			ptr := &dns.PTR{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: resultTTL},
+				Ptr: dns.Fqdn(*(PTR.Ptrdname))}
+			rrs = append(rrs, ptr)
 		}
+	}
 
-		if result.RecordSetProperties.NsRecords != nil {
-			for _, NS := range *(result.RecordSetProperties.NsRecords) {
-				ns := &dns.NS{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: resultTTL},
-					Ns: *(NS.Nsdname)}
-				newZ.Insert(ns)
-			}
+	if result.RecordSetProperties.SrvRecords != nil {
+		for _, SRV := range *(result.RecordSetProperties.SrvRecords) {
+			srv := &dns.SRV{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: resultTTL},
+				Priority: uint16(*(SRV.Priority)),
+				Weight:   uint16(*(SRV.Weight)),
+				Port:     uint16(*(SRV.Port)),
+				Target:   dns.Fqdn(*(SRV.Target))}
+			rrs = append(rrs, srv)
 		}
+	}
 
-		if result.RecordSetProperties.SoaRecord != nil {
-			SOA := result.RecordSetProperties.SoaRecord
-			soa := &dns.SOA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: resultTTL},
-				Minttl:  uint32(*(SOA.MinimumTTL)),
-				Expire:  uint32(*(SOA.ExpireTime)),
-				Retry:   uint32(*(SOA.RetryTime)),
-				Refresh: uint32(*(SOA.RefreshTime)),
-				Serial:  uint32(*(SOA.SerialNumber)),
-				Mbox:    dns.Fqdn(*(SOA.Email)),
-				Ns:      *(SOA.Host)}
-			newZ.Insert(soa)
+	if result.RecordSetProperties.TxtRecords != nil {
+		for _, TXT := range *(result.RecordSetProperties.TxtRecords) {
+			txt := &dns.TXT{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: resultTTL},
+				Txt: *(TXT.Value)}
+			rrs = append(rrs, txt)
 		}
+	}
 
-		if result.RecordSetProperties.CnameRecord != nil {
-			CNAME := result.RecordSetProperties.CnameRecord.Cname
-			cname := &dns.CNAME{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: resultTTL},
-				Target: dns.Fqdn(*CNAME)}
-			newZ.Insert(cname)
+	if result.RecordSetProperties.NsRecords != nil {
+		for _, NS := range *(result.RecordSetProperties.NsRecords) {
+			ns := &dns.NS{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: resultTTL},
+				Ns: *(NS.Nsdname)}
+			rrs = append(rrs, ns)
 		}
 	}
+
+	if result.RecordSetProperties.SoaRecord != nil {
+		SOA := result.RecordSetProperties.SoaRecord
+		soa := &dns.SOA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: resultTTL},
+			Minttl:  uint32(*(SOA.MinimumTTL)),
+			Expire:  uint32(*(SOA.ExpireTime)),
+			Retry:   uint32(*(SOA.RetryTime)),
+			Refresh: uint32(*(SOA.RefreshTime)),
+			Serial:  uint32(*(SOA.SerialNumber)),
+			Mbox:    dns.Fqdn(*(SOA.Email)),
+			Ns:      *(SOA.Host)}
+		rrs = append(rrs, soa)
+	}
+
+	if result.RecordSetProperties.CnameRecord != nil {
+		CNAME := result.RecordSetProperties.CnameRecord.Cname
+		cname := &dns.CNAME{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: resultTTL},
+			Target: dns.Fqdn(*CNAME)}
+		rrs = append(rrs, cname)
+	}
+	return rrs
 }
 
-func updateZoneFromPrivateResourceSet(recordSet privatedns.RecordSetListResultPage, newZ *file.Zone) {
-	for _, result := range *(recordSet.Response().Value) {
-		resultFqdn := *(result.RecordSetProperties.Fqdn)
-		resultTTL := uint32(*(result.RecordSetProperties.TTL))
-		if result.RecordSetProperties.ARecords != nil {
-			for _, A := range *(result.RecordSetProperties.ARecords) {
-				a := &dns.A{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: resultTTL},
-					A: net.ParseIP(*(A.Ipv4Address))}
-				newZ.Insert(a)
-			}
+// privateRRs returns the RRs in the private record set result.
+func privateRRs(result privatedns.RecordSet) []dns.RR {
+	var rrs []dns.RR
+	resultFqdn := *(result.RecordSetProperties.Fqdn)
+	resultTTL := uint32(*(result.RecordSetProperties.TTL))
+	if result.RecordSetProperties.ARecords != nil {
+		for _, A := range *(result.RecordSetProperties.ARecords) {
+			a := &dns.A{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: resultTTL},
+				A: net.ParseIP(*(A.Ipv4Address))}
+			rrs = append(rrs, a)
 		}
-		if result.RecordSetProperties.AaaaRecords != nil {
-			for _, AAAA := range *(result.RecordSetProperties.AaaaRecords) {
-				aaaa := &dns.AAAA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: resultTTL},
-					AAAA: net.ParseIP(*(AAAA.Ipv6Address))}
-				newZ.Insert(aaaa)
-			}
+	}
+	if result.RecordSetProperties.AaaaRecords != nil {
+		for _, AAAA := range *(result.RecordSetProperties.AaaaRecords) {
+			aaaa := &dns.AAAA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeAAAA, Class: dns.ClassINET, Ttl: resultTTL},
+				AAAA: net.ParseIP(*(AAAA.Ipv6Address))}
+			rrs = append(rrs, aaaa)
 		}
+	}
 
-		if result.RecordSetProperties.MxRecords != nil {
-			for _, MX := range *(result.RecordSetProperties.MxRecords) {
-				mx := &dns.MX{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: resultTTL},
-					Preference: uint16(*(MX.Preference)),
-					Mx:         dns.Fqdn(*(MX.Exchange))}
-				newZ.Insert(mx)
-			}
+	if result.RecordSetProperties.MxRecords != nil {
+		for _, MX := range *(result.RecordSetProperties.MxRecords) {
+			mx := &dns.MX{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeMX, Class: dns.ClassINET, Ttl: resultTTL},
+				Preference: uint16(*(MX.Preference)),
+				Mx:         dns.Fqdn(*(MX.Exchange))}
+			rrs = append(rrs, mx)
 		}
+	}
 
-		if result.RecordSetProperties.PtrRecords != nil {
-			for _, PTR := range *(result.RecordSetProperties.PtrRecords) {
-				ptr := &dns.PTR{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: resultTTL},
-					Ptr: dns.Fqdn(*(PTR.Ptrdname))}
-				newZ.Insert(ptr)
-			}
+	if result.RecordSetProperties.PtrRecords != nil {
+		for _, PTR := range *(result.RecordSetProperties.PtrRecords) {
+			ptr := &dns.PTR{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypePTR, Class: dns.ClassINET, Ttl: resultTTL},
+				Ptr: dns.Fqdn(*(PTR.Ptrdname))}
+			rrs = append(rrs, ptr)
 		}
+	}
 
-		if result.RecordSetProperties.SrvRecords != nil {
-			for _, SRV := range *(result.RecordSetProperties.SrvRecords) {
-				srv := &dns.SRV{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: resultTTL},
-					Priority: uint16(*(SRV.Priority)),
-					Weight:   uint16(*(SRV.Weight)),
-					Port:     uint16(*(SRV.Port)),
-					Target:   dns.Fqdn(*(SRV.Target))}
-				newZ.Insert(srv)
-			}
+	if result.RecordSetProperties.SrvRecords != nil {
+		for _, SRV := range *(result.RecordSetProperties.SrvRecords) {
+			srv := &dns.SRV{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: resultTTL},
+				Priority: uint16(*(SRV.Priority)),
+				Weight:   uint16(*(SRV.Weight)),
+				Port:     uint16(*(SRV.Port)),
+				Target:   dns.Fqdn(*(SRV.Target))}
+			rrs = append(rrs, srv)
 		}
+	}
 
-		if result.RecordSetProperties.TxtRecords != nil {
-			for _, TXT := range *(result.RecordSetProperties.TxtRecords) {
-				txt := &dns.TXT{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: resultTTL},
-					Txt: *(TXT.Value)}
-				newZ.Insert(txt)
-			}
+	if result.RecordSetProperties.TxtRecords != nil {
+		for _, TXT := range *(result.RecordSetProperties.TxtRecords) {
+			txt := &dns.TXT{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: resultTTL},
+				Txt: *(TXT.Value)}
+			rrs = append(rrs, txt)
 		}
+	}
 
-		if result.RecordSetProperties.SoaRecord != nil {
-			SOA := result.RecordSetProperties.SoaRecord
-			soa := &dns.SOA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: resultTTL},
-				Minttl:  uint32(*(SOA.MinimumTTL)),
-				Expire:  uint32(*(SOA.ExpireTime)),
-				Retry:   uint32(*(SOA.RetryTime)),
-				Refresh: uint32(*(SOA.RefreshTime)),
-				Serial:  uint32(*(SOA.SerialNumber)),
-				Mbox:    dns.Fqdn(*(SOA.Email)),
-				Ns:      dns.Fqdn(*(SOA.Host))}
-			newZ.Insert(soa)
-		}
+	if result.RecordSetProperties.SoaRecord != nil {
+		SOA := result.RecordSetProperties.SoaRecord
+		soa := &dns.SOA{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: resultTTL},
+			Minttl:  uint32(*(SOA.MinimumTTL)),
+			Expire:  uint32(*(SOA.ExpireTime)),
+			Retry:   uint32(*(SOA.RetryTime)),
+			Refresh: uint32(*(SOA.RefreshTime)),
+			Serial:  uint32(*(SOA.SerialNumber)),
+			Mbox:    dns.Fqdn(*(SOA.Email)),
+			Ns:      dns.Fqdn(*(SOA.Host))}
+		rrs = append(rrs, soa)
+	}
 
-		if result.RecordSetProperties.CnameRecord != nil {
-			CNAME := result.RecordSetProperties.CnameRecord.Cname
-			cname := &dns.CNAME{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: resultTTL},
-				Target: dns.Fqdn(*CNAME)}
-			newZ.Insert(cname)
-		}
+	if result.RecordSetProperties.CnameRecord != nil {
+		CNAME := result.RecordSetProperties.CnameRecord.Cname
+		cname := &dns.CNAME{Hdr: dns.RR_Header{Name: resultFqdn, Rrtype: dns.TypeCNAME, Class: dns.ClassINET, Ttl: resultTTL},
+			Target: dns.Fqdn(*CNAME)}
+		rrs = append(rrs, cname)
 	}
+	return rrs
 }
 
 // ServeDNS implements the plugin.Handler interface.
@@ -178,3 +178,60 @@ func TestAzure(t *testing.T) {
 		}
 	}
 }
+
+func TestApplySets(t *testing.T) {
+	h := &Azure{}
+	hz := &zone{zone: "example.org", z: file.NewZone("example.org.", "")}
+
+	converted := 0
+	set := func(name, typ, etag string, rrs ...string) listedSet {
+		id := "/subscriptions/x/resourceGroups/rg/providers/Microsoft.Network/dnszones/example.org/" + typ + "/" + name
+		fqdn := name + ".example.org."
+		typ = "Microsoft.Network/dnszones/" + typ
+		return newListedSet(&id, &etag, &typ, &fqdn, func() []dns.RR {
+			converted++
+			var out []dns.RR
+			for _, s := range rrs {
+				rr, _ := dns.NewRR(s)
+				out = append(out, rr)
+			}
+			return out
+		})
+	}
+
+	changed, records := h.applySets(hz, []listedSet{
+		set("www", "A", "1", "www.example.org. 300 IN A 10.0.0.1", "www.example.org. 300 IN A 10.0.0.2"),
+		set("mail", "MX", "1", "mail.example.org. 300 IN MX 10 mx.example.org."),
+		set("txt", "TXT", "1", `txt.example.org. 300 IN TXT "txt"`),
+	})
+	if changed != 3 || records != 4 || converted != 3 {
+		t.Fatalf("Expected 3 changes, 4 records and 3 conversions, got %d, %d and %d", changed, records, converted)
+	}
+	z := hz.z
+
+	// www changed, txt is deleted and new is created.
+	converted = 0
+	changed, records = h.applySets(hz, []listedSet{
+		set("www", "A", "2", "www.example.org. 300 IN A 10.0.0.3"),
+		set("mail", "MX", "1", "mail.example.org. 300 IN MX 10 mx.example.org."),
+		set("new", "AAAA", "1", "new.example.org. 300 IN AAAA ::1"),
+	})
+	if changed != 3 || records != 3 || converted != 2 {
+		t.Errorf("Expected 3 changes, 3 records and 2 conversions, got %d, %d and %d", changed, records, converted)
+	}
+	if hz.z != z {
+		t.Error("Expected the zone to be updated in place")
+	}
+	if rrs := z.RRset("www.example.org.", dns.TypeA); len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "10.0.0.3" {
+		t.Errorf("Expected www.example.org. A to be replaced, got %v", rrs)
+	}
+	if rrs := z.RRset("txt.example.org.", dns.TypeTXT); len(rrs) != 0 {
+		t.Errorf("Expected txt.example.org. TXT to be deleted, got %v", rrs)
+	}
+	if rrs := z.RRset("new.example.org.", dns.TypeAAAA); len(rrs) != 1 {
+		t.Errorf("Expected new.example.org. AAAA to be created, got %v", rrs)
+	}
+	if rrs := z.RRset("mail.example.org.", dns.TypeMX); len(rrs) != 1 {
+		t.Errorf("Expected mail.example.org. MX to be kept, got %v", rrs)
+	}
+}
@@ -0,0 +1,35 @@
+package azure
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// refreshDuration is the time taken by each refresh of a hosted zone.
+	refreshDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "refresh_duration_seconds",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14), // from 10ms to 82 seconds
+		Help:      "Histogram of the time (in seconds) each refresh of a hosted zone took.",
+	}, []string{"zone", "hosted_zone"})
+
+	// recordCount is the number of records in a hosted zone.
+	recordCount = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "records",
+		Help:      "The number of records in a hosted zone.",
+	}, []string{"zone", "hosted_zone"})
+
+	// rrsetChangeCount is the number of RRsets changed by refreshes of a hosted zone.
+	rrsetChangeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "azure",
+		Name:      "rrset_changes_total",
+		Help:      "Counter of the RRsets created, changed or deleted by refreshes of a hosted zone.",
+	}, []string{"zone", "hosted_zone"})
+)
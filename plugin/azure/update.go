@@ -0,0 +1,207 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/admin"
+
+	publicdns "github.com/Azure/azure-sdk-for-go/profiles/latest/dns/mgmt/dns"
+	privatedns "github.com/Azure/azure-sdk-for-go/profiles/latest/privatedns/mgmt/privatedns"
+	"github.com/miekg/dns"
+)
+
+// recordSetWriter changes the record sets of a zone in azure.
+type recordSetWriter interface {
+	// upsert creates or replaces the record set name, which is relative to the zone.
+	upsert(ctx context.Context, resourceGroup, zone, name string, typ uint16, ttl uint32, rrs []dns.RR) error
+	// delete deletes the record set name, which is relative to the zone.
+	delete(ctx context.Context, resourceGroup, zone, name string, typ uint16) error
+}
+
+// Update implements the admin.Updater interface. The hosted zone is selected by its resource group.
+func (h *Azure) Update(ctx context.Context, zName, hostedZone string, changes []admin.Change) error {
+	z, ok := h.zones[zName]
+	if !ok {
+		return admin.ErrNotAuthoritative
+	}
+	ids := make([]string, len(z))
+	for i, hz := range z {
+		ids[i] = hz.id
+	}
+	i, err := admin.HostedZone(zName, hostedZone, ids)
+	if err != nil {
+		return err
+	}
+
+	w := h.publicWriter
+	if z[i].private {
+		w = h.privateWriter
+	}
+	for j, c := range changes {
+		name := "@"
+		if c.Name != zName {
+			name = strings.TrimSuffix(c.Name, "."+zName)
+		}
+		switch c.Action {
+		case admin.Upsert:
+			err = w.upsert(ctx, z[i].id, z[i].zone, name, c.Type, c.TTL, c.RRs)
+		case admin.Delete:
+			err = w.delete(ctx, z[i].id, z[i].zone, name, c.Type)
+		}
+		if err != nil {
+			// Azure has no batches, apply the changes that were made.
+			h.zMu.Lock()
+			z[i].z = admin.Apply(zName, z[i].z, changes[:j])
+			h.zMu.Unlock()
+			return fmt.Errorf("failed to change %s %s of %v in azure: %w", c.Name, dns.TypeToString[c.Type], z[i].zone, err)
+		}
+	}
+
+	h.zMu.Lock()
+	z[i].z = admin.Apply(zName, z[i].z, changes)
+	h.zMu.Unlock()
+	return nil
+}
+
+type publicWriter struct{ client publicdns.RecordSetsClient }
+
+func (p publicWriter) upsert(ctx context.Context, resourceGroup, zone, name string, typ uint16, ttl uint32, rrs []dns.RR) error {
+	t := int64(ttl)
+	props := &publicdns.RecordSetProperties{TTL: &t}
+	switch typ {
+	case dns.TypeA:
+		records := []publicdns.ARecord{}
+		for _, rr := range rrs {
+			ip := rr.(*dns.A).A.String()
+			records = append(records, publicdns.ARecord{Ipv4Address: &ip})
+		}
+		props.ARecords = &records
+	case dns.TypeAAAA:
+		records := []publicdns.AaaaRecord{}
+		for _, rr := range rrs {
+			ip := rr.(*dns.AAAA).AAAA.String()
+			records = append(records, publicdns.AaaaRecord{Ipv6Address: &ip})
+		}
+		props.AaaaRecords = &records
+	case dns.TypeCNAME:
+		if len(rrs) != 1 {
+			return fmt.Errorf("%w: only one CNAME record allowed", admin.ErrInvalid)
+		}
+		target := rrs[0].(*dns.CNAME).Target
+		props.CnameRecord = &publicdns.CnameRecord{Cname: &target}
+	case dns.TypeMX:
+		records := []publicdns.MxRecord{}
+		for _, rr := range rrs {
+			pref, mx := int32(rr.(*dns.MX).Preference), rr.(*dns.MX).Mx
+			records = append(records, publicdns.MxRecord{Preference: &pref, Exchange: &mx})
+		}
+		props.MxRecords = &records
+	case dns.TypeNS:
+		records := []publicdns.NsRecord{}
+		for _, rr := range rrs {
+			ns := rr.(*dns.NS).Ns
+			records = append(records, publicdns.NsRecord{Nsdname: &ns})
+		}
+		props.NsRecords = &records
+	case dns.TypePTR:
+		records := []publicdns.PtrRecord{}
+		for _, rr := range rrs {
+			ptr := rr.(*dns.PTR).Ptr
+			records = append(records, publicdns.PtrRecord{Ptrdname: &ptr})
+		}
+		props.PtrRecords = &records
+	case dns.TypeSRV:
+		records := []publicdns.SrvRecord{}
+		for _, rr := range rrs {
+			srv := rr.(*dns.SRV)
+			prio, weight, port, target := int32(srv.Priority), int32(srv.Weight), int32(srv.Port), srv.Target
+			records = append(records, publicdns.SrvRecord{Priority: &prio, Weight: &weight, Port: &port, Target: &target})
+		}
+		props.SrvRecords = &records
+	case dns.TypeTXT:
+		records := []publicdns.TxtRecord{}
+		for _, rr := range rrs {
+			txt := rr.(*dns.TXT).Txt
+			records = append(records, publicdns.TxtRecord{Value: &txt})
+		}
+		props.TxtRecords = &records
+	default:
+		return fmt.Errorf("%w: type %s is not supported", admin.ErrInvalid, dns.TypeToString[typ])
+	}
+	_, err := p.client.CreateOrUpdate(ctx, resourceGroup, zone, name, publicdns.RecordType(dns.TypeToString[typ]), publicdns.RecordSet{RecordSetProperties: props}, "", "")
+	return err
+}
+
+func (p publicWriter) delete(ctx context.Context, resourceGroup, zone, name string, typ uint16) error {
+	_, err := p.client.Delete(ctx, resourceGroup, zone, name, publicdns.RecordType(dns.TypeToString[typ]), "")
+	return err
+}
+
+type privateWriter struct{ client privatedns.RecordSetsClient }
+
+func (p privateWriter) upsert(ctx context.Context, resourceGroup, zone, name string, typ uint16, ttl uint32, rrs []dns.RR) error {
+	t := int64(ttl)
+	props := &privatedns.RecordSetProperties{TTL: &t}
+	switch typ {
+	case dns.TypeA:
+		records := []privatedns.ARecord{}
+		for _, rr := range rrs {
+			ip := rr.(*dns.A).A.String()
+			records = append(records, privatedns.ARecord{Ipv4Address: &ip})
+		}
+		props.ARecords = &records
+	case dns.TypeAAAA:
+		records := []privatedns.AaaaRecord{}
+		for _, rr := range rrs {
+			ip := rr.(*dns.AAAA).AAAA.String()
+			records = append(records, privatedns.AaaaRecord{Ipv6Address: &ip})
+		}
+		props.AaaaRecords = &records
+	case dns.TypeCNAME:
+		if len(rrs) != 1 {
+			return fmt.Errorf("%w: only one CNAME record allowed", admin.ErrInvalid)
+		}
+		target := rrs[0].(*dns.CNAME).Target
+		props.CnameRecord = &privatedns.CnameRecord{Cname: &target}
+	case dns.TypeMX:
+		records := []privatedns.MxRecord{}
+		for _, rr := range rrs {
+			pref, mx := int32(rr.(*dns.MX).Preference), rr.(*dns.MX).Mx
+			records = append(records, privatedns.MxRecord{Preference: &pref, Exchange: &mx})
+		}
+		props.MxRecords = &records
+	case dns.TypePTR:
+		records := []privatedns.PtrRecord{}
+		for _, rr := range rrs {
+			ptr := rr.(*dns.PTR).Ptr
+			records = append(records, privatedns.PtrRecord{Ptrdname: &ptr})
+		}
+		props.PtrRecords = &records
+	case dns.TypeSRV:
+		records := []privatedns.SrvRecord{}
+		for _, rr := range rrs {
+			srv := rr.(*dns.SRV)
+			prio, weight, port, target := int32(srv.Priority), int32(srv.Weight), int32(srv.Port), srv.Target
+			records = append(records, privatedns.SrvRecord{Priority: &prio, Weight: &weight, Port: &port, Target: &target})
+		}
+		props.SrvRecords = &records
+	case dns.TypeTXT:
+		records := []privatedns.TxtRecord{}
+		for _, rr := range rrs {
+			txt := rr.(*dns.TXT).Txt
+			records = append(records, privatedns.TxtRecord{Value: &txt})
+		}
+		props.TxtRecords = &records
+	default:
+		return fmt.Errorf("%w: type %s is not supported in private zones", admin.ErrInvalid, dns.TypeToString[typ])
+	}
+	_, err := p.client.CreateOrUpdate(ctx, resourceGroup, zone, privatedns.RecordType(dns.TypeToString[typ]), name, privatedns.RecordSet{RecordSetProperties: props}, "", "")
+	return err
+}
+
+func (p privateWriter) delete(ctx context.Context, resourceGroup, zone, name string, typ uint16) error {
+	_, err := p.client.Delete(ctx, resourceGroup, zone, privatedns.RecordType(dns.TypeToString[typ]), name, "")
+	return err
+}
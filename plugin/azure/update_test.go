@@ -0,0 +1,82 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/admin"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+type fakeWriter struct {
+	upserts, deletes []string
+	fail             string // name to fail the change of.
+}
+
+func (f *fakeWriter) upsert(_ context.Context, resourceGroup, zone, name string, typ uint16, _ uint32, _ []dns.RR) error {
+	if name == f.fail {
+		return errors.New("failed")
+	}
+	f.upserts = append(f.upserts, resourceGroup+"/"+zone+"/"+name+"/"+dns.TypeToString[typ])
+	return nil
+}
+
+func (f *fakeWriter) delete(_ context.Context, resourceGroup, zone, name string, typ uint16) error {
+	if name == f.fail {
+		return errors.New("failed")
+	}
+	f.deletes = append(f.deletes, resourceGroup+"/"+zone+"/"+name+"/"+dns.TypeToString[typ])
+	return nil
+}
+
+func TestUpdate(t *testing.T) {
+	z := testZones()
+	z["example.org."][0].id = "rg"
+	w := &fakeWriter{}
+	h := &Azure{zones: z, publicWriter: w}
+
+	changes := []admin.Change{
+		{Action: admin.Upsert, Name: "new.example.org.", Type: dns.TypeA, TTL: 60, RRs: []dns.RR{test.A("new.example.org. 60 IN A 10.0.0.1")}},
+		{Action: admin.Delete, Name: "example.org.", Type: dns.TypeAAAA},
+	}
+	if err := h.Update(context.TODO(), "example.com.", "", changes); !errors.Is(err, admin.ErrNotAuthoritative) {
+		t.Errorf("Expected ErrNotAuthoritative, got %v", err)
+	}
+	if err := h.Update(context.TODO(), "example.org.", "other-rg", changes); !errors.Is(err, admin.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+	if err := h.Update(context.TODO(), "example.org.", "", changes); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(w.upserts) != 1 || w.upserts[0] != "rg/example.org./new/A" {
+		t.Errorf("Unexpected upserts: %v", w.upserts)
+	}
+	if len(w.deletes) != 1 || w.deletes[0] != "rg/example.org./@/AAAA" {
+		t.Errorf("Unexpected deletes: %v", w.deletes)
+	}
+	if rrs := admin.RRset("example.org.", z["example.org."][0].z, "new.example.org.", dns.TypeA); len(rrs) != 1 {
+		t.Errorf("Expected new.example.org. A in the zone, got %v", rrs)
+	}
+	if rrs := admin.RRset("example.org.", z["example.org."][0].z, "example.org.", dns.TypeAAAA); len(rrs) != 0 {
+		t.Errorf("Expected example.org. AAAA to be deleted, got %v", rrs)
+	}
+
+	// A failed change stops the update, the changes made before it are kept.
+	w.fail = "txt"
+	changes = []admin.Change{
+		{Action: admin.Delete, Name: "mail.example.org.", Type: dns.TypeMX},
+		{Action: admin.Delete, Name: "txt.example.org.", Type: dns.TypeTXT},
+	}
+	if err := h.Update(context.TODO(), "example.org.", "", changes); err == nil {
+		t.Fatal("Expected error, got none")
+	}
+	if rrs := admin.RRset("example.org.", z["example.org."][0].z, "mail.example.org.", dns.TypeMX); len(rrs) != 0 {
+		t.Errorf("Expected mail.example.org. MX to be deleted, got %v", rrs)
+	}
+	if rrs := admin.RRset("example.org.", z["example.org."][0].z, "txt.example.org.", dns.TypeTXT); len(rrs) != 1 {
+		t.Errorf("Expected txt.example.org. TXT to be kept, got %v", rrs)
+	}
+}
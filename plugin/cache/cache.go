@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/cache"
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/nocache"
 	"github.com/coredns/coredns/plugin/pkg/response"
 	"github.com/coredns/coredns/request"
 
@@ -51,6 +52,13 @@ type Cache struct {
 	// Keep ttl option
 	keepttl bool
 
+	// Maximum number of responses per name that are scoped to a client subnet.
+	subnets int
+
+	// Snapshot file and the interval to save it.
+	snapshot         string
+	snapshotInterval time.Duration
+
 	// Testing.
 	now func() time.Time
 }
@@ -72,6 +80,7 @@ func New() *Cache {
 		prefetch:   0,
 		duration:   1 * time.Minute,
 		percentage: 10,
+		subnets:    defaultSubnets,
 		now:        time.Now,
 	}
 }
@@ -141,6 +150,7 @@ type ResponseWriter struct {
 	remoteAddr net.Addr
 
 	wildcardFunc func() string // function to retrieve wildcard name that synthesized the result.
+	nocache      *nocache.Flag // set when the response may not be cached.
 
 	pexcept []string // positive zone exceptions
 	nexcept []string // negative zone exceptions
@@ -149,7 +159,7 @@ type ResponseWriter struct {
 // newPrefetchResponseWriter returns a Cache ResponseWriter to be used in
 // prefetch requests. It ensures RemoteAddr() can be called even after the
 // original connection has already been closed.
-func newPrefetchResponseWriter(server string, state request.Request, c *Cache) *ResponseWriter {
+func newPrefetchResponseWriter(server string, state request.Request, c *Cache, nc *nocache.Flag) *ResponseWriter {
 	// Resolve the address now, the connection might be already closed when the
 	// actual prefetch request is made.
 	addr := state.W.RemoteAddr()
@@ -169,6 +179,7 @@ func newPrefetchResponseWriter(server string, state request.Request, c *Cache) *
 		cd:             state.Req.CheckingDisabled,
 		prefetch:       true,
 		remoteAddr:     addr,
+		nocache:        nc,
 	}
 }
 
@@ -186,6 +197,9 @@ func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
 
 	// key returns empty string for anything we don't want to cache.
 	hasKey, key := key(w.state.Name(), res, mt, w.do, w.cd)
+	if _, ok := responseSubnet(res); !ok || w.nocache.IsSet() {
+		hasKey = false
+	}
 
 	msgTTL := dnsutil.MinimalTTL(res, mt)
 	var duration time.Duration
@@ -240,6 +254,10 @@ func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration
 		if w.wildcardFunc != nil {
 			i.wildcard = w.wildcardFunc()
 		}
+		if i.subnet != nil {
+			w.setSubnet(w.pcache, i, Success)
+			return
+		}
 		if w.pcache.Add(key, i) {
 			evictions.WithLabelValues(w.server, Success, w.zonesMetricLabel, w.viewMetricLabel).Inc()
 		}
@@ -257,6 +275,10 @@ func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration
 		if w.wildcardFunc != nil {
 			i.wildcard = w.wildcardFunc()
 		}
+		if i.subnet != nil {
+			w.setSubnet(w.ncache, i, Denial)
+			return
+		}
 		if w.ncache.Add(key, i) {
 			evictions.WithLabelValues(w.server, Denial, w.zonesMetricLabel, w.viewMetricLabel).Inc()
 		}
@@ -268,6 +290,18 @@ func (w *ResponseWriter) set(m *dns.Msg, key uint64, mt response.Type, duration
 	}
 }
 
+// setSubnet stores the item i, that is scoped to a client subnet, in ca. Scoped responses aren't cached
+// at all when the number of subnets per name is set to 0.
+func (w *ResponseWriter) setSubnet(ca *cache.Cache, i *item, class string) {
+	if w.subnets == 0 {
+		return
+	}
+	k := subnetHash(w.state.Name(), i.QType, w.do, w.cd)
+	if addSubnet(ca, k, i, w.subnets, w.now()) {
+		evictions.WithLabelValues(w.server, class, w.zonesMetricLabel, w.viewMetricLabel).Inc()
+	}
+}
+
 // Write implements the dns.ResponseWriter interface.
 func (w *ResponseWriter) Write(buf []byte) (int, error) {
 	log.Warning("Caching called with Write: not caching reply")
@@ -311,7 +345,10 @@ const (
 	maxNTTL = dnsutil.MaximumDefaulTTL / 2
 	minNTTL = dnsutil.MinimalDefaultTTL
 
-	defaultCap = 10000 // default capacity of the cache.
+	defaultCap     = 10000 // default capacity of the cache.
+	defaultSubnets = 32    // default number of client subnets cached per name.
+
+	defaultSnapshotInterval = 5 * time.Minute // default interval to save a snapshot of the cache.
 
 	// Success is the class for caching positive caching.
 	Success = "success"
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// subnets holds the cached responses for a name that are scoped to a client subnet with an EDNS0 Client
+// Subnet option (RFC 7871). They are stored in the cache under subnetHash, next to the response that
+// is valid for all clients, if any.
+type subnets struct {
+	sync.Mutex
+	items []*item // all have a subnet
+}
+
+// subnetHash returns the key under which the scoped responses for qname are stored.
+func subnetHash(qname string, qtype uint16, do, cd bool) uint64 {
+	// A NUL byte can't be part of a name in presentation format, so this can't clash with hash.
+	return hash("\x00"+qname, qtype, do, cd)
+}
+
+// responseSubnet returns the subnet the response m is valid for: the address in its ECS option with the
+// scope prefix length. Nil is returned when m isn't scoped, i.e. it has no ECS option or a scope of 0.
+// The boolean is false when m is scoped, but the family of the option doesn't match its address. Such a
+// response must not be cached, as it would be used for all clients.
+func responseSubnet(m *dns.Msg) (*net.IPNet, bool) {
+	o := m.IsEdns0()
+	if o == nil {
+		return nil, true
+	}
+	for _, opt := range o.Option {
+		e, ok := opt.(*dns.EDNS0_SUBNET)
+		if !ok || e.SourceScope == 0 {
+			continue
+		}
+		var (
+			bits int
+			ip   net.IP
+		)
+		switch e.Family {
+		case 1:
+			bits, ip = net.IPv4len*8, e.Address.To4()
+		case 2:
+			if e.Address.To4() == nil {
+				bits, ip = net.IPv6len*8, e.Address.To16()
+			}
+		}
+		if ip == nil {
+			return nil, false
+		}
+		// A scope longer than the source prefix length can only be used for this source prefix.
+		ones := int(e.SourceScope)
+		if int(e.SourceNetmask) < ones {
+			ones = int(e.SourceNetmask)
+		}
+		if ones > bits {
+			ones = bits
+		}
+		mask := net.CIDRMask(ones, bits)
+		return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, true
+	}
+	return nil, true
+}
+
+// clientSubnet returns the address of the client to match scoped responses against, and the number of
+// significant bits in it. If the query has an ECS option that is used, otherwise the address the query
+// came from. A nil address means that only responses for all clients may be used.
+func clientSubnet(state request.Request) (net.IP, int) {
+	if o := state.Req.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+				if e.SourceNetmask == 0 {
+					return nil, 0 // the client opted out
+				}
+				return e.Address, int(e.SourceNetmask)
+			}
+		}
+	}
+	ip := net.ParseIP(state.IP())
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4, net.IPv4len * 8
+	}
+	return ip, net.IPv6len * 8
+}
+
+// get returns the item with the longest subnet that contains ip, the subnet can't be longer than the
+// source prefix length of ip.
+func (s *subnets) get(ip net.IP, source int) *item {
+	s.Lock()
+	defer s.Unlock()
+
+	var (
+		best *item
+		max  = -1
+	)
+	for _, i := range s.items {
+		ones, _ := i.subnet.Mask.Size()
+		if ones > source || ones <= max || !i.subnet.Contains(ip) {
+			continue
+		}
+		best, max = i, ones
+	}
+	return best
+}
+
+// add adds item i, replacing the item for the same subnet. If there are already capacity items, the one
+// that expires first is evicted, in which case true is returned.
+func (s *subnets) add(i *item, capacity int, now time.Time) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	for j, x := range s.items {
+		if x.subnet.String() == i.subnet.String() {
+			s.items[j] = i
+			return false
+		}
+	}
+	if len(s.items) < capacity {
+		s.items = append(s.items, i)
+		return false
+	}
+
+	first := 0
+	for j, x := range s.items {
+		if x.ttl(now) < s.items[first].ttl(now) {
+			first = j
+		}
+	}
+	s.items[first] = i
+	return true
+}
+
+// getSubnet returns the scoped item in ca under key k for the client in state.
+func getSubnet(ca *cache.Cache, k uint64, state request.Request) *item {
+	e, ok := ca.Get(k)
+	if !ok {
+		return nil
+	}
+	s, ok := e.(*subnets)
+	if !ok {
+		return nil
+	}
+	ip, source := clientSubnet(state)
+	if ip == nil {
+		return nil
+	}
+	return s.get(ip, source)
+}
+
+// addSubnet adds the scoped item i to ca under key k. The boolean is true if an item, or all items for
+// another name, had to be evicted.
+func addSubnet(ca *cache.Cache, k uint64, i *item, capacity int, now time.Time) bool {
+	if e, ok := ca.Get(k); ok {
+		if s, ok := e.(*subnets); ok {
+			return s.add(i, capacity, now)
+		}
+	}
+	s := &subnets{items: []*item{i}}
+	return ca.Add(k, s)
+}
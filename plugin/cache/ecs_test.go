@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// ecsBackend answers with the /24 of the client subnet in the query as the address, scoped to that /24.
+// Queries without ECS get a global answer.
+func ecsBackend(queries *int) plugin.Handler {
+	return plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		*queries++
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Response, m.RecursionAvailable = true, true
+
+		addr := "192.0.2.1"
+		if o := r.IsEdns0(); o != nil {
+			for _, opt := range o.Option {
+				if e, ok := opt.(*dns.EDNS0_SUBNET); ok {
+					addr = e.Address.Mask(net.CIDRMask(24, 32)).String()
+					m.SetEdns0(4096, false)
+					m.Extra[0].(*dns.OPT).Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET,
+						Family: 1, SourceNetmask: e.SourceNetmask, SourceScope: 24, Address: e.Address}}
+				}
+			}
+		}
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A " + addr)}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+}
+
+func ecsQuery(subnet string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if subnet == "" {
+		return m
+	}
+	_, ipnet, _ := net.ParseCIDR(subnet)
+	ones, _ := ipnet.Mask.Size()
+	m.SetEdns0(4096, false)
+	m.Extra[0].(*dns.OPT).Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET,
+		Family: 1, SourceNetmask: uint8(ones), Address: ipnet.IP}}
+	return m
+}
+
+func TestCacheClientSubnet(t *testing.T) {
+	queries := 0
+	c := New()
+	c.Next = ecsBackend(&queries)
+
+	tests := []struct {
+		subnet  string
+		answer  string
+		queries int // total number of queries sent to the backend after this one
+	}{
+		{"10.0.1.0/24", "10.0.1.0", 1},
+		{"10.0.1.0/24", "10.0.1.0", 1},
+		{"10.0.2.0/24", "10.0.2.0", 2},
+		{"10.0.1.0/24", "10.0.1.0", 2},
+		// a longer source prefix is in the cached /24
+		{"10.0.2.128/25", "10.0.2.0", 2},
+		// a shorter one isn't
+		{"10.0.0.0/16", "10.0.0.0", 3},
+		// no ECS, the client address 10.240.0.1 is used to look up the scoped answers
+		{"", "192.0.2.1", 4},
+		{"", "192.0.2.1", 4},
+	}
+
+	for i, tc := range tests {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c.ServeDNS(context.TODO(), rec, ecsQuery(tc.subnet))
+		if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Test %d: expected one answer, got %v", i, rec.Msg)
+		}
+		if got := rec.Msg.Answer[0].(*dns.A).A.String(); got != tc.answer {
+			t.Errorf("Test %d: expected answer %s, got %s", i, tc.answer, got)
+		}
+		if queries != tc.queries {
+			t.Errorf("Test %d: expected %d queries to the backend, got %d", i, tc.queries, queries)
+		}
+	}
+}
+
+func TestCacheClientSubnetDisabled(t *testing.T) {
+	queries := 0
+	c := New()
+	c.subnets = 0
+	c.Next = ecsBackend(&queries)
+
+	for i := 0; i < 2; i++ {
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), ecsQuery("10.0.1.0/24"))
+	}
+	if queries != 2 {
+		t.Errorf("Expected scoped answers not to be cached, got %d queries to the backend", queries)
+	}
+}
+
+func TestSubnets(t *testing.T) {
+	now := time.Now()
+	newScoped := func(subnet string, ttl time.Duration) *item {
+		i := newItem(new(dns.Msg), now, ttl)
+		_, i.subnet, _ = net.ParseCIDR(subnet)
+		return i
+	}
+
+	s := &subnets{}
+	s.add(newScoped("10.0.0.0/8", 300*time.Second), 2, now)
+	s.add(newScoped("10.1.0.0/16", 100*time.Second), 2, now)
+
+	tests := []struct {
+		ip     string
+		source int
+		subnet string
+	}{
+		{"10.1.2.3", 32, "10.1.0.0/16"},
+		{"10.2.2.3", 32, "10.0.0.0/8"},
+		{"10.1.2.3", 8, "10.0.0.0/8"},
+		{"192.0.2.1", 32, ""},
+	}
+	for i, tc := range tests {
+		itm := s.get(net.ParseIP(tc.ip), tc.source)
+		if itm == nil {
+			if tc.subnet != "" {
+				t.Errorf("Test %d: expected %s, got nothing", i, tc.subnet)
+			}
+			continue
+		}
+		if itm.subnet.String() != tc.subnet {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.subnet, itm.subnet)
+		}
+	}
+
+	// Replacing a subnet doesn't evict, adding a third one evicts the one that expires first.
+	if s.add(newScoped("10.0.0.0/8", 300*time.Second), 2, now) {
+		t.Error("Expected no eviction when replacing a subnet")
+	}
+	if !s.add(newScoped("10.2.0.0/16", 200*time.Second), 2, now) {
+		t.Error("Expected an eviction")
+	}
+	if itm := s.get(net.ParseIP("10.1.2.3"), 32); itm == nil || itm.subnet.String() != "10.0.0.0/8" {
+		t.Errorf("Expected 10.1.0.0/16 to be evicted, got %v", itm)
+	}
+}
+
+func TestResponseSubnet(t *testing.T) {
+	tests := []struct {
+		family        uint16
+		source, scope uint8
+		address       string
+		subnet        string
+		valid         bool
+	}{
+		{1, 24, 0, "10.1.2.3", "<nil>", true},
+		{1, 24, 16, "10.1.2.3", "10.1.0.0/16", true},
+		{1, 24, 24, "10.1.2.3", "10.1.2.0/24", true},
+		{1, 16, 24, "10.1.2.3", "10.1.0.0/16", true},
+		{2, 56, 48, "2001:db8:1:2::1", "2001:db8:1::/48", true},
+		// family and address disagree
+		{1, 24, 24, "2001:db8::1", "<nil>", false},
+		{2, 24, 24, "10.1.2.3", "<nil>", false},
+		{3, 24, 24, "10.1.2.3", "<nil>", false},
+	}
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetEdns0(4096, false)
+		m.Extra[0].(*dns.OPT).Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET,
+			Family: tc.family, SourceNetmask: tc.source, SourceScope: tc.scope, Address: net.ParseIP(tc.address)}}
+		got, valid := responseSubnet(m)
+		if got.String() != tc.subnet {
+			t.Errorf("Test %d: expected %s, got %s", i, tc.subnet, got)
+		}
+		if valid != tc.valid {
+			t.Errorf("Test %d: expected valid to be %t, got %t", i, tc.valid, valid)
+		}
+	}
+}
+
+func TestCacheClientSubnetMismatch(t *testing.T) {
+	queries := 0
+	c := New()
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		queries++
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.SetEdns0(4096, false)
+		// An IPv6 address with the IPv4 family, the subnet of this scoped answer can't be determined.
+		m.Extra[0].(*dns.OPT).Option = []dns.EDNS0{&dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET,
+			Family: 1, SourceNetmask: 24, SourceScope: 24, Address: net.ParseIP("2001:db8::1")}}
+		m.Answer = []dns.RR{test.A("example.org. 300 IN A 10.0.1.1")}
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	for _, subnet := range []string{"10.0.1.0/24", "10.0.2.0/24", ""} {
+		c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), ecsQuery(subnet))
+	}
+	if queries != 3 {
+		t.Errorf("Expected the scoped answer not to be cached, got %d queries to the backend", queries)
+	}
+}
+
+func TestCacheEntryTypeMismatch(t *testing.T) {
+	c := New()
+	m := ecsQuery("10.0.1.0/24")
+	state := request.Request{W: &test.ResponseWriter{}, Req: m}
+	k := hash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
+	sk := subnetHash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
+
+	// Swap the entries, as if the keys collided.
+	c.pcache.Add(k, &subnets{})
+	c.pcache.Add(sk, newItem(m, time.Now(), time.Minute))
+	if itm := c.getIgnoreTTL(time.Now(), state, "dns://:53"); itm != nil {
+		t.Errorf("Expected a cache miss, got %v", itm)
+	}
+	if itm := c.exists(state); itm != nil {
+		t.Errorf("Expected a cache miss, got %v", itm)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/metadata"
 	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/nocache"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -15,6 +16,11 @@ import (
 
 // ServeDNS implements the plugin.Handler interface.
 func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	// Only queries are cached, dynamic updates and notifies are for the plugins after us.
+	if r.Opcode != dns.OpcodeQuery {
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
 	rc := r.Copy() // We potentially modify r, to prevent other plugins from seeing this (r is a pointer), copy r into rc.
 	state := request.Request{W: w, Req: rc}
 	do := state.Do()
@@ -28,6 +34,8 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 
 	now := c.now().UTC()
 	server := metrics.WithServer(ctx)
+	// The plugins after us set nc when their response may not be cached.
+	ctx, nc := nocache.NewContext(ctx)
 
 	// On cache refresh, we will just use the DO bit from the incoming query for the refresh since we key our cache
 	// with the query DO bit. That means two separate cache items for the query DO bit true or false. In the situation
@@ -38,14 +46,14 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 	i := c.getIgnoreTTL(now, state, server)
 	if i == nil {
 		crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, do: do, ad: ad, cd: cd,
-			nexcept: c.nexcept, pexcept: c.pexcept, wildcardFunc: wildcardFunc(ctx)}
+			nexcept: c.nexcept, pexcept: c.pexcept, wildcardFunc: wildcardFunc(ctx), nocache: nc}
 		return c.doRefresh(ctx, state, crr)
 	}
 	ttl = i.ttl(now)
 	if ttl < 0 {
 		// serve stale behavior
 		if c.verifyStale {
-			crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, do: do, cd: cd}
+			crr := &ResponseWriter{ResponseWriter: w, Cache: c, state: state, server: server, do: do, cd: cd, nocache: nc}
 			cw := newVerifyStaleResponseWriter(crr)
 			ret, err := c.doRefresh(ctx, state, cw)
 			if cw.refreshed {
@@ -56,12 +64,12 @@ func (c *Cache) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg)
 		// Adjust the time to get a 0 TTL in the reply built from a stale item.
 		now = now.Add(time.Duration(ttl) * time.Second)
 		if !c.verifyStale {
-			cw := newPrefetchResponseWriter(server, state, c)
+			cw := newPrefetchResponseWriter(server, state, c, nc)
 			go c.doPrefetch(ctx, state, cw, i, now)
 		}
 		servedStale.WithLabelValues(server, c.zonesMetricLabel, c.viewMetricLabel).Inc()
 	} else if c.shouldPrefetch(i, now) {
-		cw := newPrefetchResponseWriter(server, state, c)
+		cw := newPrefetchResponseWriter(server, state, c, nc)
 		go c.doPrefetch(ctx, state, cw, i, now)
 	}
 
@@ -120,38 +128,61 @@ func (c *Cache) shouldPrefetch(i *item, now time.Time) bool {
 // Name implements the Handler interface.
 func (c *Cache) Name() string { return "cache" }
 
-// getIgnoreTTL unconditionally returns an item if it exists in the cache.
+// getIgnoreTTL unconditionally returns an item if it exists in the cache. Responses scoped to the subnet
+// of the client have precedence over responses that are valid for all clients.
 func (c *Cache) getIgnoreTTL(now time.Time, state request.Request, server string) *item {
 	k := hash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
+	sk := subnetHash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
 	cacheRequests.WithLabelValues(server, c.zonesMetricLabel, c.viewMetricLabel).Inc()
 
+	if itm := getSubnet(c.ncache, sk, state); itm != nil && c.usable(itm, state, now) {
+		cacheHits.WithLabelValues(server, Denial, c.zonesMetricLabel, c.viewMetricLabel).Inc()
+		return itm
+	}
+	if itm := getSubnet(c.pcache, sk, state); itm != nil && c.usable(itm, state, now) {
+		cacheHits.WithLabelValues(server, Success, c.zonesMetricLabel, c.viewMetricLabel).Inc()
+		return itm
+	}
 	if i, ok := c.ncache.Get(k); ok {
-		itm := i.(*item)
-		ttl := itm.ttl(now)
-		if itm.matches(state) && (ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds()))) {
+		if itm, ok := i.(*item); ok && c.usable(itm, state, now) {
 			cacheHits.WithLabelValues(server, Denial, c.zonesMetricLabel, c.viewMetricLabel).Inc()
-			return i.(*item)
+			return itm
 		}
 	}
 	if i, ok := c.pcache.Get(k); ok {
-		itm := i.(*item)
-		ttl := itm.ttl(now)
-		if itm.matches(state) && (ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds()))) {
+		if itm, ok := i.(*item); ok && c.usable(itm, state, now) {
 			cacheHits.WithLabelValues(server, Success, c.zonesMetricLabel, c.viewMetricLabel).Inc()
-			return i.(*item)
+			return itm
 		}
 	}
 	cacheMisses.WithLabelValues(server, c.zonesMetricLabel, c.viewMetricLabel).Inc()
 	return nil
 }
 
+// usable returns true if itm answers state and is either fresh or within the serve stale window.
+func (c *Cache) usable(itm *item, state request.Request, now time.Time) bool {
+	ttl := itm.ttl(now)
+	return itm.matches(state) && (ttl > 0 || (c.staleUpTo > 0 && -ttl < int(c.staleUpTo.Seconds())))
+}
+
 func (c *Cache) exists(state request.Request) *item {
 	k := hash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
+	sk := subnetHash(state.Name(), state.QType(), state.Do(), state.Req.CheckingDisabled)
+	if i := getSubnet(c.ncache, sk, state); i != nil {
+		return i
+	}
+	if i := getSubnet(c.pcache, sk, state); i != nil {
+		return i
+	}
 	if i, ok := c.ncache.Get(k); ok {
-		return i.(*item)
+		if itm, ok := i.(*item); ok {
+			return itm
+		}
 	}
 	if i, ok := c.pcache.Get(k); ok {
-		return i.(*item)
+		if itm, ok := i.(*item); ok {
+			return itm
+		}
 	}
 	return nil
 }
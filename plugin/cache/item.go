@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"net"
 	"strings"
 	"time"
 
@@ -20,6 +21,7 @@ type item struct {
 	Ns                 []dns.RR
 	Extra              []dns.RR
 	wildcard           string
+	subnet             *net.IPNet // when not nil, the response is only valid for clients in this subnet (RFC 7871)
 
 	origTTL uint32
 	stored  time.Time
@@ -49,6 +51,7 @@ func newItem(m *dns.Msg, now time.Time, d time.Duration) *item {
 		j++
 	}
 	i.Extra = i.Extra[:j]
+	i.subnet, _ = responseSubnet(m)
 
 	i.origTTL = uint32(d.Seconds())
 	i.stored = now.UTC()
@@ -3,6 +3,7 @@ package cache
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,33 @@ func setup(c *caddy.Controller) error {
 		return nil
 	})
 
+	if ca.snapshot != "" {
+		stop := make(chan struct{})
+		c.OnStartup(func() error {
+			n, err := ca.load()
+			if err != nil {
+				log.Warningf("Failed to load snapshot %q: %s", ca.snapshot, err)
+			} else if n > 0 {
+				log.Infof("Loaded %d entries from snapshot %q", n, ca.snapshot)
+			}
+			go ca.saveSnapshots(ca.snapshotInterval, stop)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			close(stop)
+			return nil
+		})
+		// Save the cache before a reload, so the new instance starts from where we left off, and when exiting.
+		save := func() error {
+			if err := ca.save(); err != nil {
+				log.Warningf("Failed to save snapshot %q: %s", ca.snapshot, err)
+			}
+			return nil
+		}
+		c.OnRestart(save)
+		c.OnFinalShutdown(save)
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		ca.Next = next
 		return ca
@@ -246,6 +274,39 @@ func cacheParse(c *caddy.Controller) (*Cache, error) {
 					return nil, c.ArgErr()
 				}
 				ca.keepttl = true
+			case "subnets":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				subnets, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if subnets < 0 {
+					return nil, fmt.Errorf("cache subnets can not be negative: %d", subnets)
+				}
+				ca.subnets = subnets
+			case "snapshot":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				ca.snapshot = args[0]
+				if root := dnsserver.GetConfig(c).Root; !filepath.IsAbs(ca.snapshot) && root != "" {
+					ca.snapshot = filepath.Join(root, ca.snapshot)
+				}
+				ca.snapshotInterval = defaultSnapshotInterval
+				if len(args) > 1 {
+					d, err := time.ParseDuration(args[1])
+					if err != nil {
+						return nil, err
+					}
+					if d <= 0 {
+						return nil, fmt.Errorf("cache snapshot interval can not be zero or negative: %s", d)
+					}
+					ca.snapshotInterval = d
+				}
 			default:
 				return nil, c.ArgErr()
 			}
@@ -260,3 +260,74 @@ func TestKeepttl(t *testing.T) {
 		}
 	}
 }
+
+func TestSubnetsSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		subnets   int
+	}{
+		// positive
+		{"", false, defaultSubnets},
+		{"subnets 5", false, 5},
+		{"subnets 0", false, 0},
+		// negative
+		{"subnets", true, 0},
+		{"subnets -1", true, 0},
+		{"subnets many", true, 0},
+		{"subnets 1 2", true, 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr {
+			continue
+		}
+		if ca.subnets != test.subnets {
+			t.Errorf("Test %v: Expected subnets %d but got: %d", i, test.subnets, ca.subnets)
+		}
+	}
+}
+
+func TestSnapshotSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		snapshot  string
+		interval  time.Duration
+	}{
+		// positive
+		{"", false, "", 0},
+		{"snapshot /tmp/cache.snapshot", false, "/tmp/cache.snapshot", defaultSnapshotInterval},
+		{"snapshot /tmp/cache.snapshot 1m", false, "/tmp/cache.snapshot", time.Minute},
+		// negative
+		{"snapshot", true, "", 0},
+		{"snapshot /tmp/cache.snapshot 0s", true, "", 0},
+		{"snapshot /tmp/cache.snapshot often", true, "", 0},
+		{"snapshot /tmp/cache.snapshot 1m 2m", true, "", 0},
+	}
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", fmt.Sprintf("cache {\n%s\n}", test.input))
+		ca, err := cacheParse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %v: Expected error but found nil", i)
+			continue
+		} else if !test.shouldErr && err != nil {
+			t.Errorf("Test %v: Expected no error but found error: %v", i, err)
+			continue
+		}
+		if test.shouldErr {
+			continue
+		}
+		if ca.snapshot != test.snapshot || ca.snapshotInterval != test.interval {
+			t.Errorf("Test %v: Expected snapshot %q every %s but got: %q every %s", i, test.snapshot, test.interval, ca.snapshot, ca.snapshotInterval)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+// snapshotVersion is the version of the snapshot format, snapshots with another version are ignored.
+const snapshotVersion = 1
+
+// header is the first value in a snapshot, it's followed by entries.
+type header struct {
+	Version int
+}
+
+// entry is a cached item as it is stored in a snapshot.
+type entry struct {
+	Denial   bool   // true if the item is stored in the denial cache
+	Key      uint64 // key of the item, or of the subnets it belongs to
+	Msg      []byte // question, rcode, flags and sections of the item in wire format
+	Wildcard string
+	Subnet   string
+	OrigTTL  uint32
+	Stored   time.Time
+}
+
+// cached is a cached item and where it is stored.
+type cached struct {
+	denial bool
+	key    uint64
+	*item
+}
+
+// save writes all cached items to the snapshot file. The file is replaced atomically, so a
+// crash while saving leaves the previous snapshot intact.
+func (c *Cache) save() error {
+	var items []cached
+	collect := func(ca *cache.Cache, denial bool) {
+		ca.Walk(func(m map[uint64]interface{}, k uint64) bool {
+			switch x := m[k].(type) {
+			case *item:
+				items = append(items, cached{denial, k, x})
+			case *subnets:
+				x.Lock()
+				for _, i := range x.items {
+					items = append(items, cached{denial, k, i})
+				}
+				x.Unlock()
+			}
+			return true
+		})
+	}
+	collect(c.pcache, false)
+	collect(c.ncache, true)
+
+	f, err := os.CreateTemp(filepath.Dir(c.snapshot), filepath.Base(c.snapshot)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails after the rename, which is fine
+
+	buf := bufio.NewWriter(f)
+	enc := gob.NewEncoder(buf)
+	if err := enc.Encode(header{Version: snapshotVersion}); err != nil {
+		f.Close()
+		return err
+	}
+	for _, s := range items {
+		e, err := s.entry()
+		if err != nil {
+			continue // unlikely, as it was packed before; skip it
+		}
+		if err := enc.Encode(e); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), c.snapshot)
+}
+
+// load adds the items in the snapshot file to the cache. Items that have expired are dropped,
+// unless they can still be served stale. A missing snapshot file is not an error.
+func (c *Cache) load() (int, error) {
+	f, err := os.Open(c.snapshot)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	h := header{}
+	if err := dec.Decode(&h); err != nil {
+		return 0, err
+	}
+	if h.Version != snapshotVersion {
+		return 0, fmt.Errorf("unsupported snapshot version %d", h.Version)
+	}
+
+	now := c.now()
+	n := 0
+	for {
+		e := entry{}
+		if err := dec.Decode(&e); err != nil {
+			if err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+		i, err := e.item()
+		if err != nil {
+			return n, err
+		}
+		if ttl := i.ttl(now); ttl <= 0 && (c.staleUpTo == 0 || -ttl >= int(c.staleUpTo.Seconds())) {
+			continue
+		}
+
+		ca := c.pcache
+		if e.Denial {
+			ca = c.ncache
+		}
+		if i.subnet != nil {
+			if c.subnets == 0 {
+				continue
+			}
+			addSubnet(ca, e.Key, i, c.subnets, now)
+		} else {
+			ca.Add(e.Key, i)
+		}
+		n++
+	}
+}
+
+// entry returns s as an entry in a snapshot.
+func (s cached) entry() (entry, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(s.Name, s.QType)
+	m.Rcode = s.Rcode
+	m.AuthenticatedData = s.AuthenticatedData
+	m.RecursionAvailable = s.RecursionAvailable
+	m.Answer, m.Ns, m.Extra = s.Answer, s.Ns, s.Extra
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		return entry{}, err
+	}
+
+	e := entry{Denial: s.denial, Key: s.key, Msg: buf, Wildcard: s.wildcard, OrigTTL: s.origTTL, Stored: s.stored}
+	if s.subnet != nil {
+		e.Subnet = s.subnet.String()
+	}
+	return e, nil
+}
+
+// item returns the cached item stored in e.
+func (e entry) item() (*item, error) {
+	m := new(dns.Msg)
+	if err := m.Unpack(e.Msg); err != nil {
+		return nil, err
+	}
+	i := newItem(m, e.Stored, time.Duration(e.OrigTTL)*time.Second)
+	i.wildcard = e.Wildcard
+	if e.Subnet != "" {
+		_, subnet, err := net.ParseCIDR(e.Subnet)
+		if err != nil {
+			return nil, err
+		}
+		i.subnet = subnet
+	}
+	return i, nil
+}
+
+// saveSnapshots saves a snapshot every interval, until stop is closed.
+func (c *Cache) saveSnapshots(interval time.Duration, stop <-chan struct{}) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-tick.C:
+			if err := c.save(); err != nil {
+				log.Warningf("Failed to save snapshot %q: %s", c.snapshot, err)
+			}
+		}
+	}
+}
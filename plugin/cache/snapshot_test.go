@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.snapshot")
+	now := time.Now()
+
+	queries := 0
+	c := New()
+	c.snapshot = file
+	c.now = func() time.Time { return now }
+	c.Next = ecsBackend(&queries)
+	c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), ecsQuery("10.0.1.0/24"))
+	c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), ecsQuery(""))
+	c.Next = nxDomainBackend(60)
+	m := new(dns.Msg)
+	m.SetQuestion("nx.example.org.", dns.TypeA)
+	c.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), m)
+
+	if err := c.save(); err != nil {
+		t.Fatalf("Expected no error saving the snapshot, got %s", err)
+	}
+
+	tests := []struct {
+		after   time.Duration
+		stale   time.Duration
+		entries int
+	}{
+		{0, 0, 3},
+		{100 * time.Second, 0, 2},         // the denial expired
+		{400 * time.Second, 0, 0},         // all expired
+		{400 * time.Second, time.Hour, 3}, // all stale
+		{2 * time.Hour, 1 * time.Hour, 0}, // too stale
+	}
+	for i, tc := range tests {
+		c1 := New()
+		c1.snapshot = file
+		c1.staleUpTo = tc.stale
+		c1.now = func() time.Time { return now.Add(tc.after) }
+		n, err := c1.load()
+		if err != nil {
+			t.Fatalf("Test %d: expected no error loading the snapshot, got %s", i, err)
+		}
+		if n != tc.entries {
+			t.Errorf("Test %d: expected %d entries, got %d", i, tc.entries, n)
+		}
+	}
+
+	// The loaded cache answers without asking the backend, with the remaining TTL.
+	c1 := New()
+	c1.snapshot = file
+	c1.now = func() time.Time { return now.Add(100 * time.Second) }
+	if _, err := c1.load(); err != nil {
+		t.Fatal(err)
+	}
+	queries = 0
+	c1.Next = ecsBackend(&queries)
+	for _, subnet := range []string{"", "10.0.1.0/24"} {
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		c1.ServeDNS(context.TODO(), rec, ecsQuery(subnet))
+		if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+			t.Fatalf("Expected one answer for %q, got %v", subnet, rec.Msg)
+		}
+		if ttl := rec.Msg.Answer[0].Header().Ttl; ttl != 200 {
+			t.Errorf("Expected TTL 200 for %q, got %d", subnet, ttl)
+		}
+	}
+	if queries != 0 {
+		t.Errorf("Expected no queries to the backend, got %d", queries)
+	}
+}
+
+func TestSnapshotMissing(t *testing.T) {
+	c := New()
+	c.snapshot = filepath.Join(t.TempDir(), "cache.snapshot")
+	if n, err := c.load(); n != 0 || err != nil {
+		t.Errorf("Expected nothing to be loaded from a missing snapshot, got %d, %v", n, err)
+	}
+}
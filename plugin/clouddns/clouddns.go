@@ -6,12 +6,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 	"github.com/coredns/coredns/request"
@@ -38,6 +40,9 @@ type zone struct {
 	zoneName    string
 	z           *file.Zone
 	dns         string
+
+	synced bool  // True once all resource record sets are listed.
+	change int64 // The sequence number of the last change applied.
 }
 
 type zones map[string][]*zone
@@ -152,29 +157,21 @@ func (h *CloudDNS) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	return dns.RcodeSuccess, nil
 }
 
-func updateZoneFromRRS(rrs *gcp.ResourceRecordSetsListResponse, z *file.Zone) error {
-	for _, rr := range rrs.Rrsets {
-		var rfc1035 string
-		var r dns.RR
-		var err error
-		for _, value := range rr.Rrdatas {
-			if rr.Type == "CNAME" || rr.Type == "PTR" {
-				value = dns.Fqdn(value)
-			}
-			// Assemble RFC 1035 conforming record to pass into dns scanner.
-			rfc1035 = fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(rr.Name), rr.Ttl, rr.Type, value)
-			r, err = dns.NewRR(rfc1035)
-			if err != nil {
-				return fmt.Errorf("failed to parse resource record: %v", err)
-			}
-
-			err = z.Insert(r)
-			if err != nil {
-				return fmt.Errorf("failed to insert record: %v", err)
-			}
+func rrsFromRRS(rrs *gcp.ResourceRecordSet) ([]dns.RR, error) {
+	var out []dns.RR
+	for _, value := range rrs.Rrdatas {
+		if rrs.Type == "CNAME" || rrs.Type == "PTR" {
+			value = dns.Fqdn(value)
+		}
+		// Assemble RFC 1035 conforming record to pass into dns scanner.
+		rfc1035 := fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(rrs.Name), rrs.Ttl, rrs.Type, value)
+		r, err := dns.NewRR(rfc1035)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse resource record: %v", err)
 		}
+		out = append(out, r)
 	}
-	return nil
+	return out, nil
 }
 
 // updateZones re-queries resource record sets for each zone and updates the
@@ -187,24 +184,15 @@ func (h *CloudDNS) updateZones(ctx context.Context) error {
 	for zName, z := range h.zones {
 		go func(zName string, z []*zone) {
 			var err error
-			var rrListResponse *gcp.ResourceRecordSetsListResponse
 			defer func() {
 				errc <- err
 			}()
 
-			for i, hostedZone := range z {
-				newZ := file.NewZone(zName, "")
-				newZ.Upstream = h.upstream
-				rrListResponse, err = h.client.listRRSets(ctx, hostedZone.projectName, hostedZone.zoneName)
-				if err != nil {
+			for _, hostedZone := range z {
+				if err = h.updateZone(ctx, hostedZone); err != nil {
 					err = fmt.Errorf("failed to list resource records for %v:%v:%v from gcp: %v", zName, hostedZone.projectName, hostedZone.zoneName, err)
 					return
 				}
-				updateZoneFromRRS(rrListResponse, newZ)
-
-				h.zMu.Lock()
-				(*z[i]).z = newZ
-				h.zMu.Unlock()
 			}
 		}(zName, z)
 	}
@@ -223,5 +211,124 @@ func (h *CloudDNS) updateZones(ctx context.Context) error {
 	return nil
 }
 
+// updateZone updates hostedZone. The first update lists all resource record sets, later ones only
+// list the changes made since and apply those to the zone.
+func (h *CloudDNS) updateZone(ctx context.Context, hostedZone *zone) error {
+	start := time.Now()
+	var (
+		changed int
+		err     error
+	)
+	if hostedZone.synced {
+		changed, err = h.applyChanges(ctx, hostedZone)
+	} else {
+		changed, err = h.listZone(ctx, hostedZone)
+	}
+	if err != nil {
+		return err
+	}
+
+	h.zMu.RLock()
+	records := len(hostedZone.z.Apex.NS)
+	if hostedZone.z.Apex.SOA != nil {
+		records++
+	}
+	hostedZone.z.Tree.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		records += len(e.All())
+		return nil
+	})
+	h.zMu.RUnlock()
+
+	id := hostedZone.projectName + ":" + hostedZone.zoneName
+	refreshDuration.WithLabelValues(hostedZone.dns, id).Observe(time.Since(start).Seconds())
+	recordCount.WithLabelValues(hostedZone.dns, id).Set(float64(records))
+	rrsetChangeCount.WithLabelValues(hostedZone.dns, id).Add(float64(changed))
+	return nil
+}
+
+// listZone lists all resource record sets of hostedZone and replaces its zone. It returns the number of RRsets.
+func (h *CloudDNS) listZone(ctx context.Context, hostedZone *zone) (int, error) {
+	// Get the latest change first, the changes made while listing are applied (again) by the next update.
+	seq, err := h.client.latestChange(ctx, hostedZone.projectName, hostedZone.zoneName)
+	if err != nil {
+		return 0, err
+	}
+	rrListResponse, err := h.client.listRRSets(ctx, hostedZone.projectName, hostedZone.zoneName)
+	if err != nil {
+		return 0, err
+	}
+
+	newZ := file.NewZone(hostedZone.dns, "")
+	newZ.Upstream = h.upstream
+	for _, rrs := range rrListResponse.Rrsets {
+		rr, err := rrsFromRRS(rrs)
+		if err != nil {
+			// Maybe unsupported record type. Log and carry on.
+			log.Warningf("Failed to process resource record set: %v", err)
+			continue
+		}
+		for _, r := range rr {
+			newZ.Insert(r)
+		}
+	}
+
+	h.zMu.Lock()
+	hostedZone.z = newZ
+	h.zMu.Unlock()
+	hostedZone.change = seq
+	hostedZone.synced = true
+	return len(rrListResponse.Rrsets), nil
+}
+
+// applyChanges applies the changes made to hostedZone since the last update. It returns the number of RRsets
+// changed.
+func (h *CloudDNS) applyChanges(ctx context.Context, hostedZone *zone) (int, error) {
+	changes, err := h.client.listChanges(ctx, hostedZone.projectName, hostedZone.zoneName, hostedZone.change)
+	if err != nil {
+		return 0, err
+	}
+
+	type rrsetKey struct {
+		name string
+		typ  uint16
+	}
+	rrsets := map[rrsetKey][]dns.RR{}
+	seq := hostedZone.change
+	for _, change := range changes {
+		if change.Status != "done" {
+			// Later changes are applied after this one.
+			break
+		}
+		for _, rrs := range change.Deletions {
+			if typ, ok := dns.StringToType[rrs.Type]; ok {
+				rrsets[rrsetKey{dns.CanonicalName(rrs.Name), typ}] = nil
+			}
+		}
+		for _, rrs := range change.Additions {
+			typ, ok := dns.StringToType[rrs.Type]
+			if !ok {
+				continue
+			}
+			rr, err := rrsFromRRS(rrs)
+			if err != nil {
+				log.Warningf("Failed to process resource record set: %v", err)
+				continue
+			}
+			rrsets[rrsetKey{dns.CanonicalName(rrs.Name), typ}] = rr
+		}
+		if seq, err = strconv.ParseInt(change.Id, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+
+	h.zMu.Lock()
+	for key, rrs := range rrsets {
+		hostedZone.z.SetRRset(key.name, key.typ, rrs)
+	}
+	h.zMu.Unlock()
+	hostedZone.change = seq
+	return len(rrsets), nil
+}
+
 // Name implements the Handler interface.
 func (h *CloudDNS) Name() string { return "clouddns" }
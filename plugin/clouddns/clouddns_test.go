@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"strconv"
 	"testing"
 
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
@@ -24,6 +25,21 @@ func (c fakeGCPClient) zoneExists(projectName, hostedZoneName string) error {
 	return nil
 }
 
+func (c fakeGCPClient) changeRRSets(ctx context.Context, projectName, hostedZoneName string, change *gcp.Change) error {
+	if projectName == "bad-project" || hostedZoneName == "bad-zone" {
+		return errors.New("the 'parameters.managedZone' resource named 'bad-zone' does not exist")
+	}
+	return nil
+}
+
+func (c fakeGCPClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (int64, error) {
+	return 0, nil
+}
+
+func (c fakeGCPClient) listChanges(ctx context.Context, projectName, hostedZoneName string, after int64) ([]*gcp.Change, error) {
+	return nil, nil
+}
+
 func (c fakeGCPClient) listRRSets(ctx context.Context, projectName, hostedZoneName string) (*gcp.ResourceRecordSetsListResponse, error) {
 	if projectName == "bad-project" || hostedZoneName == "bad-zone" {
 		return nil, errors.New("the 'parameters.managedZone' resource named 'bad-zone' does not exist")
@@ -325,3 +341,76 @@ func TestCloudDNS(t *testing.T) {
 		}
 	}
 }
+
+type changesGCPClient struct {
+	fakeGCPClient
+	changes []*gcp.Change
+}
+
+func (c *changesGCPClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (int64, error) {
+	return 1, nil
+}
+
+func (c *changesGCPClient) listChanges(ctx context.Context, projectName, hostedZoneName string, after int64) ([]*gcp.Change, error) {
+	var changes []*gcp.Change
+	for _, change := range c.changes {
+		if seq, _ := strconv.ParseInt(change.Id, 10, 64); seq > after {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+func TestUpdateZoneIncremental(t *testing.T) {
+	ctx := context.Background()
+	c := &changesGCPClient{}
+	r, err := New(ctx, c, map[string][]string{"org.": {"sample-project-1:sample-zone-1"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	hz := r.zones["org."][0]
+	z := hz.z
+	if hz.change != 1 {
+		t.Fatalf("Expected change 1, got %d", hz.change)
+	}
+
+	c.changes = []*gcp.Change{
+		// Already part of the listing.
+		{Id: "1", Status: "done", Deletions: []*gcp.ResourceRecordSet{{Name: "example.org.", Type: "A", Ttl: 300, Rrdatas: []string{"1.2.3.4"}}}},
+		{
+			Id:        "2",
+			Status:    "done",
+			Deletions: []*gcp.ResourceRecordSet{{Name: "example.org.", Type: "A", Ttl: 300, Rrdatas: []string{"1.2.3.4"}}, {Name: "sample.example.org.", Type: "CNAME", Ttl: 300, Rrdatas: []string{"example.org"}}},
+			Additions: []*gcp.ResourceRecordSet{{Name: "example.org.", Type: "A", Ttl: 60, Rrdatas: []string{"10.0.0.1", "10.0.0.2"}}},
+		},
+		{Id: "3", Status: "done", Additions: []*gcp.ResourceRecordSet{{Name: "new.example.org.", Type: "TXT", Ttl: 60, Rrdatas: []string{`"new"`}}}},
+		{Id: "4", Status: "pending", Additions: []*gcp.ResourceRecordSet{{Name: "pending.example.org.", Type: "A", Ttl: 60, Rrdatas: []string{"10.0.0.3"}}}},
+	}
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	if hz.z != z {
+		t.Error("Expected the zone to be updated in place")
+	}
+	if hz.change != 3 {
+		t.Errorf("Expected change 3, got %d", hz.change)
+	}
+	if rrs := z.RRset("example.org.", dns.TypeA); len(rrs) != 2 {
+		t.Errorf("Expected example.org. A to be replaced, got %v", rrs)
+	}
+	if rrs := z.RRset("sample.example.org.", dns.TypeCNAME); len(rrs) != 0 {
+		t.Errorf("Expected sample.example.org. CNAME to be deleted, got %v", rrs)
+	}
+	if rrs := z.RRset("new.example.org.", dns.TypeTXT); len(rrs) != 1 {
+		t.Errorf("Expected new.example.org. TXT to be added, got %v", rrs)
+	}
+	if rrs := z.RRset("pending.example.org.", dns.TypeA); len(rrs) != 0 {
+		t.Errorf("Expected the pending change not to be applied, got %v", rrs)
+	}
+	if rrs := z.RRset("example.org.", dns.TypeAAAA); len(rrs) != 1 {
+		t.Errorf("Expected example.org. AAAA to be left alone, got %v", rrs)
+	}
+}
@@ -2,6 +2,8 @@ package clouddns
 
 import (
 	"context"
+	"errors"
+	"strconv"
 
 	gcp "google.golang.org/api/dns/v1"
 )
@@ -9,6 +11,9 @@ import (
 type gcpDNS interface {
 	zoneExists(projectName, hostedZoneName string) error
 	listRRSets(ctx context.Context, projectName, hostedZoneName string) (*gcp.ResourceRecordSetsListResponse, error)
+	changeRRSets(ctx context.Context, projectName, hostedZoneName string, change *gcp.Change) error
+	latestChange(ctx context.Context, projectName, hostedZoneName string) (int64, error)
+	listChanges(ctx context.Context, projectName, hostedZoneName string, after int64) ([]*gcp.Change, error)
 }
 
 type gcpClient struct {
@@ -38,3 +43,53 @@ func (c gcpClient) listRRSets(ctx context.Context, projectName, hostedZoneName s
 	}
 	return &gcp.ResourceRecordSetsListResponse{Rrsets: rs}, nil
 }
+
+// changeRRSets is a wrapper method around `gcp.Service.Changes.Create`
+// it applies the additions and deletions of change to a hosted zone.
+func (c gcpClient) changeRRSets(ctx context.Context, projectName, hostedZoneName string, change *gcp.Change) error {
+	_, err := c.Changes.Create(projectName, hostedZoneName, change).Context(ctx).Do()
+	return err
+}
+
+// latestChange is a wrapper method around `gcp.Service.Changes.List`
+// it returns the sequence number of the latest change to a hosted zone.
+func (c gcpClient) latestChange(ctx context.Context, projectName, hostedZoneName string) (int64, error) {
+	resp, err := c.Changes.List(projectName, hostedZoneName).SortBy("changeSequence").SortOrder("descending").MaxResults(1).Context(ctx).Do()
+	if err != nil {
+		return 0, err
+	}
+	if len(resp.Changes) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(resp.Changes[0].Id, 10, 64)
+}
+
+var errSeen = errors.New("change already seen")
+
+// listChanges is a wrapper method around `gcp.Service.Changes.List`
+// it fetches and returns the changes to a hosted zone with a sequence number
+// larger than after, oldest first.
+func (c gcpClient) listChanges(ctx context.Context, projectName, hostedZoneName string, after int64) ([]*gcp.Change, error) {
+	req := c.Changes.List(projectName, hostedZoneName).SortBy("changeSequence").SortOrder("descending")
+	var changes []*gcp.Change
+	err := req.Pages(ctx, func(page *gcp.ChangesListResponse) error {
+		for _, change := range page.Changes {
+			seq, err := strconv.ParseInt(change.Id, 10, 64)
+			if err != nil {
+				return err
+			}
+			if seq <= after {
+				return errSeen
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	if err != nil && err != errSeen {
+		return nil, err
+	}
+	for i, j := 0, len(changes)-1; i < j; i, j = i+1, j-1 {
+		changes[i], changes[j] = changes[j], changes[i]
+	}
+	return changes, nil
+}
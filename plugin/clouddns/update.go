@@ -0,0 +1,70 @@
+package clouddns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/admin"
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/miekg/dns"
+	gcp "google.golang.org/api/dns/v1"
+)
+
+// Update implements the admin.Updater interface. The hosted zone is selected as PROJECT_NAME:HOSTED_ZONE_NAME.
+func (h *CloudDNS) Update(ctx context.Context, zName, hostedZone string, changes []admin.Change) error {
+	z, ok := h.zones[zName]
+	if !ok {
+		return admin.ErrNotAuthoritative
+	}
+	ids := make([]string, len(z))
+	for i, hz := range z {
+		ids[i] = hz.projectName + ":" + hz.zoneName
+	}
+	i, err := admin.HostedZone(zName, hostedZone, ids)
+	if err != nil {
+		return err
+	}
+
+	// The zone is changed in place when it is refreshed, read it under the lock.
+	h.zMu.RLock()
+	change, err := newChange(zName, z[i].z, changes)
+	h.zMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if err := h.client.changeRRSets(ctx, z[i].projectName, z[i].zoneName, change); err != nil {
+		return fmt.Errorf("failed to change resource record sets of %v:%v:%v in gcp: %v", zName, z[i].projectName, z[i].zoneName, err)
+	}
+
+	h.zMu.Lock()
+	z[i].z = admin.Apply(zName, z[i].z, changes)
+	h.zMu.Unlock()
+	return nil
+}
+
+// newChange returns the Cloud DNS change that applies changes to the zone z. Cloud DNS has no upsert,
+// an existing record set is deleted, which only works if it matches exactly.
+func newChange(zName string, z *file.Zone, changes []admin.Change) (*gcp.Change, error) {
+	change := &gcp.Change{}
+	for _, c := range changes {
+		if rrs := admin.RRset(zName, z, c.Name, c.Type); len(rrs) > 0 {
+			change.Deletions = append(change.Deletions, resourceRecordSet(c.Name, c.Type, rrs[0].Header().Ttl, rrs))
+		} else if c.Action == admin.Delete {
+			return nil, fmt.Errorf("%w: %s %s doesn't exist", admin.ErrInvalid, c.Name, dns.TypeToString[c.Type])
+		}
+		if c.Action == admin.Upsert {
+			change.Additions = append(change.Additions, resourceRecordSet(c.Name, c.Type, c.TTL, c.RRs))
+		}
+	}
+	return change, nil
+}
+
+func resourceRecordSet(name string, typ uint16, ttl uint32, rrs []dns.RR) *gcp.ResourceRecordSet {
+	set := &gcp.ResourceRecordSet{Name: name, Type: dns.TypeToString[typ], Ttl: int64(ttl)}
+	for _, rr := range rrs {
+		set.Rrdatas = append(set.Rrdatas, admin.Rdata(rr))
+	}
+	return set
+}
@@ -0,0 +1,69 @@
+package clouddns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/admin"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	gcp "google.golang.org/api/dns/v1"
+)
+
+type changeGCPClient struct {
+	fakeGCPClient
+	change *gcp.Change
+}
+
+func (c *changeGCPClient) changeRRSets(ctx context.Context, projectName, hostedZoneName string, change *gcp.Change) error {
+	c.change = change
+	return c.fakeGCPClient.changeRRSets(ctx, projectName, hostedZoneName, change)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := &changeGCPClient{}
+	h, err := New(ctx, c, map[string][]string{"org.": {"sample-project-1:sample-zone-2", "sample-project-1:sample-zone-1"}}, &upstream.Upstream{})
+	if err != nil {
+		t.Fatalf("Failed to create Cloud DNS: %v", err)
+	}
+	if err := h.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+
+	changes := []admin.Change{
+		{Action: admin.Upsert, Name: "example.org.", Type: dns.TypeA, TTL: 60, RRs: []dns.RR{test.A("example.org. 60 IN A 10.0.0.1")}},
+		{Action: admin.Delete, Name: "sample.example.org.", Type: dns.TypeCNAME},
+	}
+	if err := h.Update(ctx, "example.com.", "", changes); !errors.Is(err, admin.ErrNotAuthoritative) {
+		t.Errorf("Expected ErrNotAuthoritative, got %v", err)
+	}
+	if err := h.Update(ctx, "org.", "", changes); !errors.Is(err, admin.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for a zone in two hosted zones, got %v", err)
+	}
+	missing := []admin.Change{{Action: admin.Delete, Name: "missing.org.", Type: dns.TypeA}}
+	if err := h.Update(ctx, "org.", "sample-project-1:sample-zone-1", missing); !errors.Is(err, admin.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for deleting a missing RRset, got %v", err)
+	}
+
+	if err := h.Update(ctx, "org.", "sample-project-1:sample-zone-1", changes); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(c.change.Deletions) != 2 || c.change.Deletions[0].Rrdatas[0] != "1.2.3.4" || c.change.Deletions[1].Type != "CNAME" {
+		t.Errorf("Unexpected deletions: %v", c.change.Deletions)
+	}
+	if len(c.change.Additions) != 1 || c.change.Additions[0].Rrdatas[0] != "10.0.0.1" || c.change.Additions[0].Ttl != 60 {
+		t.Errorf("Unexpected additions: %v", c.change.Additions)
+	}
+
+	z := h.zones["org."][1].z
+	if rrs := admin.RRset("org.", z, "example.org.", dns.TypeA); len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "10.0.0.1" {
+		t.Errorf("Expected example.org. A to be replaced, got %v", rrs)
+	}
+	if rrs := admin.RRset("org.", z, "sample.example.org.", dns.TypeCNAME); len(rrs) != 0 {
+		t.Errorf("Expected sample.example.org. CNAME to be deleted, got %v", rrs)
+	}
+}
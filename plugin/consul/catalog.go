@@ -0,0 +1,275 @@
+package consul
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// instance is a healthy instance of a service.
+type instance struct {
+	id          string
+	node        string
+	nodeAddress string
+	address     string // The address of the service, the node's address if the service doesn't have one.
+	port        int
+	tags        []string
+}
+
+// healthEntry is an entry in the response of /v1/health/service/:service.
+type healthEntry struct {
+	Node struct {
+		Node    string
+		Address string
+	}
+	Service struct {
+		ID      string
+		Service string
+		Tags    []string
+		Address string
+		Port    int
+	}
+}
+
+// catalog keeps the healthy instances of all services in the Consul catalog of each datacenter up to date,
+// with blocking queries.
+type catalog struct {
+	c     *client
+	dcs   []string // Datacenters to watch, the first is the default. If empty the agent's datacenter is used.
+	wait  time.Duration
+	retry time.Duration // Delay after a failed query.
+
+	sync.RWMutex
+	services map[string]map[string][]instance // Healthy instances by datacenter and lower case service name.
+	index    uint64                           // The largest Raft index seen.
+	synced   map[string]bool                  // Datacenters that have been loaded.
+}
+
+func newCatalog(c *client, dcs []string) *catalog {
+	return &catalog{
+		c:        c,
+		dcs:      dcs,
+		wait:     5 * time.Minute,
+		retry:    time.Second,
+		services: map[string]map[string][]instance{},
+		synced:   map[string]bool{},
+	}
+}
+
+// run watches the catalog until ctx is canceled.
+func (c *catalog) run(ctx context.Context) {
+	if len(c.dcs) == 0 {
+		dc, err := c.agentDatacenter(ctx)
+		for err != nil {
+			log.Warningf("Failed to get the datacenter of the agent: %s", err)
+			if !sleep(ctx, c.retry) {
+				return
+			}
+			dc, err = c.agentDatacenter(ctx)
+		}
+		c.Lock()
+		c.dcs = []string{dc}
+		c.Unlock()
+	}
+	for _, dc := range c.datacenters() {
+		go c.watchServices(ctx, dc)
+	}
+}
+
+func (c *catalog) agentDatacenter(ctx context.Context) (string, error) {
+	self := struct{ Config struct{ Datacenter string } }{}
+	_, err := c.c.get(ctx, "/v1/agent/self", nil, 0, 0, &self)
+	return self.Config.Datacenter, err
+}
+
+// watchServices watches the services in the catalog of dc, and starts and stops a watch of the health of each.
+func (c *catalog) watchServices(ctx context.Context, dc string) {
+	type watch struct {
+		cancel context.CancelFunc
+	}
+	watches := map[string]watch{}
+	defer func() {
+		for _, w := range watches {
+			w.cancel()
+		}
+	}()
+
+	var index uint64
+	for {
+		services := map[string][]string{}
+		i, err := c.c.get(ctx, "/v1/catalog/services", url.Values{"dc": {dc}}, index, c.wait, &services)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to list the services of datacenter %s: %s", dc, err)
+			if !sleep(ctx, c.retry) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, i)
+		c.seen(i)
+
+		for name := range services {
+			if _, ok := watches[name]; ok {
+				continue
+			}
+			// The first health query isn't blocking, so all services are loaded when dc is synced.
+			hi, err := c.health(ctx, dc, name, 0)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Warningf("Failed to get the health of service %s in datacenter %s: %s", name, dc, err)
+			}
+			wctx, cancel := context.WithCancel(ctx)
+			watches[name] = watch{cancel: cancel}
+			go c.watchHealth(wctx, dc, name, hi)
+		}
+		for name, w := range watches {
+			if _, ok := services[name]; ok {
+				continue
+			}
+			w.cancel()
+			delete(watches, name)
+			c.Lock()
+			delete(c.services[dc], strings.ToLower(name))
+			c.Unlock()
+		}
+
+		c.Lock()
+		c.synced[dc] = true
+		c.Unlock()
+	}
+}
+
+// watchHealth watches the healthy instances of service in dc, starting at index.
+func (c *catalog) watchHealth(ctx context.Context, dc, service string, index uint64) {
+	for {
+		i, err := c.health(ctx, dc, service, index)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Warningf("Failed to get the health of service %s in datacenter %s: %s", service, dc, err)
+			if !sleep(ctx, c.retry) {
+				return
+			}
+			continue
+		}
+		index = nextIndex(index, i)
+	}
+}
+
+// health gets the healthy instances of service in dc, with a blocking query if index is larger than zero.
+func (c *catalog) health(ctx context.Context, dc, service string, index uint64) (uint64, error) {
+	entries := []healthEntry{}
+	i, err := c.c.get(ctx, "/v1/health/service/"+url.PathEscape(service), url.Values{"dc": {dc}, "passing": {"1"}}, index, c.wait, &entries)
+	if err != nil {
+		return index, err
+	}
+
+	instances := make([]instance, 0, len(entries))
+	for _, e := range entries {
+		inst := instance{
+			id:          e.Service.ID,
+			node:        e.Node.Node,
+			nodeAddress: e.Node.Address,
+			address:     e.Service.Address,
+			port:        e.Service.Port,
+			tags:        e.Service.Tags,
+		}
+		if inst.address == "" {
+			inst.address = inst.nodeAddress
+		}
+		instances = append(instances, inst)
+	}
+
+	c.Lock()
+	// A watch that was just stopped mustn't add the service again.
+	if ctx.Err() == nil {
+		if c.services[dc] == nil {
+			c.services[dc] = map[string][]instance{}
+		}
+		c.services[dc][strings.ToLower(service)] = instances
+	}
+	c.Unlock()
+	c.seen(i)
+	return i, nil
+}
+
+// nextIndex returns the index for the next blocking query, after a query with index returned i.
+func nextIndex(index, i uint64) uint64 {
+	// The index may go backwards, i.e. after a snapshot restore, start over.
+	if i < index {
+		return 0
+	}
+	return i
+}
+
+// sleep sleeps for d, it returns false if ctx is canceled before that.
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// seen records Raft index i.
+func (c *catalog) seen(i uint64) {
+	c.Lock()
+	defer c.Unlock()
+	if i > c.index {
+		c.index = i
+	}
+}
+
+// datacenters returns the datacenters watched, the first is the default.
+func (c *catalog) datacenters() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.dcs
+}
+
+// instances returns the healthy instances of service in dc.
+func (c *catalog) instances(dc, service string) []instance {
+	c.RLock()
+	defer c.RUnlock()
+	return c.services[dc][service]
+}
+
+// node returns the address of node in dc, if it runs a healthy instance of any service.
+func (c *catalog) node(dc, node string) (string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+	for _, instances := range c.services[dc] {
+		for _, inst := range instances {
+			if strings.EqualFold(inst.node, node) {
+				return inst.nodeAddress, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ready returns true once all datacenters have been loaded.
+func (c *catalog) ready() bool {
+	c.RLock()
+	defer c.RUnlock()
+	if len(c.dcs) == 0 {
+		return false
+	}
+	for _, dc := range c.dcs {
+		if !c.synced[dc] {
+			return false
+		}
+	}
+	return true
+}
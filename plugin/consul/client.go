@@ -0,0 +1,56 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// client is a minimal client for the HTTP API of a Consul agent.
+type client struct {
+	addr  string // Base URL of the agent, i.e. http://127.0.0.1:8500.
+	token string // ACL token, may be empty.
+	http  *http.Client
+}
+
+// get does a GET of path with query and decodes the JSON response into v. If index is larger than zero the
+// request is a blocking query: it returns when the result has changed since index or when wait has passed.
+// It returns the X-Consul-Index of the response.
+func (c *client) get(ctx context.Context, path string, query url.Values, index uint64, wait time.Duration, v interface{}) (uint64, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", wait.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.addr+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("unexpected status %s for %s: %s", resp.Status, path, body)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return 0, fmt.Errorf("failed to decode response for %s: %s", path, err)
+	}
+	if h := resp.Header.Get("X-Consul-Index"); h != "" {
+		return strconv.ParseUint(h, 10, 64)
+	}
+	return 0, nil
+}
@@ -0,0 +1,209 @@
+// Package consul implements a plugin that serves the healthy instances of the services in the Consul catalog.
+package consul
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const defaultTTL = 5
+
+var errNoItems = errors.New("no items found")
+
+// Consul is a plugin that serves the healthy instances of the services in the Consul catalog.
+type Consul struct {
+	Next     plugin.Handler
+	Fall     fall.F
+	Zones    []string
+	Upstream *upstream.Upstream
+
+	ttl     uint32
+	catalog *catalog
+}
+
+// Services implements the ServiceBackend interface.
+func (c *Consul) Services(ctx context.Context, state request.Request, exact bool, opt plugin.Options) ([]msg.Service, error) {
+	return c.Records(ctx, state, exact)
+}
+
+// Reverse implements the ServiceBackend interface.
+func (c *Consul) Reverse(ctx context.Context, state request.Request, exact bool, opt plugin.Options) ([]msg.Service, error) {
+	return nil, errNoItems
+}
+
+// Lookup implements the ServiceBackend interface.
+func (c *Consul) Lookup(ctx context.Context, state request.Request, name string, typ uint16) (*dns.Msg, error) {
+	return c.Upstream.Lookup(ctx, state, name, typ)
+}
+
+// IsNameError implements the ServiceBackend interface.
+func (c *Consul) IsNameError(err error) bool { return err == errNoItems }
+
+// Serial implements the ServiceBackend interface. It returns the largest Raft index seen.
+func (c *Consul) Serial(state request.Request) uint32 {
+	c.catalog.RLock()
+	defer c.catalog.RUnlock()
+	return uint32(c.catalog.index)
+}
+
+// MinTTL implements the ServiceBackend interface.
+func (c *Consul) MinTTL(state request.Request) uint32 { return c.ttl }
+
+// Records implements the ServiceBackend interface.
+func (c *Consul) Records(ctx context.Context, state request.Request, exact bool) ([]msg.Service, error) {
+	name := state.Name()
+	zone := plugin.Zones(c.Zones).Matches(name)
+	q, ok := parseName(name, zone)
+	if !ok {
+		return nil, errNoItems
+	}
+	if q.kind == kindApex {
+		return nil, nil
+	}
+	dcs := c.catalog.datacenters()
+	if len(dcs) == 0 {
+		return nil, errNoItems
+	}
+	dc := dcs[0]
+	if q.dc != "" {
+		dc = q.dc
+	}
+
+	var services []msg.Service
+	switch q.kind {
+	case kindService:
+		for _, inst := range c.catalog.instances(dc, q.service) {
+			if q.tag != "" && !hasTag(inst.tags, q.tag) {
+				continue
+			}
+			// The target of an SRV record is the node, if the instance has the node's address.
+			target := dnsutil.Join(inst.node, "node", dc, zone)
+			if inst.address != inst.nodeAddress {
+				target = dnsutil.Join(addrLabel(inst.address), "addr", dc, zone)
+			}
+			services = append(services, msg.Service{Host: inst.address, Port: inst.port, Priority: 1, TTL: c.ttl, Key: msg.Path(target, "consul")})
+		}
+	case kindNode:
+		if addr, ok := c.catalog.node(dc, q.node); ok {
+			services = append(services, msg.Service{Host: addr, TTL: c.ttl, Key: msg.Path(name, "consul")})
+		}
+	case kindAddr:
+		services = append(services, msg.Service{Host: q.addr, TTL: c.ttl, Key: msg.Path(name, "consul")})
+	}
+	if len(services) == 0 {
+		return nil, errNoItems
+	}
+	return services, nil
+}
+
+const (
+	kindApex = iota
+	kindService
+	kindNode
+	kindAddr
+)
+
+// query is a parsed query name.
+type query struct {
+	kind    int
+	service string
+	tag     string
+	node    string
+	addr    string
+	dc      string // Empty for the default datacenter.
+}
+
+// parseName parses name in zone. The names are:
+//
+//	[TAG.]SERVICE.service[.DC].ZONE
+//	_SERVICE._TAG.service[.DC].ZONE, where a TAG of tcp or udp matches all instances (RFC 2782).
+//	NODE.node[.DC].ZONE
+//	HEX-ADDRESS.addr[.DC].ZONE
+func parseName(name, zone string) (query, bool) {
+	q := query{}
+	if name == zone {
+		return q, true
+	}
+	labels := dns.SplitDomainName(strings.TrimSuffix(name, "."+zone))
+	n := len(labels)
+	if n >= 2 && !isKind(labels[n-1]) && isKind(labels[n-2]) {
+		q.dc = labels[n-1]
+		labels = labels[:n-1]
+		n--
+	}
+	if n < 2 {
+		return q, false
+	}
+
+	switch labels[n-1] {
+	case "service":
+		labels = labels[:n-1]
+		q.kind = kindService
+		switch {
+		case len(labels) == 1:
+			q.service = labels[0]
+		case len(labels) == 2 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_"):
+			q.service = labels[0][1:]
+			if tag := labels[1][1:]; tag != "tcp" && tag != "udp" {
+				q.tag = tag
+			}
+		case len(labels) == 2:
+			q.tag, q.service = labels[0], labels[1]
+		default:
+			return q, false
+		}
+	case "node":
+		if n != 2 {
+			return q, false
+		}
+		q.kind, q.node = kindNode, labels[0]
+	case "addr":
+		if n != 2 {
+			return q, false
+		}
+		b, err := hex.DecodeString(labels[0])
+		if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+			return q, false
+		}
+		q.kind, q.addr = kindAddr, net.IP(b).String()
+	}
+	return q, q.kind != kindApex
+}
+
+func isKind(label string) bool { return label == "service" || label == "node" || label == "addr" }
+
+// addrLabel returns the label for ip in addr names, its bytes in hex.
+func addrLabel(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ip
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return hex.EncodeToString(v4)
+	}
+	return hex.EncodeToString(addr)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Ready implements the ready.Readiness interface.
+func (c *Consul) Ready() bool { return c.catalog.ready() }
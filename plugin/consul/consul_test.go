@@ -0,0 +1,333 @@
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func newTestConsul(t *testing.T, f *fakeConsul, dcs ...string) *Consul {
+	t.Helper()
+	c := &Consul{Zones: []string{"consul."}, ttl: defaultTTL}
+	c.catalog = newCatalog(&client{addr: f.URL, token: f.token, http: f.Client()}, dcs)
+	c.catalog.wait = time.Second
+	c.catalog.retry = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go c.catalog.run(ctx)
+	waitFor(t, c.Ready)
+	return c
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 200; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for condition")
+}
+
+func lookup(c *Consul, name string, qtype uint16) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, m)
+	return rec.Msg
+}
+
+func testFakeConsul() *fakeConsul {
+	f := newFakeConsul()
+	f.register("web", "web1", "node1", "10.0.0.1", "", 80, false, "v1", "primary")
+	f.register("web", "web2", "node2", "10.0.0.2", "", 8080, false, "v2")
+	f.register("web", "web3", "node3", "10.0.0.3", "", 80, true, "v1")
+	f.register("db", "db1", "node1", "10.0.0.1", "10.0.1.5", 5432, false)
+	f.register("db6", "db61", "node4", "10.0.0.4", "2001:db8::5", 5432, false)
+	return f
+}
+
+func TestConsul(t *testing.T) {
+	f := testFakeConsul()
+	t.Cleanup(f.Close)
+	c := newTestConsul(t, f)
+
+	for i, tc := range []test.Case{
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("web.service.consul.	5	IN	A	10.0.0.1"),
+				test.A("web.service.consul.	5	IN	A	10.0.0.2"),
+			},
+		},
+		{
+			Qname: "WEB.service.dc1.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{
+				test.A("WEB.service.dc1.consul.	5	IN	A	10.0.0.1"),
+				test.A("WEB.service.dc1.consul.	5	IN	A	10.0.0.2"),
+			},
+		},
+		{
+			Qname: "v1.web.service.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("v1.web.service.consul.	5	IN	A	10.0.0.1")},
+		},
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{
+				test.SRV("web.service.consul.	5	IN	SRV	1 50 80 node1.node.dc1.consul."),
+				test.SRV("web.service.consul.	5	IN	SRV	1 50 8080 node2.node.dc1.consul."),
+			},
+			Extra: []dns.RR{
+				test.A("node1.node.dc1.consul.	5	IN	A	10.0.0.1"),
+				test.A("node2.node.dc1.consul.	5	IN	A	10.0.0.2"),
+			},
+		},
+		{
+			Qname: "_web._v2.service.consul.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("_web._v2.service.consul.	5	IN	SRV	1 100 8080 node2.node.dc1.consul.")},
+			Extra:  []dns.RR{test.A("node2.node.dc1.consul.	5	IN	A	10.0.0.2")},
+		},
+		{
+			Qname: "_db._tcp.service.consul.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("_db._tcp.service.consul.	5	IN	SRV	1 100 5432 0a000105.addr.dc1.consul.")},
+			Extra:  []dns.RR{test.A("0a000105.addr.dc1.consul.	5	IN	A	10.0.1.5")},
+		},
+		{
+			Qname: "db6.service.consul.", Qtype: dns.TypeAAAA,
+			Answer: []dns.RR{test.AAAA("db6.service.consul.	5	IN	AAAA	2001:db8::5")},
+		},
+		{
+			Qname: "node2.node.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("node2.node.consul.	5	IN	A	10.0.0.2")},
+		},
+		{
+			Qname: "0a000105.addr.dc1.consul.", Qtype: dns.TypeA,
+			Answer: []dns.RR{test.A("0a000105.addr.dc1.consul.	5	IN	A	10.0.1.5")},
+		},
+		// NODATA.
+		{
+			Qname: "web.service.consul.", Qtype: dns.TypeAAAA,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+		{
+			Qname: "consul.", Qtype: dns.TypeA,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+		// NXDOMAIN: the only instance with the tag is critical, unknown service, node without healthy instances,
+		// unknown datacenter.
+		{
+			Qname: "v1.web.service.consul.", Qtype: dns.TypeSRV,
+			Answer: []dns.RR{test.SRV("v1.web.service.consul.	5	IN	SRV	1 100 80 node1.node.dc1.consul.")},
+			Extra:  []dns.RR{test.A("node1.node.dc1.consul.	5	IN	A	10.0.0.1")},
+		},
+		{
+			Qname: "v3.web.service.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+		{
+			Qname: "cache.service.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+		{
+			Qname: "node3.node.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+		{
+			Qname: "web.service.dc2.consul.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{test.SOA("consul.	5	IN	SOA	ns.dns.consul. hostmaster.consul. 6 7200 1800 86400 5")},
+		},
+	} {
+		resp := lookup(c, tc.Qname, tc.Qtype)
+		if err := test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d, %s %s: %v", i, tc.Qname, dns.TypeToString[tc.Qtype], err)
+		}
+	}
+}
+
+func TestConsulWatch(t *testing.T) {
+	f := testFakeConsul()
+	f.token = "s3cret"
+	t.Cleanup(f.Close)
+	c := newTestConsul(t, f, "dc1")
+
+	answers := func(name string) int { return len(lookup(c, name, dns.TypeA).Answer) }
+	if n := answers("web.service.consul."); n != 2 {
+		t.Fatalf("Expected 2 answers, got %d", n)
+	}
+
+	// An instance fails its health check, another one recovers.
+	f.register("web", "web2", "node2", "10.0.0.2", "", 8080, true, "v2")
+	waitFor(t, func() bool { return answers("web.service.consul.") == 1 })
+	f.register("web", "web3", "node3", "10.0.0.3", "", 80, false, "v1")
+	waitFor(t, func() bool { return answers("web.service.consul.") == 2 })
+
+	// A new service is registered, and an old one deregistered.
+	f.register("cache", "cache1", "node1", "10.0.0.1", "", 6379, false)
+	waitFor(t, func() bool { return answers("cache.service.consul.") == 1 })
+	f.deregister("db")
+	waitFor(t, func() bool { return answers("db.service.consul.") == 0 })
+
+	f.Lock()
+	index := f.index
+	f.Unlock()
+	if serial := c.Serial(request.Request{}); serial != uint32(index) {
+		t.Errorf("Expected serial %d, got %d", index, serial)
+	}
+}
+
+func TestParseName(t *testing.T) {
+	for i, tc := range []struct {
+		name string
+		want query
+		ok   bool
+	}{
+		{"consul.", query{kind: kindApex}, true},
+		{"web.service.consul.", query{kind: kindService, service: "web"}, true},
+		{"v1.web.service.consul.", query{kind: kindService, service: "web", tag: "v1"}, true},
+		{"web.service.dc2.consul.", query{kind: kindService, service: "web", dc: "dc2"}, true},
+		{"_web._tcp.service.consul.", query{kind: kindService, service: "web"}, true},
+		{"_web._v1.service.dc2.consul.", query{kind: kindService, service: "web", tag: "v1", dc: "dc2"}, true},
+		{"node1.node.consul.", query{kind: kindNode, node: "node1"}, true},
+		{"0a000001.addr.consul.", query{kind: kindAddr, addr: "10.0.0.1"}, true},
+		{"20010db8000000000000000000000001.addr.dc1.consul.", query{kind: kindAddr, addr: "2001:db8::1", dc: "dc1"}, true},
+		{"service.consul.", query{}, false},
+		{"a.b.web.service.consul.", query{}, false},
+		{"a.node1.node.consul.", query{}, false},
+		{"zz.addr.consul.", query{}, false},
+		{"web.consul.", query{}, false},
+		{"web.other.dc1.consul.", query{}, false},
+	} {
+		got, ok := parseName(tc.name, "consul.")
+		if ok != tc.ok {
+			t.Errorf("Test %d: Expected ok %t for %s, got %t", i, tc.ok, tc.name, ok)
+			continue
+		}
+		if ok && got != tc.want {
+			t.Errorf("Test %d: Expected %+v for %s, got %+v", i, tc.want, tc.name, got)
+		}
+	}
+}
+
+// fakeConsul emulates the parts of the HTTP API of a Consul agent used by the plugin, including blocking queries.
+type fakeConsul struct {
+	*httptest.Server
+	dc    string
+	token string
+
+	sync.Mutex
+	index    uint64
+	changed  chan struct{} // Closed when the catalog changes.
+	services map[string][]fakeInstance
+}
+
+type fakeInstance struct {
+	healthEntry
+	critical bool
+}
+
+func newFakeConsul() *fakeConsul {
+	f := &fakeConsul{dc: "dc1", index: 1, changed: make(chan struct{}), services: map[string][]fakeInstance{}}
+	f.Server = httptest.NewServer(f)
+	return f
+}
+
+// register registers an instance of service on node.
+func (f *fakeConsul) register(service, id, node, nodeAddr, addr string, port int, critical bool, tags ...string) {
+	inst := fakeInstance{critical: critical}
+	inst.Node.Node, inst.Node.Address = node, nodeAddr
+	inst.Service.ID, inst.Service.Service, inst.Service.Address, inst.Service.Port, inst.Service.Tags = id, service, addr, port, tags
+
+	f.Lock()
+	defer f.Unlock()
+	instances := f.services[service]
+	for i := range instances {
+		if instances[i].Service.ID == id {
+			instances[i] = inst
+			f.change()
+			return
+		}
+	}
+	f.services[service] = append(instances, inst)
+	f.change()
+}
+
+// deregister deregisters all instances of service.
+func (f *fakeConsul) deregister(service string) {
+	f.Lock()
+	defer f.Unlock()
+	delete(f.services, service)
+	f.change()
+}
+
+func (f *fakeConsul) change() {
+	f.index++
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+func (f *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.token != "" && r.Header.Get("X-Consul-Token") != f.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+	if r.URL.Path == "/v1/agent/self" {
+		json.NewEncoder(w).Encode(map[string]interface{}{"Config": map[string]string{"Datacenter": f.dc}})
+		return
+	}
+	if dc := r.URL.Query().Get("dc"); dc != f.dc {
+		http.Error(w, "No path to datacenter", http.StatusInternalServerError)
+		return
+	}
+
+	// Block while the catalog didn't change since index.
+	f.Lock()
+	index, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+	if index > 0 && index >= f.index {
+		changed := f.changed
+		f.Unlock()
+		wait, _ := time.ParseDuration(r.URL.Query().Get("wait"))
+		select {
+		case <-changed:
+		case <-time.After(wait):
+		case <-r.Context().Done():
+			return
+		}
+		f.Lock()
+	}
+	defer f.Unlock()
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+
+	switch {
+	case r.URL.Path == "/v1/catalog/services":
+		services := map[string][]string{}
+		for name := range f.services {
+			services[name] = []string{}
+		}
+		json.NewEncoder(w).Encode(services)
+	case strings.HasPrefix(r.URL.Path, "/v1/health/service/"):
+		entries := []healthEntry{}
+		for _, inst := range f.services[strings.TrimPrefix(r.URL.Path, "/v1/health/service/")] {
+			if inst.critical && r.URL.Query().Get("passing") != "" {
+				continue
+			}
+			entries = append(entries, inst.healthEntry)
+		}
+		json.NewEncoder(w).Encode(entries)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,143 @@
+package consul
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+)
+
+var log = clog.NewWithPlugin("consul")
+
+func init() { plugin.Register("consul", setup) }
+
+func setup(c *caddy.Controller) error {
+	cs, err := parse(c)
+	if err != nil {
+		return plugin.Error("consul", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.OnStartup(func() error {
+		go cs.catalog.run(ctx)
+		return nil
+	})
+	c.OnShutdown(func() error {
+		cancel()
+		return nil
+	})
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		cs.Next = next
+		return cs
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Consul, error) {
+	config := dnsserver.GetConfig(c)
+	cs := &Consul{Upstream: upstream.New(), ttl: defaultTTL}
+	cl := &client{addr: defaultAddress}
+	var (
+		tlsConfig *tls.Config
+		dcs       []string
+		err       error
+	)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		cs.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+		for c.NextBlock() {
+			switch c.Val() {
+			case "address":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				cl.addr = strings.TrimSuffix(c.Val(), "/")
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+			case "token_file":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				token, err := os.ReadFile(c.Val())
+				if err != nil {
+					return nil, err
+				}
+				cl.token = string(bytes.TrimSpace(token))
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+			case "tls": // cert key cacertfile
+				args := c.RemainingArgs()
+				for i := range args {
+					if !filepath.IsAbs(args[i]) && config.Root != "" {
+						args[i] = filepath.Join(config.Root, args[i])
+					}
+				}
+				tlsConfig, err = mwtls.NewTLSConfigFromArgs(args...)
+				if err != nil {
+					return nil, err
+				}
+			case "datacenters":
+				dcs = c.RemainingArgs()
+				if len(dcs) == 0 {
+					return nil, c.ArgErr()
+				}
+				for i := range dcs {
+					dcs[i] = strings.ToLower(dcs[i])
+				}
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				t, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if t < 0 || t > 3600 {
+					return nil, c.Errf("ttl must be in range [0, 3600]: %d", t)
+				}
+				cs.ttl = uint32(t)
+			case "fallthrough":
+				cs.Fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	scheme := "http://"
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+		scheme = "https://"
+	}
+	if !strings.HasPrefix(cl.addr, "http://") && !strings.HasPrefix(cl.addr, "https://") {
+		cl.addr = scheme + cl.addr
+	}
+	cl.http = &http.Client{Transport: transport}
+	cs.catalog = newCatalog(cl, dcs)
+	return cs, nil
+}
+
+const defaultAddress = "127.0.0.1:8500"
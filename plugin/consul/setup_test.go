@@ -0,0 +1,95 @@
+package consul
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetupConsul(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input              string
+		shouldErr          bool
+		expectedAddr       string
+		expectedToken      string
+		expectedDCs        []string
+		expectedTTL        uint32
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+	}{
+		// positive
+		{`consul`, false, "http://127.0.0.1:8500", "", nil, defaultTTL, ""},
+		{`consul service.example.org {
+	address consul.example.org:8500
+	datacenters DC1 dc2
+	ttl 30
+}`, false, "http://consul.example.org:8500", "", []string{"dc1", "dc2"}, 30, ""},
+		{`consul {
+	address https://consul.example.org/
+	token_file ` + tokenFile + `
+	ttl 0
+}`, false, "https://consul.example.org", "secret", nil, 0, ""},
+		// negative
+		{`consul {
+	endpoint localhost:8500
+}`, true, "", "", nil, 0, "unknown property 'endpoint'"},
+		{`consul {
+	address
+}`, true, "", "", nil, 0, "Wrong argument count"},
+		{`consul {
+	datacenters
+}`, true, "", "", nil, 0, "Wrong argument count"},
+		{`consul {
+	ttl 3601
+}`, true, "", "", nil, 0, "ttl must be in range"},
+		{`consul {
+	ttl -1
+}`, true, "", "", nil, 0, "ttl must be in range"},
+		{`consul {
+	token_file /does/not/exist
+}`, true, "", "", nil, 0, "no such file"},
+		{`consul
+consul`, true, "", "", nil, 0, "this plugin"},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		cs, err := parse(c)
+
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+				continue
+			}
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s: %v", i, test.input, err)
+			continue
+		}
+
+		if cs.catalog.c.addr != test.expectedAddr {
+			t.Errorf("Test %d: expected address %q, got %q", i, test.expectedAddr, cs.catalog.c.addr)
+		}
+		if cs.catalog.c.token != test.expectedToken {
+			t.Errorf("Test %d: expected token %q, got %q", i, test.expectedToken, cs.catalog.c.token)
+		}
+		if !reflect.DeepEqual(cs.catalog.dcs, test.expectedDCs) {
+			t.Errorf("Test %d: expected datacenters %v, got %v", i, test.expectedDCs, cs.catalog.dcs)
+		}
+		if cs.ttl != test.expectedTTL {
+			t.Errorf("Test %d: expected ttl %d, got %d", i, test.expectedTTL, cs.ttl)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+// Package cookie implements DNS Cookies (RFC 7873) with interoperable server cookies (RFC 9018).
+package cookie
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// Cookie adds a server cookie to the responses to queries with a DNS Cookie option and validates
+// the server cookies clients send back.
+type Cookie struct {
+	Next plugin.Handler
+
+	secrets *secrets
+	rotate  time.Duration // zero if the secret is configured
+	enforce bool          // return BADCOOKIE to UDP queries without a valid server cookie
+
+	stop chan struct{}
+	now  func() time.Time
+}
+
+// New returns a new Cookie with a random secret.
+func New() (*Cookie, error) {
+	s, err := newSecrets()
+	if err != nil {
+		return nil, err
+	}
+	return &Cookie{secrets: s, rotate: defaultRotate, stop: make(chan struct{}), now: time.Now}, nil
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (c *Cookie) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	server, view := metrics.WithServer(ctx), metrics.WithView(ctx)
+
+	client, cookie, ok := clientCookie(r)
+	if !ok {
+		RequestsCount.WithLabelValues(server, view, "malformed").Inc()
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeFormatError)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+	if client == nil {
+		RequestsCount.WithLabelValues(server, view, "none").Inc()
+		metadata.SetValueFunc(ctx, pluginName+"/verified", func() string { return "false" })
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
+	now := c.now()
+	ip := clientIP(state)
+	result, verified := "client", "false"
+	valid, stale := false, false
+	if cookie != nil {
+		result = "invalid"
+		if valid, stale = c.secrets.valid(client, cookie, ip, now); valid {
+			result, verified = "valid", "true"
+		}
+	}
+	RequestsCount.WithLabelValues(server, view, result).Inc()
+	metadata.SetValueFunc(ctx, pluginName+"/verified", func() string { return verified })
+
+	if !valid || stale {
+		cookie = c.secrets.server(client, ip, now)
+	}
+	cw := &ResponseWriter{ResponseWriter: w, state: state, cookie: hex.EncodeToString(client) + hex.EncodeToString(cookie)}
+
+	// RFC 7873, section 5.2.3: a server may require a valid server cookie, TCP is source-verified already.
+	if !valid && c.enforce && state.Proto() == "udp" {
+		BadCookieCount.WithLabelValues(server, view).Inc()
+		log.Debugf("Returning BADCOOKIE to %s", state.IP())
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeBadCookie)
+		cw.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	}
+
+	return plugin.NextOrFailure(c.Name(), c.Next, ctx, cw, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (c *Cookie) Name() string { return pluginName }
+
+// ResponseWriter adds the server cookie to the response.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	state  request.Request
+	cookie string // client and server cookie in hex
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	w.state.SizeAndDo(res)
+	if o := res.IsEdns0(); o != nil {
+		opts := make([]dns.EDNS0, 0, len(o.Option)+1)
+		for _, opt := range o.Option {
+			if opt.Option() != dns.EDNS0COOKIE {
+				opts = append(opts, opt)
+			}
+		}
+		o.Option = append(opts, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: w.cookie})
+	}
+	return w.ResponseWriter.WriteMsg(res)
+}
+
+// clientCookie returns the client and server cookie from the COOKIE option in r. Both are nil if
+// there is no such option; the server cookie is nil if the client only sent a client cookie. If the
+// option is malformed, false is returned.
+func clientCookie(r *dns.Msg) ([]byte, []byte, bool) {
+	o := r.IsEdns0()
+	if o == nil {
+		return nil, nil, true
+	}
+	for _, opt := range o.Option {
+		e, ok := opt.(*dns.EDNS0_COOKIE)
+		if !ok {
+			continue
+		}
+		b, err := hex.DecodeString(e.Cookie)
+		// RFC 7873, section 5.2.2: a client cookie is 8 bytes, a server cookie 8 to 32 bytes.
+		if err != nil || (len(b) != 8 && (len(b) < 16 || len(b) > 40)) {
+			return nil, nil, false
+		}
+		if len(b) == 8 {
+			return b, nil, true
+		}
+		return b[:8], b[8:], true
+	}
+	return nil, nil, true
+}
+
+// clientIP returns the address of the client the query came from.
+func clientIP(state request.Request) net.IP {
+	addr := state.IP()
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	return net.ParseIP(addr)
+}
@@ -0,0 +1,141 @@
+package cookie
+
+import (
+	"context"
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const clientCookieHex = "0102030405060708"
+
+func newTestCookie(t *testing.T) *Cookie {
+	t.Helper()
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	now := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return now }
+	return c
+}
+
+func cookieQuery(cookie string) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	if cookie != "" {
+		m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie}}
+	}
+	return m
+}
+
+func responseCookie(m *dns.Msg) string {
+	if o := m.IsEdns0(); o != nil {
+		for _, opt := range o.Option {
+			if e, ok := opt.(*dns.EDNS0_COOKIE); ok {
+				return e.Cookie
+			}
+		}
+	}
+	return ""
+}
+
+func TestCookie(t *testing.T) {
+	c := newTestCookie(t)
+	client, _ := hex.DecodeString(clientCookieHex)
+	valid := clientCookieHex + hex.EncodeToString(c.secrets.server(client, net.ParseIP("10.240.0.1"), c.now()))
+	invalid := clientCookieHex + "01000000655043000000000000000000"
+
+	tests := []struct {
+		cookie   string
+		tcp      bool
+		enforce  bool
+		rcode    int
+		verified string
+		echo     bool // a server cookie is returned
+	}{
+		{"", false, false, dns.RcodeSuccess, "false", false},
+		{clientCookieHex, false, false, dns.RcodeSuccess, "false", true},
+		{valid, false, false, dns.RcodeSuccess, "true", true},
+		{invalid, false, false, dns.RcodeSuccess, "false", true},
+		{"0102", false, false, dns.RcodeFormatError, "", false},
+		// enforced
+		{"", false, true, dns.RcodeSuccess, "false", false},
+		{clientCookieHex, false, true, dns.RcodeBadCookie, "false", true},
+		{clientCookieHex, true, true, dns.RcodeSuccess, "false", true},
+		{valid, false, true, dns.RcodeSuccess, "true", true},
+		{invalid, false, true, dns.RcodeBadCookie, "false", true},
+	}
+
+	for i, tc := range tests {
+		c.enforce = tc.enforce
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+		if _, err := c.ServeDNS(ctx, rec, cookieQuery(tc.cookie)); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if rec.Msg == nil {
+			// The next handler doesn't write a response.
+			if tc.rcode != dns.RcodeSuccess {
+				t.Errorf("Test %d: expected rcode %s, got no response", i, dns.RcodeToString[tc.rcode])
+			}
+		} else if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+
+		got := ""
+		if f := metadata.ValueFunc(ctx, "cookie/verified"); f != nil {
+			got = f()
+		}
+		if got != tc.verified {
+			t.Errorf("Test %d: expected cookie/verified %q, got %q", i, tc.verified, got)
+		}
+
+		if tc.echo && rec.Msg != nil {
+			if cookie := responseCookie(rec.Msg); cookie != valid {
+				t.Errorf("Test %d: expected cookie %s, got %s", i, valid, cookie)
+			}
+		}
+	}
+}
+
+func TestCookieResponse(t *testing.T) {
+	c := newTestCookie(t)
+	c.Next = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		w.WriteMsg(m)
+		return dns.RcodeSuccess, nil
+	})
+
+	client, _ := hex.DecodeString(clientCookieHex)
+	expect := clientCookieHex + hex.EncodeToString(c.secrets.server(client, net.ParseIP("10.240.0.1"), c.now()))
+
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, cookieQuery(clientCookieHex))
+	if rec.Msg == nil {
+		t.Fatal("Expected a response")
+	}
+	if cookie := responseCookie(rec.Msg); cookie != expect {
+		t.Errorf("Expected cookie %s, got %s", expect, cookie)
+	}
+
+	// A fresh valid cookie is returned as is, an old one is replaced.
+	old := c.now().Add(-40 * time.Minute)
+	stale := clientCookieHex + hex.EncodeToString(c.secrets.server(client, net.ParseIP("10.240.0.1"), old))
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	c.ServeDNS(context.TODO(), rec, cookieQuery(stale))
+	if cookie := responseCookie(rec.Msg); cookie != expect {
+		t.Errorf("Expected the stale cookie to be replaced by %s, got %s", expect, cookie)
+	}
+}
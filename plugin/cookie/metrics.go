@@ -0,0 +1,26 @@
+package cookie
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RequestsCount is the number of queries per kind of DNS Cookie they carry.
+	RequestsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "requests_total",
+		Help:      "Counter of queries by the kind of DNS Cookie they carry.",
+	}, []string{"server", "view", "cookie"})
+
+	// BadCookieCount is the number of BADCOOKIE responses.
+	BadCookieCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "badcookie_total",
+		Help:      "Counter of BADCOOKIE responses.",
+	}, []string{"server", "view"})
+)
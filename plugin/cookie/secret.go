@@ -0,0 +1,111 @@
+package cookie
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	version = 1 // RFC 9018 server cookie version
+
+	maxAge    = 1 * time.Hour    // server cookies older than this are invalid
+	maxFuture = 5 * time.Minute  // server cookies can be this much in the future
+	refresh   = 30 * time.Minute // server cookies older than this are replaced
+)
+
+// secrets holds the secret server cookies are computed with. The previous secret is still accepted
+// after a rotation, so cookies handed out just before it remain valid.
+type secrets struct {
+	sync.RWMutex
+	current  [16]byte
+	previous *[16]byte
+}
+
+// newSecrets returns secrets with a random current secret.
+func newSecrets() (*secrets, error) {
+	s := &secrets{}
+	if _, err := rand.Read(s.current[:]); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// parseSecret parses a secret of 16 bytes in hex.
+func parseSecret(h string) ([16]byte, error) {
+	var k [16]byte
+	b, err := hex.DecodeString(h)
+	if err != nil || len(b) != len(k) {
+		return k, fmt.Errorf("secret must be %d hexadecimal characters: %q", 2*len(k), h)
+	}
+	copy(k[:], b)
+	return k, nil
+}
+
+// rotate replaces the current secret with a new random one.
+func (s *secrets) rotate() error {
+	var k [16]byte
+	if _, err := rand.Read(k[:]); err != nil {
+		return err
+	}
+	s.Lock()
+	defer s.Unlock()
+	prev := s.current
+	s.previous = &prev
+	s.current = k
+	return nil
+}
+
+// server returns the RFC 9018 server cookie for the client cookie and client ip at time now.
+func (s *secrets) server(client []byte, ip net.IP, now time.Time) []byte {
+	s.RLock()
+	defer s.RUnlock()
+	return serverCookie(s.current, client, ip, uint32(now.Unix()))
+}
+
+// valid returns true if server is a server cookie we handed out to the client at ip with the client
+// cookie, and it isn't too old. The second return value is true if it should be replaced by a fresh one.
+func (s *secrets) valid(client, server []byte, ip net.IP, now time.Time) (bool, bool) {
+	if len(server) != 16 || server[0] != version {
+		return false, false
+	}
+	ts := binary.BigEndian.Uint32(server[4:8])
+	// Serial number arithmetic, the timestamp wraps in 2106.
+	age := time.Duration(int32(uint32(now.Unix())-ts)) * time.Second
+	if age > maxAge || age < -maxFuture {
+		return false, false
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	ok := string(serverCookie(s.current, client, ip, ts)) == string(server)
+	if !ok && s.previous != nil {
+		ok = string(serverCookie(*s.previous, client, ip, ts)) == string(server)
+	}
+	return ok, age > refresh
+}
+
+// serverCookie computes the server cookie as specified in RFC 9018, section 4:
+//
+//	Hash = SipHash-2-4(Client Cookie | Version | Reserved | Timestamp | Client-IP, Server Secret)
+//
+// and the server cookie is Version | Reserved | Timestamp | Hash.
+func serverCookie(secret [16]byte, client []byte, ip net.IP, ts uint32) []byte {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	buf := make([]byte, 0, 8+8+net.IPv6len)
+	buf = append(buf, client...)
+	buf = append(buf, version, 0, 0, 0)
+	buf = binary.BigEndian.AppendUint32(buf, ts)
+	header := buf[len(client):]
+	buf = append(buf, ip...)
+
+	cookie := make([]byte, 0, 16)
+	cookie = append(cookie, header...)
+	return binary.LittleEndian.AppendUint64(cookie, siphash(secret, buf))
+}
@@ -0,0 +1,71 @@
+package cookie
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServerCookie(t *testing.T) {
+	// Test vector from RFC 9018, appendix A.1.
+	tests := []struct {
+		client, ip, secret string
+		ts                 uint32
+		server             string
+	}{
+		{"2464c4abcf10c957", "198.51.100.100", "e5e973e5a6b2a43f48e7dc849e37bfcf", 1559731985, "010000005cf79f111f8130c3eee29480"},
+	}
+	for i, tc := range tests {
+		client, _ := hex.DecodeString(tc.client)
+		secret, err := parseSecret(tc.secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(serverCookie(secret, client, net.ParseIP(tc.ip), tc.ts)); got != tc.server {
+			t.Errorf("Test %d: expected server cookie %s, got %s", i, tc.server, got)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	s, err := newSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	client := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	ip := net.ParseIP("192.0.2.1")
+	server := s.server(client, ip, now)
+
+	tests := []struct {
+		client []byte
+		ip     string
+		at     time.Duration
+		valid  bool
+		stale  bool
+	}{
+		{client, "192.0.2.1", 0, true, false},
+		{client, "192.0.2.1", 40 * time.Minute, true, true},
+		{client, "192.0.2.1", 2 * time.Hour, false, false},
+		{client, "192.0.2.1", -10 * time.Minute, false, false},
+		{client, "192.0.2.2", 0, false, false},
+		{[]byte{8, 7, 6, 5, 4, 3, 2, 1}, "192.0.2.1", 0, false, false},
+	}
+	for i, tc := range tests {
+		valid, stale := s.valid(tc.client, server, net.ParseIP(tc.ip), now.Add(tc.at))
+		if valid != tc.valid || stale != tc.stale {
+			t.Errorf("Test %d: expected valid %t and stale %t, got %t and %t", i, tc.valid, tc.stale, valid, stale)
+		}
+	}
+
+	// After one rotation the cookie is still valid, after two it isn't.
+	s.rotate()
+	if valid, _ := s.valid(client, server, ip, now); !valid {
+		t.Error("Expected the cookie to be valid after a rotation")
+	}
+	s.rotate()
+	if valid, _ := s.valid(client, server, ip, now); valid {
+		t.Error("Expected the cookie to be invalid after two rotations")
+	}
+}
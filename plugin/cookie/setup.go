@@ -0,0 +1,123 @@
+package cookie
+
+import (
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+const (
+	pluginName = "cookie"
+
+	defaultRotate = 24 * time.Hour
+)
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	ck, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	if ck.rotate > 0 {
+		c.OnStartup(func() error {
+			go func() {
+				tick := time.NewTicker(ck.rotate)
+				defer tick.Stop()
+				for {
+					select {
+					case <-ck.stop:
+						return
+					case <-tick.C:
+						if err := ck.secrets.rotate(); err != nil {
+							log.Warningf("Failed to rotate the secret: %s", err)
+						}
+					}
+				}
+			}()
+			return nil
+		})
+		c.OnShutdown(func() error {
+			close(ck.stop)
+			return nil
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		ck.Next = next
+		return ck
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Cookie, error) {
+	ck, err := New()
+	if err != nil {
+		return nil, err
+	}
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		if len(c.RemainingArgs()) > 0 {
+			return nil, c.ArgErr()
+		}
+
+		secret, rotate := false, false
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "secret":
+				args := c.RemainingArgs()
+				if len(args) == 0 || len(args) > 2 {
+					return nil, c.ArgErr()
+				}
+				k, err := parseSecret(args[0])
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				ck.secrets.current = k
+				if len(args) > 1 {
+					prev, err := parseSecret(args[1])
+					if err != nil {
+						return nil, c.Err(err.Error())
+					}
+					ck.secrets.previous = &prev
+				}
+				secret = true
+			case "rotate":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil || d <= 0 {
+					return nil, c.Errf("invalid rotate duration %q", args[0])
+				}
+				ck.rotate = d
+				rotate = true
+			case "enforce":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				ck.enforce = true
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+		if secret && rotate {
+			return nil, c.Err("secret and rotate are mutually exclusive")
+		}
+		if secret {
+			ck.rotate = 0
+		}
+	}
+	return ck, nil
+}
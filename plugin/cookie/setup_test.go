@@ -0,0 +1,74 @@
+package cookie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rotate    time.Duration
+		enforce   bool
+	}{
+		{`cookie`, false, defaultRotate, false},
+		{`cookie {
+			rotate 1h
+			enforce
+		}`, false, time.Hour, true},
+		{`cookie {
+			secret 000102030405060708090a0b0c0d0e0f
+		}`, false, 0, false},
+		{`cookie {
+			secret 000102030405060708090a0b0c0d0e0f f0e0d0c0b0a090807060504030201000
+		}`, false, 0, false},
+		// negative
+		{`cookie example.org`, true, 0, false},
+		{`cookie {
+			secret 0001
+		}`, true, 0, false},
+		{`cookie {
+			secret
+		}`, true, 0, false},
+		{`cookie {
+			rotate 0s
+		}`, true, 0, false},
+		{`cookie {
+			secret 000102030405060708090a0b0c0d0e0f
+			rotate 1h
+		}`, true, 0, false},
+		{`cookie {
+			enforce yes
+		}`, true, 0, false},
+		{`cookie {
+			unknown
+		}`, true, 0, false},
+		{`cookie
+		cookie`, true, 0, false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		ck, err := parse(c)
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: expected error but found none for input %s", i, test.input)
+			continue
+		}
+		if !test.shouldErr && err != nil {
+			t.Errorf("Test %d: expected no error but found one for input %s, got: %v", i, test.input, err)
+			continue
+		}
+		if test.shouldErr {
+			continue
+		}
+		if ck.rotate != test.rotate {
+			t.Errorf("Test %d: expected rotate %s, got %s", i, test.rotate, ck.rotate)
+		}
+		if ck.enforce != test.enforce {
+			t.Errorf("Test %d: expected enforce %t, got %t", i, test.enforce, ck.enforce)
+		}
+	}
+}
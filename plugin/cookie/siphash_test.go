@@ -0,0 +1,26 @@
+package cookie
+
+import "testing"
+
+func TestSiphash(t *testing.T) {
+	// Test vectors from the SipHash paper: key 00..0f and message 00..(n-1).
+	tests := map[int]uint64{
+		0:  0x726fdb47dd0e0e31,
+		7:  0xab0200f58b01d137,
+		8:  0x93f5f5799a932462,
+		15: 0xa129ca6149be45e5,
+	}
+	var k [16]byte
+	for i := range k {
+		k[i] = byte(i)
+	}
+	for n, expect := range tests {
+		p := make([]byte, n)
+		for i := range p {
+			p[i] = byte(i)
+		}
+		if got := siphash(k, p); got != expect {
+			t.Errorf("Length %d: expected %x, got %x", n, expect, got)
+		}
+	}
+}
@@ -7,12 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/etcd/msg"
 	"github.com/coredns/coredns/plugin/pkg/fall"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -38,6 +40,11 @@ type Etcd struct {
 	Client     *etcdcv3.Client
 
 	endpoints []string // Stored here as well, to aid in testing.
+	mirror    *mirror  // If not nil, queries are answered from this in-memory copy of PathPrefix.
+	transfer  *transfer.Transfer
+
+	revMu sync.Mutex
+	revs  map[string]cachedRevision // Revisions of the zones, when there is no mirror.
 }
 
 // Services implements the ServiceBackend interface.
@@ -72,15 +79,19 @@ func (e *Etcd) Records(ctx context.Context, state request.Request, exact bool) (
 	name := state.Name()
 
 	path, star := msg.PathWithWildcard(name, e.PathPrefix)
-	r, err := e.get(ctx, path, !exact)
+	kvs, err := e.get(ctx, path, !exact)
 	if err != nil {
 		return nil, err
 	}
 	segments := strings.Split(msg.Path(name, e.PathPrefix), "/")
-	return e.loopNodes(r.Kvs, segments, star, state.QType())
+	return e.loopNodes(kvs, segments, star, state.QType())
 }
 
-func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.GetResponse, error) {
+func (e *Etcd) get(ctx context.Context, path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	if e.mirror != nil && e.mirror.ready() {
+		return e.mirror.get(path, recursive)
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
 	defer cancel()
 	if recursive {
@@ -101,7 +112,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 				return nil, errKeyNotFound
 			}
 		}
-		return r, nil
+		return r.Kvs, nil
 	}
 
 	r, err := e.Client.Get(ctx, path)
@@ -111,7 +122,7 @@ func (e *Etcd) get(ctx context.Context, path string, recursive bool) (*etcdcv3.G
 	if r.Count == 0 {
 		return nil, errKeyNotFound
 	}
-	return r, nil
+	return r.Kvs, nil
 }
 
 func (e *Etcd) loopNodes(kv []*mvccpb.KeyValue, nameParts []string, star bool, qType uint16) (sx []msg.Service, err error) {
@@ -0,0 +1,50 @@
+package etcd
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// mirrorSyncCount is the number of full syncs of the mirror, by the reason for the sync.
+	mirrorSyncCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "mirror_syncs_total",
+		Help:      "Counter of full syncs of the mirror of the etcd path.",
+	}, []string{"path", "reason"})
+
+	// mirrorErrorCount is the number of failed syncs and watches of the mirror.
+	mirrorErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "mirror_errors_total",
+		Help:      "Counter of failed syncs and watches of the mirror of the etcd path.",
+	}, []string{"path"})
+
+	// mirrorRevision is the etcd revision of the mirror.
+	mirrorRevision = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "mirror_revision",
+		Help:      "The etcd revision of the mirror of the etcd path.",
+	}, []string{"path"})
+
+	// mirrorKeys is the number of keys in the mirror.
+	mirrorKeys = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "mirror_keys",
+		Help:      "The number of keys in the mirror of the etcd path.",
+	}, []string{"path"})
+
+	// mirrorLastSync is the last time the mirror was known to be up to date.
+	mirrorLastSync = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: "etcd",
+		Name:      "mirror_last_sync_timestamp_seconds",
+		Help:      "The timestamp of the last time the mirror of the etcd path was known to be up to date.",
+	}, []string{"path"})
+)
@@ -0,0 +1,334 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultResync    = 10 * time.Minute
+	progressInterval = 10 * time.Second // how often to ask etcd for the current revision when the watch is idle.
+	retryInterval    = time.Second
+)
+
+var errWatchClosed = errors.New("watch closed")
+
+// mirror is an in-memory copy of all keys under a prefix in etcd. It is kept up to date with a watch
+// that continues from the revision of the copy, and is rebuilt when that revision has been compacted
+// and every resync interval.
+type mirror struct {
+	kv      etcdcv3.KV
+	watcher etcdcv3.Watcher
+	prefix  string
+	resync  time.Duration
+
+	mu     sync.RWMutex
+	keys   []string // sorted keys of kvs, for prefix lookups.
+	kvs    map[string]*mvccpb.KeyValue
+	rev    int64 // the etcd revision the mirror is at.
+	synced bool  // true once the first full sync is done.
+
+	zones    map[string]string // path -> zone, of the zones whose changes are tracked.
+	zoneRev  map[string]int64  // revision of the last change in each zone.
+	zoneKeys map[string]int    // number of keys in each zone.
+	changed  func(zone string) // if not nil, called for each zone that changed.
+}
+
+func newMirror(kv etcdcv3.KV, watcher etcdcv3.Watcher, prefix string, resync time.Duration) *mirror {
+	return &mirror{
+		kv:       kv,
+		watcher:  watcher,
+		prefix:   prefix,
+		resync:   resync,
+		kvs:      map[string]*mvccpb.KeyValue{},
+		zones:    map[string]string{},
+		zoneRev:  map[string]int64{},
+		zoneKeys: map[string]int{},
+	}
+}
+
+// track tracks the revision of the last change in zone, whose keys are stored under path.
+func (m *mirror) track(zone, path string) { m.zones[path] = zone }
+
+// zonesOf returns the tracked zones key belongs to.
+func (m *mirror) zonesOf(key string) []string {
+	var zones []string
+	for path, zone := range m.zones {
+		if key == path || strings.HasPrefix(key, strings.TrimSuffix(path, "/")+"/") {
+			zones = append(zones, zone)
+		}
+	}
+	return zones
+}
+
+// revision returns the revision of the last change in zone.
+func (m *mirror) revision(zone string) int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.zoneRev[zone]
+}
+
+func (m *mirror) notify(zones []string) {
+	if m.changed == nil {
+		return
+	}
+	for _, z := range zones {
+		m.changed(z)
+	}
+}
+
+// run keeps the mirror in sync until ctx is canceled.
+func (m *mirror) run(ctx context.Context) {
+	reason := "initial"
+	var rev int64 // 0 means a full sync is needed.
+	for {
+		if rev == 0 {
+			var err error
+			rev, err = m.sync(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Warningf("Failed to sync %s from etcd: %s", m.prefix, err)
+				mirrorErrorCount.WithLabelValues(m.prefix).Inc()
+				m.sleep(ctx, retryInterval)
+				continue
+			}
+			mirrorSyncCount.WithLabelValues(m.prefix, reason).Inc()
+		}
+
+		var err error
+		rev, err = m.watch(ctx, rev)
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case errors.Is(err, rpctypes.ErrCompacted):
+			log.Infof("Revision of %s has been compacted in etcd, resyncing", m.prefix)
+			reason = "compacted"
+		case err != nil:
+			log.Warningf("Watch on %s failed, resuming: %s", m.prefix, err)
+			mirrorErrorCount.WithLabelValues(m.prefix).Inc()
+			m.sleep(ctx, retryInterval)
+		default:
+			reason = "periodic"
+		}
+	}
+}
+
+func (m *mirror) sleep(ctx context.Context, d time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(d):
+	}
+}
+
+// sync replaces the contents of the mirror with the keys in etcd, and returns the revision.
+func (m *mirror) sync(ctx context.Context) (int64, error) {
+	getCtx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+	r, err := m.kv.Get(getCtx, m.prefix, etcdcv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	rev := r.Header.Revision
+	kvs := make(map[string]*mvccpb.KeyValue, len(r.Kvs))
+	keys := make([]string, 0, len(r.Kvs))
+	zoneMax := map[string]int64{}
+	zoneKeys := map[string]int{}
+	for _, kv := range r.Kvs {
+		kvs[string(kv.Key)] = kv
+		keys = append(keys, string(kv.Key))
+		for _, z := range m.zonesOf(string(kv.Key)) {
+			zoneKeys[z]++
+			if kv.ModRevision > zoneMax[z] {
+				zoneMax[z] = kv.ModRevision
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	var changed []string
+	m.mu.Lock()
+	for _, z := range m.zones {
+		old, seen := m.zoneRev[z]
+		// Changes since the last sync either updated a key, which then has a higher revision, or
+		// deleted keys. The revision of a delete is gone, so use the revision of this sync instead.
+		switch {
+		case !seen && zoneKeys[z] == 0:
+			m.zoneRev[z] = rev
+		case !seen, zoneMax[z] > old:
+			m.zoneRev[z] = zoneMax[z]
+		case zoneKeys[z] < m.zoneKeys[z]:
+			m.zoneRev[z] = rev
+		default:
+			continue
+		}
+		m.zoneKeys[z] = zoneKeys[z]
+		changed = append(changed, z)
+	}
+	m.kvs = kvs
+	m.keys = keys
+	m.rev = rev
+	m.synced = true
+	m.mu.Unlock()
+
+	m.updated(rev)
+	m.notify(changed)
+	return rev, nil
+}
+
+// watch applies the changes after rev to the mirror. It returns the last revision seen, or 0 with a nil
+// error when a full sync is due, or 0 with rpctypes.ErrCompacted when the revisions after rev are gone.
+func (m *mirror) watch(ctx context.Context, rev int64) (int64, error) {
+	ctx, cancel := context.WithCancel(etcdcv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	wch := m.watcher.Watch(ctx, m.prefix, etcdcv3.WithPrefix(), etcdcv3.WithRev(rev+1), etcdcv3.WithProgressNotify())
+
+	progress := time.NewTicker(progressInterval)
+	defer progress.Stop()
+	resync := time.NewTimer(m.resync)
+	defer resync.Stop()
+
+	for {
+		select {
+		case <-resync.C:
+			return 0, nil
+		case <-progress.C:
+			m.watcher.RequestProgress(ctx)
+		case resp, ok := <-wch:
+			if !ok {
+				return rev, errWatchClosed
+			}
+			if resp.CompactRevision != 0 {
+				return 0, rpctypes.ErrCompacted
+			}
+			if err := resp.Err(); err != nil {
+				return rev, err
+			}
+			switch {
+			case len(resp.Events) > 0:
+				rev = resp.Events[len(resp.Events)-1].Kv.ModRevision
+				m.apply(resp.Events, rev)
+			case resp.IsProgressNotify() && resp.Header.Revision > rev:
+				// All events up to the revision of a progress notification have been sent.
+				rev = resp.Header.Revision
+				m.apply(nil, rev)
+			}
+		}
+	}
+}
+
+// apply applies the events to the mirror, which is then at revision rev.
+func (m *mirror) apply(events []*etcdcv3.Event, rev int64) {
+	changed := map[string]struct{}{}
+	m.mu.Lock()
+	for _, ev := range events {
+		key := string(ev.Kv.Key)
+		_, exists := m.kvs[key]
+		delta := 0
+		switch ev.Type {
+		case mvccpb.PUT:
+			if !exists {
+				i := sort.SearchStrings(m.keys, key)
+				m.keys = append(m.keys, "")
+				copy(m.keys[i+1:], m.keys[i:])
+				m.keys[i] = key
+				delta = 1
+			}
+			m.kvs[key] = ev.Kv
+		case mvccpb.DELETE:
+			if exists {
+				i := sort.SearchStrings(m.keys, key)
+				m.keys = append(m.keys[:i], m.keys[i+1:]...)
+				delta = -1
+			}
+			delete(m.kvs, key)
+		}
+		for _, z := range m.zonesOf(key) {
+			m.zoneRev[z] = ev.Kv.ModRevision
+			m.zoneKeys[z] += delta
+			changed[z] = struct{}{}
+		}
+	}
+	m.rev = rev
+	m.mu.Unlock()
+
+	m.updated(rev)
+	zones := make([]string, 0, len(changed))
+	for z := range changed {
+		zones = append(zones, z)
+	}
+	m.notify(zones)
+}
+
+// updated records that the mirror is up to date with revision rev.
+func (m *mirror) updated(rev int64) {
+	m.mu.RLock()
+	keys := len(m.keys)
+	m.mu.RUnlock()
+
+	mirrorRevision.WithLabelValues(m.prefix).Set(float64(rev))
+	mirrorKeys.WithLabelValues(m.prefix).Set(float64(keys))
+	mirrorLastSync.WithLabelValues(m.prefix).SetToCurrentTime()
+}
+
+// ready returns true when the mirror can be used to answer queries.
+func (m *mirror) ready() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.synced
+}
+
+// subtree returns the key path and all keys below it.
+func (m *mirror) subtree(path string) []*mvccpb.KeyValue {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var kvs []*mvccpb.KeyValue
+	if kv, ok := m.kvs[path]; ok {
+		kvs = append(kvs, kv)
+	}
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	for i := sort.SearchStrings(m.keys, prefix); i < len(m.keys) && strings.HasPrefix(m.keys[i], prefix); i++ {
+		kvs = append(kvs, m.kvs[m.keys[i]])
+	}
+	return kvs
+}
+
+// get is the equivalent of Etcd.get, but looks up the keys in the mirror.
+func (m *mirror) get(path string, recursive bool) ([]*mvccpb.KeyValue, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if recursive {
+		prefix := path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		var kvs []*mvccpb.KeyValue
+		for i := sort.SearchStrings(m.keys, prefix); i < len(m.keys) && strings.HasPrefix(m.keys[i], prefix); i++ {
+			kvs = append(kvs, m.kvs[m.keys[i]])
+		}
+		if len(kvs) > 0 {
+			return kvs, nil
+		}
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	kv, ok := m.kvs[path]
+	if !ok {
+		return nil, errKeyNotFound
+	}
+	return []*mvccpb.KeyValue{kv}, nil
+}
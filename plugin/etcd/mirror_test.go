@@ -0,0 +1,272 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+// fakeEtcd implements the parts of etcdcv3.KV and etcdcv3.Watcher the mirror uses.
+type fakeEtcd struct {
+	etcdcv3.KV
+	etcdcv3.Watcher
+
+	mu         sync.Mutex
+	store      map[string]*mvccpb.KeyValue
+	rev        int64
+	compactRev int64
+	events     []*etcdcv3.Event
+	watches    map[chan etcdcv3.WatchResponse]string
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{store: map[string]*mvccpb.KeyValue{}, rev: 1, watches: map[chan etcdcv3.WatchResponse]string{}}
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...etcdcv3.OpOption) (*etcdcv3.GetResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := &etcdcv3.GetResponse{Header: &pb.ResponseHeader{Revision: f.rev}}
+	for k, kv := range f.store {
+		if strings.HasPrefix(k, key) {
+			r.Kvs = append(r.Kvs, kv)
+		}
+	}
+	sort.Slice(r.Kvs, func(i, j int) bool { return string(r.Kvs[i].Key) < string(r.Kvs[j].Key) })
+	r.Count = int64(len(r.Kvs))
+	return r, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...etcdcv3.OpOption) etcdcv3.WatchChan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	ch := make(chan etcdcv3.WatchResponse, 100)
+	rev := etcdcv3.OpGet(key, opts...).Rev()
+	if rev <= f.compactRev {
+		ch <- etcdcv3.WatchResponse{Header: pb.ResponseHeader{Revision: f.rev}, CompactRevision: f.compactRev}
+		close(ch)
+		return ch
+	}
+
+	resp := etcdcv3.WatchResponse{Header: pb.ResponseHeader{Revision: f.rev}}
+	for _, ev := range f.events {
+		if ev.Kv.ModRevision >= rev && strings.HasPrefix(string(ev.Kv.Key), key) {
+			resp.Events = append(resp.Events, ev)
+		}
+	}
+	if len(resp.Events) > 0 {
+		ch <- resp
+	}
+	f.watches[ch] = key
+
+	go func() {
+		<-ctx.Done()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if _, ok := f.watches[ch]; ok {
+			delete(f.watches, ch)
+			close(ch)
+		}
+	}()
+	return ch
+}
+
+func (f *fakeEtcd) RequestProgress(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.watches {
+		ch <- etcdcv3.WatchResponse{Header: pb.ResponseHeader{Revision: f.rev}}
+	}
+	return nil
+}
+
+func (f *fakeEtcd) event(typ mvccpb.Event_EventType, key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.rev++
+	kv := &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value), ModRevision: f.rev}
+	if typ == mvccpb.PUT {
+		f.store[key] = kv
+	} else {
+		delete(f.store, key)
+	}
+	ev := &etcdcv3.Event{Type: typ, Kv: kv}
+	f.events = append(f.events, ev)
+	for ch, prefix := range f.watches {
+		if strings.HasPrefix(key, prefix) {
+			ch <- etcdcv3.WatchResponse{Header: pb.ResponseHeader{Revision: f.rev}, Events: []*etcdcv3.Event{ev}}
+		}
+	}
+}
+
+func (f *fakeEtcd) put(key, value string) { f.event(mvccpb.PUT, key, value) }
+func (f *fakeEtcd) del(key string)        { f.event(mvccpb.DELETE, key, "") }
+
+// disconnect closes all watches, as happens when the connection to etcd is lost.
+func (f *fakeEtcd) disconnect() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for ch := range f.watches {
+		delete(f.watches, ch)
+		close(ch)
+	}
+}
+
+// compact removes the history up to the current revision.
+func (f *fakeEtcd) compact() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.compactRev = f.rev
+	f.events = nil
+}
+
+// consistent returns true if the mirror has the same keys and values as the store.
+func consistent(m *mirror, f *fakeEtcd) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.kvs) != len(f.store) || len(m.keys) != len(f.store) || m.rev != f.rev {
+		return false
+	}
+	for k, kv := range f.store {
+		if mkv, ok := m.kvs[k]; !ok || string(mkv.Value) != string(kv.Value) {
+			return false
+		}
+	}
+	return sort.StringsAreSorted(m.keys)
+}
+
+func waitConsistent(t *testing.T, m *mirror, f *fakeEtcd) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !consistent(m, f) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Mirror did not catch up with etcd at revision %d, mirror is at %d", f.rev, m.rev)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMirror(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("/skydns/test/skydns/a", `{"host": "10.0.0.1"}`)
+	f.put("/skydns/test/skydns/b", `{"host": "10.0.0.2"}`)
+	f.put("/other/key", "not mirrored")
+
+	m := newMirror(f, f, "/skydns/", time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.run(ctx)
+
+	// consistent compares against the whole store, so drop the key outside of the prefix.
+	f.del("/other/key")
+	waitConsistent(t, m, f)
+
+	f.put("/skydns/test/skydns/c", `{"host": "10.0.0.3"}`)
+	f.put("/skydns/test/skydns/a", `{"host": "10.0.0.4"}`)
+	f.del("/skydns/test/skydns/b")
+	waitConsistent(t, m, f)
+
+	// Writes while the watch is down are received when it resumes.
+	f.disconnect()
+	f.put("/skydns/test/skydns/d", `{"host": "10.0.0.5"}`)
+	f.del("/skydns/test/skydns/c")
+	waitConsistent(t, m, f)
+
+	// If the history is compacted while the watch is down, the mirror is rebuilt.
+	f.disconnect()
+	f.put("/skydns/test/skydns/e", `{"host": "10.0.0.6"}`)
+	f.del("/skydns/test/skydns/d")
+	f.compact()
+	waitConsistent(t, m, f)
+
+	f.put("/skydns/test/skydns/f", `{"host": "10.0.0.7"}`)
+	waitConsistent(t, m, f)
+}
+
+func TestMirrorProgress(t *testing.T) {
+	f := newFakeEtcd()
+	m := newMirror(f, f, "/skydns/", time.Hour)
+	if m.ready() {
+		t.Fatal("Expected mirror not to be ready before the first sync")
+	}
+	if _, err := m.sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.watch(ctx, m.rev)
+	time.Sleep(10 * time.Millisecond) // let the watch start.
+
+	// A write outside of the prefix only shows up as a progress notification.
+	f.put("/other/key", "value")
+	f.RequestProgress(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		m.mu.RLock()
+		rev := m.rev
+		m.mu.RUnlock()
+		if rev == f.rev {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected mirror to be at revision %d, got %d", f.rev, rev)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMirrorGet(t *testing.T) {
+	m := newMirror(nil, nil, "/skydns/", time.Hour)
+	var events []*etcdcv3.Event
+	for i, k := range []string{"/skydns/test/skydns/a", "/skydns/test/skydns/a/x", "/skydns/test/skydns/ab", "/skydns/test/skydns/b"} {
+		events = append(events, &etcdcv3.Event{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte(k), ModRevision: int64(i + 1)}})
+	}
+	m.apply(events, int64(len(events)))
+
+	tests := []struct {
+		path      string
+		recursive bool
+		keys      []string
+	}{
+		{"/skydns/test/skydns/a", true, []string{"/skydns/test/skydns/a/x"}},
+		{"/skydns/test/skydns/a", false, []string{"/skydns/test/skydns/a"}},
+		{"/skydns/test/skydns/b", true, []string{"/skydns/test/skydns/b"}},
+		{"/skydns/test/skydns", true, []string{"/skydns/test/skydns/a", "/skydns/test/skydns/a/x", "/skydns/test/skydns/ab", "/skydns/test/skydns/b"}},
+		{"/skydns/test/skydns/c", true, nil},
+		{"/skydns/test/skydns/c", false, nil},
+	}
+	for i, tc := range tests {
+		kvs, err := m.get(tc.path, tc.recursive)
+		if tc.keys == nil {
+			if err != errKeyNotFound {
+				t.Errorf("Test %d: expected errKeyNotFound, got %v", i, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		var keys []string
+		for _, kv := range kvs {
+			keys = append(keys, string(kv.Key))
+		}
+		if strings.Join(keys, " ") != strings.Join(tc.keys, " ") {
+			t.Errorf("Test %d: expected keys %v, got %v", i, tc.keys, keys)
+		}
+	}
+}
@@ -1,18 +1,26 @@
 package etcd
 
 import (
+	"context"
 	"crypto/tls"
+	"path"
 	"path/filepath"
+	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
 	mwtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/plugin/transfer"
 
 	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
+var log = clog.NewWithPlugin("etcd")
+
 func init() { plugin.Register("etcd", setup) }
 
 func setup(c *caddy.Controller) error {
@@ -21,6 +29,23 @@ func setup(c *caddy.Controller) error {
 		return plugin.Error("etcd", err)
 	}
 
+	if e.mirror != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		c.OnStartup(func() error {
+			// get the transfer plugin, so we can send notifies when a zone changes.
+			if t := dnsserver.GetConfig(c).Handler("transfer"); t != nil {
+				e.transfer = t.(*transfer.Transfer) // if found this must be OK.
+			}
+			e.mirror.changed = e.notify
+			go e.mirror.run(ctx)
+			return nil
+		})
+		c.OnShutdown(func() error {
+			cancel()
+			return nil
+		})
+	}
+
 	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
 		e.Next = next
 		return e
@@ -38,6 +63,8 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 		endpoints = []string{defaultEndpoint}
 		username  string
 		password  string
+		watch     bool
+		resync    = defaultResync
 	)
 
 	etc.Upstream = upstream.New()
@@ -86,6 +113,21 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 					return &Etcd{}, c.Errf("credentials requires 2 arguments, username and password")
 				}
 				username, password = args[0], args[1]
+			case "watch":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return &Etcd{}, c.ArgErr()
+				}
+				if len(args) == 1 {
+					resync, err = time.ParseDuration(args[0])
+					if err != nil {
+						return &Etcd{}, err
+					}
+					if resync <= 0 {
+						return &Etcd{}, c.Errf("resync interval must be greater than 0: %q", args[0])
+					}
+				}
+				watch = true
 			default:
 				if c.Val() != "}" {
 					return &Etcd{}, c.Errf("unknown property '%s'", c.Val())
@@ -98,6 +140,12 @@ func etcdParse(c *caddy.Controller) (*Etcd, error) {
 		}
 		etc.Client = client
 		etc.endpoints = endpoints
+		if watch {
+			etc.mirror = newMirror(client, client, path.Join("/", etc.PathPrefix)+"/", resync)
+			for _, z := range etc.Zones {
+				etc.mirror.track(z, msg.Path(z, etc.PathPrefix))
+			}
+		}
 
 		return &etc, nil
 	}
@@ -66,6 +66,25 @@ func TestSetupEtcd(t *testing.T) {
 		}
 			`, true, "skydns", []string{"http://localhost:2379"}, "Wrong argument count", "", "",
 		},
+		// with watch
+		{
+			`etcd {
+			watch
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd {
+			watch 5m
+		}
+			`, false, "skydns", []string{"http://localhost:2379"}, "", "", "",
+		},
+		{
+			`etcd {
+			watch 0s
+		}
+			`, true, "skydns", []string{"http://localhost:2379"}, "resync interval must be greater than 0", "", "",
+		},
 	}
 
 	for i, test := range tests {
@@ -1,17 +1,237 @@
 package etcd
 
 import (
+	"context"
+	"strings"
 	"time"
 
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/transfer"
 	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
 )
 
-// Serial returns the serial number to use.
+// serialOffset is added to the etcd revision to get the serial. Earlier versions used the Unix time as the
+// serial, with the offset the serial is newer than those in serial number arithmetic (RFC 1982), so
+// secondaries keep transferring the zone after an upgrade. This holds as long as the Unix time is below
+// 2^31, until 2038.
+const serialOffset = 1 << 31
+
+// revisionTTL is how long the revision of a zone is cached without a mirror, so SOA records don't cost
+// requests to etcd for each query.
+const revisionTTL = 5 * time.Second
+
+// cachedRevision is the revision of a zone, as retrieved from etcd.
+type cachedRevision struct {
+	rev     int64
+	expires time.Time
+}
+
+// Serial returns the serial number to use, this is the etcd revision of the last change in the zone, plus
+// serialOffset.
 func (e *Etcd) Serial(state request.Request) uint32 {
-	return uint32(time.Now().Unix())
+	zone := plugin.Zones(e.Zones).Matches(state.Name())
+	if e.mirror != nil && e.mirror.ready() {
+		return serialOffset + uint32(e.mirror.revision(zone))
+	}
+
+	rev, err := e.cachedRevision(zone)
+	if err != nil {
+		log.Warningf("Failed to get the revision of %s: %s", zone, err)
+		return 0
+	}
+	return serialOffset + uint32(rev)
+}
+
+// cachedRevision returns the revision of zone, it is retrieved from etcd at most once per revisionTTL.
+func (e *Etcd) cachedRevision(zone string) (int64, error) {
+	now := time.Now()
+	e.revMu.Lock()
+	c, ok := e.revs[zone]
+	e.revMu.Unlock()
+	if ok && now.Before(c.expires) {
+		return c.rev, nil
+	}
+
+	rev, err := e.revision(zone)
+	if err != nil {
+		return 0, err
+	}
+	e.revMu.Lock()
+	if e.revs == nil {
+		e.revs = make(map[string]cachedRevision)
+	}
+	e.revs[zone] = cachedRevision{rev: rev, expires: now.Add(revisionTTL)}
+	e.revMu.Unlock()
+	return rev, nil
+}
+
+// revision returns the revision of the last changed key in zone. Without a mirror deletes can't be seen.
+// If the zone has no keys, the current revision is returned.
+func (e *Etcd) revision(zone string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), etcdTimeout)
+	defer cancel()
+
+	// The zone's keys are its path and the keys below it, not those of a sibling with the same prefix,
+	// i.e. /skydns/org/exampleX.
+	path := msg.Path(zone, e.PathPrefix)
+	r, err := e.Client.Get(ctx, path+"/", etcdcv3.WithPrefix(), etcdcv3.WithKeysOnly(),
+		etcdcv3.WithSort(etcdcv3.SortByModRevision, etcdcv3.SortDescend), etcdcv3.WithLimit(1))
+	if err != nil {
+		return 0, err
+	}
+	apex, err := e.Client.Get(ctx, path, etcdcv3.WithKeysOnly())
+	if err != nil {
+		return 0, err
+	}
+
+	rev := int64(0)
+	for _, kv := range append(r.Kvs, apex.Kvs...) {
+		if kv.ModRevision > rev {
+			rev = kv.ModRevision
+		}
+	}
+	if rev == 0 {
+		return apex.Header.Revision, nil
+	}
+	return rev, nil
 }
 
 // MinTTL returns the minimal TTL.
 func (e *Etcd) MinTTL(state request.Request) uint32 {
 	return 30
 }
+
+// Transfer implements the transfer.Transferer interface.
+func (e *Etcd) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if plugin.Zones(e.Zones).Matches(zone) != zone {
+		return nil, transfer.ErrNotAuthoritative
+	}
+
+	ctx := context.Background()
+	state := request.Request{Req: new(dns.Msg)}
+	state.Req.SetQuestion(zone, dns.TypeSOA)
+	opt := plugin.Options{}
+
+	soa, err := plugin.SOA(ctx, e, zone, state, opt)
+	if err != nil {
+		return nil, err
+	}
+	ns, _, err := plugin.NS(ctx, e, zone, state, opt)
+	if err != nil && !e.IsNameError(err) {
+		return nil, err
+	}
+	kvs, err := e.zone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	services, err := e.loopNodes(kvs, nil, false, dns.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []dns.RR)
+	go func() {
+		// ixfr fallback
+		if serial != 0 && soa[0].(*dns.SOA).Serial == serial {
+			ch <- soa
+			close(ch)
+			return
+		}
+		ch <- soa
+		if len(ns) > 0 {
+			ch <- ns
+		}
+		for _, serv := range services {
+			if rrs := serviceRecords(serv); len(rrs) > 0 {
+				ch <- rrs
+			}
+		}
+		ch <- soa
+		close(ch)
+	}()
+	return ch, nil
+}
+
+// zone returns all keys of zone.
+func (e *Etcd) zone(ctx context.Context, zone string) ([]*mvccpb.KeyValue, error) {
+	path := msg.Path(zone, e.PathPrefix)
+	if e.mirror != nil && e.mirror.ready() {
+		return e.mirror.subtree(path), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, etcdTimeout)
+	defer cancel()
+	r, err := e.Client.Get(ctx, path, etcdcv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	kvs := r.Kvs[:0]
+	for _, kv := range r.Kvs {
+		// Skip the keys of a sibling with the same prefix, i.e. /skydns/org/exampleX.
+		if k := string(kv.Key); k == path || strings.HasPrefix(k, path+"/") {
+			kvs = append(kvs, kv)
+		}
+	}
+	return kvs, nil
+}
+
+// serviceRecords returns the records for serv, named after its key. A query for a name also returns the
+// services stored below it, that isn't reproduced here: each record only exists at the name of its key.
+func serviceRecords(serv msg.Service) []dns.RR {
+	name := msg.Domain(serv.Key)
+	weight := uint16(serv.Weight)
+	if weight == 0 {
+		weight = 100
+	}
+
+	what, ip := serv.HostType()
+	switch what {
+	case dns.TypeA, dns.TypeAAAA:
+		var rrs []dns.RR
+		if what == dns.TypeA {
+			rrs = append(rrs, serv.NewA(name, ip))
+		} else {
+			rrs = append(rrs, serv.NewAAAA(name, ip))
+		}
+		if serv.Port > 0 {
+			serv.Host = name
+			rrs = append(rrs, serv.NewSRV(name, weight))
+		}
+		if serv.Text != "" {
+			rrs = append(rrs, serv.NewTXT(name))
+		}
+		return rrs
+	case dns.TypeCNAME:
+		if serv.Mail {
+			return []dns.RR{serv.NewMX(name)}
+		}
+		if serv.Port > 0 {
+			return []dns.RR{serv.NewSRV(name, weight)}
+		}
+		return []dns.RR{serv.NewCNAME(name, serv.Host)}
+	case dns.TypeTXT:
+		if serv.Host != "" {
+			// A CNAME can't have other data, the text is only returned when the CNAME is followed.
+			return []dns.RR{serv.NewCNAME(name, serv.Host)}
+		}
+		return []dns.RR{serv.NewTXT(name)}
+	}
+	return nil
+}
+
+// notify sends notifies for zone to the secondaries configured with the transfer plugin.
+func (e *Etcd) notify(zone string) {
+	if e.transfer == nil {
+		return
+	}
+	go func() {
+		if err := e.transfer.Notify(zone); err != nil {
+			log.Warningf("Failed to send notifies for %s: %s", zone, err)
+		}
+	}()
+}
@@ -0,0 +1,183 @@
+package etcd
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/etcd/msg"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	etcdcv3 "go.etcd.io/etcd/client/v3"
+)
+
+func newTestMirror(t *testing.T, f *fakeEtcd, zones ...string) (*mirror, map[string]int) {
+	m := newMirror(f, f, "/skydns/", time.Hour)
+	for _, z := range zones {
+		m.track(z, msg.Path(z, "skydns"))
+	}
+	changed := map[string]int{}
+	m.changed = func(zone string) { changed[zone]++ }
+	if _, err := m.sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return m, changed
+}
+
+func TestZoneRevision(t *testing.T) {
+	f := newFakeEtcd()
+	f.put("/skydns/org/example/a", `{"host": "10.0.0.1"}`)
+	f.put("/skydns/org/example/b", `{"host": "10.0.0.2"}`)
+	f.put("/skydns/org/examplex/a", `{"host": "10.0.0.3"}`)
+	f.put("/skydns/net/example/a", `{"host": "10.0.0.4"}`)
+
+	m, changed := newTestMirror(t, f, "example.org.", "example.net.", "example.com.")
+	if rev := m.revision("example.org."); rev != 3 {
+		t.Errorf("Expected revision 3 for example.org., got %d", rev)
+	}
+	if rev := m.revision("example.com."); rev != f.rev {
+		t.Errorf("Expected revision %d for the empty example.com., got %d", f.rev, rev)
+	}
+	if len(changed) != 3 {
+		t.Errorf("Expected notifies for all zones after the initial sync, got %v", changed)
+	}
+
+	event := func(typ mvccpb.Event_EventType, key string) {
+		f.event(typ, key, `{"host": "10.0.0.5"}`)
+		m.apply([]*etcdcv3.Event{f.events[len(f.events)-1]}, f.rev)
+	}
+
+	changed = map[string]int{}
+	m.changed = func(zone string) { changed[zone]++ }
+	event(mvccpb.PUT, "/skydns/org/examplex/b")
+	if rev := m.revision("example.org."); rev != 3 {
+		t.Errorf("Expected revision 3 for example.org. after a change in examplex.org., got %d", rev)
+	}
+	event(mvccpb.PUT, "/skydns/org/example/c")
+	if rev := m.revision("example.org."); rev != f.rev {
+		t.Errorf("Expected revision %d for example.org. after a put, got %d", f.rev, rev)
+	}
+	event(mvccpb.DELETE, "/skydns/org/example/a")
+	if rev := m.revision("example.org."); rev != f.rev {
+		t.Errorf("Expected revision %d for example.org. after a delete, got %d", f.rev, rev)
+	}
+	if changed["example.org."] != 2 || len(changed) != 1 {
+		t.Errorf("Expected two notifies for example.org. only, got %v", changed)
+	}
+
+	// A delete that isn't seen by the watch is found by a full sync.
+	changed = map[string]int{}
+	f.del("/skydns/org/example/b")
+	f.put("/skydns/net/example/b", `{"host": "10.0.0.6"}`)
+	if _, err := m.sync(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if rev := m.revision("example.org."); rev != f.rev {
+		t.Errorf("Expected revision %d for example.org. after a resync, got %d", f.rev, rev)
+	}
+	if rev := m.revision("example.net."); rev != f.rev {
+		t.Errorf("Expected revision %d for example.net. after a resync, got %d", f.rev, rev)
+	}
+	if len(changed) != 2 || changed["example.com."] != 0 {
+		t.Errorf("Expected notifies for example.org. and example.net., got %v", changed)
+	}
+}
+
+func TestTransfer(t *testing.T) {
+	f := newFakeEtcd()
+	for k, v := range map[string]string{
+		"/skydns/org/example/x1":           `{"host": "10.0.0.1", "ttl": 60}`,
+		"/skydns/org/example/x2":           `{"host": "2001:db8::1", "ttl": 60}`,
+		"/skydns/org/example/dns/ns/ns1":   `{"host": "10.0.0.53", "ttl": 60}`,
+		"/skydns/org/example/www":          `{"host": "x1.example.org", "ttl": 60}`,
+		"/skydns/org/example/mx":           `{"host": "mail.example.org", "mail": true, "priority": 10, "ttl": 60}`,
+		"/skydns/org/example/_tcp/_http":   `{"host": "www.example.org", "port": 80, "ttl": 60}`,
+		"/skydns/org/example/txt":          `{"text": "hello", "ttl": 60}`,
+		"/skydns/org/examplex/not-in-zone": `{"host": "10.0.0.2"}`,
+	} {
+		f.put(k, v)
+	}
+	m, _ := newTestMirror(t, f, "example.org.")
+	e := &Etcd{Zones: []string{"example.org."}, PathPrefix: "skydns", mirror: m}
+
+	if _, err := e.Transfer("sub.example.org.", 0); err == nil {
+		t.Error("Expected an error for a zone we're not authoritative for")
+	}
+
+	ch, err := e.Transfer("example.org.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	serial := serialOffset + uint32(m.revision("example.org."))
+	soa := test.SOA("example.org.	30	IN	SOA	ns.dns.example.org. hostmaster.example.org. 0 7200 1800 86400 30")
+	soa.Serial = serial
+
+	if len(rrs) < 2 || !dns.IsDuplicate(rrs[0], soa) || !dns.IsDuplicate(rrs[len(rrs)-1], soa) {
+		t.Fatalf("Expected the transfer to start and end with %s, got %v", soa, rrs)
+	}
+	expected := []dns.RR{
+		test.NS("example.org.	60	IN	NS	ns1.ns.dns.example.org."),
+		test.A("ns1.ns.dns.example.org.	60	IN	A	10.0.0.53"),
+		test.A("x1.example.org.	60	IN	A	10.0.0.1"),
+		test.AAAA("x2.example.org.	60	IN	AAAA	2001:db8::1"),
+		test.CNAME("www.example.org.	60	IN	CNAME	x1.example.org."),
+		test.MX("mx.example.org.	60	IN	MX	10 mail.example.org."),
+		test.SRV("_http._tcp.example.org.	60	IN	SRV	10 100 80 www.example.org."),
+		test.TXT("txt.example.org.	60	IN	TXT	\"hello\""),
+	}
+	got := rrs[1 : len(rrs)-1]
+	sort.Slice(got, func(i, j int) bool { return got[i].String() < got[j].String() })
+	sort.Slice(expected, func(i, j int) bool { return expected[i].String() < expected[j].String() })
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d records, got %d: %v", len(expected), len(got), got)
+	}
+	for i := range got {
+		if got[i].String() != expected[i].String() {
+			t.Errorf("Expected %s, got %s", expected[i], got[i])
+		}
+	}
+
+	// An up to date secondary only gets the SOA.
+	ch, err = e.Transfer("example.org.", serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrs = nil
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	if len(rrs) != 1 {
+		t.Errorf("Expected only the SOA, got %v", rrs)
+	}
+}
+
+func TestSerialNewerThanUnixTime(t *testing.T) {
+	// A secondary may still have the Unix time serial of an earlier version.
+	old := uint32(time.Now().Unix())
+	for _, rev := range []int64{1, 1000, 1 << 30} {
+		serial := serialOffset + uint32(rev)
+		if d := serial - old; d == 0 || d >= 1<<31 {
+			t.Errorf("Expected serial %d for revision %d to be newer than %d", serial, rev, old)
+		}
+	}
+}
+
+func TestCachedRevision(t *testing.T) {
+	// Without a client, a request to etcd panics.
+	e := &Etcd{Zones: []string{"example.org."}, PathPrefix: "skydns"}
+	e.revs = map[string]cachedRevision{"example.org.": {rev: 42, expires: time.Now().Add(revisionTTL)}}
+
+	state := request.Request{Req: new(dns.Msg)}
+	state.Req.SetQuestion("example.org.", dns.TypeSOA)
+	if serial := e.Serial(state); serial != serialOffset+42 {
+		t.Errorf("Expected serial %d from the cached revision, got %d", serialOffset+42, serial)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/transfer"
+	"github.com/coredns/coredns/plugin/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -54,7 +55,7 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 
 	// This is only for when we are a secondary zones.
 	if r.Opcode == dns.OpcodeNotify {
-		if z.isNotify(state) {
+		if z.isNotify(state, tsig.KeyName(ctx)) {
 			m := new(dns.Msg)
 			m.SetReply(r)
 			m.Authoritative = true
@@ -76,6 +77,17 @@ func (f File) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (i
 		return dns.RcodeSuccess, nil
 	}
 
+	if r.Opcode == dns.OpcodeUpdate {
+		rcode, changed := z.DynamicUpdate(state, tsig.KeyName(ctx))
+		m := new(dns.Msg)
+		m.SetRcode(r, rcode)
+		w.WriteMsg(m)
+		if changed && f.transfer != nil {
+			go f.transfer.Notify(zone)
+		}
+		return dns.RcodeSuccess, nil
+	}
+
 	z.RLock()
 	exp := z.Expired
 	z.RUnlock()
@@ -0,0 +1,155 @@
+package file
+
+import (
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// MaxJournal is the maximum number of differences kept in a zone's journal.
+const MaxJournal = 100
+
+// delta is the difference between two versions of a zone, as sent in an incremental transfer (RFC 1995).
+type delta struct {
+	from, to *dns.SOA
+	del, add []dns.RR // Without the SOA records.
+}
+
+// records returns all RRs in z except the SOA record.
+func (z *Zone) records() []dns.RR {
+	rrs := make([]dns.RR, 0, len(z.Apex.NS)+len(z.Apex.SIGSOA)+len(z.Apex.SIGNS))
+	rrs = append(rrs, z.Apex.SIGSOA...)
+	rrs = append(rrs, z.Apex.NS...)
+	rrs = append(rrs, z.Apex.SIGNS...)
+	z.Tree.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		rrs = append(rrs, e.All()...)
+		return nil
+	})
+	if z.Apex.NSEC3 != nil {
+		z.Apex.NSEC3.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+			rrs = append(rrs, e.All()...)
+			return nil
+		})
+	}
+	return rrs
+}
+
+// difference returns the RRs that must be deleted from, and added to, old to get cur. The SOA records
+// are left out. The number of RRs in cur is returned as well.
+func difference(old, cur *Zone) (del, add []dns.RR, size int) {
+	seen := map[string]struct{}{}
+	for _, rr := range old.records() {
+		seen[rr.String()] = struct{}{}
+	}
+	keep := map[string]struct{}{}
+	records := cur.records()
+	for _, rr := range records {
+		s := rr.String()
+		if _, ok := seen[s]; ok {
+			keep[s] = struct{}{}
+			continue
+		}
+		add = append(add, rr)
+	}
+	for _, rr := range old.records() {
+		if _, ok := keep[rr.String()]; !ok {
+			del = append(del, rr)
+		}
+	}
+	return del, add, len(records)
+}
+
+// diff returns the difference between the current version of z and z1, that is going to replace it, and
+// the number of RRs in z1.
+func (z *Zone) diff(z1 *Zone) (delta, int) {
+	z.RLock()
+	cur := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree}
+	z.RUnlock()
+	if cur.Apex.SOA == nil {
+		return delta{to: z1.Apex.SOA}, 0
+	}
+	del, add, size := difference(cur, z1)
+	return delta{from: cur.Apex.SOA, to: z1.Apex.SOA, del: del, add: add}, size
+}
+
+// addToJournal adds the changes from the version of z with SOA from, to the version with SOA to, to the
+// journal. Without a from SOA the journal is cleared. If these don't follow the last changes in the journal, the journal is restarted. Differences
+// are dropped from the start of the journal when there are more than MaxJournal, or when together they
+// hold more RRs than the zone, size, as a full transfer is cheaper then. The caller must hold the lock.
+func (z *Zone) addToJournal(d delta, size int) {
+	if d.from == nil || d.to == nil {
+		z.journal = nil
+		return
+	}
+	if n := len(z.journal); n > 0 && z.journal[n-1].to.Serial != d.from.Serial {
+		z.journal = nil
+	}
+	z.journal = append(z.journal, d)
+
+	total := 0
+	for _, d := range z.journal {
+		total += len(d.del) + len(d.add)
+	}
+	for len(z.journal) > MaxJournal || (total > size && len(z.journal) > 0) {
+		total -= len(z.journal[0].del) + len(z.journal[0].add)
+		z.journal = z.journal[1:]
+	}
+}
+
+// journalFrom returns the differences from the version of z with serial, to the current version. If the
+// journal doesn't reach back to serial, nil is returned. The caller must hold the lock.
+func (z *Zone) journalFrom(serial uint32) []delta {
+	n := len(z.journal)
+	if n == 0 || z.Apex.SOA == nil || z.journal[n-1].to.Serial != z.Apex.SOA.Serial {
+		return nil
+	}
+	for i := range z.journal {
+		if z.journal[i].from.Serial == serial {
+			return z.journal[i:]
+		}
+	}
+	return nil
+}
+
+// applyDelta applies the differences in d to z, which must be a copy of the zone made with copyForUpdate.
+func (z *Zone) applyDelta(d delta) {
+	for _, rr := range d.del {
+		name := dns.CanonicalName(rr.Header().Name)
+		switch {
+		case name == z.origin && rr.Header().Rrtype == dns.TypeNS:
+			z.Apex.NS = removeRR(z.Apex.NS, rr)
+		case name == z.origin && rr.Header().Rrtype == dns.TypeRRSIG && rr.(*dns.RRSIG).TypeCovered == dns.TypeSOA:
+			z.Apex.SIGSOA = removeRR(z.Apex.SIGSOA, rr)
+		case name == z.origin && rr.Header().Rrtype == dns.TypeRRSIG && rr.(*dns.RRSIG).TypeCovered == dns.TypeNS:
+			z.Apex.SIGNS = removeRR(z.Apex.SIGNS, rr)
+		case rr.Header().Rrtype == dns.TypeNSEC3, rr.Header().Rrtype == dns.TypeRRSIG && rr.(*dns.RRSIG).TypeCovered == dns.TypeNSEC3:
+			if z.Apex.NSEC3 == nil {
+				continue
+			}
+			if e, ok := z.Apex.NSEC3.Search(name); ok {
+				keep := removeRR(e.Type(rr.Header().Rrtype), rr)
+				z.Apex.NSEC3.Delete(rr)
+				for _, k := range keep {
+					z.Apex.NSEC3.Insert(k)
+				}
+			}
+		default:
+			z.deleteRR(name, rr)
+		}
+	}
+	for _, rr := range d.add {
+		z.Insert(dns.Copy(rr))
+	}
+	z.Apex.SOA = d.to
+}
+
+// removeRR returns rrs without the RRs with the same rdata as rr, in a new slice.
+func removeRR(rrs []dns.RR, rr dns.RR) []dns.RR {
+	keep := make([]dns.RR, 0, len(rrs))
+	for _, r := range rrs {
+		if !sameRdata(r, rr) {
+			keep = append(keep, r)
+		}
+	}
+	return keep
+}
@@ -0,0 +1,284 @@
+package file
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+
+	"github.com/miekg/dns"
+)
+
+const journalOrigin = "example.org."
+
+var journalVersions = []string{`$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 1 7200 3600 1209600 3600
+	3600 IN NS  a.example.org.
+a	3600 IN A   10.0.0.1
+b	3600 IN A   10.0.0.2
+www	3600 IN CNAME a.example.org.
+`, `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 2 7200 3600 1209600 3600
+	3600 IN NS  a.example.org.
+a	3600 IN A   10.0.0.1
+b	3600 IN A   10.0.0.3
+c	3600 IN A   10.0.0.4
+`, `$ORIGIN example.org.
+@	3600 IN	SOA sns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600
+	3600 IN NS  a.example.org.
+	3600 IN NS  b.example.org.
+a	3600 IN A   10.0.0.1
+b	3600 IN A   10.0.0.3
+c	3600 IN A   10.0.0.4
+`}
+
+// newJournalZone returns the zone in the version v of journalVersions, with the changes since the
+// first version in its journal, as a reload would have made them.
+func newJournalZone(t *testing.T, v int) *Zone {
+	z, err := Parse(strings.NewReader(journalVersions[0]), journalOrigin, "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= v; i++ {
+		setJournalVersion(t, z, i)
+	}
+	return z
+}
+
+func setJournalVersion(t *testing.T, z *Zone, v int) {
+	z1, err := Parse(strings.NewReader(journalVersions[v]), journalOrigin, "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, size := z.diff(z1)
+	z.Lock()
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+	z.addToJournal(d, size)
+	z.Unlock()
+}
+
+func transferred(t *testing.T, z *Zone, serial uint32) []string {
+	ch, err := z.Transfer(serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := []string{}
+	for rrs := range ch {
+		for _, rr := range rrs {
+			s = append(s, fmt.Sprintf("%s %s %s", rr.Header().Name, dns.TypeToString[rr.Header().Rrtype], strings.TrimPrefix(rr.String(), rr.Header().String())))
+		}
+	}
+	return s
+}
+
+func TestJournalTransfer(t *testing.T) {
+	z := newJournalZone(t, 2)
+
+	got := transferred(t, z, 1)
+	want := []string{
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 1 7200 3600 1209600 3600",
+		"b.example.org. A 10.0.0.2",
+		"www.example.org. CNAME a.example.org.",
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 2 7200 3600 1209600 3600",
+		"b.example.org. A 10.0.0.3",
+		"c.example.org. A 10.0.0.4",
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 2 7200 3600 1209600 3600",
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+		"example.org. NS b.example.org.",
+		"example.org. SOA sns.dns.icann.org. noc.dns.icann.org. 3 7200 3600 1209600 3600",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected incremental transfer from serial 1:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if got := transferred(t, z, 2); len(got) != 5 {
+		t.Errorf("Expected incremental transfer from serial 2 of 5 records, got %d: %v", len(got), got)
+	}
+	if got := transferred(t, z, 3); len(got) != 1 {
+		t.Errorf("Expected a single SOA for an up to date serial, got %v", got)
+	}
+	if got := transferred(t, z, 4); len(got) != 1 {
+		t.Errorf("Expected a single SOA for a newer serial, got %v", got)
+	}
+	// An AXFR, and an older serial that is not in the journal.
+	if got := transferred(t, z, 0); len(got) != 7 {
+		t.Errorf("Expected AXFR of 7 records, got %d: %v", len(got), got)
+	}
+	if got := transferred(t, z, 4294967000); len(got) != 7 {
+		t.Errorf("Expected AXFR fallback of 7 records, got %d: %v", len(got), got)
+	}
+}
+
+func TestJournalBounds(t *testing.T) {
+	z := newJournalZone(t, 0)
+	soa := z.Apex.SOA
+	for i := 0; i < MaxJournal+10; i++ {
+		to := dns.Copy(soa).(*dns.SOA)
+		to.Serial++
+		z.addToJournal(delta{from: soa, to: to}, 10)
+		soa = to
+	}
+	if len(z.journal) != MaxJournal {
+		t.Errorf("Expected %d changes in the journal, got %d", MaxJournal, len(z.journal))
+	}
+	if first := z.journal[0].from.Serial; first != 11 {
+		t.Errorf("Expected the journal to start at serial 11, got %d", first)
+	}
+
+	// More RRs than the zone holds, only the last change is kept.
+	a, _ := dns.NewRR("a.example.org. 3600 IN A 10.0.0.1")
+	to := dns.Copy(soa).(*dns.SOA)
+	to.Serial++
+	z.addToJournal(delta{from: soa, to: to, add: []dns.RR{a, a, a, a, a, a}}, 10)
+	soa, to = to, dns.Copy(to).(*dns.SOA)
+	to.Serial++
+	z.addToJournal(delta{from: soa, to: to, add: []dns.RR{a, a, a, a, a, a}}, 10)
+	if len(z.journal) != 1 || z.journal[0].to.Serial != to.Serial {
+		t.Errorf("Expected only the last change in the journal, got %d changes", len(z.journal))
+	}
+
+	// A change that doesn't follow the journal restarts it.
+	z.addToJournal(delta{from: z.Apex.SOA, to: to}, 10)
+	if len(z.journal) != 1 || z.journal[0].from != z.Apex.SOA {
+		t.Errorf("Expected the journal to be restarted, got %d changes", len(z.journal))
+	}
+}
+
+// primary answers transfers of the zone z.
+type primary struct{ z *Zone }
+
+func (p primary) Handler(w dns.ResponseWriter, req *dns.Msg) {
+	var serial uint32
+	if req.Question[0].Qtype == dns.TypeIXFR {
+		serial = req.Ns[0].(*dns.SOA).Serial
+	}
+	ch, _ := p.z.Transfer(serial)
+	m := new(dns.Msg)
+	m.SetReply(req)
+	for rrs := range ch {
+		m.Answer = append(m.Answer, rrs...)
+	}
+	w.WriteMsg(m)
+}
+
+func zoneRecords(z *Zone) string {
+	z.RLock()
+	defer z.RUnlock()
+	s := []string{z.Apex.SOA.String()}
+	for _, rr := range z.records() {
+		s = append(s, rr.String())
+	}
+	sort.Strings(s)
+	return strings.Join(s, "\n")
+}
+
+func TestTransferInIncremental(t *testing.T) {
+	p := primary{newJournalZone(t, 0)}
+	s := dnstest.NewServer(p.Handler)
+	defer s.Close()
+
+	z := NewZone(journalOrigin, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if len(z.journal) != 0 {
+		t.Errorf("Expected an empty journal after AXFR, got %d changes", len(z.journal))
+	}
+
+	setJournalVersion(t, p.z, 1)
+	setJournalVersion(t, p.z, 2)
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if got, want := zoneRecords(z), zoneRecords(p.z); got != want {
+		t.Errorf("Expected zone after IXFR:\n%s\ngot:\n%s", want, got)
+	}
+	if len(z.journal) != 2 {
+		t.Errorf("Expected 2 changes in the journal after IXFR, got %d", len(z.journal))
+	}
+	// The secondary can transfer the changes onwards.
+	if got := transferred(t, z, 1); len(got) != 11 {
+		t.Errorf("Expected incremental transfer of 11 records, got %d: %v", len(got), got)
+	}
+
+	// Up to date.
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if z.Apex.SOA.Serial != 3 {
+		t.Errorf("Expected serial 3, got %d", z.Apex.SOA.Serial)
+	}
+
+	// The primary lost its journal, it falls back to AXFR.
+	z1, _ := Parse(strings.NewReader(strings.Replace(journalVersions[0], " 1 7200", " 4 7200", 1)), journalOrigin, "stdin", 0)
+	p.z.Lock()
+	p.z.Apex, p.z.Tree, p.z.journal = z1.Apex, z1.Tree, nil
+	p.z.Unlock()
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if got, want := zoneRecords(z), zoneRecords(p.z); got != want {
+		t.Errorf("Expected zone after AXFR fallback:\n%s\ngot:\n%s", want, got)
+	}
+	if len(z.journal) != 0 {
+		t.Errorf("Expected an empty journal after AXFR, got %d changes", len(z.journal))
+	}
+}
+
+func TestTransferInIncrementalMismatch(t *testing.T) {
+	p := primary{newJournalZone(t, 1)}
+	s := dnstest.NewServer(p.Handler)
+	defer s.Close()
+
+	z := NewZone(journalOrigin, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+
+	// This primary sends the changes since serial 1 to a secondary at serial 2, the secondary falls
+	// back to AXFR.
+	setJournalVersion(t, p.z, 2)
+	stale := dnstest.NewServer(func(w dns.ResponseWriter, req *dns.Msg) {
+		if req.Question[0].Qtype == dns.TypeIXFR {
+			req.Ns[0].(*dns.SOA).Serial--
+		}
+		p.Handler(w, req)
+	})
+	defer stale.Close()
+	z.TransferFrom = []string{stale.Addr}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if got, want := zoneRecords(z), zoneRecords(p.z); got != want {
+		t.Errorf("Expected zone after AXFR fallback:\n%s\ngot:\n%s", want, got)
+	}
+	if len(z.journal) != 0 {
+		t.Errorf("Expected an empty journal after AXFR, got %d changes", len(z.journal))
+	}
+}
+
+func TestIncrementalEnd(t *testing.T) {
+	var rrs []dns.RR
+	for _, s := range []string{
+		"example.org. 3600 IN SOA a. b. 3 7200 3600 1209600 3600",
+		"example.org. 3600 IN SOA a. b. 1 7200 3600 1209600 3600",
+		"example.org. 3600 IN SOA a. b. 2 7200 3600 1209600 3600",
+		"a.example.org. 3600 IN A 10.0.0.1",
+		"example.org. 3600 IN SOA a. b. 3 7200 3600 1209600 3600",
+	} {
+		rr, _ := dns.NewRR(s)
+		rrs = append(rrs, rr)
+	}
+	if _, err := incremental(rrs[1].(*dns.SOA), rrs); err == nil {
+		t.Error("Expected an error for changes that don't end at the serial of the transfer")
+	}
+	if _, err := incremental(rrs[2].(*dns.SOA), rrs); err == nil {
+		t.Error("Expected an error for changes that don't start at the serial of the zone")
+	}
+}
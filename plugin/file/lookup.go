@@ -163,6 +163,10 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 			if do {
 				dss := typeFromElem(elem, dns.TypeDS, do)
 				nsrrs = append(nsrrs, dss...)
+				// Prove there is no DS record (RFC 5155, Section 7.2.7).
+				if n := z.nsec3(ap, tr); n != nil && len(dss) == 0 {
+					nsrrs = append(nsrrs, n.noData(elem.Name())...)
+				}
 			}
 
 			return nil, nsrrs, glue, Delegation
@@ -189,6 +193,10 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		if len(rrs) == 0 {
 			ret := ap.soa(do)
 			if do {
+				if n := z.nsec3(ap, tr); n != nil {
+					ret = append(ret, n.noData(qname)...)
+					return nil, ret, nil, NoData
+				}
 				nsec := typeFromElem(elem, dns.TypeNSEC, do)
 				ret = append(ret, nsec...)
 			}
@@ -228,6 +236,10 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		if len(rrs) == 0 {
 			ret := ap.soa(do)
 			if do {
+				if n := z.nsec3(ap, tr); n != nil {
+					ret = append(ret, n.wildcardNoData(qname)...)
+					return nil, ret, nil, NoData
+				}
 				nsec := typeFromElem(wildElem, dns.TypeNSEC, do)
 				ret = append(ret, nsec...)
 			}
@@ -237,7 +249,9 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 		auth := ap.ns(do)
 		if do {
 			// An NSEC is needed to say no longer name exists under this wildcard.
-			if deny, found := tr.Prev(qname); found {
+			if n := z.nsec3(ap, tr); n != nil {
+				auth = append(auth, n.wildcard(qname)...)
+			} else if deny, found := tr.Prev(qname); found {
 				nsec := typeFromElem(deny, dns.TypeNSEC, do)
 				auth = append(auth, nsec...)
 			}
@@ -261,6 +275,15 @@ func (z *Zone) Lookup(ctx context.Context, state request.Request, qname string)
 
 	ret := ap.soa(do)
 	if do {
+		if n := z.nsec3(ap, tr); n != nil {
+			if rcode == NameError {
+				ret = append(ret, n.nameError(qname)...)
+			} else {
+				ret = append(ret, n.noData(qname)...)
+			}
+			goto Out
+		}
+
 		deny, found := tr.Prev(qname)
 		if !found {
 			goto Out
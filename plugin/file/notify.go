@@ -2,7 +2,9 @@ package file
 
 import (
 	"net"
+	"strings"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -11,7 +13,9 @@ import (
 // isNotify checks if state is a notify message and if so, will *also* check if it
 // is from one of the configured masters. If not it will not be a valid notify
 // message. If the zone z is not a secondary zone the message will also be ignored.
-func (z *Zone) isNotify(state request.Request) bool {
+// Masters that sign transfers with a TSIG key must have signed the notify with it, keyName
+// is the name of the key the notify was verified with.
+func (z *Zone) isNotify(state request.Request, keyName string) bool {
 	if state.Req.Opcode != dns.OpcodeNotify {
 		return false
 	}
@@ -21,11 +25,11 @@ func (z *Zone) isNotify(state request.Request) bool {
 	// If remote IP matches we accept.
 	remote := state.IP()
 	for _, f := range z.TransferFrom {
-		from, _, err := net.SplitHostPort(f)
+		from, _, err := net.SplitHostPort(strings.TrimPrefix(f, transport.TLS+"://"))
 		if err != nil {
 			continue
 		}
-		if from == remote {
+		if from == remote && (z.TransferKeys[f] == "" || z.TransferKeys[f] == keyName) {
 			return true
 		}
 	}
@@ -0,0 +1,129 @@
+package file
+
+import (
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// insertNSEC3 inserts r, an NSEC3 record or its signature, in the NSEC3 tree of z.
+func (z *Zone) insertNSEC3(r dns.RR) {
+	if z.Apex.NSEC3 == nil {
+		z.Apex.NSEC3 = &tree.Tree{}
+	}
+	z.Apex.NSEC3.Insert(r)
+}
+
+// nsec3 holds what is needed to deny the existence of names and types in a zone signed with NSEC3 (RFC 5155).
+type nsec3 struct {
+	origin string
+	param  *dns.NSEC3PARAM
+	tree   *tree.Tree
+}
+
+// nsec3 returns the NSEC3 parameters of the zone with apex ap and tree tr. If the zone isn't signed with
+// NSEC3, nil is returned.
+func (z *Zone) nsec3(ap Apex, tr *tree.Tree) *nsec3 {
+	if ap.NSEC3 == nil || ap.NSEC3.Len() == 0 {
+		return nil
+	}
+	e, ok := tr.Search(z.origin)
+	if !ok {
+		return nil
+	}
+	params := e.Type(dns.TypeNSEC3PARAM)
+	if len(params) == 0 {
+		return nil
+	}
+	return &nsec3{origin: z.origin, param: params[0].(*dns.NSEC3PARAM), tree: ap.NSEC3}
+}
+
+// hash returns the hashed owner name of name.
+func (n *nsec3) hash(name string) string {
+	return strings.ToLower(dns.HashName(name, n.param.Hash, n.param.Iterations, n.param.Salt)) + "." + n.origin
+}
+
+// match returns the element with the NSEC3 record that matches name, or nil if there is none.
+func (n *nsec3) match(name string) *tree.Elem {
+	e, _ := n.tree.Search(n.hash(name))
+	return e
+}
+
+// cover returns the element with the NSEC3 record that covers name, which does not exist.
+func (n *nsec3) cover(name string) *tree.Elem {
+	if e, ok := n.tree.Prev(n.hash(name)); ok {
+		return e
+	}
+	// The hash of name sorts before the first one, the last NSEC3 record wraps around to cover it.
+	return n.tree.Max()
+}
+
+// closestEncloser returns the closest provable encloser of name, the longest existing ancestor of name that
+// has an NSEC3 record, and the next closer name, the name one label longer than it (RFC 5155, Section 7.2.1).
+// If name itself exists, next is empty.
+func (n *nsec3) closestEncloser(name string) (ce, next string) {
+	for ce = name; dns.IsSubDomain(n.origin, ce); {
+		if n.match(ce) != nil {
+			return ce, next
+		}
+		next = ce
+		i, end := dns.NextLabel(ce, 0)
+		if end {
+			break
+		}
+		ce = ce[i:]
+	}
+	return n.origin, next
+}
+
+// records returns the NSEC3 records, and their signatures, of the elements. Elements that are nil or that
+// are given more than once are skipped.
+func (n *nsec3) records(elems ...*tree.Elem) []dns.RR {
+	rrs := []dns.RR{}
+	seen := map[string]struct{}{}
+	for _, e := range elems {
+		if e == nil {
+			continue
+		}
+		if _, ok := seen[e.Name()]; ok {
+			continue
+		}
+		seen[e.Name()] = struct{}{}
+		rrs = append(rrs, typeFromElem(e, dns.TypeNSEC3, true)...)
+	}
+	return rrs
+}
+
+// noData returns the NSEC3 records that prove name doesn't have the queried type, this is the NSEC3 record
+// matching name (RFC 5155, Section 7.2.3). An unsigned delegation may not have one when opt-out is used; the
+// closest provable encloser proof shows it is covered by an opt-out NSEC3 record instead (Section 7.2.4).
+func (n *nsec3) noData(name string) []dns.RR {
+	if e := n.match(name); e != nil {
+		return n.records(e)
+	}
+	ce, next := n.closestEncloser(name)
+	return n.records(n.match(ce), n.cover(next))
+}
+
+// nameError returns the NSEC3 records that prove name doesn't exist: the closest encloser proof, and the
+// NSEC3 record that covers the wildcard at the closest encloser (RFC 5155, Section 7.2.2).
+func (n *nsec3) nameError(name string) []dns.RR {
+	ce, next := n.closestEncloser(name)
+	return n.records(n.match(ce), n.cover(next), n.cover("*."+ce))
+}
+
+// wildcardNoData returns the NSEC3 records that prove the wildcard that matches name doesn't have the
+// queried type: the closest encloser proof and the NSEC3 record matching the wildcard (RFC 5155, Section 7.2.5).
+func (n *nsec3) wildcardNoData(name string) []dns.RR {
+	ce, next := n.closestEncloser(name)
+	return n.records(n.match(ce), n.cover(next), n.match("*."+ce))
+}
+
+// wildcard returns the NSEC3 record that proves name doesn't exist, and that a wildcard answer was
+// synthesized for it: the NSEC3 record that covers the next closer name (RFC 5155, Section 7.2.6).
+func (n *nsec3) wildcard(name string) []dns.RR {
+	_, next := n.closestEncloser(name)
+	return n.records(n.cover(next))
+}
@@ -3,19 +3,64 @@ package file
 import (
 	"strings"
 	"testing"
+
+	"github.com/miekg/dns"
 )
 
 func TestParseNSEC3PARAM(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin", 0)
-	if err == nil {
-		t.Fatalf("Expected error when reading zone, got nothing")
+	z, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %s", err)
+	}
+	e, _ := z.Search("miek.nl.")
+	if e == nil || len(e.Type(dns.TypeNSEC3PARAM)) != 1 {
+		t.Errorf("Expected an NSEC3PARAM record at the apex")
 	}
 }
 
 func TestParseNSEC3(t *testing.T) {
-	_, err := Parse(strings.NewReader(nsec3Test), "miek.nl", "stdin", 0)
-	if err == nil {
-		t.Fatalf("Expected error when reading zone, got nothing")
+	z, err := Parse(strings.NewReader(nsec3Test), "example.org", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %s", err)
+	}
+	// The hashed owner names are kept out of the zone's tree.
+	name := "aub8v9ce95ie18spjubsr058h41n7pa5.example.org."
+	if _, ok := z.Search(name); ok {
+		t.Errorf("Expected %s not to be in the zone's tree", name)
+	}
+	e, ok := z.Apex.NSEC3.Search(name)
+	if !ok {
+		t.Fatalf("Expected the NSEC3 records of %s", name)
+	}
+	if len(e.Type(dns.TypeNSEC3)) != 1 || len(e.Type(dns.TypeRRSIG)) != 1 {
+		t.Errorf("Expected an NSEC3 record and its signature for %s, got %v", name, e.All())
+	}
+
+	// They are transferred, and removed by an incremental transfer.
+	if got := transferred(t, z, 0); len(got) != 4 {
+		t.Errorf("Expected AXFR of 4 records, got %d: %v", len(got), got)
+	}
+	z1 := z.copyForUpdate()
+	z1.applyDelta(delta{to: z.Apex.SOA, del: e.Type(dns.TypeNSEC3)})
+	if e, _ := z1.Apex.NSEC3.Search(name); e == nil || len(e.Type(dns.TypeNSEC3)) != 0 || len(e.Type(dns.TypeRRSIG)) != 1 {
+		t.Errorf("Expected only the NSEC3 record to be removed")
+	}
+	if e, _ := z.Apex.NSEC3.Search(name); len(e.Type(dns.TypeNSEC3)) != 1 {
+		t.Errorf("Expected the NSEC3 record to be kept in the original zone")
+	}
+}
+
+func TestApplyDeltaWithoutNSEC3(t *testing.T) {
+	z, err := Parse(strings.NewReader(nsec3paramTest), "miek.nl", "stdin", 0)
+	if err != nil {
+		t.Fatalf("Expected no error when reading zone, got %s", err)
+	}
+	// A change that removes an NSEC3 record the zone doesn't have.
+	rr, _ := dns.NewRR("aub8v9ce95ie18spjubsr058h41n7pa5.miek.nl. 284 IN NSEC3 1 1 5 D0CBEAAF0AC77314 AUB95P93VPKP55G6U5S4SGS7LS61ND85 NS SOA")
+	z1 := z.copyForUpdate()
+	z1.applyDelta(delta{to: z.Apex.SOA, del: []dns.RR{rr}})
+	if z1.Apex.NSEC3 != nil {
+		t.Errorf("Expected no NSEC3 records")
 	}
 }
 
@@ -19,30 +19,9 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 		for {
 			select {
 			case <-tick.C:
-				zFile := z.File()
-				reader, err := os.Open(filepath.Clean(zFile))
-				if err != nil {
-					log.Errorf("Failed to open zone %q in %q: %v", z.origin, zFile, err)
+				if !z.reload() {
 					continue
 				}
-
-				serial := z.SOASerialIfDefined()
-				zone, err := Parse(reader, z.origin, zFile, serial)
-				reader.Close()
-				if err != nil {
-					if _, ok := err.(*serialErr); !ok {
-						log.Errorf("Parsing zone %q: %v", z.origin, err)
-					}
-					continue
-				}
-
-				// copy elements we need
-				z.Lock()
-				z.Apex = zone.Apex
-				z.Tree = zone.Tree
-				z.Unlock()
-
-				log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, z.Apex.SOA.Serial)
 				if t != nil {
 					if err := t.Notify(z.origin); err != nil {
 						log.Warningf("Failed sending notifies: %s", err)
@@ -58,6 +37,42 @@ func (z *Zone) Reload(t *transfer.Transfer) error {
 	return nil
 }
 
+// reload loads the zone from its file if the SOA serial changed, and returns true if it did. Dynamic updates
+// can't run at the same time, the reloaded zone would overwrite them, or the other way around.
+func (z *Zone) reload() bool {
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	zFile := z.File()
+	reader, err := os.Open(filepath.Clean(zFile))
+	if err != nil {
+		log.Errorf("Failed to open zone %q in %q: %v", z.origin, zFile, err)
+		return false
+	}
+
+	serial := z.SOASerialIfDefined()
+	zone, err := Parse(reader, z.origin, zFile, serial)
+	reader.Close()
+	if err != nil {
+		if _, ok := err.(*serialErr); !ok {
+			log.Errorf("Parsing zone %q: %v", z.origin, err)
+		}
+		return false
+	}
+
+	d, size := z.diff(zone)
+
+	// copy elements we need
+	z.Lock()
+	z.Apex = zone.Apex
+	z.Tree = zone.Tree
+	z.addToJournal(d, size)
+	z.Unlock()
+
+	log.Infof("Successfully reloaded zone %q in %q with %d SOA serial", z.origin, zFile, zone.Apex.SOA.Serial)
+	return true
+}
+
 // SOASerialIfDefined returns the SOA's serial if the zone has a SOA record in the Apex, or -1 otherwise.
 func (z *Zone) SOASerialIfDefined() int64 {
 	z.RLock()
@@ -0,0 +1,23 @@
+package file
+
+import "github.com/miekg/dns"
+
+// RRset returns the RRs of type qtype at name, which must be fully qualified and lower case.
+func (z *Zone) RRset(name string, qtype uint16) []dns.RR { return z.rrset(name, qtype) }
+
+// SetRRset replaces the RRs of type qtype at name with rrs, an empty rrs deletes the RRset. Unlike
+// dynamic updates, z is modified in place, the caller must make sure it isn't used concurrently.
+func (z *Zone) SetRRset(name string, qtype uint16, rrs []dns.RR) {
+	name = dns.CanonicalName(name)
+	switch {
+	case qtype == dns.TypeSOA:
+		z.Apex.SOA = nil
+	case qtype == dns.TypeNS && name == z.origin:
+		z.Apex.NS = nil
+	default:
+		z.deleteRRset(name, qtype)
+	}
+	for _, rr := range rrs {
+		z.Insert(rr)
+	}
+}
@@ -0,0 +1,46 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestSetRRset(t *testing.T) {
+	z := NewZone("example.org.", "")
+	for _, rr := range []dns.RR{
+		test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 3600 300 2419200 300"),
+		test.NS("example.org. 300 IN NS ns1.example.org."),
+		test.NS("example.org. 300 IN NS ns2.example.org."),
+		test.A("www.example.org. 300 IN A 10.0.0.1"),
+		test.A("www.example.org. 300 IN A 10.0.0.2"),
+		test.TXT(`www.example.org. 300 IN TXT "www"`),
+	} {
+		z.Insert(rr)
+	}
+
+	z.SetRRset("WWW.example.org.", dns.TypeA, []dns.RR{test.A("www.example.org. 60 IN A 10.0.0.3")})
+	if rrs := z.RRset("www.example.org.", dns.TypeA); len(rrs) != 1 || rrs[0].(*dns.A).A.String() != "10.0.0.3" {
+		t.Errorf("Expected www.example.org. A to be replaced, got %v", rrs)
+	}
+	if rrs := z.RRset("www.example.org.", dns.TypeTXT); len(rrs) != 1 {
+		t.Errorf("Expected www.example.org. TXT to be kept, got %v", rrs)
+	}
+
+	z.SetRRset("www.example.org.", dns.TypeTXT, nil)
+	if rrs := z.RRset("www.example.org.", dns.TypeTXT); len(rrs) != 0 {
+		t.Errorf("Expected www.example.org. TXT to be deleted, got %v", rrs)
+	}
+
+	z.SetRRset("example.org.", dns.TypeNS, []dns.RR{test.NS("example.org. 300 IN NS ns3.example.org.")})
+	if len(z.Apex.NS) != 1 || z.Apex.NS[0].(*dns.NS).Ns != "ns3.example.org." {
+		t.Errorf("Expected apex NS to be replaced, got %v", z.Apex.NS)
+	}
+
+	z.SetRRset("example.org.", dns.TypeSOA, []dns.RR{test.SOA("example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 2 3600 300 2419200 300")})
+	if z.Apex.SOA.Serial != 2 {
+		t.Errorf("Expected SOA serial 2, got %d", z.Apex.SOA.Serial)
+	}
+}
@@ -1,84 +1,265 @@
 package file
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
-// TransferIn retrieves the zone from the masters, parses it and sets it live.
+// TransferIn retrieves the zone from the masters, parses it and sets it live. When the zone has a SOA
+// record an incremental transfer (IXFR) is requested, the differences it returns are applied to a copy of
+// the zone. Masters may fall back to sending the whole zone, if the differences don't apply to the zone
+// the whole zone is requested (AXFR).
 func (z *Zone) TransferIn() error {
 	if len(z.TransferFrom) == 0 {
 		return nil
 	}
-	m := new(dns.Msg)
-	m.SetAxfr(z.origin)
+	z.RLock()
+	soa := z.Apex.SOA
+	z.RUnlock()
 
-	z1 := z.CopyWithoutApex()
 	var (
-		Err error
-		tr  string
+		Err    error
+		tr     string
+		rrs    []dns.RR
+		z1     *Zone
+		deltas []delta
 	)
 
-Transfer:
 	for _, tr = range z.TransferFrom {
-		t := new(dns.Transfer)
-		c, err := t.In(m, tr)
-		if err != nil {
-			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
-			Err = err
-			continue Transfer
+		rrs, Err = z.transferFrom(tr, soa)
+		if Err != nil {
+			continue
 		}
-		for env := range c {
-			if env.Error != nil {
-				log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
-				Err = env.Error
-				continue Transfer
-			}
-			for _, rr := range env.RR {
-				if err := z1.Insert(rr); err != nil {
-					log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
-					Err = err
-					continue Transfer
-				}
-			}
+		if len(rrs) == 1 {
+			break
+		}
+		z1, deltas, Err = z.applyIncremental(soa, rrs)
+		if Err == nil {
+			break
+		}
+		log.Warningf("Failed to apply incremental transfer `%s' from %q, requesting a full transfer: %v", z.origin, tr, Err)
+		if rrs, Err = z.transferFrom(tr, nil); Err == nil {
+			break
 		}
-		Err = nil
-		break
 	}
 	if Err != nil {
 		return Err
 	}
 
+	if len(rrs) == 1 {
+		z.saveTransfer(false)
+		log.Infof("Transferred: %s from %s, zone is up to date", z.origin, tr)
+		return nil
+	}
+
+	if deltas != nil {
+		size := len(z1.records())
+		z.Lock()
+		z.Tree = z1.Tree
+		z.Apex = z1.Apex
+		z.Expired = false
+		for _, d := range deltas {
+			z.addToJournal(d, size)
+		}
+		z.Unlock()
+		z.saveTransfer(true)
+		log.Infof("Transferred: %s from %s, incrementally with %d change(s)", z.origin, tr, len(deltas))
+		return nil
+	}
+
+	z1 = z.CopyWithoutApex()
+	for _, rr := range rrs {
+		if err := z1.Insert(rr); err != nil {
+			log.Errorf("Failed to parse transfer `%s' from: %q: %v", z.origin, tr, err)
+			return err
+		}
+	}
+
 	z.Lock()
 	z.Tree = z1.Tree
 	z.Apex = z1.Apex
 	z.Expired = false
+	z.journal = nil
 	z.Unlock()
+	z.saveTransfer(true)
 	log.Infof("Transferred: %s from %s", z.origin, tr)
 	return nil
 }
 
+// transferFrom transfers the zone from the master tr. An IXFR is requested if soa isn't nil, an AXFR
+// otherwise.
+func (z *Zone) transferFrom(tr string, soa *dns.SOA) ([]dns.RR, error) {
+	key, secret, err := z.tsigKey(tr)
+	if err != nil {
+		log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
+		return nil, err
+	}
+
+	m := new(dns.Msg)
+	if soa == nil {
+		m.SetAxfr(z.origin)
+	} else {
+		m.SetIxfr(z.origin, soa.Serial, soa.Ns, soa.Mbox)
+	}
+	z.signRequest(m, key)
+
+	addr, cfg := z.transferAddr(tr)
+	t := &dns.Transfer{TLS: cfg}
+	p := &tsigProvider{secret: secret}
+	if key != "" {
+		t.TsigProvider = p
+		t.Conn, err = p.dial(addr, cfg)
+		if err != nil {
+			log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
+			return nil, err
+		}
+	}
+	c, err := t.In(m, addr)
+	if err != nil {
+		log.Errorf("Failed to setup transfer `%s' with `%q': %v", z.origin, tr, err)
+		return nil, err
+	}
+	// The last message must be signed, and only up to 99 in a row may be unsigned (RFC 8945, Section 5.3.1).
+	var rrs []dns.RR
+	msgs, unsigned, tooMany := 0, 0, false
+	for env := range c {
+		if env.Error != nil {
+			log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, env.Error)
+			return nil, env.Error
+		}
+		rrs = append(rrs, env.RR...)
+		msgs++
+		if p.isVerified(msgs) {
+			unsigned = 0
+			continue
+		}
+		unsigned++
+		tooMany = tooMany || unsigned > maxUnsigned
+	}
+	if key != "" && (unsigned > 0 || tooMany) {
+		log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, errUnsigned)
+		return nil, errUnsigned
+	}
+	if len(rrs) == 0 {
+		return nil, errors.New("empty transfer")
+	}
+	if _, ok := rrs[0].(*dns.SOA); !ok {
+		err := errors.New("transfer doesn't start with a SOA record")
+		log.Errorf("Failed to transfer `%s' from %q: %v", z.origin, tr, err)
+		return nil, err
+	}
+	return rrs, nil
+}
+
+// applyIncremental applies the differences in rrs, the records of an incremental transfer, to a copy of
+// the zone and returns it. If rrs is a full transfer, nil is returned.
+func (z *Zone) applyIncremental(soa *dns.SOA, rrs []dns.RR) (*Zone, []delta, error) {
+	deltas, err := incremental(soa, rrs)
+	if err != nil || deltas == nil {
+		return nil, nil, err
+	}
+
+	z.RLock()
+	cur := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree}
+	z.RUnlock()
+
+	z1 := cur.copyForUpdate()
+	for _, d := range deltas {
+		if d.from.Serial != z1.Apex.SOA.Serial {
+			return nil, nil, fmt.Errorf("changes start at serial %d, expected %d", d.from.Serial, z1.Apex.SOA.Serial)
+		}
+		z1.applyDelta(d)
+	}
+	return z1, deltas, nil
+}
+
+// incremental returns the differences in rrs, the records of an incremental transfer for the zone with
+// soa. If rrs is a full transfer, nil is returned.
+func incremental(soa *dns.SOA, rrs []dns.RR) ([]delta, error) {
+	if soa == nil || len(rrs) < 3 {
+		return nil, nil
+	}
+	first, ok := rrs[1].(*dns.SOA)
+	if !ok || first.Serial == rrs[0].(*dns.SOA).Serial {
+		return nil, nil
+	}
+	if first.Serial != soa.Serial {
+		return nil, fmt.Errorf("incremental transfer starts at serial %d, expected %d", first.Serial, soa.Serial)
+	}
+
+	// Each difference sequence is: old SOA, deleted RRs, new SOA, added RRs. The transfer ends with
+	// the current SOA.
+	deltas := []delta{}
+	i := 1
+	for i < len(rrs)-1 {
+		d := delta{from: rrs[i].(*dns.SOA)}
+		for i++; i < len(rrs); i++ {
+			if to, ok := rrs[i].(*dns.SOA); ok {
+				d.to = to
+				break
+			}
+			d.del = append(d.del, rrs[i])
+		}
+		if d.to == nil {
+			return nil, fmt.Errorf("incremental transfer ends in the deletions from serial %d", d.from.Serial)
+		}
+		for i++; i < len(rrs); i++ {
+			if _, ok := rrs[i].(*dns.SOA); ok {
+				break
+			}
+			d.add = append(d.add, rrs[i])
+		}
+		deltas = append(deltas, d)
+	}
+	last := deltas[len(deltas)-1].to
+	if end, ok := rrs[len(rrs)-1].(*dns.SOA); !ok || end.Serial != last.Serial {
+		return nil, fmt.Errorf("incremental transfer ends at serial %d, not with its SOA record", last.Serial)
+	}
+	return deltas, nil
+}
+
 // shouldTransfer checks the primaries of zone, retrieves the SOA record, checks the current serial
 // and the remote serial and will return true if the remote one is higher than the locally configured one.
 func (z *Zone) shouldTransfer() (bool, error) {
-	c := new(dns.Client)
-	c.Net = "tcp" // do this query over TCP to minimize spoofing
-	m := new(dns.Msg)
-	m.SetQuestion(z.origin, dns.TypeSOA)
-
 	var Err error
 	serial := -1
 
 Transfer:
 	for _, tr := range z.TransferFrom {
 		Err = nil
-		ret, _, err := c.Exchange(m, tr)
+		key, secret, err := z.tsigKey(tr)
+		if err != nil {
+			Err = err
+			continue
+		}
+		c := new(dns.Client)
+		c.Net = "tcp" // do this query over TCP to minimize spoofing
+		addr, cfg := z.transferAddr(tr)
+		if cfg != nil {
+			c.Net = "tcp-tls"
+			c.TLSConfig = cfg
+		}
+		p := &tsigProvider{secret: secret}
+		if key != "" {
+			c.TsigProvider = p
+		}
+		m := new(dns.Msg)
+		m.SetQuestion(z.origin, dns.TypeSOA)
+		z.signRequest(m, key)
+
+		ret, _, err := c.Exchange(m, addr)
 		if err != nil || ret.Rcode != dns.RcodeSuccess {
 			Err = err
 			continue
 		}
+		if key != "" && !p.isVerified(0) {
+			Err = errUnsigned
+			continue
+		}
 		for _, a := range ret.Answer {
 			if a.Header().Rrtype == dns.TypeSOA {
 				serial = int(a.(*dns.SOA).Serial)
@@ -149,6 +330,8 @@ Restart:
 					// transfer failed, leave retryActive true
 					break
 				}
+			} else {
+				z.saveTransfer(false)
 			}
 
 			// no errors, stop timers and restart
@@ -175,6 +358,8 @@ Restart:
 					retryActive = true
 					break
 				}
+			} else {
+				z.saveTransfer(false)
 			}
 
 			// no errors, stop timers and restart
@@ -129,13 +129,25 @@ func TestIsNotify(t *testing.T) {
 	state.Req.Opcode = dns.OpcodeNotify
 
 	z.TransferFrom = []string{"10.240.0.1:53"} // IP from testing/responseWriter
-	if !z.isNotify(state) {
+	if !z.isNotify(state, "") {
 		t.Fatal("Should have been valid notify")
 	}
 	z.TransferFrom = []string{"10.240.0.2:53"}
-	if z.isNotify(state) {
+	if z.isNotify(state, "") {
 		t.Fatal("Should have been invalid notify")
 	}
+
+	z.TransferFrom = []string{"10.240.0.1:53"}
+	z.TransferKeys = map[string]string{"10.240.0.1:53": "transfer.key."}
+	if !z.isNotify(state, "transfer.key.") {
+		t.Fatal("Should have been valid signed notify")
+	}
+	if z.isNotify(state, "") {
+		t.Fatal("Should have been invalid unsigned notify")
+	}
+	if z.isNotify(state, "other.key.") {
+		t.Fatal("Should have been invalid notify signed with another key")
+	}
 }
 
 func newRequest(zone string, qtype uint16) request.Request {
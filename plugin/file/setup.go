@@ -75,6 +75,7 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 
 	var openErr error
 	reload := 1 * time.Minute
+	var update *UpdatePolicy
 
 	for c.Next() {
 		// file db.file [zones...]
@@ -131,6 +132,27 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 				// remove soon
 				c.RemainingArgs()
 
+			case "update":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return Zones{}, c.ArgErr()
+				}
+				if update == nil {
+					update = &UpdatePolicy{}
+				}
+				if err := update.AddSources(args); err != nil {
+					return Zones{}, c.Err(err.Error())
+				}
+			case "update_key":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return Zones{}, c.ArgErr()
+				}
+				if update == nil {
+					update = &UpdatePolicy{}
+				}
+				update.AddKeys(args)
+
 			default:
 				return Zones{}, c.Errf("unknown property '%s'", c.Val())
 			}
@@ -139,7 +161,12 @@ func fileParse(c *caddy.Controller) (Zones, error) {
 		for i := range origins {
 			z[origins[i]].ReloadInterval = reload
 			z[origins[i]].Upstream = upstream.New()
+			z[origins[i]].UpdatePolicy = update
+		}
+		if update != nil {
+			config.UpdateZones = append(config.UpdateZones, origins...)
 		}
+		update = nil
 	}
 
 	if openErr != nil {
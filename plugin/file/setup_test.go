@@ -122,3 +122,61 @@ func TestParseReload(t *testing.T) {
 		}
 	}
 }
+
+func TestParseUpdate(t *testing.T) {
+	name, rm, err := test.TempFile(".", dbMiekNL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		policy    bool
+		keys      int
+	}{
+		{`file ` + name + ` example.org.`, false, false, 0},
+		{`file ` + name + ` example.org. {
+			update 10.0.0.0/8 ::1
+		}`, false, true, 0},
+		{`file ` + name + ` example.org. {
+			update *
+			update_key key.example.org. other.example.org.
+		}`, false, true, 2},
+		{`file ` + name + ` example.org. {
+			update_key key.example.org.
+		}`, false, true, 1},
+		// errors
+		{`file ` + name + ` example.org. {
+			update
+		}`, true, false, 0},
+		{`file ` + name + ` example.org. {
+			update 10.0.0.0/33
+		}`, true, false, 0},
+		{`file ` + name + ` example.org. {
+			update_key
+		}`, true, false, 0},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		z, err := fileParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d expected errors, but got no error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d expected no errors, but got '%v'", i, err)
+		}
+		p := z.Z["example.org."].UpdatePolicy
+		if (p != nil) != test.policy {
+			t.Fatalf("Test %d expected update policy to be set: %t", i, test.policy)
+		}
+		if p != nil && len(p.Keys) != test.keys {
+			t.Errorf("Test %d expected %d update keys, got %d", i, test.keys, len(p.Keys))
+		}
+	}
+}
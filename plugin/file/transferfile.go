@@ -0,0 +1,73 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LoadTransferFile loads the zone from z.TransferFile, as written after an earlier transfer. The modification
+// time of the file is the time of that transfer, or of the last refresh that found the zone up to date. The
+// time at which the zone expires, according to the SOA expire timer, is returned. A zone that has already
+// expired isn't loaded and an error is returned. If there is no file, the zero time is returned.
+func (z *Zone) LoadTransferFile() (time.Time, error) {
+	if z.TransferFile == "" {
+		return time.Time{}, nil
+	}
+	reader, err := os.Open(filepath.Clean(z.TransferFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	defer reader.Close()
+	info, err := reader.Stat()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	z1, err := Parse(reader, z.origin, z.TransferFile, 0)
+	if err != nil {
+		return time.Time{}, err
+	}
+	expire := info.ModTime().Add(time.Duration(z1.Apex.SOA.Expire) * time.Second)
+	if time.Now().After(expire) {
+		return expire, fmt.Errorf("zone transferred at %s expired at %s", info.ModTime().Format(time.RFC3339), expire.Format(time.RFC3339))
+	}
+
+	z.Lock()
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+	z.Expired = false
+	z.Unlock()
+	return expire, nil
+}
+
+// saveTransfer writes z to z.TransferFile after a transfer. If changed is false the zone is already up
+// to date in the file, only its modification time is set to now.
+func (z *Zone) saveTransfer(changed bool) {
+	if z.TransferFile == "" {
+		return
+	}
+	if !changed {
+		now := time.Now()
+		if err := os.Chtimes(z.TransferFile, now, now); err == nil || !os.IsNotExist(err) {
+			if err != nil {
+				log.Warningf("Failed to update the transfer time of %q in %q: %s", z.origin, z.TransferFile, err)
+			}
+			return
+		}
+	}
+
+	z.RLock()
+	cur := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree}
+	z.RUnlock()
+	if cur.Apex.SOA == nil {
+		return
+	}
+	if err := cur.save(z.TransferFile, "written by the secondary plugin after a transfer"); err != nil {
+		log.Warningf("Failed to write %q to %q: %s", z.origin, z.TransferFile, err)
+	}
+}
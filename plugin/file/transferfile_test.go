@@ -0,0 +1,72 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+)
+
+func TestTransferFile(t *testing.T) {
+	p := primary{newJournalZone(t, 0)}
+	s := dnstest.NewServer(p.Handler)
+	defer s.Close()
+
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	z := NewZone(journalOrigin, "stdin")
+	z.TransferFrom = []string{s.Addr}
+	z.TransferFile = name
+
+	if expire, err := z.LoadTransferFile(); err != nil || !expire.IsZero() {
+		t.Fatalf("Expected nothing to load before the first transfer, got %s, %v", expire, err)
+	}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	setJournalVersion(t, p.z, 1)
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+
+	// A restart, the zone is loaded from the file.
+	z1 := NewZone(journalOrigin, "stdin")
+	z1.TransferFile = name
+	expire, err := z1.LoadTransferFile()
+	if err != nil {
+		t.Fatalf("Failed to load the transferred zone: %s", err)
+	}
+	if got, want := zoneRecords(z1), zoneRecords(p.z); got != want {
+		t.Errorf("Expected loaded zone:\n%s\ngot:\n%s", want, got)
+	}
+	if d := time.Until(expire); d < 1209500*time.Second || d > 1209600*time.Second {
+		t.Errorf("Expected the zone to expire in 1209600s, got %s", d)
+	}
+
+	// A refresh that finds the zone up to date sets the transfer time.
+	old := time.Now().Add(-1209000 * time.Second)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.TransferIn(); err != nil {
+		t.Fatalf("Failed to transfer: %s", err)
+	}
+	if info, _ := os.Stat(name); time.Since(info.ModTime()) > time.Minute {
+		t.Errorf("Expected the transfer time to be updated, got %s", info.ModTime())
+	}
+
+	// Once the zone expired, it isn't loaded.
+	old = time.Now().Add(-1209601 * time.Second)
+	if err := os.Chtimes(name, old, old); err != nil {
+		t.Fatal(err)
+	}
+	z2 := NewZone(journalOrigin, "stdin")
+	z2.TransferFile = name
+	if _, err := z2.LoadTransferFile(); err == nil {
+		t.Errorf("Expected an error loading an expired zone")
+	}
+	if z2.Apex.SOA != nil {
+		t.Errorf("Expected the expired zone not to be loaded")
+	}
+}
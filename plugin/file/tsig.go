@@ -0,0 +1,152 @@
+package file
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/tsig"
+
+	"github.com/miekg/dns"
+)
+
+// tsigKey returns the name of the TSIG key used for transfers from primary, and its secret. If no key is
+// used, the name is empty.
+func (z *Zone) tsigKey(primary string) (string, string, error) {
+	key := z.TransferKeys[primary]
+	if key == "" {
+		return "", "", nil
+	}
+	secret, ok := z.TsigSecret[key]
+	if !ok {
+		return "", "", fmt.Errorf("TSIG key %q is not defined", key)
+	}
+	return key, secret, nil
+}
+
+// tsigProvider implements dns.TsigProvider for a single secret, it records which messages were verified.
+// The dns package only verifies messages that have a TSIG record, and accepts the others, this makes it
+// possible to check that responses were signed. In a zone transfer the messages are counted by the
+// connection from dial, the first one is 1; a single response is message 0.
+type tsigProvider struct {
+	secret string
+
+	mu       sync.Mutex
+	msg      int          // the message being read
+	verified map[int]bool // the messages that were verified
+}
+
+// Generate implements dns.TsigProvider.
+func (p *tsigProvider) Generate(msg []byte, t *dns.TSIG) ([]byte, error) {
+	secret, err := base64.StdEncoding.DecodeString(p.secret)
+	if err != nil {
+		return nil, err
+	}
+	var h func() hash.Hash
+	switch dns.CanonicalName(t.Algorithm) {
+	case dns.HmacSHA1:
+		h = sha1.New
+	case dns.HmacSHA224:
+		h = sha256.New224
+	case dns.HmacSHA256:
+		h = sha256.New
+	case dns.HmacSHA384:
+		h = sha512.New384
+	case dns.HmacSHA512:
+		h = sha512.New
+	default:
+		return nil, dns.ErrKeyAlg
+	}
+	mac := hmac.New(h, secret)
+	mac.Write(msg)
+	return mac.Sum(nil), nil
+}
+
+// Verify implements dns.TsigProvider.
+func (p *tsigProvider) Verify(msg []byte, t *dns.TSIG) error {
+	b, err := p.Generate(msg, t)
+	if err != nil {
+		return err
+	}
+	mac, err := hex.DecodeString(t.MAC)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(b, mac) {
+		return dns.ErrSig
+	}
+	p.mu.Lock()
+	if p.verified == nil {
+		p.verified = map[int]bool{}
+	}
+	p.verified[p.msg] = true
+	p.mu.Unlock()
+	return nil
+}
+
+// isVerified returns true if message n was verified.
+func (p *tsigProvider) isVerified(n int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.verified[n]
+}
+
+// maxUnsigned is the maximum number of consecutive messages without TSIG in a transfer (RFC 8945, Section 5.3.1).
+const maxUnsigned = 99
+
+// dial connects to addr for a zone transfer, with TLS if cfg isn't nil. The returned connection counts the
+// messages read for p.
+func (p *tsigProvider) dial(addr string, cfg *tls.Config) (*dns.Conn, error) {
+	var (
+		co  *dns.Conn
+		err error
+	)
+	if cfg != nil {
+		co, err = dns.DialTimeoutWithTLS("tcp-tls", addr, cfg, dialTimeout)
+	} else {
+		co, err = dns.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	co.Conn = &countingConn{Conn: co.Conn, p: p}
+	return co, nil
+}
+
+// dialTimeout is the timeout dns.Transfer uses when it dials itself.
+const dialTimeout = 2 * time.Second
+
+// countingConn counts the messages read in a zone transfer: dns.Transfer sets a read deadline before it
+// reads each message, and hands out one envelope per message, so envelope n holds message n. This is
+// checked by TestTsigMessageCount.
+type countingConn struct {
+	net.Conn
+	p *tsigProvider
+}
+
+// SetReadDeadline implements net.Conn.
+func (c *countingConn) SetReadDeadline(t time.Time) error {
+	c.p.mu.Lock()
+	c.p.msg++
+	c.p.mu.Unlock()
+	return c.Conn.SetReadDeadline(t)
+}
+
+// signRequest TSIG signs m with key, if not empty, using the algorithm defined for it.
+func (z *Zone) signRequest(m *dns.Msg, key string) {
+	if key != "" {
+		m.SetTsig(key, tsig.Algorithm(z.TsigAlgorithm, key), tsig.Fudge, time.Now().Unix())
+	}
+}
+
+var errUnsigned = errors.New("response is not TSIG signed")
@@ -0,0 +1,148 @@
+package file
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	tsigTestKey    = "transfer.example.org."
+	tsigTestSecret = "so6ZGir4GPAqINNh9U5c3A=="
+)
+
+// tsigPrimary serves an AXFR of testZone in n messages, the messages in unsigned are sent without TSIG.
+type tsigPrimary struct {
+	n        int
+	unsigned map[int]bool
+	alg      string
+}
+
+func (p *tsigPrimary) handler(w dns.ResponseWriter, r *dns.Msg) {
+	if t := r.IsTsig(); t == nil || t.Algorithm != p.alg || w.TsigStatus() != nil {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(m)
+		return
+	}
+	soa := test.SOA(fmt.Sprintf("%s IN SOA bla. bla. 250 0 0 0 0", testZone))
+	for i := 0; i < p.n; i++ {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Answer = []dns.RR{test.A(fmt.Sprintf("a%d.%s IN A 127.0.0.1", i, testZone))}
+		if i == 0 {
+			m.Answer = append([]dns.RR{soa}, m.Answer...)
+		}
+		if i == p.n-1 {
+			m.Answer = append(m.Answer, soa)
+		}
+		if !p.unsigned[i] {
+			m.SetTsig(tsigTestKey, p.alg, 300, time.Now().Unix())
+		}
+		w.WriteMsg(m)
+		w.TsigTimersOnly(true)
+	}
+}
+
+func newTsigPrimary(t *testing.T, p *tsigPrimary) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	s := &dns.Server{Listener: l, Net: "tcp", Handler: dns.HandlerFunc(p.handler),
+		TsigSecret: map[string]string{tsigTestKey: tsigTestSecret}, NotifyStartedFunc: func() { close(started) }}
+	go s.ActivateAndServe()
+	<-started
+	t.Cleanup(func() { s.Shutdown() })
+	return l.Addr().String()
+}
+
+func TestTransferInTsig(t *testing.T) {
+	tests := []struct {
+		n        int
+		unsigned []int
+		valid    bool
+	}{
+		{n: 3, valid: true},
+		{n: 3, unsigned: []int{2}},
+		{n: 3, unsigned: []int{0, 1, 2}},
+		{n: 101, unsigned: seq(1, 100), valid: true}, // 99 unsigned messages
+		{n: 102, unsigned: seq(1, 101)},              // 100 unsigned messages
+	}
+
+	for i, tc := range tests {
+		p := &tsigPrimary{n: tc.n, unsigned: map[int]bool{}, alg: dns.HmacSHA512}
+		for _, u := range tc.unsigned {
+			p.unsigned[u] = true
+		}
+		addr := newTsigPrimary(t, p)
+
+		z := NewZone(testZone, "stdin")
+		z.TransferFrom = []string{addr}
+		z.TransferKeys = map[string]string{addr: tsigTestKey}
+		z.TsigSecret = map[string]string{tsigTestKey: tsigTestSecret}
+		z.TsigAlgorithm = map[string]string{tsigTestKey: dns.HmacSHA512}
+
+		err := z.TransferIn()
+		if tc.valid && err != nil {
+			t.Errorf("Test %d: expected transfer to succeed, got %s", i, err)
+		}
+		if !tc.valid && err == nil {
+			t.Errorf("Test %d: expected transfer to fail", i)
+		}
+		if tc.valid && len(z.All()) != tc.n {
+			t.Errorf("Test %d: expected %d records, got %d", i, tc.n, len(z.All()))
+		}
+	}
+}
+
+// TestTsigMessageCount pins the assumption of countingConn: dns.Transfer sets a read deadline once per
+// message, so the verification of message n is known when envelope n is received.
+func TestTsigMessageCount(t *testing.T) {
+	unsigned := map[int]bool{1: true, 3: true, 4: true}
+	addr := newTsigPrimary(t, &tsigPrimary{n: 6, unsigned: unsigned, alg: dns.HmacSHA256})
+
+	m := new(dns.Msg)
+	m.SetAxfr(testZone)
+	m.SetTsig(tsigTestKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+	p := &tsigProvider{secret: tsigTestSecret}
+	tr := &dns.Transfer{TsigProvider: p}
+	var err error
+	if tr.Conn, err = p.dial(addr, nil); err != nil {
+		t.Fatal(err)
+	}
+	c, err := tr.In(m, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := 0
+	for env := range c {
+		if env.Error != nil {
+			t.Fatalf("Expected no error, got %s", env.Error)
+		}
+		if verified := p.isVerified(msgs + 1); verified == unsigned[msgs] {
+			t.Errorf("Expected message %d to be verified: %t, got %t", msgs, !unsigned[msgs], verified)
+		}
+		msgs++
+	}
+	if msgs != 6 {
+		t.Errorf("Expected 6 messages, got %d", msgs)
+	}
+}
+
+// seq returns the integers from i up to, but not including, j.
+func seq(i, j int) []int {
+	s := []int{}
+	for ; i < j; i++ {
+		s = append(s, i)
+	}
+	return s
+}
@@ -0,0 +1,531 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/request"
+
+	"github.com/infobloxopen/go-trees/iptree"
+	"github.com/miekg/dns"
+)
+
+// UpdatePolicy defines who is allowed to send dynamic updates (RFC 2136) for a zone.
+type UpdatePolicy struct {
+	Allow *iptree.Tree // Source addresses allowed to update the zone, nil allows all.
+	Keys  []string     // If not empty, updates must be TSIG signed with one of these keys.
+}
+
+// AddSources adds the sources in args, single addresses or CIDR networks, to the allowed sources. The
+// source "*" allows all addresses.
+func (p *UpdatePolicy) AddSources(args []string) error {
+	if p.Allow == nil {
+		p.Allow = iptree.NewTree()
+	}
+	for _, a := range args {
+		if a == "*" {
+			a = "0.0.0.0/0"
+			_, all6, _ := net.ParseCIDR("::/0")
+			p.Allow.InplaceInsertNet(all6, struct{}{})
+		}
+		if !strings.Contains(a, "/") {
+			if strings.Contains(a, ":") {
+				a += "/128"
+			} else {
+				a += "/32"
+			}
+		}
+		_, source, err := net.ParseCIDR(a)
+		if err != nil {
+			return fmt.Errorf("illegal CIDR notation %q", a)
+		}
+		p.Allow.InplaceInsertNet(source, struct{}{})
+	}
+	return nil
+}
+
+// AddKeys adds the TSIG key names in args to the keys that can sign updates.
+func (p *UpdatePolicy) AddKeys(args []string) {
+	for _, k := range args {
+		p.Keys = append(p.Keys, dns.CanonicalName(k))
+	}
+}
+
+// allowed returns true if an update from state, TSIG signed with keyName, is permitted.
+func (p *UpdatePolicy) allowed(state request.Request, keyName string) bool {
+	if p.Allow != nil {
+		addr := state.IP()
+		if i := strings.IndexByte(addr, '%'); i >= 0 {
+			addr = addr[:i]
+		}
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		if _, ok := p.Allow.GetByIP(ip); !ok {
+			return false
+		}
+	}
+	if len(p.Keys) == 0 {
+		return true
+	}
+	for _, k := range p.Keys {
+		if k == keyName {
+			return true
+		}
+	}
+	return false
+}
+
+// DynamicUpdate applies the dynamic update (RFC 2136) in state to z. The update must have been TSIG verified with
+// keyName, or keyName is empty. The response code is returned, together with a boolean that is true when the
+// zone was changed. Changes are written back to the zone's file before they become visible. Zones without an
+// update policy reply with NOTIMP, as they did before updates were supported.
+func (z *Zone) DynamicUpdate(state request.Request, keyName string) (int, bool) {
+	if z.UpdatePolicy == nil {
+		return dns.RcodeNotImplemented, false
+	}
+	r := state.Req
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError, false
+	}
+	if dns.CanonicalName(r.Question[0].Name) != z.origin {
+		return dns.RcodeNotAuth, false
+	}
+	if !z.UpdatePolicy.allowed(state, keyName) {
+		log.Infof("Refusing update for %s from %s", z.origin, state.IP())
+		return dns.RcodeRefused, false
+	}
+
+	// Serialize updates and reloads, each update works on its own copy of the zone that replaces the zone
+	// when done.
+	z.updateMu.Lock()
+	defer z.updateMu.Unlock()
+
+	// Rewriting the file would copy the included records into it, and lose the include.
+	include, err := hasInclude(z.File())
+	if err != nil {
+		log.Errorf("Failed to read %q for update of %s: %s", z.File(), z.origin, err)
+		return dns.RcodeServerFailure, false
+	}
+	if include {
+		log.Warningf("Refusing update for %s from %s: %q uses $INCLUDE", z.origin, state.IP(), z.File())
+		return dns.RcodeRefused, false
+	}
+
+	z.RLock()
+	cur := &Zone{origin: z.origin, Apex: z.Apex, Tree: z.Tree}
+	z.RUnlock()
+
+	if cur.Apex.SOA == nil {
+		return dns.RcodeServerFailure, false
+	}
+	if len(cur.Apex.SIGSOA) > 0 {
+		log.Warningf("Refusing update for %s from %s: zone is signed", z.origin, state.IP())
+		return dns.RcodeRefused, false
+	}
+
+	if rcode := cur.prerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		return rcode, false
+	}
+	if rcode := cur.prescan(r.Ns); rcode != dns.RcodeSuccess {
+		return rcode, false
+	}
+
+	z1 := cur.copyForUpdate()
+	changed, soaChanged := false, false
+	for _, rr := range r.Ns {
+		c, s := z1.apply(rr)
+		changed = changed || c
+		soaChanged = soaChanged || s
+	}
+	if !changed {
+		return dns.RcodeSuccess, false
+	}
+	if !soaChanged {
+		soa := dns.Copy(z1.Apex.SOA).(*dns.SOA)
+		soa.Serial++
+		z1.Apex.SOA = soa
+	}
+
+	if err := z1.save(z.File(), "written by the file plugin after a dynamic update"); err != nil {
+		log.Errorf("Failed to write update for %s to disk: %s", z.origin, err)
+		return dns.RcodeServerFailure, false
+	}
+
+	del, add, size := difference(cur, z1)
+
+	z.Lock()
+	z.Apex = z1.Apex
+	z.Tree = z1.Tree
+	z.addToJournal(delta{from: cur.Apex.SOA, to: z1.Apex.SOA, del: del, add: add}, size)
+	z.Unlock()
+
+	log.Infof("Applied update for %s from %s, SOA serial is now %d", z.origin, state.IP(), z1.Apex.SOA.Serial)
+	return dns.RcodeSuccess, true
+}
+
+// prerequisites checks the prerequisite section of an update, see RFC 2136 section 3.2.
+func (z *Zone) prerequisites(prereqs []dns.RR) int {
+	// Value dependent RRset checks are done per RRset, after all RRs are collected.
+	type rrset struct {
+		name  string
+		qtype uint16
+	}
+	sets := map[rrset][]dns.RR{}
+
+	for _, rr := range prereqs {
+		h := rr.Header()
+		name := dns.CanonicalName(h.Name)
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+		if !dns.IsSubDomain(z.origin, name) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				if !z.nameInUse(name) {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(z.rrset(name, h.Rrtype)) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rrtype == dns.TypeANY {
+				if z.nameInUse(name) {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(z.rrset(name, h.Rrtype)) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			k := rrset{name, h.Rrtype}
+			sets[k] = append(sets[k], rr)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for k, want := range sets {
+		if !equalRRset(want, z.rrset(k.name, k.qtype)) {
+			return dns.RcodeNXRrset
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// prescan checks the update section of an update, see RFC 2136 section 3.4.1.
+func (z *Zone) prescan(updates []dns.RR) int {
+	for _, rr := range updates {
+		h := rr.Header()
+		if !dns.IsSubDomain(z.origin, dns.CanonicalName(h.Name)) {
+			return dns.RcodeNotZone
+		}
+		switch h.Class {
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeANY || isMeta(h.Rrtype) {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeNSEC3 || h.Rrtype == dns.TypeNSEC3PARAM {
+				return dns.RcodeRefused
+			}
+		case dns.ClassANY:
+			if h.Ttl != 0 || (h.Rrtype != dns.TypeANY && isMeta(h.Rrtype)) {
+				return dns.RcodeFormatError
+			}
+		case dns.ClassNONE:
+			if h.Ttl != 0 || h.Rrtype == dns.TypeANY || isMeta(h.Rrtype) {
+				return dns.RcodeFormatError
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// apply applies a single update RR to z, see RFC 2136 section 3.4.2. It returns true if the zone changed and
+// if the SOA record was replaced.
+func (z *Zone) apply(rr dns.RR) (changed bool, soa bool) {
+	h := rr.Header()
+	name := dns.CanonicalName(h.Name)
+	apex := name == z.origin
+
+	switch h.Class {
+	case dns.ClassINET:
+		if h.Rrtype == dns.TypeSOA {
+			if !apex || !isSerialNewer(rr.(*dns.SOA).Serial, z.Apex.SOA.Serial) {
+				return false, false
+			}
+			z.Insert(dns.Copy(rr))
+			return true, true
+		}
+
+		cname := len(z.rrset(name, dns.TypeCNAME)) > 0
+		if h.Rrtype == dns.TypeCNAME {
+			if z.nameInUse(name) && !cname {
+				return false, false
+			}
+			z.deleteRRset(name, dns.TypeCNAME)
+		} else if cname {
+			return false, false
+		}
+
+		for _, old := range z.rrset(name, h.Rrtype) {
+			if dns.IsDuplicate(old, rr) {
+				return false, false
+			}
+		}
+		z.Insert(dns.Copy(rr))
+		return true, false
+
+	case dns.ClassANY:
+		if h.Rrtype == dns.TypeANY {
+			// The apex SOA and NS records aren't in the tree, so these are left alone.
+			for _, t := range z.types(name) {
+				changed = z.deleteRRset(name, t) || changed
+			}
+			return changed, false
+		}
+		if apex && (h.Rrtype == dns.TypeSOA || h.Rrtype == dns.TypeNS) {
+			return false, false
+		}
+		return z.deleteRRset(name, h.Rrtype), false
+
+	case dns.ClassNONE:
+		if h.Rrtype == dns.TypeSOA {
+			return false, false
+		}
+		if apex && h.Rrtype == dns.TypeNS {
+			keep := z.Apex.NS[:0:0]
+			for _, ns := range z.Apex.NS {
+				if !sameRdata(ns, rr) {
+					keep = append(keep, ns)
+				}
+			}
+			// Never remove the last NS record.
+			if len(keep) == len(z.Apex.NS) || len(keep) == 0 {
+				return false, false
+			}
+			z.Apex.NS = keep
+			return true, false
+		}
+		return z.deleteRR(name, rr), false
+	}
+	return false, false
+}
+
+// rrset returns the RRs of type qtype at name.
+func (z *Zone) rrset(name string, qtype uint16) []dns.RR {
+	if name == z.origin {
+		switch qtype {
+		case dns.TypeSOA:
+			if z.Apex.SOA == nil {
+				return nil
+			}
+			return []dns.RR{z.Apex.SOA}
+		case dns.TypeNS:
+			return z.Apex.NS
+		}
+	}
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return nil
+	}
+	return e.Type(qtype)
+}
+
+// nameInUse returns true if there are any RRs at name.
+func (z *Zone) nameInUse(name string) bool {
+	if name == z.origin {
+		return true
+	}
+	e, ok := z.Tree.Search(name)
+	return ok && !e.Empty()
+}
+
+// types returns the types in the tree at name, the apex SOA and NS records are not included.
+func (z *Zone) types(name string) []uint16 {
+	e, ok := z.Tree.Search(name)
+	if !ok {
+		return nil
+	}
+	return e.Types()
+}
+
+// deleteRRset deletes the RRset of type qtype at name from the tree.
+func (z *Zone) deleteRRset(name string, qtype uint16) bool {
+	e, ok := z.Tree.Search(name)
+	if !ok || len(e.Type(qtype)) == 0 {
+		return false
+	}
+	z.Tree.Delete(&dns.ANY{Hdr: dns.RR_Header{Name: name, Rrtype: qtype}})
+	return true
+}
+
+// deleteRR deletes the RR with the same rdata as rr at name from the tree.
+func (z *Zone) deleteRR(name string, rr dns.RR) bool {
+	rrs := z.rrset(name, rr.Header().Rrtype)
+	keep := make([]dns.RR, 0, len(rrs))
+	for _, old := range rrs {
+		if !sameRdata(old, rr) {
+			keep = append(keep, old)
+		}
+	}
+	if len(keep) == len(rrs) {
+		return false
+	}
+	z.deleteRRset(name, rr.Header().Rrtype)
+	for _, k := range keep {
+		z.Tree.Insert(k)
+	}
+	return true
+}
+
+// copyForUpdate returns a copy of z with its own apex and tree, that can be modified without
+// affecting z. The RRs themselves are shared, these are never modified.
+func (z *Zone) copyForUpdate() *Zone {
+	z1 := &Zone{origin: z.origin, Tree: &tree.Tree{}}
+	z1.Apex = Apex{
+		SOA:    z.Apex.SOA,
+		NS:     append([]dns.RR(nil), z.Apex.NS...),
+		SIGSOA: append([]dns.RR(nil), z.Apex.SIGSOA...),
+		SIGNS:  append([]dns.RR(nil), z.Apex.SIGNS...),
+	}
+	z.Tree.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			z1.Tree.Insert(rr)
+		}
+		return nil
+	})
+	if z.Apex.NSEC3 != nil {
+		z.Apex.NSEC3.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+			for _, rr := range e.All() {
+				z1.insertNSEC3(rr)
+			}
+			return nil
+		})
+	}
+	return z1
+}
+
+// save writes z in master file format to file, with comment in its first line. A temporary file is written first,
+// and renamed when complete. A new file is created with mode 0644.
+func (z *Zone) save(file, comment string) error {
+	mode := os.FileMode(0644)
+	info, err := os.Stat(file)
+	switch {
+	case err == nil:
+		mode = info.Mode()
+	case !os.IsNotExist(err):
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails when renamed, which is fine
+
+	w := bufio.NewWriter(tmp)
+	fmt.Fprintf(w, "; zone %s, %s\n", z.origin, comment)
+	fmt.Fprintln(w, z.Apex.SOA.String())
+	for _, rr := range z.Apex.SIGSOA {
+		fmt.Fprintln(w, rr.String())
+	}
+	for _, rr := range z.Apex.NS {
+		fmt.Fprintln(w, rr.String())
+	}
+	for _, rr := range z.Apex.SIGNS {
+		fmt.Fprintln(w, rr.String())
+	}
+	writeElem := func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.All() {
+			fmt.Fprintln(w, rr.String())
+		}
+		return nil
+	}
+	z.Tree.Walk(writeElem)
+	if z.Apex.NSEC3 != nil {
+		z.Apex.NSEC3.Walk(writeElem)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// hasInclude returns true if the zone file uses the $INCLUDE directive.
+func hasInclude(file string) (bool, error) {
+	buf, err := os.ReadFile(filepath.Clean(file))
+	if err != nil {
+		return false, err
+	}
+	for _, line := range strings.Split(string(buf), "\n") {
+		if len(line) >= len("$INCLUDE") && strings.EqualFold(line[:len("$INCLUDE")], "$INCLUDE") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// equalRRset returns true if a and b hold the same RRs, ignoring TTLs.
+func equalRRset(a, b []dns.RR) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if sameRdata(x, y) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sameRdata returns true if a and b have the same name, type and rdata. The class and TTL are ignored, as
+// these carry a special meaning in updates.
+func sameRdata(a, b dns.RR) bool {
+	a1, b1 := dns.Copy(a), dns.Copy(b)
+	a1.Header().Class, b1.Header().Class = dns.ClassINET, dns.ClassINET
+	a1.Header().Name, b1.Header().Name = dns.CanonicalName(a1.Header().Name), dns.CanonicalName(b1.Header().Name)
+	return dns.IsDuplicate(a1, b1)
+}
+
+// isMeta returns true for the meta types that can't appear in an update.
+func isMeta(t uint16) bool {
+	switch t {
+	case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT, dns.TypeTSIG:
+		return true
+	}
+	return false
+}
+
+// isSerialNewer returns true if serial a is newer than b using serial number arithmetic (RFC 1982).
+func isSerialNewer(a, b uint32) bool {
+	return a != b && a-b < 1<<31
+}
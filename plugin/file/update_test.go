@@ -0,0 +1,204 @@
+package file
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func newUpdateZone(t *testing.T, policy *UpdatePolicy) (*Zone, string) {
+	t.Helper()
+	fileName, rm, err := test.TempFile(".", updateZoneTest)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	t.Cleanup(func() { rm() })
+	reader, err := os.Open(fileName)
+	if err != nil {
+		t.Fatalf("Failed to open zone: %s", err)
+	}
+	defer reader.Close()
+	z, err := Parse(reader, "example.org.", fileName, 0)
+	if err != nil {
+		t.Fatalf("Failed to parse zone: %s", err)
+	}
+	z.UpdatePolicy = policy
+	return z, fileName
+}
+
+func updateState(m *dns.Msg) request.Request {
+	return request.Request{W: &test.ResponseWriter{}, Req: m}
+}
+
+func allowAll(t *testing.T) *UpdatePolicy {
+	t.Helper()
+	p := &UpdatePolicy{}
+	if err := p.AddSources([]string{"*"}); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDynamicUpdateAdd(t *testing.T) {
+	z, fileName := newUpdateZone(t, allowAll(t))
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+
+	rcode, changed := z.DynamicUpdate(updateState(m), "")
+	if rcode != dns.RcodeSuccess || !changed {
+		t.Fatalf("Expected successful update, got rcode %d, changed %t", rcode, changed)
+	}
+	if z.Apex.SOA.Serial != 1282630058 {
+		t.Errorf("Expected serial to be incremented to %d, got %d", 1282630058, z.Apex.SOA.Serial)
+	}
+	if rrs := z.rrset("new.example.org.", dns.TypeA); len(rrs) != 1 {
+		t.Errorf("Expected 1 A record for new.example.org., got %d", len(rrs))
+	}
+
+	buf, err := os.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "new.example.org.") {
+		t.Errorf("Expected zone file to contain the new record, got:\n%s", buf)
+	}
+	// The written file must load again.
+	if _, err := Parse(strings.NewReader(string(buf)), "example.org.", fileName, 0); err != nil {
+		t.Errorf("Failed to parse updated zone: %s", err)
+	}
+}
+
+func TestDynamicUpdateDelete(t *testing.T) {
+	z, _ := newUpdateZone(t, allowAll(t))
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.RemoveRRset([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")})
+
+	if rcode, changed := z.DynamicUpdate(updateState(m), ""); rcode != dns.RcodeSuccess || !changed {
+		t.Fatalf("Expected successful update, got rcode %d, changed %t", rcode, changed)
+	}
+	if rrs := z.rrset("a.example.org.", dns.TypeA); len(rrs) != 0 {
+		t.Errorf("Expected no A records for a.example.org., got %d", len(rrs))
+	}
+	if rrs := z.rrset("a.example.org.", dns.TypeAAAA); len(rrs) != 1 {
+		t.Errorf("Expected AAAA record for a.example.org. to survive, got %d", len(rrs))
+	}
+
+	// Deleting it again is a no-op, the serial must not change.
+	serial := z.Apex.SOA.Serial
+	if rcode, changed := z.DynamicUpdate(updateState(m), ""); rcode != dns.RcodeSuccess || changed {
+		t.Fatalf("Expected no-op update, got rcode %d, changed %t", rcode, changed)
+	}
+	if z.Apex.SOA.Serial != serial {
+		t.Errorf("Expected serial %d, got %d", serial, z.Apex.SOA.Serial)
+	}
+}
+
+func TestDynamicUpdatePrerequisites(t *testing.T) {
+	tests := []struct {
+		prereq func(m *dns.Msg)
+		rcode  int
+	}{
+		{func(m *dns.Msg) { m.RRsetUsed([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeSuccess},
+		{func(m *dns.Msg) { m.RRsetUsed([]dns.RR{test.A("b.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeNXRrset},
+		{func(m *dns.Msg) { m.RRsetNotUsed([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeYXRrset},
+		{func(m *dns.Msg) { m.NameUsed([]dns.RR{test.A("b.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeNameError},
+		{func(m *dns.Msg) { m.NameNotUsed([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeYXDomain},
+		{func(m *dns.Msg) { m.Used([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.1")}) }, dns.RcodeSuccess},
+		{func(m *dns.Msg) { m.Used([]dns.RR{test.A("a.example.org. 0 IN A 127.0.0.9")}) }, dns.RcodeNXRrset},
+		{func(m *dns.Msg) { m.NameUsed([]dns.RR{test.A("a.example.net. 0 IN A 127.0.0.1")}) }, dns.RcodeNotZone},
+	}
+
+	for i, tc := range tests {
+		z, _ := newUpdateZone(t, allowAll(t))
+		m := new(dns.Msg)
+		m.SetUpdate("example.org.")
+		tc.prereq(m)
+		m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+
+		rcode, changed := z.DynamicUpdate(updateState(m), "")
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+		if changed != (tc.rcode == dns.RcodeSuccess) {
+			t.Errorf("Test %d: expected changed to be %t", i, tc.rcode == dns.RcodeSuccess)
+		}
+	}
+}
+
+func TestDynamicUpdatePolicy(t *testing.T) {
+	other := &UpdatePolicy{}
+	other.AddSources([]string{"192.0.2.0/24"})
+	keyed := allowAll(t)
+	keyed.AddKeys([]string{"update.example.org"})
+
+	tests := []struct {
+		policy *UpdatePolicy
+		key    string
+		rcode  int
+	}{
+		{nil, "", dns.RcodeNotImplemented},
+		{other, "", dns.RcodeRefused},
+		{allowAll(t), "", dns.RcodeSuccess},
+		{keyed, "", dns.RcodeRefused},
+		{keyed, "other.example.org.", dns.RcodeRefused},
+		{keyed, "update.example.org.", dns.RcodeSuccess},
+	}
+
+	for i, tc := range tests {
+		z, _ := newUpdateZone(t, tc.policy)
+		m := new(dns.Msg)
+		m.SetUpdate("example.org.")
+		m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+
+		if rcode, _ := z.DynamicUpdate(updateState(m), tc.key); rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+	}
+}
+
+func TestDynamicUpdateNotAuth(t *testing.T) {
+	z, _ := newUpdateZone(t, allowAll(t))
+	m := new(dns.Msg)
+	m.SetUpdate("example.net.")
+	m.Insert([]dns.RR{test.A("new.example.net. 300 IN A 127.0.0.2")})
+
+	if rcode, _ := z.DynamicUpdate(updateState(m), ""); rcode != dns.RcodeNotAuth {
+		t.Errorf("Expected rcode %s, got %s", dns.RcodeToString[dns.RcodeNotAuth], dns.RcodeToString[rcode])
+	}
+}
+
+func TestDynamicUpdateInclude(t *testing.T) {
+	z, fileName := newUpdateZone(t, allowAll(t))
+	// Parsing doesn't matter here, the update only looks at the file.
+	if err := os.WriteFile(fileName, []byte(updateZoneTest+"$INCLUDE db.other\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+
+	if rcode, changed := z.DynamicUpdate(updateState(m), ""); rcode != dns.RcodeRefused || changed {
+		t.Errorf("Expected rcode %s, got %s", dns.RcodeToString[dns.RcodeRefused], dns.RcodeToString[rcode])
+	}
+	if buf, _ := os.ReadFile(fileName); !strings.Contains(string(buf), "$INCLUDE") {
+		t.Errorf("Expected the zone file not to be rewritten")
+	}
+}
+
+const updateZoneTest = `$ORIGIN example.org.
+@	IN	SOA	sns.dns.icann.org. noc.dns.icann.org. 1282630057 7200 3600 1209600 3600
+	IN	NS	a.iana-servers.net.
+	IN	NS	b.iana-servers.net.
+a	IN	A	127.0.0.1
+a	IN	AAAA	::1
+`
@@ -16,27 +16,59 @@ func (f File) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
 	return z.Transfer(serial)
 }
 
-// Transfer transfers a zone with serial in the returned channel and implements IXFR fallback, by just
-// sending a single SOA record.
+// Transfer transfers a zone with serial in the returned channel. If serial is not 0, this is an IXFR: when
+// serial is the zone's serial or newer, a single SOA record is sent. When the zone's journal reaches back
+// to serial, the differences since then are sent (RFC 1995), otherwise it falls back to sending the
+// whole zone.
 func (z *Zone) Transfer(serial uint32) (<-chan []dns.RR, error) {
 	// get soa and apex
 	apex, err := z.ApexIfDefined()
 	if err != nil {
 		return nil, err
 	}
+	soa := apex[0].(*dns.SOA)
+
+	z.RLock()
+	hashed := z.Apex.NSEC3
+	z.RUnlock()
+
+	var deltas []delta
+	if serial != 0 {
+		z.RLock()
+		deltas = z.journalFrom(serial)
+		z.RUnlock()
+		// The zone may have changed since the apex was retrieved.
+		if n := len(deltas); n > 0 && deltas[n-1].to.Serial != soa.Serial {
+			deltas = nil
+		}
+	}
 
 	ch := make(chan []dns.RR)
 	go func() {
-		if serial != 0 && apex[0].(*dns.SOA).Serial == serial { // ixfr fallback, only send SOA
-			ch <- []dns.RR{apex[0]}
+		switch {
+		case serial != 0 && !less(serial, soa.Serial): // up to date, only send SOA
+			ch <- []dns.RR{soa}
 
-			close(ch)
-			return
-		}
+		case len(deltas) > 0:
+			ch <- []dns.RR{soa}
+			for _, d := range deltas {
+				rrs := make([]dns.RR, 0, len(d.del)+len(d.add)+2)
+				rrs = append(rrs, d.from)
+				rrs = append(rrs, d.del...)
+				rrs = append(rrs, d.to)
+				rrs = append(rrs, d.add...)
+				ch <- rrs
+			}
+			ch <- []dns.RR{soa}
 
-		ch <- apex
-		z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
-		ch <- []dns.RR{apex[0]}
+		default:
+			ch <- apex
+			z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
+			if hashed != nil {
+				hashed.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error { ch <- e.All(); return nil })
+			}
+			ch <- []dns.RR{soa}
+		}
 
 		close(ch)
 	}()
@@ -0,0 +1,23 @@
+package file
+
+import (
+	"crypto/tls"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+)
+
+// transferAddr returns the address to reach primary on, and the TLS configuration to use for it. The
+// configuration is nil, unless primary has the tls:// prefix and zone transfers are done over TLS (RFC 9103).
+func (z *Zone) transferAddr(primary string) (string, *tls.Config) {
+	addr, ok := strings.CutPrefix(primary, transport.TLS+"://")
+	if !ok {
+		return primary, nil
+	}
+	cfg := &tls.Config{}
+	if z.TransferTLS != nil {
+		cfg = z.TransferTLS.Clone()
+	}
+	cfg.NextProtos = []string{"dot"} // RFC 9103, Section 7.1.
+	return addr, cfg
+}
@@ -1,6 +1,7 @@
 package file
 
 import (
+	"crypto/tls"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -22,23 +23,36 @@ type Zone struct {
 	Apex
 	Expired bool
 
+	journal []delta // The last changes to the zone, for incremental transfers.
+
 	sync.RWMutex
 
-	StartupOnce  sync.Once
-	TransferFrom []string
+	StartupOnce   sync.Once
+	TransferFrom  []string
+	TransferKeys  map[string]string // Names of the TSIG keys for the addresses in TransferFrom that sign transfers.
+	TsigSecret    map[string]string // TSIG secrets by key name, as defined by the tsig plugin.
+	TsigAlgorithm map[string]string // TSIG algorithms by key name, for the keys that define one.
+	TransferFile  string            // If not empty, the zone is written to this file after each transfer.
+	TransferTLS   *tls.Config       // TLS configuration for the addresses in TransferFrom with the tls:// prefix.
 
 	ReloadInterval time.Duration
 	reloadShutdown chan bool
 
 	Upstream *upstream.Upstream // Upstream for looking up external names during the resolution process.
+
+	UpdatePolicy *UpdatePolicy // If not nil, dynamic updates are allowed according to this policy.
+	updateMu     sync.Mutex    // serializes dynamic updates and reloads
 }
 
-// Apex contains the apex records of a zone: SOA, NS and their potential signatures.
+// Apex contains the apex records of a zone: SOA, NS and their potential signatures. The NSEC3 records
+// of a zone signed with NSEC3 are kept here as well, as their hashed owner names are not part of the
+// zone's tree.
 type Apex struct {
 	SOA    *dns.SOA
 	NS     []dns.RR
 	SIGSOA []dns.RR
 	SIGNS  []dns.RR
+	NSEC3  *tree.Tree // NSEC3 records and their signatures, nil if there are none.
 }
 
 // NewZone returns a new zone.
@@ -56,6 +70,11 @@ func NewZone(name, file string) *Zone {
 func (z *Zone) Copy() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
+	z1.TsigSecret = z.TsigSecret
+	z1.TsigAlgorithm = z.TsigAlgorithm
+	z1.TransferFile = z.TransferFile
+	z1.TransferTLS = z.TransferTLS
 	z1.Expired = z.Expired
 
 	z1.Apex = z.Apex
@@ -66,6 +85,11 @@ func (z *Zone) Copy() *Zone {
 func (z *Zone) CopyWithoutApex() *Zone {
 	z1 := NewZone(z.origin, z.file)
 	z1.TransferFrom = z.TransferFrom
+	z1.TransferKeys = z.TransferKeys
+	z1.TsigSecret = z.TsigSecret
+	z1.TsigAlgorithm = z.TsigAlgorithm
+	z1.TransferFile = z.TransferFile
+	z1.TransferTLS = z.TransferTLS
 	z1.Expired = z.Expired
 
 	return z1
@@ -89,8 +113,9 @@ func (z *Zone) Insert(r dns.RR) error {
 
 		z.Apex.SOA = r.(*dns.SOA)
 		return nil
-	case dns.TypeNSEC3, dns.TypeNSEC3PARAM:
-		return fmt.Errorf("NSEC3 zone is not supported, dropping RR: %s for zone: %s", r.Header().Name, z.origin)
+	case dns.TypeNSEC3:
+		z.insertNSEC3(r)
+		return nil
 	case dns.TypeRRSIG:
 		x := r.(*dns.RRSIG)
 		switch x.TypeCovered {
@@ -102,6 +127,9 @@ func (z *Zone) Insert(r dns.RR) error {
 				z.Apex.SIGNS = append(z.Apex.SIGNS, x)
 				return nil
 			}
+		case dns.TypeNSEC3:
+			z.insertNSEC3(r)
+			return nil
 		}
 	case dns.TypeCNAME:
 		r.(*dns.CNAME).Target = strings.ToLower(r.(*dns.CNAME).Target)
@@ -124,7 +124,7 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 	}
 
 	transports := make([]string, len(toHosts))
-	allowedTrans := map[string]bool{"dns": true, "tls": true}
+	allowedTrans := map[string]bool{transport.DNS: true, transport.TLS: true, transport.HTTPS: true, transport.QUIC: true}
 	for i, host := range toHosts {
 		trans, h := parse.Transport(host)
 
@@ -132,6 +132,7 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 			return f, fmt.Errorf("'%s' is not supported as a destination protocol in forward: %s", trans, host)
 		}
 		p := proxy.NewProxy("forward", h, trans)
+		p.EnableCookies()
 		f.proxies = append(f.proxies, p)
 		transports[i] = trans
 	}
@@ -152,13 +153,14 @@ func parseStanza(c *caddy.Controller) (*Forward, error) {
 
 	for i := range f.proxies {
 		// Only set this for proxies that need it.
-		if transports[i] == transport.TLS {
+		encrypted := transports[i] == transport.TLS || transports[i] == transport.HTTPS || transports[i] == transport.QUIC
+		if encrypted {
 			f.proxies[i].SetTLSConfig(f.tlsConfig)
 		}
 		f.proxies[i].SetExpire(f.expire)
 		f.proxies[i].GetHealthchecker().SetRecursionDesired(f.opts.HCRecursionDesired)
 		// when TLS is used, checks are set to tcp-tls
-		if f.opts.ForceTCP && transports[i] != transport.TLS {
+		if f.opts.ForceTCP && !encrypted {
 			f.proxies[i].GetHealthchecker().SetTCPTransport()
 		}
 		f.proxies[i].GetHealthchecker().SetDomain(f.opts.HCDomain)
@@ -0,0 +1,292 @@
+// Package crd implements a plugin that serves records defined as DNSRecord and ClusterDNSRecord
+// custom resources in a Kubernetes cluster.
+package crd
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnsutil"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+	"github.com/coredns/coredns/plugin/pkg/upstream"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// CRD is a plugin that serves the records of DNSRecord and ClusterDNSRecord resources.
+type CRD struct {
+	Next  plugin.Handler
+	Zones []string
+	Fall  fall.F
+
+	ttl   uint32
+	allow map[string][]string // namespace (or "*") -> zones its DNSRecords may write to.
+
+	ClientConfig clientcmd.ClientConfig
+	upstream     *upstream.Upstream
+
+	recLister         cache.Indexer
+	recController     cache.Controller
+	clusterLister     cache.Indexer
+	clusterController cache.Controller
+	modified          chan struct{}
+	stopCh            chan struct{}
+
+	mu        sync.Mutex // serializes updates.
+	serial    uint32
+	conflicts map[string]string // key -> resource version of the records left out in the last update.
+
+	zMu   sync.RWMutex
+	zones map[string]*file.Zone
+}
+
+// New returns a new CRD plugin for zones.
+func New(zones []string) *CRD {
+	return &CRD{
+		Zones:    zones,
+		ttl:      5,
+		allow:    map[string][]string{},
+		upstream: upstream.New(),
+		modified: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+		zones:    map[string]*file.Zone{},
+	}
+}
+
+// initInformers creates the informers for the DNSRecords and ClusterDNSRecords.
+func (c *CRD) initInformers(ctx context.Context, client dynamic.Interface) {
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { c.modify() },
+		UpdateFunc: func(oldObj, newObj interface{}) { c.modify() },
+		DeleteFunc: func(obj interface{}) { c.modify() },
+	}
+	c.recLister, c.recController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  listFunc(ctx, client, DNSRecordResource, api.NamespaceAll),
+			WatchFunc: watchFunc(ctx, client, DNSRecordResource, api.NamespaceAll),
+		},
+		&unstructured.Unstructured{},
+		handlers,
+		cache.Indexers{},
+		object.DefaultProcessor(c.toRecord, nil),
+	)
+	c.clusterLister, c.clusterController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  listFunc(ctx, client, ClusterDNSRecordResource, ""),
+			WatchFunc: watchFunc(ctx, client, ClusterDNSRecordResource, ""),
+		},
+		&unstructured.Unstructured{},
+		handlers,
+		cache.Indexers{},
+		object.DefaultProcessor(c.toRecord, nil),
+	)
+}
+
+func listFunc(ctx context.Context, client dynamic.Interface, r schema.GroupVersionResource, ns string) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		if ns == "" {
+			return client.Resource(r).List(ctx, opts)
+		}
+		return client.Resource(r).Namespace(ns).List(ctx, opts)
+	}
+}
+
+func watchFunc(ctx context.Context, client dynamic.Interface, r schema.GroupVersionResource, ns string) func(meta.ListOptions) (watch.Interface, error) {
+	return func(opts meta.ListOptions) (watch.Interface, error) {
+		if ns == "" {
+			return client.Resource(r).Watch(ctx, opts)
+		}
+		return client.Resource(r).Namespace(ns).Watch(ctx, opts)
+	}
+}
+
+// Run starts the informers and rebuilds the zones whenever records change, until Stop is called.
+func (c *CRD) Run() {
+	go c.recController.Run(c.stopCh)
+	go c.clusterController.Run(c.stopCh)
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-c.modified:
+			c.update()
+		}
+	}
+}
+
+// HasSynced returns true when the initial lists of records have been received.
+func (c *CRD) HasSynced() bool {
+	return c.recController.HasSynced() && c.clusterController.HasSynced()
+}
+
+// Stop stops the informers.
+func (c *CRD) Stop() error {
+	close(c.stopCh)
+	return nil
+}
+
+// modify signals the zones need to be rebuilt, signals are coalesced while an update is running.
+func (c *CRD) modify() {
+	select {
+	case c.modified <- struct{}{}:
+	default:
+	}
+}
+
+// update rebuilds all zones from the records in the informers' caches.
+func (c *CRD) update() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The serial is the time of the update, but always increases, even with several updates a second.
+	c.serial++
+	if now := uint32(time.Now().Unix()); now > c.serial {
+		c.serial = now
+	}
+
+	zones := make(map[string]*file.Zone, len(c.Zones))
+	for _, z := range c.Zones {
+		zones[z] = c.newZone(z, c.serial)
+	}
+	var records []*Record
+	for _, lister := range []cache.Indexer{c.recLister, c.clusterLister} {
+		for _, o := range lister.List() {
+			if r, ok := o.(*Record); ok && len(r.RRs) > 0 {
+				records = append(records, r)
+			}
+		}
+	}
+	// A CNAME can't share its name with other records, the one that takes precedence is served.
+	sort.Slice(records, func(i, j int) bool { return records[i].before(records[j]) })
+
+	type owner struct {
+		r     *Record
+		cname bool
+	}
+	owners := map[string]owner{}
+	conflicts := map[string]string{}
+	for _, r := range records {
+		z, ok := zones[r.Zone]
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(r.RRs[0].Header().Name)
+		_, cname := r.RRs[0].(*dns.CNAME)
+		if o, ok := owners[name]; ok && (cname || o.cname) {
+			conflicts[r.key()] = r.Version
+			if c.conflicts[r.key()] != r.Version {
+				log.Warningf("Ignoring %s: CNAME conflicts with %s at %s", r.key(), o.r.key(), name)
+				rejectedCount.WithLabelValues("conflict").Inc()
+			}
+			continue
+		}
+		if _, ok := owners[name]; !ok {
+			owners[name] = owner{r: r, cname: cname}
+		}
+		for _, rr := range r.RRs {
+			z.Insert(dns.Copy(rr))
+		}
+	}
+	c.conflicts = conflicts
+
+	c.zMu.Lock()
+	c.zones = zones
+	c.zMu.Unlock()
+}
+
+// newZone returns an otherwise empty zone with the SOA and NS records for zone.
+func (c *CRD) newZone(zone string, serial uint32) *file.Zone {
+	z := file.NewZone(zone, "")
+	z.Upstream = c.upstream
+
+	ns := dnsutil.Join("ns1", "dns", zone)
+	z.Insert(&dns.SOA{
+		Hdr:     dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: c.ttl},
+		Ns:      ns,
+		Mbox:    dnsutil.Join("hostmaster", "dns", zone),
+		Serial:  serial,
+		Refresh: 7200,
+		Retry:   1800,
+		Expire:  86400,
+		Minttl:  c.ttl,
+	})
+	z.Insert(&dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET, Ttl: c.ttl}, Ns: ns})
+	return z
+}
+
+// allowed returns true if DNSRecords in namespace may write name. ClusterDNSRecords, those without a
+// namespace, may write to all zones.
+func (c *CRD) allowed(namespace, name string) bool {
+	if namespace == "" {
+		return true
+	}
+	if plugin.Zones(c.allow[namespace]).Matches(name) != "" {
+		return true
+	}
+	return plugin.Zones(c.allow["*"]).Matches(name) != ""
+}
+
+// zone returns the zone with the name zone, or nil if it doesn't exist.
+func (c *CRD) zone(zone string) *file.Zone {
+	c.zMu.RLock()
+	defer c.zMu.RUnlock()
+	return c.zones[zone]
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (c *CRD) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	qname := state.Name()
+
+	zone := plugin.Zones(c.Zones).Matches(qname)
+	if zone == "" {
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+	z := c.zone(zone)
+	if z == nil {
+		return dns.RcodeServerFailure, nil
+	}
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	var result file.Result
+	m.Answer, m.Ns, m.Extra, result = z.Lookup(ctx, state, qname)
+
+	if result == file.NameError && c.Fall.Through(qname) {
+		return plugin.NextOrFailure(c.Name(), c.Next, ctx, w, r)
+	}
+
+	switch result {
+	case file.Success:
+	case file.NoData:
+	case file.NameError:
+		m.Rcode = dns.RcodeNameError
+	case file.Delegation:
+		m.Authoritative = false
+	case file.ServerFailure:
+		return dns.RcodeServerFailure, nil
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (c *CRD) Name() string { return pluginName }
@@ -0,0 +1,296 @@
+package crd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func record(kind, namespace, name string, spec map[string]interface{}) *unstructured.Unstructured {
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "coredns.io/v1alpha1",
+		"kind":       kind,
+		"metadata":   metadata,
+		"spec":       spec,
+	}}
+}
+
+var records = []runtime.Object{
+	record("DNSRecord", "team-a", "www", map[string]interface{}{
+		"name": "www.team-a.example.org",
+		"a":    []interface{}{"192.0.2.1"},
+		"aaaa": []interface{}{"2001:db8::1"},
+	}),
+	record("DNSRecord", "team-a", "mail", map[string]interface{}{
+		"name": "team-a.example.org.",
+		"ttl":  int64(300),
+		"mx":   []interface{}{map[string]interface{}{"preference": int64(10), "host": "mail.team-a.example.org"}},
+		"txt":  []interface{}{"v=spf1 mx -all"},
+	}),
+	record("DNSRecord", "team-a", "http", map[string]interface{}{
+		"name": "_http._tcp.team-a.example.org",
+		"srv":  []interface{}{map[string]interface{}{"priority": int64(10), "weight": int64(5), "port": int64(80), "target": "www.team-a.example.org"}},
+	}),
+	record("DNSRecord", "team-a", "alias", map[string]interface{}{
+		"name":  "alias.team-a.example.org",
+		"cname": "www.team-a.example.org",
+	}),
+	// invalid address
+	record("DNSRecord", "team-a", "bad", map[string]interface{}{
+		"name": "bad.team-a.example.org",
+		"a":    []interface{}{"2001:db8::1"},
+	}),
+	// team-b may not write to team-a.example.org
+	record("DNSRecord", "team-b", "hijack", map[string]interface{}{
+		"name": "www.team-a.example.org",
+		"a":    []interface{}{"192.0.2.66"},
+	}),
+	// nor to example.org
+	record("DNSRecord", "team-b", "apex", map[string]interface{}{
+		"name": "example.org",
+		"a":    []interface{}{"192.0.2.66"},
+	}),
+	record("DNSRecord", "team-b", "www", map[string]interface{}{
+		"name": "www.team-b.example.org",
+		"a":    []interface{}{"192.0.2.2"},
+	}),
+	record("ClusterDNSRecord", "", "ns1", map[string]interface{}{
+		"name": "ns1.dns.example.org",
+		"a":    []interface{}{"192.0.2.53"},
+	}),
+}
+
+func newTestCRD(t *testing.T, records []runtime.Object) *CRD {
+	gvrs := map[schema.GroupVersionResource]string{DNSRecordResource: "DNSRecordList", ClusterDNSRecordResource: "ClusterDNSRecordList"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrs, records...)
+
+	c := New([]string{"example.org."})
+	c.allow["team-a"] = []string{"team-a.example.org."}
+	c.allow["*"] = []string{"team-b.example.org."}
+	c.initInformers(context.Background(), client)
+	// The informers run without the update loop of Run, so the zones, and their serial, only change
+	// when a test calls update.
+	go c.recController.Run(c.stopCh)
+	go c.clusterController.Run(c.stopCh)
+	for !c.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+	c.update()
+	return c
+}
+
+func TestCRD(t *testing.T) {
+	c := newTestCRD(t, records)
+	defer c.Stop()
+
+	ctx := context.Background()
+	for i, tc := range tests {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := c.ServeDNS(ctx, w, r); err != nil {
+			t.Errorf("Test %d, expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
+
+var tests = []test.Case{
+	{
+		Qname: "www.team-a.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.A("www.team-a.example.org.	5	IN	A	192.0.2.1")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "www.team-a.example.org.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.AAAA("www.team-a.example.org.	5	IN	AAAA	2001:db8::1")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "www.team-a.example.org.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.org.	5	IN	SOA	ns1.dns.example.org. hostmaster.dns.example.org. 1 7200 1800 86400 5")},
+	},
+	{
+		Qname: "team-a.example.org.", Qtype: dns.TypeMX, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.MX("team-a.example.org.	300	IN	MX	10 mail.team-a.example.org.")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "team-a.example.org.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.TXT("team-a.example.org.	300	IN	TXT	\"v=spf1 mx -all\"")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "_http._tcp.team-a.example.org.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.SRV("_http._tcp.team-a.example.org.	5	IN	SRV	10 5 80 www.team-a.example.org.")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+		Extra:  []dns.RR{test.A("www.team-a.example.org.	5	IN	A	192.0.2.1"), test.AAAA("www.team-a.example.org.	5	IN	AAAA	2001:db8::1")},
+	},
+	{
+		Qname: "alias.team-a.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("alias.team-a.example.org.	5	IN	CNAME	www.team-a.example.org."),
+			test.A("www.team-a.example.org.	5	IN	A	192.0.2.1"),
+		},
+		Ns: []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "bad.team-a.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{test.SOA("example.org.	5	IN	SOA	ns1.dns.example.org. hostmaster.dns.example.org. 1 7200 1800 86400 5")},
+	},
+	{
+		Qname: "www.team-b.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.A("www.team-b.example.org.	5	IN	A	192.0.2.2")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{test.SOA("example.org.	5	IN	SOA	ns1.dns.example.org. hostmaster.dns.example.org. 1 7200 1800 86400 5")},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeSOA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.SOA("example.org.	5	IN	SOA	ns1.dns.example.org. hostmaster.dns.example.org. 1 7200 1800 86400 5")},
+		Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+	},
+	{
+		Qname: "example.org.", Qtype: dns.TypeNS, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+		Extra:  []dns.RR{test.A("ns1.dns.example.org.	5	IN	A	192.0.2.53")},
+	},
+}
+
+func TestTransfer(t *testing.T) {
+	c := newTestCRD(t, records)
+	defer c.Stop()
+
+	if _, err := c.Transfer("example.net.", 0); err == nil {
+		t.Error("Expected an error for a zone we're not authoritative for")
+	}
+
+	ch, err := c.Transfer("example.org.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rrs []dns.RR
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	// SOA, NS, 8 records and the closing SOA.
+	if len(rrs) != 11 {
+		t.Fatalf("Expected 11 records, got %d: %v", len(rrs), rrs)
+	}
+	if rrs[0].Header().Rrtype != dns.TypeSOA || rrs[len(rrs)-1].Header().Rrtype != dns.TypeSOA {
+		t.Errorf("Expected the transfer to start and end with the SOA, got %v", rrs)
+	}
+
+	// An up to date secondary only gets the SOA.
+	ch, err = c.Transfer("example.org.", rrs[0].(*dns.SOA).Serial)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rrs = nil
+	for x := range ch {
+		rrs = append(rrs, x...)
+	}
+	if len(rrs) != 1 {
+		t.Errorf("Expected only the SOA, got %v", rrs)
+	}
+}
+
+func TestUpdateSerial(t *testing.T) {
+	c := newTestCRD(t, records)
+	defer c.Stop()
+
+	serial := c.zone("example.org.").SOA.Serial
+	c.update()
+	if s := c.zone("example.org.").SOA.Serial; s <= serial {
+		t.Errorf("Expected serial to increase from %d, got %d", serial, s)
+	}
+}
+
+// created returns u with its creation timestamp set to t.
+func created(u *unstructured.Unstructured, t time.Time) *unstructured.Unstructured {
+	u.SetCreationTimestamp(meta.NewTime(t))
+	return u
+}
+
+func TestCRDConflict(t *testing.T) {
+	now := time.Now()
+	c := newTestCRD(t, []runtime.Object{
+		created(record("DNSRecord", "team-b", "www", map[string]interface{}{
+			"name": "www.team-b.example.org",
+			"a":    []interface{}{"192.0.2.2"},
+		}), now.Add(-time.Hour)),
+		// newer than the A record with the same name
+		created(record("DNSRecord", "team-c", "www", map[string]interface{}{
+			"name":  "www.team-b.example.org",
+			"cname": "www.example.net",
+		}), now),
+		created(record("DNSRecord", "team-b", "alias", map[string]interface{}{
+			"name":  "alias.team-b.example.org",
+			"cname": "www.team-b.example.org",
+		}), now),
+		// a ClusterDNSRecord wins from an older DNSRecord
+		created(record("ClusterDNSRecord", "", "alias", map[string]interface{}{
+			"name":  "alias.team-b.example.org",
+			"cname": "www.example.net",
+		}), now.Add(time.Hour)),
+		// records without a CNAME can share a name
+		created(record("DNSRecord", "team-c", "txt", map[string]interface{}{
+			"name": "www.team-b.example.org",
+			"txt":  []interface{}{"team-c"},
+		}), now),
+	})
+	defer c.Stop()
+
+	tests := []test.Case{
+		{
+			Qname: "www.team-b.example.org.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.A("www.team-b.example.org.	5	IN	A	192.0.2.2")},
+			Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+		},
+		{
+			Qname: "www.team-b.example.org.", Qtype: dns.TypeTXT, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.TXT("www.team-b.example.org.	5	IN	TXT	\"team-c\"")},
+			Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+		},
+		{
+			Qname: "alias.team-b.example.org.", Qtype: dns.TypeCNAME, Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{test.CNAME("alias.team-b.example.org.	5	IN	CNAME	www.example.net.")},
+			Ns:     []dns.RR{test.NS("example.org.	5	IN	NS	ns1.dns.example.org.")},
+		},
+	}
+
+	ctx := context.Background()
+	for i, tc := range tests {
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := c.ServeDNS(ctx, w, tc.Msg()); err != nil {
+			t.Errorf("Test %d, expected no error, got %v", i, err)
+			continue
+		}
+		if err := test.SortAndCheck(w.Msg, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.conflicts) != 2 {
+		t.Errorf("Expected 2 conflicting records, got %v", c.conflicts)
+	}
+}
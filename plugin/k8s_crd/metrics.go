@@ -0,0 +1,16 @@
+package crd
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// rejectedCount is the number of DNSRecords and ClusterDNSRecords that are not served.
+var rejectedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: plugin.Namespace,
+	Subsystem: "k8s_crd",
+	Name:      "rejected_records_total",
+	Help:      "Counter of DNSRecords and ClusterDNSRecords that are not served, because they are invalid, not allowed in the zone or conflict with other records.",
+}, []string{"reason"})
@@ -0,0 +1,314 @@
+package crd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+
+	"github.com/miekg/dns"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// DNSRecordResource is the resource of the namespaced DNSRecords.
+	DNSRecordResource = schema.GroupVersionResource{Group: "coredns.io", Version: "v1alpha1", Resource: "dnsrecords"}
+	// ClusterDNSRecordResource is the resource of the cluster-scoped ClusterDNSRecords.
+	ClusterDNSRecordResource = schema.GroupVersionResource{Group: "coredns.io", Version: "v1alpha1", Resource: "clusterdnsrecords"}
+)
+
+// Record is a stripped down DNSRecord or ClusterDNSRecord, holding the resource records it defines.
+// A record that is invalid or that writes to a zone it isn't allowed to, has no RRs.
+type Record struct {
+	Version   string
+	Name      string
+	Namespace string // empty for a ClusterDNSRecord.
+	Zone      string // the zone the RRs are in.
+	Created   time.Time
+	RRs       []dns.RR
+
+	*object.Empty
+}
+
+// toRecord converts an unstructured DNSRecord or ClusterDNSRecord to a *Record. Records that can't be
+// served are logged and counted, but still returned, so they are tracked (and deleted) by the informer.
+func (c *CRD) toRecord(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	r := &Record{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Created:   u.GetCreationTimestamp().Time,
+	}
+
+	rrs, err := parseSpec(u, c.ttl)
+	if err == nil {
+		err = c.check(r, rrs)
+	}
+	u.Object = nil
+	if err != nil {
+		reason := "invalid"
+		if errors.Is(err, errDenied) {
+			reason = "denied"
+		}
+		log.Warningf("Ignoring %s: %s", r.key(), err)
+		rejectedCount.WithLabelValues(reason).Inc()
+		return r, nil
+	}
+	r.RRs = rrs
+	return r, nil
+}
+
+var errDenied = errors.New("not allowed")
+
+// check checks if the record r with the resource records rrs may be served, and sets its zone.
+func (c *CRD) check(r *Record, rrs []dns.RR) error {
+	owner := rrs[0].Header().Name
+	zone := plugin.Zones(c.Zones).Matches(owner)
+	if zone == "" || !c.allowed(r.Namespace, owner) {
+		return fmt.Errorf("%w to write %s", errDenied, owner)
+	}
+	if _, ok := rrs[0].(*dns.CNAME); ok && owner == zone {
+		return fmt.Errorf("CNAME at the apex of %s", zone)
+	}
+	r.Zone = zone
+	return nil
+}
+
+// parseSpec returns the resource records defined in the spec of u. A TTL of 0 in the spec means ttl.
+func parseSpec(u *unstructured.Unstructured, ttl uint32) ([]dns.RR, error) {
+	spec, ok, err := unstructured.NestedMap(u.Object, "spec")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("no spec")
+	}
+
+	name, _, err := unstructured.NestedString(spec, "name")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := dns.IsDomainName(name); !ok || name == "" {
+		return nil, fmt.Errorf("invalid name %q", name)
+	}
+	name = dns.Fqdn(name)
+
+	t, _, err := unstructured.NestedInt64(spec, "ttl")
+	if err != nil {
+		return nil, err
+	}
+	if t < 0 || t > 2147483647 {
+		return nil, fmt.Errorf("invalid ttl %d", t)
+	}
+	if t > 0 {
+		ttl = uint32(t)
+	}
+	hdr := func(rrtype uint16) dns.RR_Header {
+		return dns.RR_Header{Name: name, Rrtype: rrtype, Class: dns.ClassINET, Ttl: ttl}
+	}
+
+	var rrs []dns.RR
+
+	a, _, err := unstructured.NestedStringSlice(spec, "a")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range a {
+		ip := net.ParseIP(s).To4()
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IPv4 address %q", s)
+		}
+		rrs = append(rrs, &dns.A{Hdr: hdr(dns.TypeA), A: ip})
+	}
+
+	aaaa, _, err := unstructured.NestedStringSlice(spec, "aaaa")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range aaaa {
+		ip := net.ParseIP(s)
+		if ip == nil || ip.To4() != nil {
+			return nil, fmt.Errorf("invalid IPv6 address %q", s)
+		}
+		rrs = append(rrs, &dns.AAAA{Hdr: hdr(dns.TypeAAAA), AAAA: ip})
+	}
+
+	txt, _, err := unstructured.NestedStringSlice(spec, "txt")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range txt {
+		rrs = append(rrs, &dns.TXT{Hdr: hdr(dns.TypeTXT), Txt: split255(s)})
+	}
+
+	srv, _, err := unstructured.NestedSlice(spec, "srv")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range srv {
+		m, ok := s.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid srv %v", s)
+		}
+		target, err := domainField(m, "target")
+		if err != nil {
+			return nil, err
+		}
+		priority, err := uint16Field(m, "priority")
+		if err != nil {
+			return nil, err
+		}
+		weight, err := uint16Field(m, "weight")
+		if err != nil {
+			return nil, err
+		}
+		port, err := uint16Field(m, "port")
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, &dns.SRV{Hdr: hdr(dns.TypeSRV), Priority: priority, Weight: weight, Port: port, Target: target})
+	}
+
+	mx, _, err := unstructured.NestedSlice(spec, "mx")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range mx {
+		m, ok := s.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid mx %v", s)
+		}
+		host, err := domainField(m, "host")
+		if err != nil {
+			return nil, err
+		}
+		preference, err := uint16Field(m, "preference")
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, &dns.MX{Hdr: hdr(dns.TypeMX), Preference: preference, Mx: host})
+	}
+
+	cname, ok, err := unstructured.NestedString(spec, "cname")
+	if err != nil {
+		return nil, err
+	}
+	if ok && cname != "" {
+		if len(rrs) > 0 {
+			return nil, fmt.Errorf("cname can't be combined with other records")
+		}
+		if _, ok := dns.IsDomainName(cname); !ok {
+			return nil, fmt.Errorf("invalid cname %q", cname)
+		}
+		rrs = append(rrs, &dns.CNAME{Hdr: hdr(dns.TypeCNAME), Target: dns.Fqdn(cname)})
+	}
+
+	if len(rrs) == 0 {
+		return nil, fmt.Errorf("no records")
+	}
+	return rrs, nil
+}
+
+func domainField(m map[string]interface{}, field string) (string, error) {
+	s, _, err := unstructured.NestedString(m, field)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := dns.IsDomainName(s); !ok || s == "" {
+		return "", fmt.Errorf("invalid %s %q", field, s)
+	}
+	return dns.Fqdn(s), nil
+}
+
+func uint16Field(m map[string]interface{}, field string) (uint16, error) {
+	i, _, err := unstructured.NestedInt64(m, field)
+	if err != nil {
+		return 0, err
+	}
+	if i < 0 || i > 65535 {
+		return 0, fmt.Errorf("invalid %s %d", field, i)
+	}
+	return uint16(i), nil
+}
+
+// split255 splits s into strings of at most 255 characters, the maximum length of a TXT string.
+func split255(s string) []string {
+	if len(s) <= 255 {
+		return []string{s}
+	}
+	var sx []string
+	for len(s) > 255 {
+		sx = append(sx, s[:255])
+		s = s[255:]
+	}
+	if s != "" {
+		sx = append(sx, s)
+	}
+	return sx
+}
+
+// before returns true if r takes precedence over r1 when their records conflict. ClusterDNSRecords take
+// precedence over DNSRecords, otherwise the oldest record wins.
+func (r *Record) before(r1 *Record) bool {
+	if (r.Namespace == "") != (r1.Namespace == "") {
+		return r.Namespace == ""
+	}
+	if !r.Created.Equal(r1.Created) {
+		return r.Created.Before(r1.Created)
+	}
+	return r.key() < r1.key()
+}
+
+// key returns the kind and name of r, as used in log messages.
+func (r *Record) key() string {
+	if r.Namespace == "" {
+		return "ClusterDNSRecord " + r.Name
+	}
+	return "DNSRecord " + r.Namespace + "/" + r.Name
+}
+
+var _ runtime.Object = &Record{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (r *Record) DeepCopyObject() runtime.Object {
+	r1 := &Record{
+		Version:   r.Version,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Zone:      r.Zone,
+		Created:   r.Created,
+		RRs:       make([]dns.RR, len(r.RRs)),
+	}
+	for i, rr := range r.RRs {
+		r1.RRs[i] = dns.Copy(rr)
+	}
+	return r1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (r *Record) GetNamespace() string { return r.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (r *Record) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (r *Record) GetName() string { return r.Name }
+
+// SetName implements the metav1.Object interface.
+func (r *Record) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (r *Record) GetResourceVersion() string { return r.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (r *Record) SetResourceVersion(version string) {}
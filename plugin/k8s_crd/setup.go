@@ -0,0 +1,143 @@
+package crd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const pluginName = "k8s_crd"
+
+var log = clog.NewWithPlugin(pluginName)
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	k, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	config, err := k.getClientConfig()
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return plugin.Error(pluginName, fmt.Errorf("failed to create kubernetes client: %q", err))
+	}
+	k.initInformers(context.Background(), client)
+
+	c.OnStartup(func() error {
+		go k.Run()
+
+		timeout := time.After(5 * time.Second)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+	wait:
+		for !k.HasSynced() {
+			select {
+			case <-ticker.C:
+			case <-timeout:
+				log.Warning("starting server with unsynced Kubernetes API")
+				break wait
+			}
+		}
+		k.update()
+		return nil
+	})
+	c.OnShutdown(k.Stop)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		k.Next = next
+		return k
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*CRD, error) {
+	var k *CRD
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		k = New(plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys))
+
+		for c.NextBlock() {
+			switch c.Val() {
+			case "kubeconfig":
+				args := c.RemainingArgs()
+				if len(args) != 1 && len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				overrides := &clientcmd.ConfigOverrides{}
+				if len(args) == 2 {
+					overrides.CurrentContext = args[1]
+				}
+				k.ClientConfig = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+					&clientcmd.ClientConfigLoadingRules{ExplicitPath: args[0]},
+					overrides,
+				)
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				zones := k.Zones
+				if len(args) > 1 {
+					zones = plugin.Host(args[1]).NormalizeExact()
+					for _, z := range args[2:] {
+						zones = append(zones, plugin.Host(z).NormalizeExact()...)
+					}
+					for _, z := range zones {
+						if plugin.Zones(k.Zones).Matches(z) == "" {
+							return nil, c.Errf("zone %q is not in the plugin's zones", z)
+						}
+					}
+				}
+				k.allow[args[0]] = append(k.allow[args[0]], zones...)
+			case "ttl":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				t, err := strconv.Atoi(args[0])
+				if err != nil {
+					return nil, err
+				}
+				if t < 0 || t > 3600 {
+					return nil, c.Errf("ttl must be in range [0, 3600]: %d", t)
+				}
+				k.ttl = uint32(t)
+			case "fallthrough":
+				k.Fall.SetZonesFromArgs(c.RemainingArgs())
+			default:
+				return nil, c.Errf("unknown property '%s'", c.Val())
+			}
+		}
+	}
+	return k, nil
+}
+
+// getClientConfig returns the configuration to connect to the API server, this is the in-cluster
+// configuration unless a kubeconfig is given.
+func (c *CRD) getClientConfig() (*rest.Config, error) {
+	if c.ClientConfig != nil {
+		return c.ClientConfig.ClientConfig()
+	}
+	return rest.InClusterConfig()
+}
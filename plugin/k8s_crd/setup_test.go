@@ -0,0 +1,80 @@
+package crd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/pkg/fall"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input         string
+		shouldErr     bool
+		expectedZones []string
+		expectedTTL   uint32
+		expectedAllow map[string][]string
+		expectedFall  fall.F
+	}{
+		{`k8s_crd example.org`, false, []string{"example.org."}, 5, map[string][]string{}, fall.Zero},
+		{`k8s_crd example.org example.net {
+			ttl 60
+			fallthrough
+		}`, false, []string{"example.org.", "example.net."}, 60, map[string][]string{}, fall.Root},
+		{`k8s_crd example.org example.net {
+			allow team-a team-a.example.org
+			allow team-b
+			allow * shared.example.org shared.example.net
+		}`, false, []string{"example.org.", "example.net."}, 5, map[string][]string{
+			"team-a": {"team-a.example.org."},
+			"team-b": {"example.org.", "example.net."},
+			"*":      {"shared.example.org.", "shared.example.net."},
+		}, fall.Zero},
+		// zone outside of the plugin's zones
+		{`k8s_crd example.org {
+			allow team-a example.net
+		}`, true, nil, 0, nil, fall.Zero},
+		{`k8s_crd example.org {
+			allow
+		}`, true, nil, 0, nil, fall.Zero},
+		{`k8s_crd example.org {
+			ttl 7200
+		}`, true, nil, 0, nil, fall.Zero},
+		{`k8s_crd example.org {
+			kubeconfig
+		}`, true, nil, 0, nil, fall.Zero},
+		{`k8s_crd example.org {
+			foo
+		}`, true, nil, 0, nil, fall.Zero},
+		{`k8s_crd example.org
+		k8s_crd example.net`, true, nil, 0, nil, fall.Zero},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k, err := parse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error but found none for input %s", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(k.Zones, test.expectedZones) {
+			t.Errorf("Test %d: Expected zones %v, got %v", i, test.expectedZones, k.Zones)
+		}
+		if k.ttl != test.expectedTTL {
+			t.Errorf("Test %d: Expected ttl %d, got %d", i, test.expectedTTL, k.ttl)
+		}
+		if !reflect.DeepEqual(k.allow, test.expectedAllow) {
+			t.Errorf("Test %d: Expected allow %v, got %v", i, test.expectedAllow, k.allow)
+		}
+		if !k.Fall.Equal(test.expectedFall) {
+			t.Errorf("Test %d: Expected fallthrough %v, got %v", i, test.expectedFall, k.Fall)
+		}
+	}
+}
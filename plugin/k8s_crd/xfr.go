@@ -0,0 +1,16 @@
+package crd
+
+import (
+	"github.com/coredns/coredns/plugin/transfer"
+
+	"github.com/miekg/dns"
+)
+
+// Transfer implements the transfer.Transferer interface.
+func (c *CRD) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	z := c.zone(zone)
+	if z == nil {
+		return nil, transfer.ErrNotAuthoritative
+	}
+	return z.Transfer(serial)
+}
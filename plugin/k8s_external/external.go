@@ -26,7 +26,7 @@ import (
 type Externaler interface {
 	// External returns a slice of msg.Services that are looked up in the backend and match
 	// the request.
-	External(request.Request, bool) ([]msg.Service, int)
+	External(context.Context, request.Request, bool) ([]msg.Service, int)
 	// ExternalAddress should return a string slice of addresses for the nameserving endpoint.
 	ExternalAddress(state request.Request, headless bool) []dns.RR
 	// ExternalServices returns all services in the given zone as a slice of msg.Service and if enabled, headless services as a map of services.
@@ -35,6 +35,13 @@ type Externaler interface {
 	ExternalSerial(string) uint32
 }
 
+// HostWatcher is implemented by plugins that can also serve the hostnames of Ingresses and Gateway API
+// Gateways and HTTPRoutes from External and ExternalServices.
+type HostWatcher interface {
+	// WatchHosts starts watching the Ingresses and/or the Gateway API resources.
+	WatchHosts(ingress, gateway bool)
+}
+
 // External serves records for External IPs and Loadbalance IPs of Services in Kubernetes clusters.
 type External struct {
 	Next  plugin.Handler
@@ -45,10 +52,12 @@ type External struct {
 	apex       string
 	ttl        uint32
 	headless   bool
+	ingress    bool
+	gateway    bool
 
 	upstream *upstream.Upstream
 
-	externalFunc         func(request.Request, bool) ([]msg.Service, int)
+	externalFunc         func(context.Context, request.Request, bool) ([]msg.Service, int)
 	externalAddrFunc     func(request.Request, bool) []dns.RR
 	externalSerialFunc   func(string) uint32
 	externalServicesFunc func(string, bool) ([]msg.Service, map[string][]msg.Service)
@@ -83,7 +92,7 @@ func (e *External) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		}
 	}
 
-	svc, rcode := e.externalFunc(state, e.headless)
+	svc, rcode := e.externalFunc(ctx, state, e.headless)
 
 	m := new(dns.Msg)
 	m.SetReply(state.Req)
@@ -277,16 +277,61 @@ var tests = []test.Case{
 			test.A("endpoint-svc-1.svc-headless.testns.example.com.	5	IN	A	1.2.3.5"),
 		},
 	},
+	// Ingress and Gateway API hostnames
+	{
+		Qname: "app.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.A("app.example.com.	5	IN	A	1.2.3.10"),
+		},
+	},
+	{
+		Qname: "app.example.com.", Qtype: dns.TypeAAAA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.AAAA("app.example.com.	5	IN	AAAA	1:2::10"),
+		},
+	},
+	{
+		Qname: "app.example.com.", Qtype: dns.TypeSRV, Rcode: dns.RcodeSuccess,
+		Ns: []dns.RR{
+			test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
+	{
+		Qname: "www.shop.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeSuccess,
+		Answer: []dns.RR{
+			test.CNAME("www.shop.example.com.	5	IN	CNAME	lb.example.net."),
+		},
+	},
+	{
+		// The namespace of the Ingress isn't exposed.
+		Qname: "hidden.example.com.", Qtype: dns.TypeA, Rcode: dns.RcodeNameError,
+		Ns: []dns.RR{
+			test.SOA("example.com.	5	IN	SOA	ns1.dns.example.com. hostmaster.example.com. 1499347823 7200 1800 86400 5"),
+		},
+	},
 }
 
 type external struct{}
 
-func (external) HasSynced() bool                           { return true }
-func (external) Run()                                      {}
-func (external) Stop() error                               { return nil }
-func (external) EpIndexReverse(string) []*object.Endpoints { return nil }
-func (external) SvcIndexReverse(string) []*object.Service  { return nil }
-func (external) Modified(bool) int64                       { return 0 }
+func (external) HasSynced() bool                                  { return true }
+func (external) Run()                                             {}
+func (external) Stop() error                                      { return nil }
+func (external) EpIndexReverse(string) []*object.Endpoints        { return nil }
+func (external) SvcImportIndex(string) []*object.ServiceImport    { return nil }
+func (external) McEpIndex(string) []*object.MultiClusterEndpoints { return nil }
+func (external) GetNode(string) (*object.Node, error)             { return nil, nil }
+func (external) WatchHosts(bool, bool)                            {}
+func (external) HostList() []object.Host                          { return hostsExternal }
+func (external) HostIndex(host string) (hosts []object.Host) {
+	for _, h := range hostsExternal {
+		if h.Name == host {
+			hosts = append(hosts, h)
+		}
+	}
+	return hosts
+}
+func (external) SvcIndexReverse(string) []*object.Service { return nil }
+func (external) Modified(bool) int64                      { return 0 }
 func (external) EpIndex(s string) []*object.Endpoints {
 	return epIndexExternal[s]
 }
@@ -321,6 +366,12 @@ func (external) GetNamespaceByName(name string) (*object.Namespace, error) {
 	}, nil
 }
 
+var hostsExternal = []object.Host{
+	{Name: "app.example.com.", Namespace: "testns", Addresses: []string{"1.2.3.10", "1:2::10"}},
+	{Name: "www.shop.example.com.", Namespace: "testns", Addresses: []string{"lb.example.net"}},
+	{Name: "hidden.example.com.", Namespace: "otherns", Addresses: []string{"1.2.3.11"}},
+}
+
 var epIndexExternal = map[string][]*object.Endpoints{
 	"svc-headless.testns": {
 		{
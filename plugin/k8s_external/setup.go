@@ -36,6 +36,15 @@ func setup(c *caddy.Controller) error {
 		e.externalAddrFunc = x.ExternalAddress
 		e.externalServicesFunc = x.ExternalServices
 		e.externalSerialFunc = x.ExternalSerial
+
+		if e.ingress || e.gateway {
+			h, ok := m.(HostWatcher)
+			if !ok {
+				return plugin.Error(pluginName, errors.New("kubernetes plugin does not implement the HostWatcher interface"))
+			}
+			// This runs before the kubernetes plugin starts its watches.
+			h.WatchHosts(e.ingress, e.gateway)
+		}
 		return nil
 	})
 
@@ -77,6 +86,16 @@ func parse(c *caddy.Controller) (*External, error) {
 				e.apex = args[0]
 			case "headless":
 				e.headless = true
+			case "ingress":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				e.ingress = true
+			case "gateway":
+				if len(c.RemainingArgs()) != 0 {
+					return nil, c.ArgErr()
+				}
+				e.gateway = true
 			case "fallthrough":
 				e.Fall.SetZonesFromArgs(c.RemainingArgs())
 			default:
@@ -63,9 +63,12 @@ func (e *External) Transfer(zone string, serial uint32) (<-chan []dns.RR, error)
 				if len(as) > 0 {
 					ch <- as
 				}
-				aaaas, _ := e.aaaa(ctx, []msg.Service{svcs[i]}, s)
-				if len(aaaas) > 0 {
-					ch <- aaaas
+				// A hostname of a load balancer is a CNAME, which a has already returned.
+				if len(as) == 0 || as[0].Header().Rrtype != dns.TypeCNAME {
+					aaaas, _ := e.aaaa(ctx, []msg.Service{svcs[i]}, s)
+					if len(aaaas) > 0 {
+						ch <- aaaas
+					}
 				}
 				// Add bare SRV record, ensuring uniqueness
 				recs, _ := e.srv(ctx, []msg.Service{svcs[i]}, s)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,10 +13,15 @@ import (
 
 	api "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
+	networking "k8s.io/api/networking/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
@@ -27,6 +33,11 @@ const (
 	svcExtIPIndex         = "ServiceExternalIP"
 	epNameNamespaceIndex  = "EndpointNameNamespace"
 	epIPIndex             = "EndpointsIP"
+
+	hostnameIndex = "Hostname"
+
+	svcImportNameNamespaceIndex = "ServiceImportNameNamespace"
+	mcEpNameNamespaceIndex      = "MultiClusterEndpointNameNamespace"
 )
 
 type dnsController interface {
@@ -38,9 +49,19 @@ type dnsController interface {
 	PodIndex(string) []*object.Pod
 	EpIndex(string) []*object.Endpoints
 	EpIndexReverse(string) []*object.Endpoints
+	SvcImportIndex(string) []*object.ServiceImport
+	McEpIndex(string) []*object.MultiClusterEndpoints
 
 	GetNodeByName(context.Context, string) (*api.Node, error)
 	GetNamespaceByName(string) (*object.Namespace, error)
+	GetNode(string) (*object.Node, error)
+
+	// WatchHosts enables the watches on Ingresses and/or Gateway API Gateways and HTTPRoutes. It must be
+	// called before Run.
+	WatchHosts(ingress, gateway bool)
+	// HostIndex returns the hosts with the hostname, HostList returns all of them.
+	HostIndex(string) []object.Host
+	HostList() []object.Host
 
 	Run()
 	HasSynced() bool
@@ -60,20 +81,39 @@ type dnsControl struct {
 	// services with external facing IP addresses
 	extModified int64
 
-	client kubernetes.Interface
+	client    kubernetes.Interface
+	dynClient dynamic.Interface
 
 	selector          labels.Selector
 	namespaceSelector labels.Selector
 
-	svcController cache.Controller
-	podController cache.Controller
-	epController  cache.Controller
-	nsController  cache.Controller
+	svcController  cache.Controller
+	podController  cache.Controller
+	epController   cache.Controller
+	nsController   cache.Controller
+	nodeController cache.Controller
+
+	svcImportController cache.Controller
+	mcEpController      cache.Controller
+
+	ingController   cache.Controller
+	gwController    cache.Controller
+	routeController cache.Controller
+	watchIngress    bool
+	watchGateway    bool
 
-	svcLister cache.Indexer
-	podLister cache.Indexer
-	epLister  cache.Indexer
-	nsLister  cache.Store
+	svcLister  cache.Indexer
+	podLister  cache.Indexer
+	epLister   cache.Indexer
+	nsLister   cache.Store
+	nodeLister cache.Store
+
+	svcImportLister cache.Indexer
+	mcEpLister      cache.Indexer
+
+	ingLister   cache.Indexer
+	gwLister    cache.Indexer
+	routeLister cache.Indexer
 
 	// stopLock is used to enforce only a single call to Stop is active.
 	// Needed because we allow stopping through an http endpoint and
@@ -89,6 +129,7 @@ type dnsControl struct {
 type dnsControlOpts struct {
 	initPodCache       bool
 	initEndpointsCache bool
+	initNodeCache      bool
 	ignoreEmptyService bool
 
 	// Label handling.
@@ -97,14 +138,24 @@ type dnsControlOpts struct {
 	namespaceLabelSelector *meta.LabelSelector
 	namespaceSelector      labels.Selector
 
+	// namespacePolicyKey is the label, or the annotation if namespacePolicyAnnotation is set, with the
+	// DNS policy of a namespace.
+	namespacePolicyKey        string
+	namespacePolicyAnnotation bool
+
 	zones            []string
 	endpointNameMode bool
+
+	// multiclusterZones are the zones that serve the services imported into the cluster set.
+	multiclusterZones []string
 }
 
-// newdnsController creates a controller for CoreDNS.
-func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, opts dnsControlOpts) *dnsControl {
+// newdnsController creates a controller for CoreDNS. The dynClient is used for the ServiceImports and the
+// Gateway API resources, it may be nil if those aren't watched.
+func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, dynClient dynamic.Interface, opts dnsControlOpts) *dnsControl {
 	dns := dnsControl{
 		client:            kubeClient,
+		dynClient:         dynClient,
 		selector:          opts.selector,
 		namespaceSelector: opts.namespaceSelector,
 		stopCh:            make(chan struct{}),
@@ -161,8 +212,81 @@ func newdnsController(ctx context.Context, kubeClient kubernetes.Interface, opts
 		&api.Namespace{},
 		cache.ResourceEventHandlerFuncs{},
 		cache.Indexers{},
-		object.DefaultProcessor(object.ToNamespace, nil),
+		object.DefaultProcessor(object.ToNamespacePolicy(opts.namespacePolicyKey, opts.namespacePolicyAnnotation), nil),
+	)
+
+	// The informers for the hosts are only run when enabled with WatchHosts.
+	hostHandler := cache.ResourceEventHandlerFuncs{AddFunc: dns.hostAddDelete, UpdateFunc: dns.hostUpdate, DeleteFunc: dns.hostAddDelete}
+	dns.ingLister, dns.ingController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  ingressListFunc(ctx, dns.client, api.NamespaceAll),
+			WatchFunc: ingressWatchFunc(ctx, dns.client, api.NamespaceAll),
+		},
+		&networking.Ingress{},
+		hostHandler,
+		cache.Indexers{hostnameIndex: ingressHostnameIndexFunc},
+		object.DefaultProcessor(object.ToIngress, nil),
+	)
+	dns.gwLister, dns.gwController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, dns.dynClient, object.GatewayResource, api.NamespaceAll),
+			WatchFunc: dynamicWatchFunc(ctx, dns.dynClient, object.GatewayResource, api.NamespaceAll),
+		},
+		&unstructured.Unstructured{},
+		hostHandler,
+		cache.Indexers{hostnameIndex: gatewayHostnameIndexFunc},
+		object.DefaultProcessor(object.ToGateway, nil),
 	)
+	dns.routeLister, dns.routeController = object.NewIndexerInformer(
+		&cache.ListWatch{
+			ListFunc:  dynamicListFunc(ctx, dns.dynClient, object.HTTPRouteResource, api.NamespaceAll),
+			WatchFunc: dynamicWatchFunc(ctx, dns.dynClient, object.HTTPRouteResource, api.NamespaceAll),
+		},
+		&unstructured.Unstructured{},
+		hostHandler,
+		cache.Indexers{hostnameIndex: routeHostnameIndexFunc},
+		object.DefaultProcessor(object.ToHTTPRoute, nil),
+	)
+
+	if opts.initNodeCache {
+		dns.nodeLister, dns.nodeController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  nodeListFunc(ctx, dns.client),
+				WatchFunc: nodeWatchFunc(ctx, dns.client),
+			},
+			&api.Node{},
+			cache.ResourceEventHandlerFuncs{},
+			cache.Indexers{},
+			object.DefaultProcessor(object.ToNode, nil),
+		)
+	}
+
+	if len(opts.multiclusterZones) > 0 {
+		dns.svcImportLister, dns.svcImportController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  dynamicListFunc(ctx, dns.dynClient, object.ServiceImportResource, api.NamespaceAll),
+				WatchFunc: dynamicWatchFunc(ctx, dns.dynClient, object.ServiceImportResource, api.NamespaceAll),
+			},
+			&unstructured.Unstructured{},
+			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+			cache.Indexers{svcImportNameNamespaceIndex: svcImportNameNamespaceIndexFunc},
+			object.DefaultProcessor(object.ToServiceImport, nil),
+		)
+
+		// Only the EndpointSlices of imported services carry the source cluster label.
+		req, _ := labels.NewRequirement(object.LabelSourceCluster, selection.Exists, nil)
+		mcSelector := labels.NewSelector().Add(*req)
+		dns.mcEpLister, dns.mcEpController = object.NewIndexerInformer(
+			&cache.ListWatch{
+				ListFunc:  endpointSliceListFunc(ctx, dns.client, api.NamespaceAll, mcSelector),
+				WatchFunc: endpointSliceWatchFunc(ctx, dns.client, api.NamespaceAll, mcSelector),
+			},
+			&discovery.EndpointSlice{},
+			cache.ResourceEventHandlerFuncs{AddFunc: dns.Add, UpdateFunc: dns.Update, DeleteFunc: dns.Delete},
+			cache.Indexers{mcEpNameNamespaceIndex: mcEpNameNamespaceIndexFunc},
+			object.DefaultProcessor(object.EndpointSliceToMultiClusterEndpoints, nil),
+		)
+	}
 
 	return &dns
 }
@@ -234,6 +358,46 @@ func epIPIndexFunc(obj interface{}) ([]string, error) {
 	return ep.IndexIP, nil
 }
 
+func svcImportNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	s, ok := obj.(*object.ServiceImport)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{s.Index}, nil
+}
+
+func mcEpNameNamespaceIndexFunc(obj interface{}) ([]string, error) {
+	s, ok := obj.(*object.MultiClusterEndpoints)
+	if !ok {
+		return nil, errObj
+	}
+	return []string{s.Index}, nil
+}
+
+func ingressHostnameIndexFunc(obj interface{}) ([]string, error) {
+	i, ok := obj.(*object.Ingress)
+	if !ok {
+		return nil, errObj
+	}
+	return i.Hostnames, nil
+}
+
+func gatewayHostnameIndexFunc(obj interface{}) ([]string, error) {
+	g, ok := obj.(*object.Gateway)
+	if !ok {
+		return nil, errObj
+	}
+	return g.Hostnames, nil
+}
+
+func routeHostnameIndexFunc(obj interface{}) ([]string, error) {
+	r, ok := obj.(*object.HTTPRoute)
+	if !ok {
+		return nil, errObj
+	}
+	return r.Hostnames, nil
+}
+
 func serviceListFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(meta.ListOptions) (runtime.Object, error) {
 	return func(opts meta.ListOptions) (runtime.Object, error) {
 		if s != nil {
@@ -274,6 +438,24 @@ func namespaceListFunc(ctx context.Context, c kubernetes.Interface, s labels.Sel
 	}
 }
 
+func nodeListFunc(ctx context.Context, c kubernetes.Interface) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		return c.CoreV1().Nodes().List(ctx, opts)
+	}
+}
+
+func ingressListFunc(ctx context.Context, c kubernetes.Interface, ns string) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		return c.NetworkingV1().Ingresses(ns).List(ctx, opts)
+	}
+}
+
+func dynamicListFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string) func(meta.ListOptions) (runtime.Object, error) {
+	return func(opts meta.ListOptions) (runtime.Object, error) {
+		return c.Resource(r).Namespace(ns).List(ctx, opts)
+	}
+}
+
 func serviceWatchFunc(ctx context.Context, c kubernetes.Interface, ns string, s labels.Selector) func(options meta.ListOptions) (watch.Interface, error) {
 	return func(options meta.ListOptions) (watch.Interface, error) {
 		if s != nil {
@@ -314,6 +496,24 @@ func namespaceWatchFunc(ctx context.Context, c kubernetes.Interface, s labels.Se
 	}
 }
 
+func nodeWatchFunc(ctx context.Context, c kubernetes.Interface) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		return c.CoreV1().Nodes().Watch(ctx, options)
+	}
+}
+
+func ingressWatchFunc(ctx context.Context, c kubernetes.Interface, ns string) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		return c.NetworkingV1().Ingresses(ns).Watch(ctx, options)
+	}
+}
+
+func dynamicWatchFunc(ctx context.Context, c dynamic.Interface, r schema.GroupVersionResource, ns string) func(options meta.ListOptions) (watch.Interface, error) {
+	return func(options meta.ListOptions) (watch.Interface, error) {
+		return c.Resource(r).Namespace(ns).Watch(ctx, options)
+	}
+}
+
 // Stop stops the  controller.
 func (dns *dnsControl) Stop() error {
 	dns.stopLock.Lock()
@@ -342,6 +542,20 @@ func (dns *dnsControl) Run() {
 		go dns.podController.Run(dns.stopCh)
 	}
 	go dns.nsController.Run(dns.stopCh)
+	if dns.nodeController != nil {
+		go dns.nodeController.Run(dns.stopCh)
+	}
+	if dns.svcImportController != nil {
+		go dns.svcImportController.Run(dns.stopCh)
+		go dns.mcEpController.Run(dns.stopCh)
+	}
+	if dns.watchIngress {
+		go dns.ingController.Run(dns.stopCh)
+	}
+	if dns.watchGateway {
+		go dns.gwController.Run(dns.stopCh)
+		go dns.routeController.Run(dns.stopCh)
+	}
 	<-dns.stopCh
 }
 
@@ -357,7 +571,23 @@ func (dns *dnsControl) HasSynced() bool {
 		c = dns.podController.HasSynced()
 	}
 	d := dns.nsController.HasSynced()
-	return a && b && c && d
+	e := true
+	if dns.svcImportController != nil {
+		e = dns.svcImportController.HasSynced() && dns.mcEpController.HasSynced()
+	}
+	f := true
+	if dns.nodeController != nil {
+		f = dns.nodeController.HasSynced()
+	}
+	g := true
+	if dns.watchIngress {
+		g = dns.ingController.HasSynced()
+	}
+	h := true
+	if dns.watchGateway {
+		h = dns.gwController.HasSynced() && dns.routeController.HasSynced()
+	}
+	return a && b && c && d && e && f && g && h
 }
 
 func (dns *dnsControl) ServiceList() (svcs []*object.Service) {
@@ -476,6 +706,42 @@ func (dns *dnsControl) EpIndexReverse(ip string) (ep []*object.Endpoints) {
 	return ep
 }
 
+func (dns *dnsControl) SvcImportIndex(idx string) (svcs []*object.ServiceImport) {
+	if dns.svcImportLister == nil {
+		return nil
+	}
+	os, err := dns.svcImportLister.ByIndex(svcImportNameNamespaceIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		s, ok := o.(*object.ServiceImport)
+		if !ok {
+			continue
+		}
+		svcs = append(svcs, s)
+	}
+	return svcs
+}
+
+func (dns *dnsControl) McEpIndex(idx string) (ep []*object.MultiClusterEndpoints) {
+	if dns.mcEpLister == nil {
+		return nil
+	}
+	os, err := dns.mcEpLister.ByIndex(mcEpNameNamespaceIndex, idx)
+	if err != nil {
+		return nil
+	}
+	for _, o := range os {
+		e, ok := o.(*object.MultiClusterEndpoints)
+		if !ok {
+			continue
+		}
+		ep = append(ep, e)
+	}
+	return ep
+}
+
 // GetNodeByName return the node by name. If nothing is found an error is
 // returned. This query causes a round trip to the k8s API server, so use
 // sparingly. Currently, this is only used for Federation.
@@ -500,6 +766,106 @@ func (dns *dnsControl) GetNamespaceByName(name string) (*object.Namespace, error
 	return ns, nil
 }
 
+// GetNode returns the node by name from the cache. If nothing is found an error is returned.
+func (dns *dnsControl) GetNode(name string) (*object.Node, error) {
+	if dns.nodeLister == nil {
+		return nil, fmt.Errorf("node cache not enabled")
+	}
+	o, exists, err := dns.nodeLister.GetByKey(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("node not found")
+	}
+	node, ok := o.(*object.Node)
+	if !ok {
+		return nil, fmt.Errorf("found key but not node")
+	}
+	return node, nil
+}
+
+// WatchHosts enables the watches on Ingresses and/or Gateway API Gateways and HTTPRoutes.
+func (dns *dnsControl) WatchHosts(ingress, gateway bool) {
+	dns.watchIngress = dns.watchIngress || ingress
+	dns.watchGateway = dns.watchGateway || gateway
+}
+
+// HostIndex returns the hosts with the hostname host from the Ingresses, Gateways and HTTPRoutes.
+func (dns *dnsControl) HostIndex(host string) (hosts []object.Host) {
+	if os, err := dns.ingLister.ByIndex(hostnameIndex, host); err == nil {
+		for _, o := range os {
+			if i, ok := o.(*object.Ingress); ok {
+				hosts = append(hosts, object.Host{Name: host, Namespace: i.Namespace, Addresses: i.Addresses})
+			}
+		}
+	}
+	if os, err := dns.gwLister.ByIndex(hostnameIndex, host); err == nil {
+		for _, o := range os {
+			if g, ok := o.(*object.Gateway); ok {
+				hosts = append(hosts, object.Host{Name: host, Namespace: g.Namespace, Addresses: g.Addresses})
+			}
+		}
+	}
+	if os, err := dns.routeLister.ByIndex(hostnameIndex, host); err == nil {
+		for _, o := range os {
+			if r, ok := o.(*object.HTTPRoute); ok {
+				hosts = append(hosts, object.Host{Name: host, Namespace: r.Namespace, Addresses: dns.routeAddresses(r)})
+			}
+		}
+	}
+	return hosts
+}
+
+// HostList returns all hosts of the Ingresses, Gateways and HTTPRoutes.
+func (dns *dnsControl) HostList() (hosts []object.Host) {
+	for _, o := range dns.ingLister.List() {
+		if i, ok := o.(*object.Ingress); ok {
+			for _, h := range i.Hostnames {
+				hosts = append(hosts, object.Host{Name: h, Namespace: i.Namespace, Addresses: i.Addresses})
+			}
+		}
+	}
+	for _, o := range dns.gwLister.List() {
+		if g, ok := o.(*object.Gateway); ok {
+			for _, h := range g.Hostnames {
+				hosts = append(hosts, object.Host{Name: h, Namespace: g.Namespace, Addresses: g.Addresses})
+			}
+		}
+	}
+	for _, o := range dns.routeLister.List() {
+		if r, ok := o.(*object.HTTPRoute); ok {
+			addrs := dns.routeAddresses(r)
+			for _, h := range r.Hostnames {
+				hosts = append(hosts, object.Host{Name: h, Namespace: r.Namespace, Addresses: addrs})
+			}
+		}
+	}
+	return hosts
+}
+
+// routeAddresses returns the addresses of the parent Gateways of r.
+func (dns *dnsControl) routeAddresses(r *object.HTTPRoute) (addrs []string) {
+	for _, key := range r.Parents {
+		o, exists, err := dns.gwLister.GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if g, ok := o.(*object.Gateway); ok {
+			addrs = append(addrs, g.Addresses...)
+		}
+	}
+	return addrs
+}
+
+func (dns *dnsControl) hostAddDelete(obj interface{}) { dns.updateExtModified() }
+
+func (dns *dnsControl) hostUpdate(oldObj, newObj interface{}) {
+	if oldObj.(meta.Object).GetResourceVersion() != newObj.(meta.Object).GetResourceVersion() {
+		dns.updateExtModified()
+	}
+}
+
 func (dns *dnsControl) Add(obj interface{})               { dns.updateModified() }
 func (dns *dnsControl) Delete(obj interface{})            { dns.updateModified() }
 func (dns *dnsControl) Update(oldObj, newObj interface{}) { dns.detectChanges(oldObj, newObj) }
@@ -529,6 +895,12 @@ func (dns *dnsControl) detectChanges(oldObj, newObj interface{}) {
 		if !endpointsEquivalent(oldObj.(*object.Endpoints), newObj.(*object.Endpoints)) {
 			dns.updateModified()
 		}
+	case *object.ServiceImport:
+		dns.updateModified()
+	case *object.MultiClusterEndpoints:
+		if !endpointsEquivalent(&oldObj.(*object.MultiClusterEndpoints).Endpoints, &newObj.(*object.MultiClusterEndpoints).Endpoints) {
+			dns.updateModified()
+		}
 	default:
 		log.Warningf("Updates for %T not supported.", ob)
 	}
@@ -556,6 +928,9 @@ func subsetsEquivalent(sa, sb object.EndpointSubset) bool {
 		if aaddr.Hostname != baddr.Hostname {
 			return false
 		}
+		if aaddr.Zone != baddr.Zone || strings.Join(aaddr.ForZones, ",") != strings.Join(baddr.ForZones, ",") {
+			return false
+		}
 	}
 
 	for port, aport := range sa.Ports {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"net"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,7 +15,12 @@ import (
 	"github.com/miekg/dns"
 	api "k8s.io/api/core/v1"
 	discovery "k8s.io/api/discovery/v1"
+	networking "k8s.io/api/networking/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 )
@@ -34,7 +40,7 @@ func kubernetesWithFakeClient(ctx context.Context, zone, cidr string, initEndpoi
 		zones:              []string{zone},
 		initEndpointsCache: initEndpointsCache,
 	}
-	controller := newdnsController(ctx, client, dco)
+	controller := newdnsController(ctx, client, nil, dco)
 
 	// Add resources
 	_, err := client.CoreV1().Namespaces().Create(ctx, &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}}, meta.CreateOptions{})
@@ -301,3 +307,112 @@ func TestServiceModified(t *testing.T) {
 		}
 	}
 }
+
+func TestHostIndex(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	gvrs := map[schema.GroupVersionResource]string{object.GatewayResource: "GatewayList", object.HTTPRouteResource: "HTTPRouteList"}
+	gw := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]interface{}{"name": "gw1", "namespace": "infra"},
+		"spec": map[string]interface{}{
+			"listeners": []interface{}{
+				map[string]interface{}{"name": "http", "hostname": "gw.example.org"},
+				map[string]interface{}{"name": "wildcard", "hostname": "*.example.org"},
+			},
+		},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"type": "IPAddress", "value": "192.0.2.1"}},
+		},
+	}}
+	route := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "HTTPRoute",
+		"metadata":   map[string]interface{}{"name": "route1", "namespace": "testns"},
+		"spec": map[string]interface{}{
+			"hostnames": []interface{}{"Route.example.org"},
+			"parentRefs": []interface{}{
+				map[string]interface{}{"name": "gw1", "namespace": "infra"},
+				map[string]interface{}{"name": "gw2", "namespace": "infra"},
+			},
+		},
+		"status": map[string]interface{}{
+			"parents": []interface{}{
+				map[string]interface{}{
+					"parentRef":  map[string]interface{}{"name": "gw1", "namespace": "infra"},
+					"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "True"}},
+				},
+				// Not accepted, its addresses don't serve the route.
+				map[string]interface{}{
+					"parentRef":  map[string]interface{}{"name": "gw2", "namespace": "infra"},
+					"conditions": []interface{}{map[string]interface{}{"type": "Accepted", "status": "False"}},
+				},
+			},
+		},
+	}}
+	gw2 := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "gateway.networking.k8s.io/v1",
+		"kind":       "Gateway",
+		"metadata":   map[string]interface{}{"name": "gw2", "namespace": "infra"},
+		"status": map[string]interface{}{
+			"addresses": []interface{}{map[string]interface{}{"type": "IPAddress", "value": "192.0.2.3"}},
+		},
+	}}
+	// Created through the client, the fake guesses the wrong resource for Gateway objects it's seeded with.
+	dynClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), gvrs)
+	if _, err := dynClient.Resource(object.GatewayResource).Namespace("infra").Create(ctx, gw, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dynClient.Resource(object.GatewayResource).Namespace("infra").Create(ctx, gw2, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dynClient.Resource(object.HTTPRouteResource).Namespace("testns").Create(ctx, route, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	ing := &networking.Ingress{
+		ObjectMeta: meta.ObjectMeta{Name: "ing1", Namespace: "testns"},
+		Spec:       networking.IngressSpec{Rules: []networking.IngressRule{{Host: "ing.example.org"}}},
+		Status: networking.IngressStatus{LoadBalancer: networking.IngressLoadBalancerStatus{
+			Ingress: []networking.IngressLoadBalancerIngress{{IP: "192.0.2.2"}, {Hostname: "lb.example.net"}},
+		}},
+	}
+	if _, err := client.NetworkingV1().Ingresses("testns").Create(ctx, ing, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	controller := newdnsController(ctx, client, dynClient, dnsControlOpts{})
+	controller.WatchHosts(true, true)
+	go controller.Run()
+	defer controller.Stop()
+	for !controller.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []struct {
+		host      string
+		namespace string
+		addresses []string
+	}{
+		{"ing.example.org.", "testns", []string{"192.0.2.2", "lb.example.net"}},
+		{"gw.example.org.", "infra", []string{"192.0.2.1"}},
+		{"route.example.org.", "testns", []string{"192.0.2.1"}},
+	}
+	for i, tc := range tests {
+		hosts := controller.HostIndex(tc.host)
+		if len(hosts) != 1 {
+			t.Errorf("Test %d: expected 1 host for %s, got %d", i, tc.host, len(hosts))
+			continue
+		}
+		if hosts[0].Namespace != tc.namespace {
+			t.Errorf("Test %d: expected namespace %s, got %s", i, tc.namespace, hosts[0].Namespace)
+		}
+		if strings.Join(hosts[0].Addresses, ",") != strings.Join(tc.addresses, ",") {
+			t.Errorf("Test %d: expected addresses %v, got %v", i, tc.addresses, hosts[0].Addresses)
+		}
+	}
+	if hosts := controller.HostList(); len(hosts) != len(tests) {
+		t.Errorf("Expected %d hosts, got %d", len(tests), len(hosts))
+	}
+}
@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"context"
 	"strings"
 
 	"github.com/coredns/coredns/plugin/etcd/msg"
@@ -25,11 +26,11 @@ const (
 
 // External implements the ExternalFunc call from the external plugin.
 // It returns any services matching in the services' ExternalIPs and if enabled, headless endpoints..
-func (k *Kubernetes) External(state request.Request, headless bool) ([]msg.Service, int) {
+func (k *Kubernetes) External(ctx context.Context, state request.Request, headless bool) ([]msg.Service, int) {
 	if state.QType() == dns.TypePTR {
 		ip := dnsutil.ExtractAddressFromReverse(state.Name())
 		if ip != "" {
-			svcs, err := k.ExternalReverse(ip)
+			svcs, err := k.ExternalReverse(ctx, state, ip)
 			if err != nil {
 				return nil, dns.RcodeNameError
 			}
@@ -38,6 +39,10 @@ func (k *Kubernetes) External(state request.Request, headless bool) ([]msg.Servi
 		// for invalid reverse names, fall through to determine proper nxdomain/nodata response
 	}
 
+	if svcs, ok := k.externalHosts(ctx, state); ok {
+		return svcs, dns.RcodeSuccess
+	}
+
 	base, _ := dnsutil.TrimZone(state.Name(), state.Zone)
 
 	segs := dns.SplitDomainName(base)
@@ -51,7 +56,7 @@ func (k *Kubernetes) External(state request.Request, headless bool) ([]msg.Servi
 	// endpoint.service.namespace.<base>
 	var port, protocol, endpoint string
 	namespace := segs[last]
-	if !k.namespaceExposed(namespace) {
+	if !k.namespaceExposed(namespace) || !k.namespaceAllowed(ctx, state, namespace) {
 		return nil, dns.RcodeNameError
 	}
 
@@ -148,6 +153,31 @@ func (k *Kubernetes) External(state request.Request, headless bool) ([]msg.Servi
 	return services, rcode
 }
 
+// externalHosts returns the addresses of the Ingresses, Gateways and HTTPRoutes with the hostname in state
+// as msg.Services. The boolean is false if there are none. Only address records are returned.
+func (k *Kubernetes) externalHosts(ctx context.Context, state request.Request) ([]msg.Service, bool) {
+	var services []msg.Service
+	found := false
+	for _, h := range k.APIConn.HostIndex(state.Name()) {
+		if !k.namespaceExposed(h.Namespace) || !k.namespaceAllowed(ctx, state, h.Namespace) {
+			continue
+		}
+		found = true
+		if state.QType() != dns.TypeA && state.QType() != dns.TypeAAAA {
+			continue
+		}
+		for _, addr := range h.Addresses {
+			// Port -1 makes sure no SRV records are created.
+			services = append(services, msg.Service{Host: addr, Port: -1, TTL: k.ttl, Key: msg.Path(h.Name, coredns)})
+		}
+	}
+	return services, found
+}
+
+// WatchHosts makes the plugin watch the Ingresses and/or the Gateway API Gateways and HTTPRoutes, so their
+// hostnames are returned by External and ExternalServices. It must be called before the plugin starts.
+func (k *Kubernetes) WatchHosts(ingress, gateway bool) { k.APIConn.WatchHosts(ingress, gateway) }
+
 // ExternalAddress returns the external service address(es) for the CoreDNS service.
 func (k *Kubernetes) ExternalAddress(state request.Request, headless bool) []dns.RR {
 	// If CoreDNS is running inside the Kubernetes cluster: k.nsAddrs() will return the external IPs of the services
@@ -158,11 +188,15 @@ func (k *Kubernetes) ExternalAddress(state request.Request, headless bool) []dns
 	return k.nsAddrs(true, headless, state.Zone)
 }
 
-// ExternalServices returns all services with external IPs and if enabled headless services
+// ExternalServices returns all services with external IPs and if enabled headless services. Services
+// in namespaces with a `namespace_policy` are left out.
 func (k *Kubernetes) ExternalServices(zone string, headless bool) (services []msg.Service, headlessServices map[string][]msg.Service) {
 	zonePath := msg.Path(zone, coredns)
 	headlessServices = make(map[string][]msg.Service)
 	for _, svc := range k.APIConn.ServiceList() {
+		if k.namespacePolicy(svc.Namespace) != "" {
+			continue
+		}
 		// Endpoints and headless services
 		if headless && len(svc.ExternalIPs) == 0 && svc.Headless() {
 			idx := object.ServiceKey(svc.Name, svc.Namespace)
@@ -207,6 +241,14 @@ func (k *Kubernetes) ExternalServices(zone string, headless bool) (services []ms
 			}
 		}
 	}
+	for _, h := range k.APIConn.HostList() {
+		if !dns.IsSubDomain(zone, h.Name) || !k.namespaceExposed(h.Namespace) || k.namespacePolicy(h.Namespace) != "" {
+			continue
+		}
+		for _, addr := range h.Addresses {
+			services = append(services, msg.Service{Host: addr, Port: -1, TTL: k.ttl, Key: msg.Path(h.Name, coredns)})
+		}
+	}
 	return services, headlessServices
 }
 
@@ -216,20 +258,23 @@ func (k *Kubernetes) ExternalSerial(string) uint32 {
 }
 
 // ExternalReverse does a reverse lookup for the external IPs
-func (k *Kubernetes) ExternalReverse(ip string) ([]msg.Service, error) {
-	records := k.serviceRecordForExternalIP(ip)
+func (k *Kubernetes) ExternalReverse(ctx context.Context, state request.Request, ip string) ([]msg.Service, error) {
+	records := k.serviceRecordForExternalIP(ctx, state, ip)
 	if len(records) == 0 {
 		return records, errNoItems
 	}
 	return records, nil
 }
 
-func (k *Kubernetes) serviceRecordForExternalIP(ip string) []msg.Service {
+func (k *Kubernetes) serviceRecordForExternalIP(ctx context.Context, state request.Request, ip string) []msg.Service {
 	var svcs []msg.Service
 	for _, service := range k.APIConn.SvcExtIndexReverse(ip) {
 		if len(k.Namespaces) > 0 && !k.namespaceExposed(service.Namespace) {
 			continue
 		}
+		if !k.namespaceAllowed(ctx, state, service.Namespace) {
+			continue
+		}
 		domain := strings.Join([]string{service.Name, service.Namespace}, ".")
 		svcs = append(svcs, msg.Service{Host: domain, TTL: k.ttl})
 	}
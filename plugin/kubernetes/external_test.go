@@ -70,7 +70,7 @@ func TestExternal(t *testing.T) {
 	for i, tc := range extCases {
 		state := testRequest(tc.Qname)
 
-		svc, rcode := k.External(state, true)
+		svc, rcode := k.External(context.TODO(), state, true)
 
 		if x := tc.Rcode; x != rcode {
 			t.Errorf("Test %d, expected rcode %d, got %d", i, x, rcode)
@@ -90,13 +90,19 @@ func TestExternal(t *testing.T) {
 
 type external struct{}
 
-func (external) HasSynced() bool                             { return true }
-func (external) Run()                                        {}
-func (external) Stop() error                                 { return nil }
-func (external) EpIndexReverse(string) []*object.Endpoints   { return nil }
-func (external) SvcIndexReverse(string) []*object.Service    { return nil }
-func (external) SvcExtIndexReverse(string) []*object.Service { return nil }
-func (external) Modified(bool) int64                         { return 0 }
+func (external) HasSynced() bool                                  { return true }
+func (external) Run()                                             {}
+func (external) Stop() error                                      { return nil }
+func (external) EpIndexReverse(string) []*object.Endpoints        { return nil }
+func (external) SvcImportIndex(string) []*object.ServiceImport    { return nil }
+func (external) McEpIndex(string) []*object.MultiClusterEndpoints { return nil }
+func (external) GetNode(string) (*object.Node, error)             { return nil, nil }
+func (external) WatchHosts(bool, bool)                            {}
+func (external) HostIndex(string) []object.Host                   { return nil }
+func (external) HostList() []object.Host                          { return nil }
+func (external) SvcIndexReverse(string) []*object.Service         { return nil }
+func (external) SvcExtIndexReverse(string) []*object.Service      { return nil }
+func (external) Modified(bool) int64                              { return 0 }
 func (external) EpIndex(s string) []*object.Endpoints {
 	return epIndexExternal[s]
 }
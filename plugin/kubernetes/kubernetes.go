@@ -20,6 +20,7 @@ import (
 	api "k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -40,6 +41,7 @@ type Kubernetes struct {
 	Namespaces       map[string]struct{}
 	podMode          string
 	endpointNameMode bool
+	topology         string // If set, prefer the endpoints close to the client, either "zone" or "node".
 	Fall             fall.F
 	ttl              uint32
 	opts             dnsControlOpts
@@ -251,12 +253,20 @@ func (k *Kubernetes) InitKubeCache(ctx context.Context) (onStart func() error, o
 		k.opts.namespaceSelector = selector
 	}
 
-	k.opts.initPodCache = k.podMode == podModeVerified
+	// Topology aware answers need to find the pod, and the zone of its node.
+	k.opts.initPodCache = k.podMode == podModeVerified || k.topology != "" || k.opts.namespacePolicyKey != ""
+	k.opts.initNodeCache = k.topology != ""
 
 	k.opts.zones = k.Zones
 	k.opts.endpointNameMode = k.endpointNameMode
 
-	k.APIConn = newdnsController(ctx, kubeClient, k.opts)
+	// The dynamic client is used for the custom resources of the Multi-Cluster Services and Gateway APIs.
+	dynClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create kubernetes notification controller: %q", err)
+	}
+
+	k.APIConn = newdnsController(ctx, kubeClient, dynClient, k.opts)
 
 	onStart = func() error {
 		go func() {
@@ -295,7 +305,8 @@ func (k *Kubernetes) InitKubeCache(ctx context.Context) (onStart func() error, o
 
 // Records looks up services in kubernetes.
 func (k *Kubernetes) Records(ctx context.Context, state request.Request, exact bool) ([]msg.Service, error) {
-	r, e := parseRequest(state.Name(), state.Zone)
+	multicluster := k.isMultiClusterZone(state.Zone)
+	r, e := parseRequest(state.Name(), state.Zone, multicluster)
 	if e != nil {
 		return nil, e
 	}
@@ -311,12 +322,21 @@ func (k *Kubernetes) Records(ctx context.Context, state request.Request, exact b
 		return nil, errNsNotExposed
 	}
 
+	if !k.namespaceAllowed(ctx, state, r.namespace) {
+		return nil, errNsNotExposed
+	}
+
+	if multicluster {
+		services, err := k.findMultiClusterServices(r, state.Zone)
+		return services, err
+	}
+
 	if r.podOrSvc == Pod {
 		pods, err := k.findPods(r, state.Zone)
 		return pods, err
 	}
 
-	services, err := k.findServices(r, state.Zone)
+	services, err := k.findServices(r, state.Zone, k.clientLocality(state))
 	return services, err
 }
 
@@ -394,8 +414,9 @@ func (k *Kubernetes) findPods(r recordRequest, zone string) (pods []msg.Service,
 	return pods, err
 }
 
-// findServices returns the services matching r from the cache.
-func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.Service, err error) {
+// findServices returns the services matching r from the cache. The endpoints of headless services are
+// limited to the ones close to l, if there are any.
+func (k *Kubernetes) findServices(r recordRequest, zone string, l locality) (services []msg.Service, err error) {
 	if !k.namespaceExposed(r.namespace) {
 		return nil, errNoItems
 	}
@@ -465,6 +486,11 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 				endpointsList = endpointsListFunc()
 			}
 
+			var local map[string]struct{}
+			if r.endpoint == "" {
+				local = k.localAddresses(endpointsList, object.EndpointsKey(svc.Name, svc.Namespace), l)
+			}
+
 			for _, ep := range endpointsList {
 				if object.EndpointsKey(svc.Name, svc.Namespace) != ep.Index {
 					continue
@@ -478,6 +504,11 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 								continue
 							}
 						}
+						if local != nil {
+							if _, ok := local[addr.IP]; !ok {
+								continue
+							}
+						}
 
 						for _, p := range eps.Ports {
 							if !(matchPortAndProtocol(r.port, p.Name, r.protocol, p.Protocol)) {
@@ -514,6 +545,86 @@ func (k *Kubernetes) findServices(r recordRequest, zone string) (services []msg.
 	return services, err
 }
 
+// findMultiClusterServices returns the services imported into the cluster set matching r from the cache.
+func (k *Kubernetes) findMultiClusterServices(r recordRequest, zone string) (services []msg.Service, err error) {
+	if !k.namespaceExposed(r.namespace) {
+		return nil, errNoItems
+	}
+
+	// handle empty service name
+	if r.service == "" {
+		// NODATA
+		return nil, nil
+	}
+
+	err = errNoItems
+
+	idx := object.ServiceKey(r.service, r.namespace)
+	zonePath := msg.Path(zone, coredns)
+	for _, svc := range k.APIConn.SvcImportIndex(idx) {
+		if !(match(r.namespace, svc.Namespace) && match(r.service, svc.Name)) {
+			continue
+		}
+
+		// Endpoint query or headless service, the endpoints of all clusters in the cluster set are returned.
+		if svc.Headless() || r.endpoint != "" {
+			for _, ep := range k.APIConn.McEpIndex(idx) {
+				if r.cluster != "" && !match(r.cluster, ep.ClusterID) {
+					continue
+				}
+
+				for _, eps := range ep.Subsets {
+					for _, addr := range eps.Addresses {
+						hostname := endpointHostname(addr, k.endpointNameMode)
+						if r.endpoint != "" && !match(r.endpoint, hostname) {
+							continue
+						}
+
+						for _, p := range eps.Ports {
+							if !(matchPortAndProtocol(r.port, p.Name, r.protocol, p.Protocol)) {
+								continue
+							}
+							s := msg.Service{Host: addr.IP, Port: int(p.Port), TTL: k.ttl}
+							s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name, ep.ClusterID, hostname}, "/")
+
+							err = nil
+
+							services = append(services, s)
+						}
+					}
+				}
+			}
+			continue
+		}
+
+		// ClusterSetIP service
+		for _, p := range svc.Ports {
+			if !(matchPortAndProtocol(r.port, p.Name, r.protocol, string(p.Protocol))) {
+				continue
+			}
+
+			err = nil
+
+			for _, ip := range svc.ClusterIPs {
+				s := msg.Service{Host: ip, Port: int(p.Port), TTL: k.ttl}
+				s.Key = strings.Join([]string{zonePath, Svc, svc.Namespace, svc.Name}, "/")
+				services = append(services, s)
+			}
+		}
+	}
+	return services, err
+}
+
+// isMultiClusterZone returns true if zone serves the services imported into the cluster set.
+func (k *Kubernetes) isMultiClusterZone(zone string) bool {
+	for _, z := range k.opts.multiclusterZones {
+		if z == zone {
+			return true
+		}
+	}
+	return false
+}
+
 // Serial return the SOA serial.
 func (k *Kubernetes) Serial(state request.Request) uint32 { return uint32(k.APIConn.Modified(false)) }
 
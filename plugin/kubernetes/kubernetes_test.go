@@ -39,14 +39,20 @@ func TestEndpointHostname(t *testing.T) {
 
 type APIConnServiceTest struct{}
 
-func (APIConnServiceTest) HasSynced() bool                             { return true }
-func (APIConnServiceTest) Run()                                        {}
-func (APIConnServiceTest) Stop() error                                 { return nil }
-func (APIConnServiceTest) PodIndex(string) []*object.Pod               { return nil }
-func (APIConnServiceTest) SvcIndexReverse(string) []*object.Service    { return nil }
-func (APIConnServiceTest) SvcExtIndexReverse(string) []*object.Service { return nil }
-func (APIConnServiceTest) EpIndexReverse(string) []*object.Endpoints   { return nil }
-func (APIConnServiceTest) Modified(bool) int64                         { return 0 }
+func (APIConnServiceTest) HasSynced() bool                                  { return true }
+func (APIConnServiceTest) Run()                                             {}
+func (APIConnServiceTest) Stop() error                                      { return nil }
+func (APIConnServiceTest) PodIndex(string) []*object.Pod                    { return nil }
+func (APIConnServiceTest) SvcIndexReverse(string) []*object.Service         { return nil }
+func (APIConnServiceTest) SvcExtIndexReverse(string) []*object.Service      { return nil }
+func (APIConnServiceTest) EpIndexReverse(string) []*object.Endpoints        { return nil }
+func (APIConnServiceTest) SvcImportIndex(string) []*object.ServiceImport    { return nil }
+func (APIConnServiceTest) McEpIndex(string) []*object.MultiClusterEndpoints { return nil }
+func (APIConnServiceTest) GetNode(string) (*object.Node, error)             { return nil, nil }
+func (APIConnServiceTest) WatchHosts(bool, bool)                            {}
+func (APIConnServiceTest) HostIndex(string) []object.Host                   { return nil }
+func (APIConnServiceTest) HostList() []object.Host                          { return nil }
+func (APIConnServiceTest) Modified(bool) int64                              { return 0 }
 
 func (APIConnServiceTest) SvcIndex(string) []*object.Service {
 	svcs := []*object.Service{
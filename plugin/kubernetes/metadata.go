@@ -33,7 +33,7 @@ func (k *Kubernetes) Metadata(ctx context.Context, state request.Request) contex
 		return ctx
 	}
 	// possible optimization: cache r so it doesn't need to be calculated again in ServeDNS
-	r, err := parseRequest(state.Name(), zone)
+	r, err := parseRequest(state.Name(), zone, k.isMultiClusterZone(zone))
 	if err != nil {
 		metadata.SetValueFunc(ctx, "kubernetes/parse-error", func() string {
 			return err.Error()
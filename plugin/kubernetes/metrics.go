@@ -49,6 +49,17 @@ var (
 		},
 		[]string{"code", "method", "host"},
 	)
+
+	// policyDeniedCount counts the lookups denied by the namespace policy, grouped by the namespace looked up.
+	policyDeniedCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: plugin.Namespace,
+			Subsystem: "kubernetes",
+			Name:      "policy_denied_requests_total",
+			Help:      "Counter of lookups denied by the namespace policy, by the namespace looked up.",
+		},
+		[]string{"namespace"},
+	)
 )
 
 func init() {
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func serviceImport(name, typ string, ips ...string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "multicluster.x-k8s.io/v1alpha1",
+		"kind":       "ServiceImport",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "testns"},
+		"spec": map[string]interface{}{
+			"type":  typ,
+			"ports": []interface{}{map[string]interface{}{"name": "http", "protocol": "TCP", "port": int64(80)}},
+		},
+	}}
+	if len(ips) > 0 {
+		list := make([]interface{}, len(ips))
+		for i := range ips {
+			list[i] = ips[i]
+		}
+		unstructured.SetNestedSlice(u.Object, list, "spec", "ips")
+	}
+	return u
+}
+
+func multiClusterEndpointSlice(service, cluster, hostname, ip string) *discovery.EndpointSlice {
+	port := int32(80)
+	protocol := api.ProtocolTCP
+	name := "http"
+	return &discovery.EndpointSlice{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      service + "-" + cluster,
+			Namespace: "testns",
+			Labels: map[string]string{
+				object.LabelMultiClusterServiceName: service,
+				object.LabelSourceCluster:           cluster,
+			},
+		},
+		Endpoints: []discovery.Endpoint{{Addresses: []string{ip}, Hostname: &hostname}},
+		Ports:     []discovery.EndpointPort{{Port: &port, Protocol: &protocol, Name: &name}},
+	}
+}
+
+func kubernetesWithMultiCluster(ctx context.Context, t *testing.T) *Kubernetes {
+	client := fake.NewSimpleClientset()
+	mcsClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{object.ServiceImportResource: "ServiceImportList"},
+		serviceImport("svc1", object.ServiceImportClusterSetIP, "10.0.0.1"),
+		serviceImport("hdls1", object.ServiceImportHeadless),
+	)
+
+	if _, err := client.CoreV1().Namespaces().Create(ctx, &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}}, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, eps := range []*discovery.EndpointSlice{
+		multiClusterEndpointSlice("hdls1", "cluster1", "pod1", "172.0.0.1"),
+		multiClusterEndpointSlice("hdls1", "cluster2", "pod1", "172.0.0.2"),
+	} {
+		if _, err := client.DiscoveryV1().EndpointSlices("testns").Create(ctx, eps, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	k := New([]string{"cluster.local.", "clusterset.local."})
+	k.opts.multiclusterZones = []string{"clusterset.local."}
+	k.opts.zones = k.Zones
+	k.APIConn = newdnsController(ctx, client, mcsClient, k.opts)
+	return k
+}
+
+func TestMultiCluster(t *testing.T) {
+	ctx := context.Background()
+	k := kubernetesWithMultiCluster(ctx, t)
+
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	tests := []test.Case{
+		{
+			Qname: "svc1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.0.0.1"),
+			},
+		},
+		{
+			Qname: "_http._tcp.svc1.testns.svc.clusterset.local.", Qtype: dns.TypeSRV,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.SRV("_http._tcp.svc1.testns.svc.clusterset.local.	5	IN	SRV	0 100 80 svc1.testns.svc.clusterset.local."),
+			},
+			Extra: []dns.RR{
+				test.A("svc1.testns.svc.clusterset.local.	5	IN	A	10.0.0.1"),
+			},
+		},
+		{
+			Qname: "hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.1"),
+				test.A("hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.2"),
+			},
+		},
+		{
+			Qname: "pod1.cluster2.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeSuccess,
+			Answer: []dns.RR{
+				test.A("pod1.cluster2.hdls1.testns.svc.clusterset.local.	5	IN	A	172.0.0.2"),
+			},
+		},
+		{
+			Qname: "pod1.cluster3.hdls1.testns.svc.clusterset.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("clusterset.local.	5	IN	SOA	ns.dns.clusterset.local. hostmaster.clusterset.local. 1499347823 7200 1800 86400 5"),
+			},
+		},
+		{
+			// The imported services are not in the cluster zone.
+			Qname: "svc1.testns.svc.cluster.local.", Qtype: dns.TypeA,
+			Rcode: dns.RcodeNameError,
+			Ns: []dns.RR{
+				test.SOA("cluster.local.	5	IN	SOA	ns.dns.cluster.local. hostmaster.cluster.local. 1499347823 7200 1800 86400 5"),
+			},
+		},
+	}
+
+	for i, tc := range tests {
+		r := tc.Msg()
+		w := dnstest.NewRecorder(&test.ResponseWriter{})
+
+		if _, err := k.ServeDNS(ctx, w, r); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d: got nil message", i)
+		}
+		// The SOA serial is the time of the last change, don't check it.
+		for _, rr := range resp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				soa.Serial = 1499347823
+			}
+		}
+		if err := test.SortAndCheck(resp, tc); err != nil {
+			t.Errorf("Test %d: %v", i, err)
+		}
+	}
+}
@@ -1,5 +1,13 @@
 package kubernetes
 
+import (
+	"context"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/pkg/nocache"
+	"github.com/coredns/coredns/request"
+)
+
 // filteredNamespaceExists checks if namespace exists in this cluster
 // according to any `namespace_labels` plugin configuration specified.
 // Returns true even for namespaces not exposed by plugin configuration,
@@ -22,3 +30,58 @@ func (k *Kubernetes) configuredNamespace(namespace string) bool {
 func (k *Kubernetes) namespaceExposed(namespace string) bool {
 	return k.configuredNamespace(namespace) && k.filteredNamespaceExists(namespace)
 }
+
+// namespaceAllowed returns true when the client of state may resolve names in namespace according to
+// the `namespace_policy` configuration. A namespace without a policy is visible to all clients. Otherwise
+// it is only visible to pods in that namespace, and to pods in namespaces that share one of the
+// (comma separated) groups of its policy. As the answer then depends on the client, ctx is marked so
+// the response isn't cached.
+func (k *Kubernetes) namespaceAllowed(ctx context.Context, state request.Request, namespace string) bool {
+	policy := k.namespacePolicy(namespace)
+	if policy == "" {
+		return true
+	}
+	nocache.Set(ctx)
+
+	if pods := k.APIConn.PodIndex(state.IP()); len(pods) > 0 {
+		if pods[0].Namespace == namespace {
+			return true
+		}
+		if client, err := k.APIConn.GetNamespaceByName(pods[0].Namespace); err == nil && sharePolicyGroup(client.Policy, policy) {
+			return true
+		}
+	}
+
+	policyDeniedCount.WithLabelValues(namespace).Inc()
+	return false
+}
+
+// namespacePolicy returns the policy of namespace, or the empty string if it has none or `namespace_policy`
+// isn't configured. Zone transfers have no client that can be checked against a policy, so they skip the
+// namespaces that have one.
+func (k *Kubernetes) namespacePolicy(namespace string) string {
+	if k.opts.namespacePolicyKey == "" {
+		return ""
+	}
+	ns, err := k.APIConn.GetNamespaceByName(namespace)
+	if err != nil {
+		return ""
+	}
+	return ns.Policy
+}
+
+// sharePolicyGroup returns true if the policies a and b have a group in common.
+func sharePolicyGroup(a, b string) bool {
+	for _, ga := range strings.Split(a, ",") {
+		ga = strings.TrimSpace(ga)
+		if ga == "" {
+			continue
+		}
+		for _, gb := range strings.Split(b, ",") {
+			if ga == strings.TrimSpace(gb) {
+				return true
+			}
+		}
+	}
+	return false
+}
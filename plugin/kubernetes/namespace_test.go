@@ -1,7 +1,20 @@
 package kubernetes
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/cache"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestFilteredNamespaceExists(t *testing.T) {
@@ -70,3 +83,245 @@ func TestNamespaceValid(t *testing.T) {
 		}
 	}
 }
+
+func kubernetesWithPolicy(ctx context.Context, t *testing.T, annotation bool) *Kubernetes {
+	client := fake.NewSimpleClientset()
+
+	for _, ns := range []struct{ name, policy string }{{"tenant-a1", "tenant-a"}, {"tenant-a2", "tenant-a, shared"}, {"tenant-b", "tenant-b"}, {"public", ""}} {
+		n := &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: ns.name}}
+		if ns.policy != "" {
+			if annotation {
+				n.Annotations = map[string]string{"dns.coredns.io/policy": ns.policy}
+			} else {
+				n.Labels = map[string]string{"dns.coredns.io/policy": ns.policy}
+			}
+		}
+		if _, err := client.CoreV1().Namespaces().Create(ctx, n, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pod := range []struct{ name, namespace, ip string }{{"client", "tenant-a1", "10.240.0.1"}, {"client", "tenant-b", "10.240.0.2"}, {"client", "public", "10.240.0.3"}} {
+		p := &api.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: pod.name, Namespace: pod.namespace},
+			Status:     api.PodStatus{PodIP: pod.ip},
+		}
+		if _, err := client.CoreV1().Pods(pod.namespace).Create(ctx, p, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, svc := range []struct{ namespace, ip, external string }{{"tenant-a1", "10.0.0.1", "192.0.2.1"}, {"tenant-a2", "10.0.0.2", "192.0.2.2"}, {"tenant-b", "10.0.0.3", "192.0.2.3"}, {"public", "10.0.0.4", "192.0.2.4"}} {
+		s := &api.Service{
+			ObjectMeta: meta.ObjectMeta{Name: "svc", Namespace: svc.namespace},
+			Spec: api.ServiceSpec{Type: api.ServiceTypeClusterIP, ClusterIP: svc.ip, ClusterIPs: []string{svc.ip}, ExternalIPs: []string{svc.external},
+				Ports: []api.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}}},
+		}
+		if _, err := client.CoreV1().Services(svc.namespace).Create(ctx, s, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	k := New([]string{"cluster.local.", "in-addr.arpa."})
+	k.opts.namespacePolicyKey = "dns.coredns.io/policy"
+	k.opts.namespacePolicyAnnotation = annotation
+	k.opts.initPodCache = true
+	k.APIConn = newdnsController(ctx, client, nil, k.opts)
+	return k
+}
+
+func TestNamespacePolicy(t *testing.T) {
+	tests := []struct {
+		client string
+		qname  string
+		qtype  uint16
+		rcode  int
+	}{
+		// own namespace
+		{"10.240.0.1", "svc.tenant-a1.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		// same tenant
+		{"10.240.0.1", "svc.tenant-a2.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.1", "_http._tcp.svc.tenant-a2.svc.cluster.local.", dns.TypeSRV, dns.RcodeSuccess},
+		// other tenant
+		{"10.240.0.1", "svc.tenant-b.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.1", "_http._tcp.svc.tenant-b.svc.cluster.local.", dns.TypeSRV, dns.RcodeNameError},
+		{"10.240.0.2", "svc.tenant-a1.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.2", "3.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess},
+		{"10.240.0.2", "1.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeNameError},
+		// namespaces without a policy are visible to all
+		{"10.240.0.2", "svc.public.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.9", "svc.public.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		// but have no access to the namespaces with a policy
+		{"10.240.0.3", "svc.tenant-b.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+		// nor have unknown clients
+		{"10.240.0.9", "svc.tenant-a1.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+	}
+
+	ctx := context.Background()
+	for _, annotation := range []bool{false, true} {
+		k := kubernetesWithPolicy(ctx, t, annotation)
+		go k.APIConn.Run()
+		for !k.APIConn.HasSynced() {
+			time.Sleep(time.Millisecond)
+		}
+
+		for i, tc := range tests {
+			m := new(dns.Msg)
+			m.SetQuestion(tc.qname, tc.qtype)
+			w := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.client})
+			if _, err := k.ServeDNS(ctx, w, m); err != nil {
+				t.Errorf("Test %d: expected no error, got %v", i, err)
+				continue
+			}
+			if w.Msg == nil {
+				t.Errorf("Test %d: got nil message", i)
+				continue
+			}
+			if w.Msg.Rcode != tc.rcode {
+				t.Errorf("Test %d (annotation %t): expected rcode %s for %s from %s, got %s", i, annotation, dns.RcodeToString[tc.rcode], tc.qname, tc.client, dns.RcodeToString[w.Msg.Rcode])
+			}
+			if tc.rcode == dns.RcodeSuccess && len(w.Msg.Answer) == 0 {
+				t.Errorf("Test %d (annotation %t): expected an answer for %s from %s", i, annotation, tc.qname, tc.client)
+			}
+		}
+		k.APIConn.Stop()
+	}
+}
+
+func TestNamespacePolicyExternal(t *testing.T) {
+	tests := []struct {
+		client string
+		qname  string
+		qtype  uint16
+		rcode  int
+	}{
+		{"10.240.0.1", "svc.tenant-a1.example.org.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.1", "svc.tenant-a2.example.org.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.1", "svc.tenant-b.example.org.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.1", "_http._tcp.svc.tenant-b.example.org.", dns.TypeSRV, dns.RcodeNameError},
+		{"10.240.0.9", "svc.tenant-a1.example.org.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.9", "svc.public.example.org.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.2", "3.2.0.192.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess},
+		{"10.240.0.2", "1.2.0.192.in-addr.arpa.", dns.TypePTR, dns.RcodeNameError},
+	}
+
+	ctx := context.Background()
+	k := kubernetesWithPolicy(ctx, t, false)
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		state := request.Request{W: &test.ResponseWriter{RemoteIP: tc.client}, Req: m, Zone: "example.org."}
+		if tc.qtype == dns.TypePTR {
+			state.Zone = "in-addr.arpa."
+		}
+		svcs, rcode := k.External(ctx, state, false)
+		if rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s for %s from %s, got %s", i, dns.RcodeToString[tc.rcode], tc.qname, tc.client, dns.RcodeToString[rcode])
+		}
+		if tc.rcode == dns.RcodeSuccess && len(svcs) == 0 {
+			t.Errorf("Test %d: expected services for %s from %s", i, tc.qname, tc.client)
+		}
+	}
+}
+
+func TestNamespacePolicyTransfer(t *testing.T) {
+	ctx := context.Background()
+	k := kubernetesWithPolicy(ctx, t, false)
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+
+	ch, err := k.Transfer("cluster.local.", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	public := false
+	for rrs := range ch {
+		for _, rr := range rrs {
+			if strings.Contains(rr.Header().Name, "tenant-") {
+				t.Errorf("Expected no records of namespaces with a policy in the transfer, got %s", rr)
+			}
+			if strings.HasSuffix(rr.Header().Name, ".public.svc.cluster.local.") {
+				public = true
+			}
+		}
+	}
+	if !public {
+		t.Errorf("Expected the records of the namespace without a policy in the transfer")
+	}
+
+	svcs, _ := k.ExternalServices("example.org.", false)
+	if len(svcs) == 0 {
+		t.Errorf("Expected the external services of the namespace without a policy")
+	}
+	for _, s := range svcs {
+		if strings.Contains(s.Key, "tenant-") {
+			t.Errorf("Expected no external services of namespaces with a policy, got %s", s.Key)
+		}
+	}
+}
+
+func TestNamespacePolicyCache(t *testing.T) {
+	tests := []struct {
+		client string
+		qname  string
+		qtype  uint16
+		rcode  int
+	}{
+		{"10.240.0.1", "svc.tenant-a1.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.2", "svc.tenant-a1.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.2", "svc.tenant-b.svc.cluster.local.", dns.TypeA, dns.RcodeSuccess},
+		{"10.240.0.1", "svc.tenant-b.svc.cluster.local.", dns.TypeA, dns.RcodeNameError},
+		{"10.240.0.1", "3.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeNameError},
+		{"10.240.0.2", "3.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess},
+		{"10.240.0.2", "1.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeNameError},
+		{"10.240.0.1", "1.0.0.10.in-addr.arpa.", dns.TypePTR, dns.RcodeSuccess},
+	}
+
+	ctx := context.Background()
+	k := kubernetesWithPolicy(ctx, t, false)
+	go k.APIConn.Run()
+	defer k.APIConn.Stop()
+	for !k.APIConn.HasSynced() {
+		time.Sleep(time.Millisecond)
+	}
+	c := cache.New()
+	c.Next = k
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		w := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.client})
+		if _, err := c.ServeDNS(ctx, w, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+			continue
+		}
+		if w.Msg == nil || w.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s for %s from %s, got %v", i, dns.RcodeToString[tc.rcode], tc.qname, tc.client, w.Msg)
+		}
+	}
+}
+
+func TestSharePolicyGroup(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"tenant-a", "tenant-a", true},
+		{"tenant-a", "tenant-b", false},
+		{"tenant-a,shared", "tenant-b, shared", true},
+		{"", "", false},
+		{"tenant-a,", ",tenant-b", false},
+	}
+	for i, tc := range tests {
+		if got := sharePolicyGroup(tc.a, tc.b); got != tc.expected {
+			t.Errorf("Test %d: expected %t for %q and %q, got %t", i, tc.expected, tc.a, tc.b, got)
+		}
+	}
+}
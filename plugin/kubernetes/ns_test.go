@@ -90,6 +90,12 @@ func (APIConnTest) EpIndexReverse(ip string) []*object.Endpoints {
 	}
 	return eps
 }
+func (APIConnTest) SvcImportIndex(string) []*object.ServiceImport    { return nil }
+func (APIConnTest) McEpIndex(string) []*object.MultiClusterEndpoints { return nil }
+func (APIConnTest) GetNode(string) (*object.Node, error)             { return nil, nil }
+func (APIConnTest) WatchHosts(bool, bool)                            {}
+func (APIConnTest) HostIndex(string) []object.Host                   { return nil }
+func (APIConnTest) HostList() []object.Host                          { return nil }
 
 func (APIConnTest) GetNodeByName(ctx context.Context, name string) (*api.Node, error) {
 	return &api.Node{}, nil
@@ -34,6 +34,10 @@ type EndpointAddress struct {
 	Hostname      string
 	NodeName      string
 	TargetRefName string
+	// Zone is the zone the endpoint is in.
+	Zone string
+	// ForZones are the zones the endpoint should be used for, from the topology hints.
+	ForZones []string
 }
 
 // EndpointPort is a tuple that describes a single port.
@@ -99,6 +103,14 @@ func EndpointSliceToEndpoints(obj meta.Object) (meta.Object, error) {
 			if end.NodeName != nil {
 				ea.NodeName = *end.NodeName
 			}
+			if end.Zone != nil {
+				ea.Zone = *end.Zone
+			}
+			if end.Hints != nil {
+				for _, z := range end.Hints.ForZones {
+					ea.ForZones = append(ea.ForZones, z.Name)
+				}
+			}
 			e.Subsets[0].Addresses = append(e.Subsets[0].Addresses, ea)
 			e.IndexIP = append(e.IndexIP, a)
 		}
@@ -151,7 +163,11 @@ func (e *Endpoints) DeepCopyObject() runtime.Object {
 			Ports:     make([]EndpointPort, len(eps.Ports)),
 		}
 		for j, a := range eps.Addresses {
-			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName}
+			ea := EndpointAddress{IP: a.IP, Hostname: a.Hostname, NodeName: a.NodeName, TargetRefName: a.TargetRefName, Zone: a.Zone}
+			if a.ForZones != nil {
+				ea.ForZones = make([]string, len(a.ForZones))
+				copy(ea.ForZones, a.ForZones)
+			}
 			sub.Addresses[j] = ea
 		}
 		for k, p := range eps.Ports {
@@ -0,0 +1,324 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+	networking "k8s.io/api/networking/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	// GatewayResource is the resource of the Gateways of the Gateway API.
+	GatewayResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}
+	// HTTPRouteResource is the resource of the HTTPRoutes of the Gateway API.
+	HTTPRouteResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+)
+
+// Host is a hostname declared on an Ingress, Gateway or HTTPRoute, together with the load balancer
+// addresses it resolves to.
+type Host struct {
+	Name      string
+	Namespace string // namespace of the object declaring the hostname.
+	Addresses []string
+}
+
+// Ingress is a stripped down networking.Ingress with only the items we need for CoreDNS.
+type Ingress struct {
+	Version   string
+	Name      string
+	Namespace string
+	Hostnames []string
+	Addresses []string
+
+	*Empty
+}
+
+// ToIngress converts a networking.Ingress to a *Ingress.
+func ToIngress(obj meta.Object) (meta.Object, error) {
+	ing, ok := obj.(*networking.Ingress)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	i := &Ingress{
+		Version:   ing.GetResourceVersion(),
+		Name:      ing.GetName(),
+		Namespace: ing.GetNamespace(),
+	}
+	for _, r := range ing.Spec.Rules {
+		if h := hostname(r.Host); h != "" {
+			i.Hostnames = append(i.Hostnames, h)
+		}
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.IP != "" {
+			i.Addresses = append(i.Addresses, lb.IP)
+			continue
+		}
+		if lb.Hostname != "" {
+			i.Addresses = append(i.Addresses, lb.Hostname)
+		}
+	}
+	*ing = networking.Ingress{}
+	return i, nil
+}
+
+var _ runtime.Object = &Ingress{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (i *Ingress) DeepCopyObject() runtime.Object {
+	i1 := &Ingress{
+		Version:   i.Version,
+		Name:      i.Name,
+		Namespace: i.Namespace,
+		Hostnames: make([]string, len(i.Hostnames)),
+		Addresses: make([]string, len(i.Addresses)),
+	}
+	copy(i1.Hostnames, i.Hostnames)
+	copy(i1.Addresses, i.Addresses)
+	return i1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (i *Ingress) GetNamespace() string { return i.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (i *Ingress) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (i *Ingress) GetName() string { return i.Name }
+
+// SetName implements the metav1.Object interface.
+func (i *Ingress) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) GetResourceVersion() string { return i.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (i *Ingress) SetResourceVersion(version string) {}
+
+// Gateway is a stripped down Gateway API Gateway with only the items we need for CoreDNS.
+type Gateway struct {
+	Version   string
+	Name      string
+	Namespace string
+	Hostnames []string // hostnames of the listeners.
+	Addresses []string
+
+	*Empty
+}
+
+// ToGateway converts an unstructured Gateway to a *Gateway.
+func ToGateway(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	g := &Gateway{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+	}
+
+	listeners, _, err := unstructured.NestedSlice(u.Object, "spec", "listeners")
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range listeners {
+		m, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if h, _, _ := unstructured.NestedString(m, "hostname"); hostname(h) != "" {
+			g.Hostnames = append(g.Hostnames, hostname(h))
+		}
+	}
+	addrs, _, err := unstructured.NestedSlice(u.Object, "status", "addresses")
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range addrs {
+		m, ok := a.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Both the IPAddress and Hostname types have the address in value.
+		if v, _, _ := unstructured.NestedString(m, "value"); v != "" {
+			g.Addresses = append(g.Addresses, v)
+		}
+	}
+
+	u.Object = nil
+	return g, nil
+}
+
+var _ runtime.Object = &Gateway{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (g *Gateway) DeepCopyObject() runtime.Object {
+	g1 := &Gateway{
+		Version:   g.Version,
+		Name:      g.Name,
+		Namespace: g.Namespace,
+		Hostnames: make([]string, len(g.Hostnames)),
+		Addresses: make([]string, len(g.Addresses)),
+	}
+	copy(g1.Hostnames, g.Hostnames)
+	copy(g1.Addresses, g.Addresses)
+	return g1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (g *Gateway) GetNamespace() string { return g.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (g *Gateway) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (g *Gateway) GetName() string { return g.Name }
+
+// SetName implements the metav1.Object interface.
+func (g *Gateway) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) GetResourceVersion() string { return g.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (g *Gateway) SetResourceVersion(version string) {}
+
+// HTTPRoute is a stripped down Gateway API HTTPRoute with only the items we need for CoreDNS.
+type HTTPRoute struct {
+	Version   string
+	Name      string
+	Namespace string
+	Hostnames []string
+	Parents   []string // the parent Gateways that accepted the route, as namespace/name.
+
+	*Empty
+}
+
+// ToHTTPRoute converts an unstructured HTTPRoute to a *HTTPRoute.
+func ToHTTPRoute(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	r := &HTTPRoute{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+	}
+
+	hosts, _, err := unstructured.NestedStringSlice(u.Object, "spec", "hostnames")
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range hosts {
+		if h := hostname(h); h != "" {
+			r.Hostnames = append(r.Hostnames, h)
+		}
+	}
+	// Only the parents that accepted the route, as reported by their controllers, serve it.
+	parents, _, err := unstructured.NestedSlice(u.Object, "status", "parents")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range parents {
+		m, ok := p.(map[string]interface{})
+		if !ok || !accepted(m) {
+			continue
+		}
+		ref, _, _ := unstructured.NestedMap(m, "parentRef")
+		// Only Gateways have addresses.
+		if kind, ok, _ := unstructured.NestedString(ref, "kind"); ok && kind != "Gateway" {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(ref, "name")
+		if name == "" {
+			continue
+		}
+		namespace, ok, _ := unstructured.NestedString(ref, "namespace")
+		if !ok || namespace == "" {
+			namespace = r.Namespace
+		}
+		r.Parents = appendParent(r.Parents, namespace+"/"+name)
+	}
+
+	u.Object = nil
+	return r, nil
+}
+
+var _ runtime.Object = &HTTPRoute{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (r *HTTPRoute) DeepCopyObject() runtime.Object {
+	r1 := &HTTPRoute{
+		Version:   r.Version,
+		Name:      r.Name,
+		Namespace: r.Namespace,
+		Hostnames: make([]string, len(r.Hostnames)),
+		Parents:   make([]string, len(r.Parents)),
+	}
+	copy(r1.Hostnames, r.Hostnames)
+	copy(r1.Parents, r.Parents)
+	return r1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) GetNamespace() string { return r.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (r *HTTPRoute) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (r *HTTPRoute) GetName() string { return r.Name }
+
+// SetName implements the metav1.Object interface.
+func (r *HTTPRoute) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) GetResourceVersion() string { return r.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (r *HTTPRoute) SetResourceVersion(version string) {}
+
+// appendParent appends parent to parents, unless it's already there. Several controllers may report on
+// the same parent.
+func appendParent(parents []string, parent string) []string {
+	for _, p := range parents {
+		if p == parent {
+			return parents
+		}
+	}
+	return append(parents, parent)
+}
+
+// accepted returns true if the route parent status m has an Accepted condition that is True.
+func accepted(m map[string]interface{}) bool {
+	conditions, _, _ := unstructured.NestedSlice(m, "conditions")
+	for _, c := range conditions {
+		c, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		typ, _, _ := unstructured.NestedString(c, "type")
+		status, _, _ := unstructured.NestedString(c, "status")
+		if typ == "Accepted" {
+			return status == "True"
+		}
+	}
+	return false
+}
+
+// hostname returns h as a lower cased fully qualified domain name. Wildcard hostnames can't be served and
+// are returned as the empty string.
+func hostname(h string) string {
+	if h == "" || strings.HasPrefix(h, "*") {
+		return ""
+	}
+	return dns.Fqdn(strings.ToLower(h))
+}
@@ -0,0 +1,164 @@
+package object
+
+import (
+	"fmt"
+
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ServiceImportResource is the resource of the ServiceImports of the Multi-Cluster Services API (KEP-1645).
+var ServiceImportResource = schema.GroupVersionResource{Group: "multicluster.x-k8s.io", Version: "v1alpha1", Resource: "serviceimports"}
+
+const (
+	// LabelMultiClusterServiceName is the label on an EndpointSlice of an imported service that holds
+	// the name of the ServiceImport.
+	LabelMultiClusterServiceName = "multicluster.kubernetes.io/service-name"
+	// LabelSourceCluster is the label on an EndpointSlice of an imported service that holds the
+	// cluster ID of the cluster the endpoints are in.
+	LabelSourceCluster = "multicluster.kubernetes.io/source-cluster"
+
+	// ServiceImportClusterSetIP is the type of a ServiceImport with cluster set IPs.
+	ServiceImportClusterSetIP = "ClusterSetIP"
+	// ServiceImportHeadless is the type of a ServiceImport of a headless service.
+	ServiceImportHeadless = "Headless"
+)
+
+// ServiceImport is a stripped down ServiceImport with only the items we need for CoreDNS.
+type ServiceImport struct {
+	Version    string
+	Name       string
+	Namespace  string
+	Index      string
+	ClusterIPs []string
+	Type       string
+	Ports      []api.ServicePort
+
+	*Empty
+}
+
+// ToServiceImport converts an unstructured ServiceImport to a *ServiceImport.
+func ToServiceImport(obj meta.Object) (meta.Object, error) {
+	u, ok := obj.(*unstructured.Unstructured)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	s := &ServiceImport{
+		Version:   u.GetResourceVersion(),
+		Name:      u.GetName(),
+		Namespace: u.GetNamespace(),
+		Index:     ServiceKey(u.GetName(), u.GetNamespace()),
+	}
+
+	var err error
+	if s.Type, _, err = unstructured.NestedString(u.Object, "spec", "type"); err != nil {
+		return nil, err
+	}
+	if s.ClusterIPs, _, err = unstructured.NestedStringSlice(u.Object, "spec", "ips"); err != nil {
+		return nil, err
+	}
+	ports, _, err := unstructured.NestedSlice(u.Object, "spec", "ports")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range ports {
+		m, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		port := api.ServicePort{Protocol: api.ProtocolTCP}
+		if name, ok, _ := unstructured.NestedString(m, "name"); ok {
+			port.Name = name
+		}
+		if proto, ok, _ := unstructured.NestedString(m, "protocol"); ok {
+			port.Protocol = api.Protocol(proto)
+		}
+		if n, ok, _ := unstructured.NestedInt64(m, "port"); ok {
+			port.Port = int32(n)
+		}
+		s.Ports = append(s.Ports, port)
+	}
+	if len(s.Ports) == 0 {
+		// Add sentinel if there are no ports.
+		s.Ports = []api.ServicePort{{Port: -1}}
+	}
+
+	u.Object = nil
+
+	return s, nil
+}
+
+// Headless returns true if the imported service is headless.
+func (s *ServiceImport) Headless() bool { return s.Type == ServiceImportHeadless }
+
+var _ runtime.Object = &ServiceImport{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (s *ServiceImport) DeepCopyObject() runtime.Object {
+	s1 := &ServiceImport{
+		Version:    s.Version,
+		Name:       s.Name,
+		Namespace:  s.Namespace,
+		Index:      s.Index,
+		Type:       s.Type,
+		ClusterIPs: make([]string, len(s.ClusterIPs)),
+		Ports:      make([]api.ServicePort, len(s.Ports)),
+	}
+	copy(s1.ClusterIPs, s.ClusterIPs)
+	copy(s1.Ports, s.Ports)
+	return s1
+}
+
+// GetNamespace implements the metav1.Object interface.
+func (s *ServiceImport) GetNamespace() string { return s.Namespace }
+
+// SetNamespace implements the metav1.Object interface.
+func (s *ServiceImport) SetNamespace(namespace string) {}
+
+// GetName implements the metav1.Object interface.
+func (s *ServiceImport) GetName() string { return s.Name }
+
+// SetName implements the metav1.Object interface.
+func (s *ServiceImport) SetName(name string) {}
+
+// GetResourceVersion implements the metav1.Object interface.
+func (s *ServiceImport) GetResourceVersion() string { return s.Version }
+
+// SetResourceVersion implements the metav1.Object interface.
+func (s *ServiceImport) SetResourceVersion(version string) {}
+
+// MultiClusterEndpoints is the Endpoints of an imported service in one of the clusters of the cluster set.
+type MultiClusterEndpoints struct {
+	Endpoints
+	ClusterID string
+}
+
+// EndpointSliceToMultiClusterEndpoints converts a *discovery.EndpointSlice of an imported service to
+// a *MultiClusterEndpoints.
+func EndpointSliceToMultiClusterEndpoints(obj meta.Object) (meta.Object, error) {
+	ends, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		return nil, fmt.Errorf("unexpected object %v", obj)
+	}
+	// EndpointSliceToEndpoints clears ends.
+	service, cluster := ends.Labels[LabelMultiClusterServiceName], ends.Labels[LabelSourceCluster]
+
+	o, err := EndpointSliceToEndpoints(ends)
+	if err != nil {
+		return nil, err
+	}
+	e := &MultiClusterEndpoints{Endpoints: *o.(*Endpoints), ClusterID: cluster}
+	e.Index = EndpointsKey(service, e.Namespace)
+	return e, nil
+}
+
+var _ runtime.Object = &MultiClusterEndpoints{}
+
+// DeepCopyObject implements the ObjectKind interface.
+func (e *MultiClusterEndpoints) DeepCopyObject() runtime.Object {
+	return &MultiClusterEndpoints{Endpoints: *e.Endpoints.DeepCopyObject().(*Endpoints), ClusterID: e.ClusterID}
+}
@@ -13,22 +13,38 @@ type Namespace struct {
 	// Don't add new fields to this struct without talking to the CoreDNS maintainers.
 	Version string
 	Name    string
+	Policy  string // the value of the label or annotation with the DNS policy, see ToNamespacePolicy.
 
 	*Empty
 }
 
 // ToNamespace returns a function that converts an api.Namespace to a *Namespace.
 func ToNamespace(obj meta.Object) (meta.Object, error) {
-	ns, ok := obj.(*api.Namespace)
-	if !ok {
-		return nil, fmt.Errorf("unexpected object %v", obj)
-	}
-	n := &Namespace{
-		Version: ns.GetResourceVersion(),
-		Name:    ns.GetName(),
+	return ToNamespacePolicy("", false)(obj)
+}
+
+// ToNamespacePolicy returns a function that converts an api.Namespace to a *Namespace, with the Policy
+// set to the value of the label key, or the annotation key if annotation is true.
+func ToNamespacePolicy(key string, annotation bool) ToFunc {
+	return func(obj meta.Object) (meta.Object, error) {
+		ns, ok := obj.(*api.Namespace)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object %v", obj)
+		}
+		n := &Namespace{
+			Version: ns.GetResourceVersion(),
+			Name:    ns.GetName(),
+		}
+		if key != "" {
+			if annotation {
+				n.Policy = ns.GetAnnotations()[key]
+			} else {
+				n.Policy = ns.GetLabels()[key]
+			}
+		}
+		*ns = api.Namespace{}
+		return n, nil
 	}
-	*ns = api.Namespace{}
-	return n, nil
 }
 
 var _ runtime.Object = &Namespace{}
@@ -38,6 +54,7 @@ func (n *Namespace) DeepCopyObject() runtime.Object {
 	n1 := &Namespace{
 		Version: n.Version,
 		Name:    n.Name,
+		Policy:  n.Policy,
 	}
 	return n1
 }
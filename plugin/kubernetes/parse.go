@@ -1,6 +1,8 @@
 package kubernetes
 
 import (
+	"strings"
+
 	"github.com/coredns/coredns/plugin/pkg/dnsutil"
 
 	"github.com/miekg/dns"
@@ -14,6 +16,8 @@ type recordRequest struct {
 	// SRV record.
 	protocol string
 	endpoint string
+	// The cluster ID of the endpoint, only used in multicluster zones.
+	cluster string
 	// The servicename used in Kubernetes.
 	service string
 	// The namespace used in Kubernetes.
@@ -25,11 +29,15 @@ type recordRequest struct {
 // parseRequest parses the qname to find all the elements we need for querying k8s. Anything
 // that is not parsed will have the wildcard "*" value (except r.endpoint).
 // Potential underscores are stripped from _port and _protocol.
-func parseRequest(name, zone string) (r recordRequest, err error) {
+// If multicluster is true the zone is a multicluster zone (i.e. clusterset.local) which only has
+// services and qualifies endpoints with the cluster ID.
+func parseRequest(name, zone string, multicluster bool) (r recordRequest, err error) {
 	// 3 Possible cases:
 	// 1. _port._protocol.service.namespace.pod|svc.zone
 	// 2. (endpoint): endpoint.service.namespace.pod|svc.zone
 	// 3. (service): service.namespace.pod|svc.zone
+	//
+	// In a multicluster zone case 2 is endpoint.cluster.service.namespace.svc.zone
 
 	base, _ := dnsutil.TrimZone(name, zone)
 	// return NODATA for apex queries
@@ -46,6 +54,9 @@ func parseRequest(name, zone string) (r recordRequest, err error) {
 	if r.podOrSvc != Pod && r.podOrSvc != Svc {
 		return r, errInvalidRequest
 	}
+	if multicluster && r.podOrSvc == Pod {
+		return r, errInvalidRequest
+	}
 	last--
 	if last < 0 {
 		return r, nil
@@ -67,8 +78,17 @@ func parseRequest(name, zone string) (r recordRequest, err error) {
 	// Anything else is a query that is too long to answer and can safely be delegated to return an nxdomain.
 	switch last {
 	case 0: // endpoint only
+		if multicluster { // endpoints always come with a cluster
+			return r, errInvalidRequest
+		}
 		r.endpoint = segs[last]
-	case 1: // service and port
+	case 1:
+		if multicluster && !strings.HasPrefix(segs[last-1], "_") { // endpoint and cluster
+			r.cluster = segs[last]
+			r.endpoint = segs[last-1]
+			break
+		}
+		// service and port
 		r.protocol = stripUnderscore(segs[last])
 		r.port = stripUnderscore(segs[last-1])
 
@@ -31,7 +31,7 @@ func TestParseRequest(t *testing.T) {
 		m.SetQuestion(tc.query, dns.TypeA)
 		state := request.Request{Zone: zone, Req: m}
 
-		r, e := parseRequest(state.Name(), state.Zone)
+		r, e := parseRequest(state.Name(), state.Zone, false)
 		if e != nil {
 			t.Errorf("Test %d, expected no error, got '%v'.", i, e)
 		}
@@ -53,7 +53,42 @@ func TestParseInvalidRequest(t *testing.T) {
 		m.SetQuestion(query, dns.TypeA)
 		state := request.Request{Zone: zone, Req: m}
 
-		if _, e := parseRequest(state.Name(), state.Zone); e == nil {
+		if _, e := parseRequest(state.Name(), state.Zone, false); e == nil {
+			t.Errorf("Test %d: expected error from %s, got none", i, query)
+		}
+	}
+}
+
+func TestParseMultiClusterRequest(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected recordRequest
+	}{
+		// service
+		{"webs.mynamespace.svc.inter.webs.tests.", recordRequest{service: "webs", namespace: "mynamespace", podOrSvc: Svc}},
+		// valid SRV request
+		{"_http._tcp.webs.mynamespace.svc.inter.webs.tests.", recordRequest{port: "http", protocol: "tcp", service: "webs", namespace: "mynamespace", podOrSvc: Svc}},
+		// endpoint in a cluster
+		{"1-2-3-4.cluster1.webs.mynamespace.svc.inter.webs.tests.", recordRequest{endpoint: "1-2-3-4", cluster: "cluster1", service: "webs", namespace: "mynamespace", podOrSvc: Svc}},
+		// bare zone
+		{"inter.webs.tests.", recordRequest{}},
+	}
+	for i, tc := range tests {
+		r, e := parseRequest(tc.query, zone, true)
+		if e != nil {
+			t.Errorf("Test %d, expected no error, got '%v'.", i, e)
+		}
+		if r != tc.expected {
+			t.Errorf("Test %d, expected recordRequest: %+v, got %+v", i, tc.expected, r)
+		}
+	}
+
+	invalid := []string{
+		"1-2-3-4.webs.mynamespace.svc.inter.webs.tests.", // Endpoint without a cluster.
+		"1-2-3-4.mynamespace.pod.inter.webs.tests.",      // No pods in the cluster set.
+	}
+	for i, query := range invalid {
+		if _, e := parseRequest(query, zone, true); e == nil {
 			t.Errorf("Test %d: expected error from %s, got none", i, query)
 		}
 	}
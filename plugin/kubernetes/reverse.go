@@ -18,7 +18,7 @@ func (k *Kubernetes) Reverse(ctx context.Context, state request.Request, exact b
 		return nil, e
 	}
 
-	records := k.serviceRecordForIP(ip, state.Name())
+	records := k.serviceRecordForIP(ctx, state, ip)
 	if len(records) == 0 {
 		return records, errNoItems
 	}
@@ -27,12 +27,15 @@ func (k *Kubernetes) Reverse(ctx context.Context, state request.Request, exact b
 
 // serviceRecordForIP gets a service record with a cluster ip matching the ip argument
 // If a service cluster ip does not match, it checks all endpoints
-func (k *Kubernetes) serviceRecordForIP(ip, name string) []msg.Service {
+func (k *Kubernetes) serviceRecordForIP(ctx context.Context, state request.Request, ip string) []msg.Service {
 	// First check services with cluster ips
 	for _, service := range k.APIConn.SvcIndexReverse(ip) {
 		if len(k.Namespaces) > 0 && !k.namespaceExposed(service.Namespace) {
 			continue
 		}
+		if !k.namespaceAllowed(ctx, state, service.Namespace) {
+			continue
+		}
 		domain := strings.Join([]string{service.Name, service.Namespace, Svc, k.primaryZone()}, ".")
 		return []msg.Service{{Host: domain, TTL: k.ttl}}
 	}
@@ -42,6 +45,9 @@ func (k *Kubernetes) serviceRecordForIP(ip, name string) []msg.Service {
 		if len(k.Namespaces) > 0 && !k.namespaceExposed(ep.Namespace) {
 			continue
 		}
+		if !k.namespaceAllowed(ctx, state, ep.Namespace) {
+			continue
+		}
 		for _, eps := range ep.Subsets {
 			for _, addr := range eps.Addresses {
 				if addr.IP == ip {
@@ -133,6 +133,12 @@ func (APIConnReverseTest) EpIndexReverse(ip string) []*object.Endpoints {
 	}
 	return nil
 }
+func (APIConnReverseTest) SvcImportIndex(string) []*object.ServiceImport    { return nil }
+func (APIConnReverseTest) McEpIndex(string) []*object.MultiClusterEndpoints { return nil }
+func (APIConnReverseTest) GetNode(string) (*object.Node, error)             { return nil, nil }
+func (APIConnReverseTest) WatchHosts(bool, bool)                            {}
+func (APIConnReverseTest) HostIndex(string) []object.Host                   { return nil }
+func (APIConnReverseTest) HostList() []object.Host                          { return nil }
 
 func (APIConnReverseTest) GetNodeByName(ctx context.Context, name string) (*api.Node, error) {
 	return &api.Node{
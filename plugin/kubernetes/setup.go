@@ -184,6 +184,19 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				continue
 			}
 			return nil, c.ArgErr()
+		case "namespace_policy":
+			args := c.RemainingArgs()
+			if len(args) != 2 {
+				return nil, c.ArgErr()
+			}
+			switch args[0] {
+			case "label":
+			case "annotation":
+				k8s.opts.namespacePolicyAnnotation = true
+			default:
+				return nil, c.Errf("namespace_policy must be 'label' or 'annotation': %s", args[0])
+			}
+			k8s.opts.namespacePolicyKey = args[1]
 		case "fallthrough":
 			k8s.Fall.SetZonesFromArgs(c.RemainingArgs())
 		case "ttl":
@@ -229,6 +242,34 @@ func ParseStanza(c *caddy.Controller) (*Kubernetes, error) {
 				overrides,
 			)
 			k8s.ClientConfig = config
+		case "topology":
+			args := c.RemainingArgs()
+			switch len(args) {
+			case 0:
+				k8s.topology = topologyZone
+			case 1:
+				if args[0] != topologyZone && args[0] != topologyNode {
+					return nil, c.Errf("wrong value for topology: %s, must be one of: zone, node", args[0])
+				}
+				k8s.topology = args[0]
+			default:
+				return nil, c.ArgErr()
+			}
+		case "multicluster":
+			args := c.RemainingArgs()
+			if len(args) == 0 {
+				return nil, c.ArgErr()
+			}
+			for _, a := range args {
+				z := plugin.Name(a).Normalize()
+				if plugin.Zones(k8s.Zones).Matches(z) != z {
+					return nil, c.Errf("multicluster zone '%s' is not a zone of the plugin", a)
+				}
+				if dnsutil.IsReverse(z) > 0 {
+					return nil, c.Errf("multicluster zone '%s' can not be a reverse zone", a)
+				}
+				k8s.opts.multiclusterZones = append(k8s.opts.multiclusterZones, z)
+			}
 		default:
 			return nil, c.Errf("unknown property '%s'", c.Val())
 		}
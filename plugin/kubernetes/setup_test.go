@@ -610,3 +610,163 @@ func TestKubernetesParseIgnoreEmptyService(t *testing.T) {
 		}
 	}
 }
+
+func TestKubernetesParseMulticluster(t *testing.T) {
+	tests := []struct {
+		input              string // Corefile data as string
+		shouldErr          bool   // true if test case is expected to produce an error.
+		expectedErrContent string // substring from the expected error. Empty for positive cases.
+		expectedZones      []string
+	}{
+		// valid
+		{
+			`kubernetes cluster.local clusterset.local {
+	multicluster clusterset.local
+}`,
+			false,
+			"",
+			[]string{"clusterset.local."},
+		},
+		{
+			`kubernetes cluster.local clusterset.local example.org {
+	multicluster ClusterSet.local. example.org
+}`,
+			false,
+			"",
+			[]string{"clusterset.local.", "example.org."},
+		},
+		// invalid
+		{
+			`kubernetes cluster.local {
+	multicluster clusterset.local
+}`,
+			true,
+			"is not a zone of the plugin",
+			nil,
+		},
+		{
+			`kubernetes cluster.local {
+	multicluster
+}`,
+			true,
+			"Wrong argument count",
+			nil,
+		},
+		// not set
+		{
+			`kubernetes cluster.local clusterset.local {
+}`,
+			false,
+			"",
+			nil,
+		},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+
+		if test.shouldErr && err == nil {
+			t.Errorf("Test %d: Expected error, but did not find error for input '%s'. Error was: '%v'", i, test.input, err)
+		}
+
+		if err != nil {
+			if !test.shouldErr {
+				t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+				continue
+			}
+
+			if !strings.Contains(err.Error(), test.expectedErrContent) {
+				t.Errorf("Test %d: Expected error to contain: %v, found error: %v, input: %s", i, test.expectedErrContent, err, test.input)
+			}
+			continue
+		}
+
+		found := k8sController.opts.multiclusterZones
+		if strings.Join(found, ",") != strings.Join(test.expectedZones, ",") {
+			t.Errorf("Test %d: Expected multicluster zones %v, got %v for input '%s'", i, test.expectedZones, found, test.input)
+		}
+	}
+}
+
+func TestKubernetesParseTopology(t *testing.T) {
+	tests := []struct {
+		input            string // Corefile data as string
+		shouldErr        bool   // true if test case is expected to produce an error.
+		expectedTopology string
+	}{
+		{`kubernetes cluster.local {
+	topology
+}`, false, topologyZone},
+		{`kubernetes cluster.local {
+	topology node
+}`, false, topologyNode},
+		{`kubernetes cluster.local {
+	topology region
+}`, true, ""},
+		{`kubernetes cluster.local {
+	topology zone node
+}`, true, ""},
+		{`kubernetes cluster.local`, false, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, but did not find error for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+		if k8sController.topology != test.expectedTopology {
+			t.Errorf("Test %d: Expected topology %q, got %q for input '%s'", i, test.expectedTopology, k8sController.topology, test.input)
+		}
+	}
+}
+
+func TestKubernetesParseNamespacePolicy(t *testing.T) {
+	tests := []struct {
+		input              string // Corefile data as string
+		shouldErr          bool   // true if test case is expected to produce an error.
+		expectedKey        string
+		expectedAnnotation bool
+	}{
+		{`kubernetes cluster.local {
+	namespace_policy label dns.coredns.io/policy
+}`, false, "dns.coredns.io/policy", false},
+		{`kubernetes cluster.local {
+	namespace_policy annotation dns.coredns.io/policy
+}`, false, "dns.coredns.io/policy", true},
+		{`kubernetes cluster.local {
+	namespace_policy selector dns.coredns.io/policy
+}`, true, "", false},
+		{`kubernetes cluster.local {
+	namespace_policy label
+}`, true, "", false},
+		{`kubernetes cluster.local`, false, "", false},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		k8sController, err := kubernetesParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: Expected error, but did not find error for input '%s'", i, test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: Expected no error but found one for input %s. Error was: %v", i, test.input, err)
+			continue
+		}
+		if k8sController.opts.namespacePolicyKey != test.expectedKey || k8sController.opts.namespacePolicyAnnotation != test.expectedAnnotation {
+			t.Errorf("Test %d: Expected namespace policy %q (annotation %t), got %q (annotation %t) for input '%s'", i, test.expectedKey, test.expectedAnnotation,
+				k8sController.opts.namespacePolicyKey, k8sController.opts.namespacePolicyAnnotation, test.input)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package kubernetes
+
+import (
+	"github.com/coredns/coredns/plugin/kubernetes/object"
+	"github.com/coredns/coredns/request"
+)
+
+const (
+	// topologyZone prefers the endpoints in the zone of the client.
+	topologyZone = "zone"
+	// topologyNode prefers the endpoints on the node of the client, and then those in its zone.
+	topologyNode = "node"
+)
+
+// locality is where a client runs: the node of its pod and the zone of that node.
+type locality struct {
+	node string
+	zone string
+}
+
+// clientLocality returns the locality of the pod that sent the query. It is empty if topology aware
+// answers are disabled or the pod isn't known.
+func (k *Kubernetes) clientLocality(state request.Request) (l locality) {
+	if k.topology == "" {
+		return l
+	}
+	pods := k.APIConn.PodIndex(state.IP())
+	if len(pods) == 0 || pods[0].NodeName == "" {
+		return l
+	}
+	l.node = pods[0].NodeName
+	if node, err := k.APIConn.GetNode(l.node); err == nil && node != nil {
+		l.zone = node.Zone
+	}
+	return l
+}
+
+// localAddresses returns the IP addresses of the endpoints in eps (of the service with index idx) that
+// are close to l. A nil map means all endpoints should be used, this is also the case when none of the
+// endpoints is local.
+//
+// When every endpoint has topology hints, as set by the EndpointSlice controller for a service with
+// "trafficDistribution: PreferClose" or topology aware routing, the hints define the endpoints of a zone.
+// Otherwise it's the endpoints that are in that zone.
+func (k *Kubernetes) localAddresses(eps []*object.Endpoints, idx string, l locality) map[string]struct{} {
+	if l.node == "" {
+		return nil
+	}
+
+	var addrs []object.EndpointAddress
+	for _, ep := range eps {
+		if ep.Index != idx {
+			continue
+		}
+		for _, s := range ep.Subsets {
+			addrs = append(addrs, s.Addresses...)
+		}
+	}
+
+	if k.topology == topologyNode {
+		if local := filterAddresses(addrs, func(a object.EndpointAddress) bool { return a.NodeName == l.node }); local != nil {
+			return local
+		}
+	}
+
+	if l.zone == "" {
+		return nil
+	}
+	hints := len(addrs) > 0
+	for _, a := range addrs {
+		if len(a.ForZones) == 0 {
+			hints = false
+			break
+		}
+	}
+	if hints {
+		return filterAddresses(addrs, func(a object.EndpointAddress) bool {
+			for _, z := range a.ForZones {
+				if z == l.zone {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	return filterAddresses(addrs, func(a object.EndpointAddress) bool { return a.Zone == l.zone })
+}
+
+// filterAddresses returns the IP addresses of addrs for which f returns true, or nil if there are none.
+func filterAddresses(addrs []object.EndpointAddress, f func(object.EndpointAddress) bool) map[string]struct{} {
+	var local map[string]struct{}
+	for _, a := range addrs {
+		if !f(a) {
+			continue
+		}
+		if local == nil {
+			local = make(map[string]struct{})
+		}
+		local[a.IP] = struct{}{}
+	}
+	return local
+}
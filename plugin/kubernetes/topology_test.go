@@ -0,0 +1,150 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+	api "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func topologyEndpoint(ip, node, zone string, forZones ...string) discovery.Endpoint {
+	e := discovery.Endpoint{Addresses: []string{ip}, NodeName: &node, Zone: &zone}
+	if len(forZones) > 0 {
+		e.Hints = &discovery.EndpointHints{}
+		for _, z := range forZones {
+			e.Hints.ForZones = append(e.Hints.ForZones, discovery.ForZone{Name: z})
+		}
+	}
+	return e
+}
+
+func kubernetesWithTopology(ctx context.Context, t *testing.T, topology string) *Kubernetes {
+	client := fake.NewSimpleClientset()
+
+	if _, err := client.CoreV1().Namespaces().Create(ctx, &api.Namespace{ObjectMeta: meta.ObjectMeta{Name: "testns"}}, meta.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range []struct{ name, zone string }{{"node1", "zone-a"}, {"node2", "zone-b"}, {"node3", "zone-a"}, {"node4", "zone-c"}} {
+		n := &api.Node{ObjectMeta: meta.ObjectMeta{Name: node.name, Labels: map[string]string{api.LabelTopologyZone: node.zone}}}
+		if _, err := client.CoreV1().Nodes().Create(ctx, n, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, pod := range []struct{ name, ip, node string }{{"client1", "10.240.0.1", "node1"}, {"client3", "10.240.0.3", "node3"}, {"client4", "10.240.0.4", "node4"}} {
+		p := &api.Pod{
+			ObjectMeta: meta.ObjectMeta{Name: pod.name, Namespace: "testns"},
+			Spec:       api.PodSpec{NodeName: pod.node},
+			Status:     api.PodStatus{PodIP: pod.ip},
+		}
+		if _, err := client.CoreV1().Pods("testns").Create(ctx, p, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"hdls1", "hinted"} {
+		svc := &api.Service{
+			ObjectMeta: meta.ObjectMeta{Name: name, Namespace: "testns"},
+			Spec:       api.ServiceSpec{ClusterIP: api.ClusterIPNone},
+		}
+		if _, err := client.CoreV1().Services("testns").Create(ctx, svc, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, eps := range []*discovery.EndpointSlice{
+		{
+			ObjectMeta: meta.ObjectMeta{Name: "hdls1-1", Namespace: "testns", Labels: map[string]string{discovery.LabelServiceName: "hdls1"}},
+			Endpoints: []discovery.Endpoint{
+				topologyEndpoint("172.0.0.1", "node1", "zone-a"),
+				topologyEndpoint("172.0.0.2", "node2", "zone-b"),
+				topologyEndpoint("172.0.0.3", "node3", "zone-a"),
+			},
+		},
+		{
+			ObjectMeta: meta.ObjectMeta{Name: "hinted-1", Namespace: "testns", Labels: map[string]string{discovery.LabelServiceName: "hinted"}},
+			Endpoints: []discovery.Endpoint{
+				topologyEndpoint("172.0.1.1", "node1", "zone-a", "zone-a"),
+				topologyEndpoint("172.0.1.2", "node2", "zone-b", "zone-a", "zone-b"),
+			},
+		},
+	} {
+		if _, err := client.DiscoveryV1().EndpointSlices("testns").Create(ctx, eps, meta.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	k := New([]string{"cluster.local."})
+	k.topology = topology
+	k.opts.initEndpointsCache = true
+	k.opts.initPodCache = true
+	k.opts.initNodeCache = true
+	k.APIConn = newdnsController(ctx, client, nil, k.opts)
+	return k
+}
+
+func TestTopology(t *testing.T) {
+	tests := []struct {
+		topology string
+		client   string
+		qname    string
+		expected []string
+	}{
+		// unknown client, all endpoints
+		{topologyZone, "10.240.0.9", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3"}},
+		// endpoints in zone-a
+		{topologyZone, "10.240.0.1", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.3"}},
+		// no endpoints in zone-c, all endpoints
+		{topologyZone, "10.240.0.4", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3"}},
+		// the hints for zone-a
+		{topologyZone, "10.240.0.1", "hinted.testns.svc.cluster.local.", []string{"172.0.1.1", "172.0.1.2"}},
+		// the endpoint on node1
+		{topologyNode, "10.240.0.1", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.1"}},
+		// the endpoint on node3
+		{topologyNode, "10.240.0.3", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.3"}},
+		// nothing on node3, the hints for zone-a
+		{topologyNode, "10.240.0.3", "hinted.testns.svc.cluster.local.", []string{"172.0.1.1", "172.0.1.2"}},
+		// disabled
+		{"", "10.240.0.1", "hdls1.testns.svc.cluster.local.", []string{"172.0.0.1", "172.0.0.2", "172.0.0.3"}},
+	}
+
+	ctx := context.Background()
+	for i, tc := range tests {
+		k := kubernetesWithTopology(ctx, t, tc.topology)
+		go k.APIConn.Run()
+		for !k.APIConn.HasSynced() {
+			time.Sleep(time.Millisecond)
+		}
+
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		w := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: tc.client})
+		if _, err := k.ServeDNS(ctx, w, m); err != nil {
+			t.Errorf("Test %d: expected no error, got %v", i, err)
+		}
+		k.APIConn.Stop()
+
+		resp := w.Msg
+		if resp == nil {
+			t.Fatalf("Test %d: got nil message", i)
+		}
+		got := map[string]bool{}
+		for _, rr := range resp.Answer {
+			got[rr.(*dns.A).A.String()] = true
+		}
+		if len(got) != len(tc.expected) {
+			t.Errorf("Test %d: expected %v, got %v", i, tc.expected, resp.Answer)
+			continue
+		}
+		for _, ip := range tc.expected {
+			if !got[ip] {
+				t.Errorf("Test %d: expected %s in the answer, got %v", i, ip, resp.Answer)
+			}
+		}
+	}
+}
@@ -22,6 +22,10 @@ func (k *Kubernetes) Transfer(zone string, serial uint32) (<-chan []dns.RR, erro
 	if match == "" {
 		return nil, transfer.ErrNotAuthoritative
 	}
+	// The cluster set isn't transferred, the imported services are owned by the clusters exporting them.
+	if k.isMultiClusterZone(match) {
+		return nil, transfer.ErrNotAuthoritative
+	}
 	// state is not used here, hence the empty request.Request{]
 	soa, err := plugin.SOA(context.TODO(), k, zone, request.Request{}, plugin.Options{})
 	if err != nil {
@@ -58,7 +62,7 @@ func (k *Kubernetes) Transfer(zone string, serial uint32) (<-chan []dns.RR, erro
 		})
 
 		for _, svc := range serviceList {
-			if !k.namespaceExposed(svc.Namespace) {
+			if !k.namespaceExposed(svc.Namespace) || k.namespacePolicy(svc.Namespace) != "" {
 				continue
 			}
 			svcBase := []string{zonePath, Svc, svc.Namespace, svc.Name}
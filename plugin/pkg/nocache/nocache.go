@@ -0,0 +1,31 @@
+// Package nocache lets a plugin tell the cache plugin that its response must not be cached, because
+// it depends on more than the question, e.g. on the client that sent the query.
+package nocache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type key struct{}
+
+// Flag records if the response to a query may be cached.
+type Flag struct {
+	set atomic.Bool
+}
+
+// NewContext returns a copy of ctx carrying a new Flag, and that Flag.
+func NewContext(ctx context.Context) (context.Context, *Flag) {
+	f := new(Flag)
+	return context.WithValue(ctx, key{}, f), f
+}
+
+// Set marks the response to the query of ctx as not cacheable. It is a noop if ctx has no Flag.
+func Set(ctx context.Context) {
+	if f, ok := ctx.Value(key{}).(*Flag); ok {
+		f.set.Store(true)
+	}
+}
+
+// IsSet returns true if the response must not be cached.
+func (f *Flag) IsSet() bool { return f != nil && f.set.Load() }
@@ -0,0 +1,25 @@
+package nocache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNoCache(t *testing.T) {
+	// Set is a noop without a Flag.
+	Set(context.TODO())
+
+	ctx, f := NewContext(context.TODO())
+	if f.IsSet() {
+		t.Fatal("Expected a new flag not to be set")
+	}
+	Set(ctx)
+	if !f.IsSet() {
+		t.Error("Expected the flag to be set")
+	}
+
+	var nilFlag *Flag
+	if nilFlag.IsSet() {
+		t.Error("Expected a nil flag not to be set")
+	}
+}
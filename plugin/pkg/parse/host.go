@@ -42,6 +42,15 @@ func HostPortOrFile(s ...string) ([]string, error) {
 			continue
 		}
 
+		if trans == transport.HTTPS {
+			ss, err := httpsHostPort(host)
+			if err != nil {
+				return servers, err
+			}
+			servers = append(servers, trans+"://"+ss)
+			continue
+		}
+
 		addr, _, err := net.SplitHostPort(host)
 
 		if err != nil {
@@ -88,6 +97,26 @@ func HostPortOrFile(s ...string) ([]string, error) {
 	return servers, nil
 }
 
+// httpsHostPort parses a DNS-over-HTTPS endpoint, which, unlike the other transports, may be a hostname
+// and may carry a URL path. The default port is added when missing and the path is kept as is.
+func httpsHostPort(s string) (string, error) {
+	host, path := s, ""
+	if i := strings.Index(s, "/"); i >= 0 {
+		host, path = s[:i], s[i:]
+	}
+
+	addr, port, err := net.SplitHostPort(host)
+	if err != nil {
+		addr, port = host, transport.HTTPSPort
+	}
+	if net.ParseIP(stripZone(addr)) == nil {
+		if _, ok := dns.IsDomainName(addr); !ok || strings.Contains(addr, ":") {
+			return "", fmt.Errorf("not an IP address or host name: %q", host)
+		}
+	}
+	return net.JoinHostPort(addr, port) + path, nil
+}
+
 // Try to open this is a file first.
 func tryFile(s string) ([]string, error) {
 	c, err := dns.ClientConfigFromFile(s)
@@ -68,6 +68,26 @@ func TestHostPortOrFile(t *testing.T) {
 			"",
 			true,
 		},
+		{
+			"https://1.1.1.1",
+			"https://1.1.1.1:443",
+			false,
+		},
+		{
+			"https://dns.example.org/dns-query",
+			"https://dns.example.org:443/dns-query",
+			false,
+		},
+		{
+			"https://[2001:db8::1]:8443/resolve",
+			"https://[2001:db8::1]:8443/resolve",
+			false,
+		},
+		{
+			"https://",
+			"",
+			true,
+		},
 	}
 
 	err := os.WriteFile("resolv.conf", []byte("nameserver 127.0.0.1\n"), 0600)
@@ -6,33 +6,83 @@ import (
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
 )
 
 // TransferIn parses transfer statements: 'transfer from [address...]'.
 func TransferIn(c *caddy.Controller) (froms []string, err error) {
+	froms, key, err := TransferInWithKey(c)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		return nil, c.Err("TSIG keys are not supported in transfer from")
+	}
+	for _, from := range froms {
+		if trans, _ := Transport(from); trans != transport.DNS {
+			return nil, c.Errf("transport %s is not supported in transfer from", trans)
+		}
+	}
+	return froms, nil
+}
+
+// TransferInWithKey parses transfer statements: 'transfer from [address...] [key NAME]'. The name of the TSIG
+// key is returned as a fully qualified lower case name, or empty if not given. Addresses prefixed with tls://
+// are transferred from over TLS (RFC 9103), they keep the prefix and default to port 853.
+func TransferInWithKey(c *caddy.Controller) (froms []string, key string, err error) {
 	if !c.NextArg() {
-		return nil, c.ArgErr()
+		return nil, "", c.ArgErr()
 	}
 	value := c.Val()
 	switch value {
 	default:
-		return nil, c.Errf("unknown property %s", value)
+		return nil, "", c.Errf("unknown property %s", value)
 	case "from":
-		froms = c.RemainingArgs()
+		froms, key, err = TSIGKey(c.RemainingArgs())
+		if err != nil {
+			return nil, "", c.Err(err.Error())
+		}
 		if len(froms) == 0 {
-			return nil, c.ArgErr()
+			return nil, "", c.ArgErr()
 		}
 		for i := range froms {
-			if froms[i] != "*" {
-				normalized, err := HostPort(froms[i], transport.Port)
+			if froms[i] == "*" {
+				return nil, "", fmt.Errorf("can't use '*' in transfer from")
+			}
+			trans, addr := Transport(froms[i])
+			switch trans {
+			case transport.DNS:
+				normalized, err := HostPort(addr, transport.Port)
 				if err != nil {
-					return nil, err
+					return nil, "", err
 				}
 				froms[i] = normalized
-			} else {
-				return nil, fmt.Errorf("can't use '*' in transfer from")
+			case transport.TLS:
+				normalized, err := HostPort(addr, transport.TLSPort)
+				if err != nil {
+					return nil, "", err
+				}
+				froms[i] = transport.TLS + "://" + normalized
+			default:
+				return nil, "", c.Errf("transport %s is not supported in transfer from", trans)
 			}
 		}
 	}
-	return froms, nil
+	return froms, key, nil
+}
+
+// TSIGKey splits the optional 'key NAME' at the end of args off. It returns the remaining arguments and the
+// name of the TSIG key as a fully qualified lower case name, or empty if there is none.
+func TSIGKey(args []string) ([]string, string, error) {
+	for i, a := range args {
+		if a != "key" {
+			continue
+		}
+		if i != len(args)-2 {
+			return nil, "", fmt.Errorf("key must be followed by a single key name")
+		}
+		return args[:i], dns.CanonicalName(args[i+1]), nil
+	}
+	return args, "", nil
 }
@@ -1,11 +1,62 @@
 package parse
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/coredns/caddy"
 )
 
+func TestTransferInWithKey(t *testing.T) {
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		expectedFrom []string
+		expectedKey  string
+	}{
+		{`from 127.0.0.1`, false, []string{"127.0.0.1:53"}, ""},
+		{`from 127.0.0.1 127.0.0.2 key Transfer.Key`, false, []string{"127.0.0.1:53", "127.0.0.2:53"}, "transfer.key."},
+		{`from key transfer.key`, true, nil, ""},
+		{`from 127.0.0.1 key`, true, nil, ""},
+		{`from 127.0.0.1 key a b`, true, nil, ""},
+		{`from 127.0.0.1 key a 127.0.0.2`, true, nil, ""},
+		{`from tls://127.0.0.1 tls://127.0.0.2:8853 127.0.0.3`, false, []string{"tls://127.0.0.1:853", "tls://127.0.0.2:8853", "127.0.0.3:53"}, ""},
+		{`from tls://127.0.0.1 key transfer.key`, false, []string{"tls://127.0.0.1:853"}, "transfer.key."},
+		{`from https://127.0.0.1`, true, nil, ""},
+		{`from tls://example.org`, true, nil, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		froms, key, err := TransferInWithKey(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d expected errors, but got no error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(froms, test.expectedFrom) {
+			t.Errorf("Test %d expected %v, got %v", i, test.expectedFrom, froms)
+		}
+		if key != test.expectedKey {
+			t.Errorf("Test %d expected key %q, got %q", i, test.expectedKey, key)
+		}
+	}
+
+	c := caddy.NewTestController("dns", `from 127.0.0.1 key transfer.key`)
+	if _, err := TransferIn(c); err == nil {
+		t.Errorf("Expected an error for a key in TransferIn")
+	}
+	c = caddy.NewTestController("dns", `from tls://127.0.0.1`)
+	if _, err := TransferIn(c); err == nil {
+		t.Errorf("Expected an error for TLS in TransferIn")
+	}
+}
+
 func TestTransferIn(t *testing.T) {
 	tests := []struct {
 		inputFileRules string
@@ -13,6 +13,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/coredns/coredns/plugin/pkg/transport"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -77,6 +78,20 @@ func (t *Transport) Dial(proto string) (*persistConn, bool, error) {
 func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options) (*dns.Msg, error) {
 	start := time.Now()
 
+	if p.trans == transport.HTTPS || p.trans == transport.QUIC {
+		return p.connectMsg(ctx, state, start)
+	}
+
+	ret, err := p.connect(state, opts, start)
+	if err == nil && ret.Rcode == dns.RcodeBadCookie && p.cookies != nil {
+		// The upstream wants a valid server cookie, we've learned it from this response (RFC 7873, section 5.3).
+		ret, err = p.connect(state, opts, start)
+	}
+	return ret, err
+}
+
+// connect sends the request to the upstream over UDP, TCP or TLS, with our DNS Cookie if cookies are enabled.
+func (p *Proxy) connect(state request.Request, opts Options, start time.Time) (*dns.Msg, error) {
 	proto := ""
 	switch {
 	case opts.ForceTCP: // TCP flag has precedence over UDP flag
@@ -99,6 +114,9 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options
 	}
 
 	pc.c.SetWriteDeadline(time.Now().Add(maxTimeout))
+	if p.cookies != nil {
+		defer p.cookies.set(state.Req)()
+	}
 	// records the origin Id before upstream.
 	originId := state.Req.Id
 	state.Req.Id = dns.Id()
@@ -150,6 +168,43 @@ func (p *Proxy) Connect(ctx context.Context, state request.Request, opts Options
 	ret.Id = originId
 
 	p.transport.Yield(pc)
+	if p.cookies != nil {
+		p.cookies.learn(ret)
+	}
+
+	rc, ok := dns.RcodeToString[ret.Rcode]
+	if !ok {
+		rc = strconv.Itoa(ret.Rcode)
+	}
+
+	requestDuration.WithLabelValues(p.proxyName, p.addr, rc).Observe(time.Since(start).Seconds())
+
+	return ret, nil
+}
+
+// connectMsg sends the request over DNS-over-HTTPS or DNS-over-QUIC. Both multiplex queries over a
+// single connection, so no persistConn is involved.
+func (p *Proxy) connectMsg(ctx context.Context, state request.Request, start time.Time) (*dns.Msg, error) {
+	// RFC 8484 and RFC 9250 want the message ID to be 0.
+	originId := state.Req.Id
+	state.Req.Id = 0
+	defer func() {
+		state.Req.Id = originId
+	}()
+
+	var (
+		ret *dns.Msg
+		err error
+	)
+	if p.trans == transport.HTTPS {
+		ret, err = p.transport.exchangeHTTPS(ctx, state.Req, p.readTimeout)
+	} else {
+		ret, err = p.transport.exchangeQUIC(ctx, state.Req, p.readTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	ret.Id = originId
 
 	rc, ok := dns.RcodeToString[ret.Rcode]
 	if !ok {
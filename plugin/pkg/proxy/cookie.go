@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// cookies holds the DNS Cookie (RFC 7873) state for an upstream: our client cookie and the last
+// server cookie the upstream handed out.
+type cookies struct {
+	sync.Mutex
+	client string // in hex
+	server string // in hex
+}
+
+func newCookies() *cookies {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &cookies{client: hex.EncodeToString(b)}
+}
+
+// set replaces the COOKIE option of the client in m with ours, if m has an OPT record. The returned
+// function restores the original options.
+func (c *cookies) set(m *dns.Msg) func() {
+	o := m.IsEdns0()
+	if o == nil {
+		return func() {}
+	}
+
+	c.Lock()
+	cookie := c.client + c.server
+	c.Unlock()
+
+	orig := o.Option
+	opts := make([]dns.EDNS0, 0, len(orig)+1)
+	for _, opt := range orig {
+		if opt.Option() != dns.EDNS0COOKIE {
+			opts = append(opts, opt)
+		}
+	}
+	o.Option = append(opts, &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie})
+	return func() { o.Option = orig }
+}
+
+// learn remembers the server cookie in the response m, if it belongs to our client cookie, and
+// removes the COOKIE option from m, as it is meant for us and not for our client.
+func (c *cookies) learn(m *dns.Msg) {
+	o := m.IsEdns0()
+	if o == nil {
+		return
+	}
+	opts := o.Option[:0]
+	for _, opt := range o.Option {
+		e, ok := opt.(*dns.EDNS0_COOKIE)
+		if !ok {
+			opts = append(opts, opt)
+			continue
+		}
+		// The server cookie is 8 to 32 bytes, i.e. 16 to 64 hex characters.
+		if len(e.Cookie) >= 32 && len(e.Cookie) <= 80 && strings.EqualFold(e.Cookie[:16], c.client) {
+			c.Lock()
+			c.server = strings.ToLower(e.Cookie[16:])
+			c.Unlock()
+		}
+	}
+	o.Option = opts
+}
@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestProxyCookies(t *testing.T) {
+	const server = "0123456789abcdef"
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.SetEdns0(4096, false)
+
+		cookie := ""
+		for _, opt := range r.IsEdns0().Option {
+			if e, ok := opt.(*dns.EDNS0_COOKIE); ok {
+				cookie = e.Cookie
+			}
+		}
+		mu.Lock()
+		received = append(received, cookie)
+		mu.Unlock()
+
+		// Require our server cookie, and hand it out.
+		if len(cookie) != 32 || cookie[16:] != server {
+			ret.Rcode = dns.RcodeBadCookie
+		} else {
+			ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		}
+		if len(cookie) >= 16 {
+			ret.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: cookie[:16] + server}}
+		}
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy("TestProxyCookies", s.Addr, transport.DNS)
+	p.EnableCookies()
+	p.readTimeout = 500 * time.Millisecond
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	clientOpt := &dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "fedcba9876543210"}
+	m.IsEdns0().Option = []dns.EDNS0{clientOpt}
+
+	for i := 0; i < 2; i++ {
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+		resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+		if err != nil {
+			t.Fatalf("Query %d: expected no error, got %s", i, err)
+		}
+		if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) != 1 {
+			t.Errorf("Query %d: expected an answer, got %v", i, resp)
+		}
+		if o := resp.IsEdns0(); o != nil && len(o.Option) != 0 {
+			t.Errorf("Query %d: expected the cookie of the upstream to be removed, got %v", i, o.Option)
+		}
+		if o := m.IsEdns0(); len(o.Option) != 1 || o.Option[0] != clientOpt {
+			t.Errorf("Query %d: expected the cookie of the client to be restored, got %v", i, o.Option)
+		}
+	}
+
+	// The first query gets BADCOOKIE and is retried with the server cookie, the second has it right away.
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 3 {
+		t.Fatalf("Expected 3 queries to the upstream, got %d", len(received))
+	}
+	client := p.cookies.client
+	for i, expect := range []string{client, client + server, client + server} {
+		if received[i] != expect {
+			t.Errorf("Query %d: expected cookie %s, got %s", i, expect, received[i])
+		}
+	}
+}
+
+func TestProxyCookiesDisabled(t *testing.T) {
+	// The upstream returns the cookie it received.
+	s := dnstest.NewServer(func(w dns.ResponseWriter, r *dns.Msg) {
+		ret := new(dns.Msg)
+		ret.SetReply(r)
+		ret.SetEdns0(4096, false)
+		ret.IsEdns0().Option = r.IsEdns0().Option
+		w.WriteMsg(ret)
+	})
+	defer s.Close()
+
+	p := NewProxy("TestProxyCookiesDisabled", s.Addr, transport.DNS)
+	p.readTimeout = 500 * time.Millisecond
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	m.IsEdns0().Option = []dns.EDNS0{&dns.EDNS0_COOKIE{Code: dns.EDNS0COOKIE, Cookie: "fedcba9876543210"}}
+
+	req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+	resp, err := p.Connect(context.Background(), req, Options{PreferUDP: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	o := resp.IsEdns0()
+	if o == nil || len(o.Option) != 1 || o.Option[0].(*dns.EDNS0_COOKIE).Cookie != "fedcba9876543210" {
+		t.Errorf("Expected the cookie of the client to be forwarded unchanged, got %v", resp)
+	}
+}
@@ -1,6 +1,7 @@
 package proxy
 
 import (
+	"context"
 	"crypto/tls"
 	"sync/atomic"
 	"time"
@@ -51,6 +52,14 @@ func NewHealthChecker(proxyName, trans string, recursionDesired bool, domain str
 			domain:           domain,
 			proxyName:        proxyName,
 		}
+
+	case transport.HTTPS, transport.QUIC:
+		return &msgHc{
+			recursionDesired: recursionDesired,
+			domain:           domain,
+			readTimeout:      1 * time.Second,
+			writeTimeout:     1 * time.Second,
+		}
 	}
 
 	log.Warningf("No healthchecker for transport %q", trans)
@@ -132,3 +141,49 @@ func (h *dnsHc) send(addr string) error {
 
 	return err
 }
+
+// msgHc is a health checker for DNS-over-HTTPS and DNS-over-QUIC endpoints. It sends the check
+// through the proxy's own transport, so it shares (and exercises) the upstream connection.
+type msgHc struct {
+	tlsConfig        *tls.Config
+	recursionDesired bool
+	domain           string
+	readTimeout      time.Duration
+	writeTimeout     time.Duration
+}
+
+func (h *msgHc) SetTLSConfig(cfg *tls.Config)    { h.tlsConfig = cfg }
+func (h *msgHc) GetTLSConfig() *tls.Config       { return h.tlsConfig }
+func (h *msgHc) SetRecursionDesired(rd bool)     { h.recursionDesired = rd }
+func (h *msgHc) GetRecursionDesired() bool       { return h.recursionDesired }
+func (h *msgHc) SetDomain(domain string)         { h.domain = domain }
+func (h *msgHc) GetDomain() string               { return h.domain }
+func (h *msgHc) SetTCPTransport()                {} // Both transports are already connection oriented.
+func (h *msgHc) GetReadTimeout() time.Duration   { return h.readTimeout }
+func (h *msgHc) SetReadTimeout(t time.Duration)  { h.readTimeout = t }
+func (h *msgHc) GetWriteTimeout() time.Duration  { return h.writeTimeout }
+func (h *msgHc) SetWriteTimeout(t time.Duration) { h.writeTimeout = t }
+
+// Check is used as the up.Func in the up.Probe.
+func (h *msgHc) Check(p *Proxy) error {
+	ping := new(dns.Msg)
+	ping.SetQuestion(h.domain, dns.TypeNS)
+	ping.MsgHdr.RecursionDesired = h.recursionDesired
+	ping.Id = 0
+
+	var err error
+	ctx := context.Background()
+	if p.trans == transport.HTTPS {
+		_, err = p.transport.exchangeHTTPS(ctx, ping, h.readTimeout+h.writeTimeout)
+	} else {
+		_, err = p.transport.exchangeQUIC(ctx, ping, h.readTimeout+h.writeTimeout)
+	}
+	if err != nil {
+		healthcheckFailureCount.WithLabelValues(p.proxyName, p.addr).Add(1)
+		p.incrementFails()
+		return err
+	}
+
+	atomic.StoreUint32(&p.fails, 0)
+	return nil
+}
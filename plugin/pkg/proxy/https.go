@@ -0,0 +1,91 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+// httpsURL splits the DNS-over-HTTPS address "host:port/path" into the base URL and the path. When no
+// path is given the default doh.Path is used.
+func httpsURL(addr string) (string, string) {
+	host, path := addr, doh.Path
+	if i := strings.Index(addr, "/"); i >= 0 {
+		host, path = addr[:i], addr[i:]
+	}
+	return "https://" + host, path
+}
+
+// client returns the HTTP client used for DNS-over-HTTPS. It is created on first use, so that the
+// TLS config and expire duration set after newTransport are taken into account. The underlying
+// http.Transport negotiates HTTP/2 and keeps the connection open for reuse for t.expire.
+func (t *Transport) client() *http.Client {
+	t.httpOnce.Do(func() {
+		var cfg *tls.Config
+		if t.tlsConfig != nil {
+			cfg = t.tlsConfig.Clone()
+		}
+		tr := &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: t.dialTimeout()}).DialContext,
+			TLSClientConfig:     cfg,
+			TLSHandshakeTimeout: maxDialTimeout,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     t.expire,
+		}
+		t.httpClient = &http.Client{Transport: tr}
+	})
+	return t.httpClient
+}
+
+// exchangeHTTPS sends m as a DNS-over-HTTPS POST request to the upstream and returns the reply.
+func (t *Transport) exchangeHTTPS(ctx context.Context, m *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	url, path := httpsURL(t.addr)
+	req, err := doh.NewRequest(http.MethodPost, url, m)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Path = path
+	req.URL.RawQuery = ""
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				connCacheHitsCount.WithLabelValues(t.proxyName, t.addr, transport.HTTPS).Add(1)
+				return
+			}
+			connCacheMissesCount.WithLabelValues(t.proxyName, t.addr, transport.HTTPS).Add(1)
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected HTTP status from %s: %d", t.addr, resp.StatusCode)
+	}
+	return doh.ResponseToMsg(resp)
+}
+
+// closeHTTPS closes any idle DNS-over-HTTPS connections.
+func (t *Transport) closeHTTPS() {
+	if t.httpClient != nil {
+		t.httpClient.CloseIdleConnections()
+	}
+}
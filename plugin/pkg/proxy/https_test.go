@@ -0,0 +1,89 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/pkg/doh"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func TestProxyHTTPS(t *testing.T) {
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/resolve" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		m, err := doh.RequestToMsg(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if m.Id != 0 {
+			http.Error(w, "non zero ID", http.StatusBadRequest)
+			return
+		}
+		ret := new(dns.Msg)
+		ret.SetReply(m)
+		ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+		buf, _ := ret.Pack()
+		w.Header().Set("Content-Type", doh.MimeType)
+		w.Write(buf)
+	}))
+	s.EnableHTTP2 = true
+	s.StartTLS()
+	defer s.Close()
+
+	addr := strings.TrimPrefix(s.URL, "https://") + "/resolve"
+	p := NewProxy("TestProxyHTTPS", addr, transport.HTTPS)
+	p.SetTLSConfig(s.Client().Transport.(*http.Transport).TLSClientConfig)
+	p.readTimeout = 1 * time.Second
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	for i := 0; i < 2; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.Id = 1234
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+
+		resp, err := p.Connect(context.Background(), req, Options{})
+		if err != nil {
+			t.Fatalf("Failed to connect to DoH server: %s", err)
+		}
+		if resp.Id != 1234 {
+			t.Errorf("Expected ID %d, got %d", 1234, resp.Id)
+		}
+		if x := resp.Answer[0].Header().Name; x != "example.org." {
+			t.Errorf("Expected %s, got %s", "example.org.", x)
+		}
+	}
+
+	if err := p.GetHealthchecker().Check(p); err != nil {
+		t.Errorf("Expected health check to succeed, got: %s", err)
+	}
+}
+
+func TestHTTPSURL(t *testing.T) {
+	tests := []struct {
+		addr, url, path string
+	}{
+		{"1.1.1.1:443", "https://1.1.1.1:443", doh.Path},
+		{"dns.example.org:443/resolve", "https://dns.example.org:443", "/resolve"},
+	}
+	for i, tc := range tests {
+		url, path := httpsURL(tc.addr)
+		if url != tc.url || path != tc.path {
+			t.Errorf("Test %d: expected %s %s, got %s %s", i, tc.url, tc.path, url, path)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package proxy
 
 import (
 	"crypto/tls"
+	"net/http"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
 )
 
 // a persistConn hold the dns.Conn and the last used time.
@@ -23,6 +26,13 @@ type Transport struct {
 	tlsConfig   *tls.Config
 	proxyName   string
 
+	// DNS-over-HTTPS and DNS-over-QUIC multiplex queries over a single connection,
+	// these don't use the conns buckets.
+	httpOnce   sync.Once
+	httpClient *http.Client
+	quicMu     sync.Mutex
+	quicConn   quic.Connection
+
 	dial  chan string
 	yield chan *persistConn
 	ret   chan *persistConn
@@ -143,7 +153,11 @@ func (t *Transport) Yield(pc *persistConn) {
 func (t *Transport) Start() { go t.connManager() }
 
 // Stop stops the transport's connection manager.
-func (t *Transport) Stop() { close(t.stop) }
+func (t *Transport) Stop() {
+	close(t.stop)
+	t.closeHTTPS()
+	t.closeQUIC()
+}
 
 // SetExpire sets the connection expire time in transport.
 func (t *Transport) SetExpire(expire time.Duration) { t.expire = expire }
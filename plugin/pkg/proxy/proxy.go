@@ -15,11 +15,14 @@ type Proxy struct {
 	fails     uint32
 	addr      string
 	proxyName string
+	trans     string
 
 	transport *Transport
 
 	readTimeout time.Duration
 
+	cookies *cookies // nil unless EnableCookies is called.
+
 	// health checking
 	probe  *up.Probe
 	health HealthChecker
@@ -35,6 +38,7 @@ func NewProxy(proxyName, addr, trans string) *Proxy {
 		transport:   newTransport(proxyName, addr),
 		health:      NewHealthChecker(proxyName, trans, true, "."),
 		proxyName:   proxyName,
+		trans:       trans,
 	}
 
 	runtime.SetFinalizer(p, (*Proxy).finalizer)
@@ -43,6 +47,9 @@ func NewProxy(proxyName, addr, trans string) *Proxy {
 
 func (p *Proxy) Addr() string { return p.addr }
 
+// Transport returns the transport (transport.DNS, transport.TLS, ...) used to talk to this proxy.
+func (p *Proxy) Transport() string { return p.trans }
+
 // SetTLSConfig sets the TLS config in the lower p.transport and in the healthchecking client.
 func (p *Proxy) SetTLSConfig(cfg *tls.Config) {
 	p.transport.SetTLSConfig(cfg)
@@ -86,12 +93,24 @@ func (p *Proxy) Down(maxfails uint32) bool {
 func (p *Proxy) Stop()      { p.probe.Stop() }
 func (p *Proxy) finalizer() { p.transport.Stop() }
 
+// Close stops the health checking and the transport, which closes the cached connections. Without Close
+// the transport is stopped when the proxy is garbage collected. The proxy can't be used after Close.
+func (p *Proxy) Close() {
+	runtime.SetFinalizer(p, nil)
+	p.Stop()
+	p.transport.Stop()
+}
+
 // Start starts the proxy's healthchecking.
 func (p *Proxy) Start(duration time.Duration) {
 	p.probe.Start(duration)
 	p.transport.Start()
 }
 
+// EnableCookies makes the proxy send a DNS Cookie of its own to the upstream and remember the server
+// cookie the upstream returns. The COOKIE option of the client is then not forwarded.
+func (p *Proxy) EnableCookies() { p.cookies = newCookies() }
+
 func (p *Proxy) SetReadTimeout(duration time.Duration) {
 	p.readTimeout = duration
 }
@@ -0,0 +1,143 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+// doqCodeNoError is used when the connection needs to be closed, but there is no error to signal (RFC 9250).
+const doqCodeNoError quic.ApplicationErrorCode = 0
+
+// quicConnection returns the cached QUIC connection to the upstream, or dials a new one if there is
+// none or if the cached one has been closed. The returned bool is true when the connection was cached.
+func (t *Transport) quicConnection(ctx context.Context) (quic.Connection, bool, error) {
+	t.quicMu.Lock()
+	defer t.quicMu.Unlock()
+
+	if t.quicConn != nil {
+		if t.quicConn.Context().Err() == nil {
+			connCacheHitsCount.WithLabelValues(t.proxyName, t.addr, transport.QUIC).Add(1)
+			return t.quicConn, true, nil
+		}
+		t.quicConn = nil
+	}
+	connCacheMissesCount.WithLabelValues(t.proxyName, t.addr, transport.QUIC).Add(1)
+
+	cfg := &tls.Config{}
+	if t.tlsConfig != nil {
+		cfg = t.tlsConfig.Clone()
+	}
+	cfg.NextProtos = []string{"doq"}
+
+	reqTime := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, t.dialTimeout())
+	defer cancel()
+	conn, err := quic.DialAddr(ctx, t.addr, cfg, &quic.Config{MaxIdleTimeout: t.expire})
+	t.updateDialTimeout(time.Since(reqTime))
+	if err != nil {
+		return nil, false, err
+	}
+	t.quicConn = conn
+	return conn, false, nil
+}
+
+// dropQUIC closes conn and removes it from the cache, if it is still the cached connection.
+func (t *Transport) dropQUIC(conn quic.Connection) {
+	t.quicMu.Lock()
+	if t.quicConn == conn {
+		t.quicConn = nil
+	}
+	t.quicMu.Unlock()
+	conn.CloseWithError(doqCodeNoError, "")
+}
+
+// closeQUIC closes the cached QUIC connection, if any.
+func (t *Transport) closeQUIC() {
+	t.quicMu.Lock()
+	conn := t.quicConn
+	t.quicConn = nil
+	t.quicMu.Unlock()
+	if conn != nil {
+		conn.CloseWithError(doqCodeNoError, "")
+	}
+}
+
+// exchangeQUIC sends m as a DNS-over-QUIC query on a new stream of the (cached) QUIC connection and
+// returns the reply. If a cached connection turns out to be unusable, a new one is dialed once.
+func (t *Transport) exchangeQUIC(ctx context.Context, m *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	buf, err := m.Pack()
+	if err != nil {
+		return nil, err
+	}
+	// Each message is prefixed with a 2 octet length field, as with DNS over TCP.
+	prefixed := make([]byte, 2+len(buf))
+	binary.BigEndian.PutUint16(prefixed, uint16(len(buf)))
+	copy(prefixed[2:], buf)
+
+	for {
+		conn, cached, err := t.quicConnection(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		ret, err := quicStreamExchange(ctx, conn, prefixed, timeout)
+		if err == nil {
+			return ret, nil
+		}
+		se, ok := err.(*streamError)
+		if !ok {
+			return nil, err
+		}
+		t.dropQUIC(conn)
+		if !cached {
+			return nil, se.err
+		}
+	}
+}
+
+// streamError wraps failures to open a stream, which signal a broken connection.
+type streamError struct{ err error }
+
+func (e *streamError) Error() string { return e.err.Error() }
+
+func quicStreamExchange(ctx context.Context, conn quic.Connection, buf []byte, timeout time.Duration) (*dns.Msg, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		return nil, &streamError{err}
+	}
+	defer stream.CancelRead(0)
+
+	stream.SetDeadline(time.Now().Add(timeout))
+	if _, err := stream.Write(buf); err != nil {
+		stream.CancelWrite(0)
+		return nil, err
+	}
+	// The client MUST send the query and indicate through the STREAM FIN that no further data will be sent.
+	stream.Close()
+
+	var length uint16
+	if err := binary.Read(stream, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	reply := make([]byte, length)
+	if _, err := io.ReadFull(stream, reply); err != nil {
+		return nil, err
+	}
+
+	ret := new(dns.Msg)
+	if err := ret.Unpack(reply); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
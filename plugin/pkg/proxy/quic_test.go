@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	ctls "github.com/coredns/coredns/plugin/pkg/tls"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
+)
+
+func TestProxyQUIC(t *testing.T) {
+	tlsConfig, err := ctls.NewTLSConfig("../../tls/test_cert.pem", "../../tls/test_key.pem", "../../tls/test_ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tlsConfig.NextProtos = []string{"doq"}
+
+	l, err := quic.ListenAddr("127.0.0.1:0", tlsConfig, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go serveDoQ(l)
+
+	p := NewProxy("TestProxyQUIC", l.Addr().String(), transport.QUIC)
+	clientConfig := tlsConfig.Clone()
+	clientConfig.InsecureSkipVerify = true
+	p.SetTLSConfig(clientConfig)
+	p.readTimeout = 1 * time.Second
+	p.Start(5 * time.Second)
+	defer p.Stop()
+
+	for i := 0; i < 2; i++ {
+		m := new(dns.Msg)
+		m.SetQuestion("example.org.", dns.TypeA)
+		m.Id = 1234
+		req := request.Request{Req: m, W: dnstest.NewRecorder(&test.ResponseWriter{})}
+
+		resp, err := p.Connect(context.Background(), req, Options{})
+		if err != nil {
+			t.Fatalf("Failed to connect to DoQ server: %s", err)
+		}
+		if resp.Id != 1234 {
+			t.Errorf("Expected ID %d, got %d", 1234, resp.Id)
+		}
+		if x := resp.Answer[0].Header().Name; x != "example.org." {
+			t.Errorf("Expected %s, got %s", "example.org.", x)
+		}
+	}
+
+	if err := p.GetHealthchecker().Check(p); err != nil {
+		t.Errorf("Expected health check to succeed, got: %s", err)
+	}
+}
+
+// serveDoQ answers every query on every stream with an A record for example.org.
+func serveDoQ(l *quic.Listener) {
+	for {
+		conn, err := l.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go func() {
+			for {
+				stream, err := conn.AcceptStream(context.Background())
+				if err != nil {
+					return
+				}
+				var length uint16
+				if err := binary.Read(stream, binary.BigEndian, &length); err != nil {
+					stream.Close()
+					continue
+				}
+				buf := make([]byte, length)
+				if _, err := io.ReadFull(stream, buf); err != nil {
+					stream.Close()
+					continue
+				}
+				m := new(dns.Msg)
+				if err := m.Unpack(buf); err != nil || m.Id != 0 {
+					stream.Close()
+					continue
+				}
+				ret := new(dns.Msg)
+				ret.SetReply(m)
+				ret.Answer = append(ret.Answer, test.A("example.org. IN A 127.0.0.1"))
+				out, _ := ret.Pack()
+				binary.Write(stream, binary.BigEndian, uint16(len(out)))
+				stream.Write(out)
+				stream.Close()
+			}
+		}()
+	}
+}
@@ -0,0 +1,18 @@
+package ratelimit
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RefusedCount is the number of DNS requests refused because the client exceeded its rate limit.
+	RefusedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "refused_requests_total",
+		Help:      "Counter of DNS requests refused because the client exceeded its rate limit.",
+	}, []string{"server", "zone", "view"})
+)
@@ -0,0 +1,180 @@
+// Package ratelimit implements per client query rate limiting.
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/infobloxopen/go-trees/iptree"
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// RateLimit refuses queries from clients that exceed their query budget.
+type RateLimit struct {
+	Next  plugin.Handler
+	Zones []string
+
+	rate       float64 // queries per second
+	burst      float64
+	ipv4Prefix int
+	ipv6Prefix int
+	allow      *iptree.Tree
+	ecs        bool // key on the EDNS0 client subnet address, when present
+	verified   bool // don't limit queries the cookie plugin verified the source of
+	ede        *uint16
+
+	clients *cache.Cache
+	mu      sync.Mutex // protects adding new clients
+	now     func() time.Time
+}
+
+// New returns a new RateLimit with the default settings.
+func New() *RateLimit {
+	return &RateLimit{
+		rate:       defaultRate,
+		burst:      defaultRate,
+		ipv4Prefix: 32,
+		ipv6Prefix: 128,
+		allow:      iptree.NewTree(),
+		clients:    cache.New(defaultMaxClients),
+		now:        time.Now,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RateLimit) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(rl.Zones).Matches(state.Name())
+	if zone == "" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	ip := rl.clientIP(state)
+	if ip == nil {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+	if _, ok := rl.allow.GetByIP(ip); ok {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+	if rl.verified {
+		if f := metadata.ValueFunc(ctx, "cookie/verified"); f != nil && f() == "true" {
+			return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+		}
+	}
+
+	if rl.take(rl.key(ip)) {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	RefusedCount.WithLabelValues(metrics.WithServer(ctx), zone, metrics.WithView(ctx)).Inc()
+	log.Debugf("Refusing query from %s, rate limit exceeded", state.IP())
+
+	m := new(dns.Msg)
+	m.SetRcode(r, dns.RcodeRefused)
+	// An EDE can only be returned to clients that sent an OPT record.
+	if rl.ede != nil && r.IsEdns0() != nil {
+		state.SizeAndDo(m)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_EDE{InfoCode: *rl.ede})
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (rl *RateLimit) Name() string { return pluginName }
+
+// clientIP returns the address of the client, this is the address from the EDNS0 client subnet
+// option if rl.ecs is set and that option is present, otherwise it's the source address.
+func (rl *RateLimit) clientIP(state request.Request) net.IP {
+	if rl.ecs {
+		if o := state.Req.IsEdns0(); o != nil {
+			for _, s := range o.Option {
+				if e, ok := s.(*dns.EDNS0_SUBNET); ok && e.Address != nil {
+					return e.Address
+				}
+			}
+		}
+	}
+
+	addr := state.IP()
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	return net.ParseIP(addr)
+}
+
+// key returns the key under which the budget of ip is kept.
+func (rl *RateLimit) key(ip net.IP) uint64 {
+	if ip4 := ip.To4(); ip4 != nil {
+		return cache.Hash(ip4.Mask(net.CIDRMask(rl.ipv4Prefix, 32)))
+	}
+	return cache.Hash(ip.Mask(net.CIDRMask(rl.ipv6Prefix, 128)))
+}
+
+// bucket is the token bucket of one client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+
+	sync.Mutex
+}
+
+// take takes a token from the bucket for key k, it returns false if the bucket is empty.
+func (rl *RateLimit) take(k uint64) bool {
+	now := rl.now()
+	b := rl.bucket(k, now)
+
+	b.Lock()
+	defer b.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * rl.rate
+		if b.tokens > rl.burst {
+			b.tokens = rl.burst
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// bucket returns the bucket for k, creating a full one if it doesn't exist. The number of
+// buckets is bounded by the cache, which randomly evicts when full.
+func (rl *RateLimit) bucket(k uint64, now time.Time) *bucket {
+	if b, ok := rl.clients.Get(k); ok {
+		return b.(*bucket)
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if b, ok := rl.clients.Get(k); ok {
+		return b.(*bucket)
+	}
+	b := &bucket{tokens: rl.burst, last: now}
+	rl.clients.Add(k, b)
+	return b
+}
+
+const (
+	pluginName = "ratelimit"
+
+	defaultRate       = 100.0
+	defaultMaxClients = 100000
+)
@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func newTestRateLimit(rate, burst float64) (*RateLimit, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	rl := New()
+	rl.Zones = []string{"."}
+	rl.Next = test.NextHandler(dns.RcodeSuccess, nil)
+	rl.rate = rate
+	rl.burst = burst
+	rl.now = func() time.Time { return now }
+	return rl, &now
+}
+
+func query(t *testing.T, rl *RateLimit, remote string, ecs net.IP) int {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	if ecs != nil {
+		m.SetEdns0(4096, false)
+		m.IsEdns0().Option = append(m.IsEdns0().Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1, SourceNetmask: 32, Address: ecs})
+	}
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: remote})
+	code, err := rl.ServeDNS(context.TODO(), rec, m)
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if rec.Msg != nil {
+		return rec.Msg.Rcode
+	}
+	return code
+}
+
+func TestRateLimit(t *testing.T) {
+	rl, now := newTestRateLimit(1, 2)
+
+	for i := 0; i < 2; i++ {
+		if rcode := query(t, rl, "10.0.0.1", nil); rcode != dns.RcodeSuccess {
+			t.Fatalf("Expected query %d to be allowed, got %s", i, dns.RcodeToString[rcode])
+		}
+	}
+	if rcode := query(t, rl, "10.0.0.1", nil); rcode != dns.RcodeRefused {
+		t.Errorf("Expected query to be refused, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := query(t, rl, "10.0.0.2", nil); rcode != dns.RcodeSuccess {
+		t.Errorf("Expected query from other client to be allowed, got %s", dns.RcodeToString[rcode])
+	}
+
+	*now = now.Add(time.Second)
+	if rcode := query(t, rl, "10.0.0.1", nil); rcode != dns.RcodeSuccess {
+		t.Errorf("Expected query to be allowed after refill, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestRateLimitPrefix(t *testing.T) {
+	rl, _ := newTestRateLimit(1, 1)
+	rl.ipv4Prefix = 24
+
+	query(t, rl, "10.0.0.1", nil)
+	if rcode := query(t, rl, "10.0.0.2", nil); rcode != dns.RcodeRefused {
+		t.Errorf("Expected query from the same /24 to be refused, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestRateLimitAllow(t *testing.T) {
+	rl, _ := newTestRateLimit(1, 1)
+	_, allowed, _ := net.ParseCIDR("10.0.0.0/8")
+	rl.allow.InplaceInsertNet(allowed, struct{}{})
+
+	for i := 0; i < 5; i++ {
+		if rcode := query(t, rl, "10.0.0.1", nil); rcode != dns.RcodeSuccess {
+			t.Fatalf("Expected query %d from allowed client to be allowed, got %s", i, dns.RcodeToString[rcode])
+		}
+	}
+}
+
+func TestRateLimitVerified(t *testing.T) {
+	rl, _ := newTestRateLimit(1, 1)
+	rl.verified = true
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	for i, verified := range []string{"true", "true", "false", "false"} {
+		ctx := metadata.ContextWithMetadata(context.TODO())
+		metadata.SetValueFunc(ctx, "cookie/verified", func() string { return verified })
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rl.ServeDNS(ctx, rec, m)
+		// The verified queries don't use the budget of the client, so only the last one is refused.
+		refused := rec.Msg != nil && rec.Msg.Rcode == dns.RcodeRefused
+		if refused != (i == 3) {
+			t.Errorf("Query %d: expected refused to be %t, got %t", i, i == 3, refused)
+		}
+	}
+}
+
+func TestRateLimitECS(t *testing.T) {
+	rl, _ := newTestRateLimit(1, 1)
+	rl.ecs = true
+
+	// Same resolver, different client subnets.
+	if rcode := query(t, rl, "10.0.0.1", net.ParseIP("192.0.2.1").To4()); rcode != dns.RcodeSuccess {
+		t.Errorf("Expected query to be allowed, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := query(t, rl, "10.0.0.1", net.ParseIP("198.51.100.1").To4()); rcode != dns.RcodeSuccess {
+		t.Errorf("Expected query for other subnet to be allowed, got %s", dns.RcodeToString[rcode])
+	}
+	if rcode := query(t, rl, "10.0.0.1", net.ParseIP("192.0.2.1").To4()); rcode != dns.RcodeRefused {
+		t.Errorf("Expected query to be refused, got %s", dns.RcodeToString[rcode])
+	}
+}
+
+func TestRateLimitEDE(t *testing.T) {
+	rl, _ := newTestRateLimit(1, 1)
+	code := uint16(dns.ExtendedErrorCodeProhibited)
+	rl.ede = &code
+
+	query(t, rl, "10.0.0.1", nil)
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(1232, true)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "10.0.0.1"})
+	rl.ServeDNS(context.TODO(), rec, m)
+
+	if rec.Msg == nil || rec.Msg.IsEdns0() == nil || len(rec.Msg.IsEdns0().Option) != 1 {
+		t.Fatalf("Expected response with an EDE option, got %v", rec.Msg)
+	}
+	if o := rec.Msg.IsEdns0(); o.UDPSize() != 1232 || !o.Do() {
+		t.Errorf("Expected the OPT record to match the query's, got size %d and DO %t", o.UDPSize(), o.Do())
+	}
+	ede, ok := rec.Msg.IsEdns0().Option[0].(*dns.EDNS0_EDE)
+	if !ok || ede.InfoCode != dns.ExtendedErrorCodeProhibited {
+		t.Errorf("Expected EDE code %d, got %v", dns.ExtendedErrorCodeProhibited, rec.Msg.IsEdns0().Option[0])
+	}
+
+	// A query without an OPT record doesn't get one in the response.
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: "10.0.0.1"})
+	rl.ServeDNS(context.TODO(), rec, m)
+
+	if rec.Msg == nil || rec.Msg.Rcode != dns.RcodeRefused {
+		t.Fatalf("Expected a refused response, got %v", rec.Msg)
+	}
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record in the response, got %v", rec.Msg.IsEdns0())
+	}
+}
@@ -0,0 +1,162 @@
+package ratelimit
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+
+	"github.com/miekg/dns"
+)
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	rl, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*RateLimit, error) {
+	rl := New()
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rl.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		burstSet := false
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "rate":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				r, err := strconv.ParseFloat(args[0], 64)
+				if err != nil || r <= 0 {
+					return nil, c.Errf("rate must be a positive number: %s", args[0])
+				}
+				rl.rate = r
+			case "burst":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				b, err := strconv.Atoi(args[0])
+				if err != nil || b < 1 {
+					return nil, c.Errf("burst must be a positive integer: %s", args[0])
+				}
+				rl.burst = float64(b)
+				burstSet = true
+			case "ipv4-prefix-length":
+				n, err := prefixArg(c, 32)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv4Prefix = n
+			case "ipv6-prefix-length":
+				n, err := prefixArg(c, 128)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv6Prefix = n
+			case "allow":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					_, source, err := net.ParseCIDR(normalize(a))
+					if err != nil {
+						return nil, c.Errf("illegal CIDR notation %q", a)
+					}
+					rl.allow.InplaceInsertNet(source, struct{}{})
+				}
+			case "ecs":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.ecs = true
+			case "allow-verified":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.verified = true
+			case "ede":
+				args := c.RemainingArgs()
+				if len(args) > 1 {
+					return nil, c.ArgErr()
+				}
+				code := uint16(dns.ExtendedErrorCodeProhibited)
+				if len(args) == 1 {
+					n, err := strconv.ParseUint(args[0], 10, 16)
+					if err != nil {
+						return nil, c.Errf("invalid extended error code: %s", args[0])
+					}
+					code = uint16(n)
+				}
+				rl.ede = &code
+			case "max-clients":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return nil, c.Errf("max-clients must be a positive integer: %s", args[0])
+				}
+				rl.clients = cache.New(n)
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+		if !burstSet {
+			rl.burst = rl.rate
+		}
+		if rl.burst < 1 {
+			rl.burst = 1
+		}
+	}
+	return rl, nil
+}
+
+func prefixArg(c *caddy.Controller, hi int) (int, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 0 || n > hi {
+		return 0, c.Errf("%s must be between 0 and %d: %s", prop, hi, args[0])
+	}
+	return n, nil
+}
+
+// normalize appends '/32' for any single IPv4 address and '/128' for IPv6, like the acl plugin does.
+func normalize(rawNet string) string {
+	if strings.Contains(rawNet, "/") {
+		return rawNet
+	}
+	if strings.Contains(rawNet, ":") {
+		return rawNet + "/128"
+	}
+	return rawNet + "/32"
+}
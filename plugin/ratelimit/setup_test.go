@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"net"
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rate      float64
+		burst     float64
+	}{
+		{`ratelimit`, false, defaultRate, defaultRate},
+		{`ratelimit example.org {
+			rate 10
+		}`, false, 10, 10},
+		{`ratelimit {
+			rate 0.5
+			burst 5
+			ipv4-prefix-length 24
+			ipv6-prefix-length 56
+			allow 10.0.0.0/8 192.168.1.1 ::1
+			allow-verified
+			ecs
+			ede 18
+			max-clients 1000
+		}`, false, 0.5, 5},
+		{`ratelimit {
+			rate 0.5
+		}`, false, 0.5, 1},
+		// negative
+		{`ratelimit {
+			rate 0
+		}`, true, 0, 0},
+		{`ratelimit {
+			burst
+		}`, true, 0, 0},
+		{`ratelimit {
+			ipv6-prefix-length 129
+		}`, true, 0, 0},
+		{`ratelimit {
+			allow 10.0.0.0/33
+		}`, true, 0, 0},
+		{`ratelimit {
+			ede blocked
+		}`, true, 0, 0},
+		{`ratelimit {
+			blah
+		}`, true, 0, 0},
+		{`ratelimit
+		ratelimit`, true, 0, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rl, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if rl.rate != tc.rate || rl.burst != tc.burst {
+			t.Errorf("Test %d: expected rate %f and burst %f, got %f and %f", i, tc.rate, tc.burst, rl.rate, rl.burst)
+		}
+	}
+}
+
+func TestSetupAllow(t *testing.T) {
+	c := caddy.NewTestController("dns", `ratelimit {
+		allow 10.0.0.0/8 192.168.1.1
+	}`)
+	rl, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"10.1.2.3", "192.168.1.1"} {
+		if _, ok := rl.allow.GetByIP(net.ParseIP(ip)); !ok {
+			t.Errorf("Expected %s to be allowed", ip)
+		}
+	}
+	if _, ok := rl.allow.GetByIP(net.ParseIP("192.168.1.2")); ok {
+		t.Errorf("Expected %s not to be allowed", "192.168.1.2")
+	}
+}
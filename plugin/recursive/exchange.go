@@ -0,0 +1,230 @@
+package recursive
+
+import (
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var (
+	errMismatch     = errors.New("question in response does not match")
+	errCaseMismatch = errors.New("case of the question in response does not match")
+)
+
+// exchange sends the query for qname and qtype to the name servers of d, and returns the first usable
+// response. See race.
+func (r *Recursive) exchange(ctx context.Context, res *resolution, d *delegation, qname string, qtype uint16) (*dns.Msg, error) {
+	ips, err := r.addresses(ctx, res, d)
+	if err != nil {
+		return nil, err
+	}
+	addrs := r.order(ips)
+	if len(addrs) == 0 {
+		return nil, errNoServers
+	}
+
+	m, err := r.race(ctx, res, addrs, d.zone, qname, qtype, r.use0x20)
+	if err == errCaseMismatch {
+		// None of the name servers preserves the case of the question, they won't get 0x20.
+		m, err = r.race(ctx, res, addrs, d.zone, qname, qtype, false)
+	}
+	return m, err
+}
+
+// race sends the query to the name servers in addrs, fastest first. When a name server doesn't answer
+// within its round trip time, or fails, the query is sent to the next one as well. The first usable
+// response wins.
+func (r *Recursive) race(ctx context.Context, res *resolution, addrs []string, zone, qname string, qtype uint16, use0x20 bool) (*dns.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		m   *dns.Msg
+		err error
+	}
+	results := make(chan result, len(addrs)) // buffered, so late responses don't block
+
+	var (
+		next, pending int
+		wait          time.Duration
+		err           error = errNoServers
+		mismatch      bool
+	)
+	launch := func() {
+		if next == len(addrs) {
+			return
+		}
+		if res.queries >= r.maxQueries {
+			err = errMaxQueries
+			return
+		}
+		res.queries++
+		addr := addrs[next]
+		next++
+		pending++
+		wait = stagger(r.server(addr).rtt(), r.timeout)
+		go func() {
+			m, err := r.query(ctx, res, addr, zone, qname, qtype, use0x20)
+			results <- result{m, err}
+		}()
+	}
+
+	launch()
+	for pending > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case x := <-results:
+			pending--
+			if x.err == nil {
+				timer.Stop()
+				return x.m, nil
+			}
+			if x.err == errCaseMismatch {
+				mismatch = true
+			}
+			err = x.err
+			launch()
+		case <-timer.C:
+			launch()
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		timer.Stop()
+	}
+	if mismatch {
+		return nil, errCaseMismatch
+	}
+	return nil, err
+}
+
+// query sends the query for qname and qtype to the name server at addr, which is authoritative for zone.
+// The response is checked and the records that are out of bailiwick are removed.
+func (r *Recursive) query(ctx context.Context, res *resolution, addr, zone, qname string, qtype uint16, use0x20 bool) (*dns.Msg, error) {
+	name := qname
+	if use0x20 {
+		name = randomCase(qname)
+	}
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.RecursionDesired = false
+	m.SetEdns0(ednsSize, true)
+
+	s := r.servers.get(addr, true)
+	defer r.servers.release(s)
+	state := request.Request{W: res.w, Req: m}
+	opts := proxy.Options{PreferUDP: true}
+
+	var (
+		ret *dns.Msg
+		err error
+	)
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		ret, err = s.proxy.Connect(ctx, state, opts)
+		if err == proxy.ErrCachedClosed { // Remote side closed conn, can only happen with TCP.
+			continue
+		}
+		if err == nil && ret.Truncated && !opts.ForceTCP {
+			opts.ForceTCP = true
+			continue
+		}
+		break
+	}
+	if err != nil {
+		s.penalize()
+		return nil, err
+	}
+	s.observe(time.Since(start))
+
+	rc, ok := dns.RcodeToString[ret.Rcode]
+	if !ok {
+		rc = strconv.Itoa(ret.Rcode)
+	}
+	RequestsCount.WithLabelValues(metrics.WithServer(ctx), res.zone, metrics.WithView(ctx), rc).Inc()
+
+	if len(ret.Question) != 1 || ret.Question[0].Qtype != qtype || !strings.EqualFold(ret.Question[0].Name, name) {
+		return nil, errMismatch
+	}
+	if ret.Question[0].Name != name {
+		return nil, errCaseMismatch
+	}
+	if ret.Rcode != dns.RcodeSuccess && ret.Rcode != dns.RcodeNameError {
+		return nil, fmt.Errorf("%s returned %s for %s/%s", addr, rc, qname, dns.TypeToString[qtype])
+	}
+
+	if cut := strings.ToLower(referral(ret)); cut != "" {
+		// A referral must lead down, towards qname.
+		if cut == zone || !dns.IsSubDomain(zone, cut) || !dns.IsSubDomain(cut, qname) {
+			return nil, errLame
+		}
+	}
+	sanitize(ret, zone)
+	return ret, nil
+}
+
+// sanitize lower cases the owner names of the records in m and removes the records that are not in zone.
+func sanitize(m *dns.Msg, zone string) {
+	m.Answer = inZone(m.Answer, zone)
+	m.Ns = inZone(m.Ns, zone)
+	m.Extra = inZone(m.Extra, zone)
+}
+
+func inZone(rrs []dns.RR, zone string) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype == dns.TypeOPT {
+			continue
+		}
+		h.Name = strings.ToLower(h.Name)
+		if !dns.IsSubDomain(zone, h.Name) {
+			continue
+		}
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
+
+// randomCase randomises the case of the letters in name, see draft-vixie-dnsext-dns0x20.
+func randomCase(name string) string {
+	b := []byte(name)
+	rnd := make([]byte, len(b))
+	if _, err := crand.Read(rnd); err != nil {
+		return name
+	}
+	for i, c := range b {
+		if c >= 'a' && c <= 'z' && rnd[i]&1 == 1 {
+			b[i] = c - ('a' - 'A')
+		}
+	}
+	return string(b)
+}
+
+// stagger returns how long to wait for a name server with the round trip time rtt, before sending the
+// query to the next one.
+func stagger(rtt, timeout time.Duration) time.Duration {
+	if rtt < minStagger {
+		return minStagger
+	}
+	if rtt > timeout {
+		return timeout
+	}
+	return rtt
+}
+
+const (
+	ednsSize   = 1232 // see https://www.dnsflagday.net/2020/
+	minStagger = 10 * time.Millisecond
+)
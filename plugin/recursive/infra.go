@@ -0,0 +1,216 @@
+package recursive
+
+import (
+	"container/list"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/proxy"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+
+	"github.com/miekg/dns"
+)
+
+// delegation is a zone cut: the zone, the names of its name servers and the addresses of these name
+// servers, as far as they are known from glue.
+type delegation struct {
+	zone    string
+	ns      []string
+	addrs   map[string][]string // addresses of the name servers, keyed by name
+	expires time.Time           // zero for the root hints
+}
+
+// closest returns the deepest delegation for name that is cached, falling back to the root hints.
+func (r *Recursive) closest(name string) *delegation {
+	now := r.now()
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		e, ok := r.cuts.Get(cache.Hash([]byte(name[off:])))
+		if !ok {
+			continue
+		}
+		if d := e.(*delegation); d.zone == name[off:] && now.Before(d.expires) {
+			return d
+		}
+	}
+	return r.hints
+}
+
+// addDelegation caches d until it expires.
+func (r *Recursive) addDelegation(d *delegation) {
+	r.cuts.Add(cache.Hash([]byte(d.zone)), d)
+}
+
+// host holds the resolved addresses of a name server.
+type host struct {
+	name    string
+	addrs   []string
+	expires time.Time
+}
+
+// hostAddrs returns the cached addresses for the name server name.
+func (r *Recursive) hostAddrs(name string) []string {
+	e, ok := r.hosts.Get(cache.Hash([]byte(name)))
+	if !ok {
+		return nil
+	}
+	if h := e.(*host); h.name == name && r.now().Before(h.expires) {
+		return h.addrs
+	}
+	return nil
+}
+
+// addHost caches the addresses of the name server name for ttl seconds.
+func (r *Recursive) addHost(name string, addrs []string, ttl uint32) {
+	r.hosts.Add(cache.Hash([]byte(name)), &host{name: name, addrs: addrs, expires: r.now().Add(time.Duration(ttl) * time.Second)})
+}
+
+// server is a name server address, with the proxy used to reach it and its smoothed round trip time.
+type server struct {
+	addr  string
+	proxy *proxy.Proxy
+
+	mu   sync.Mutex
+	srtt time.Duration
+
+	// Protected by the mutex of servers.
+	users   int  // queries using the proxy
+	evicted bool // the server was dropped from servers, the proxy is closed when it isn't used anymore
+	closed  bool
+}
+
+// rtt returns the smoothed round trip time of s.
+func (s *server) rtt() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.srtt
+}
+
+// observe updates the smoothed round trip time of s with a new measurement.
+func (s *server) observe(rtt time.Duration) {
+	s.mu.Lock()
+	s.srtt = (7*s.srtt + 3*rtt) / 10
+	s.mu.Unlock()
+}
+
+// penalize doubles the round trip time of s, after it failed to answer.
+func (s *server) penalize() {
+	s.mu.Lock()
+	if s.srtt *= 2; s.srtt > maxRTT {
+		s.srtt = maxRTT
+	}
+	s.mu.Unlock()
+}
+
+// servers holds the name servers that were used, up to size. When it is full the least recently used server
+// is dropped, and its proxy is closed once no query uses it.
+type servers struct {
+	mu    sync.Mutex
+	size  int
+	lru   *list.List // *server, the most recently used first
+	addrs map[string]*list.Element
+	new   func(addr string) *server
+}
+
+func newServers(size int, new func(addr string) *server) *servers {
+	return &servers{size: size, lru: list.New(), addrs: map[string]*list.Element{}, new: new}
+}
+
+// get returns the server for addr, creating it when it isn't known yet. If use is true the server's proxy is
+// used for a query, and the server must be released when done.
+func (ss *servers) get(addr string, use bool) *server {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	var s *server
+	if e, ok := ss.addrs[addr]; ok {
+		ss.lru.MoveToFront(e)
+		s = e.Value.(*server)
+	} else {
+		s = ss.new(addr)
+		ss.addrs[addr] = ss.lru.PushFront(s)
+		for ss.lru.Len() > ss.size {
+			old := ss.lru.Remove(ss.lru.Back()).(*server)
+			delete(ss.addrs, old.addr)
+			old.evicted = true
+			old.closeIfUnused()
+		}
+	}
+	if use {
+		s.users++
+	}
+	return s
+}
+
+// release is called when a query is done with the proxy of s.
+func (ss *servers) release(s *server) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	s.users--
+	s.closeIfUnused()
+}
+
+// close drops all servers.
+func (ss *servers) close() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for e := ss.lru.Front(); e != nil; e = e.Next() {
+		s := e.Value.(*server)
+		s.evicted = true
+		s.closeIfUnused()
+	}
+	ss.lru.Init()
+	ss.addrs = map[string]*list.Element{}
+}
+
+// closeIfUnused closes the proxy of s, if it was dropped and isn't used anymore. The mutex of servers must be held.
+func (s *server) closeIfUnused() {
+	if s.evicted && s.users == 0 && !s.closed {
+		s.proxy.Close()
+		s.closed = true
+	}
+}
+
+// newServer returns a server for addr.
+func (r *Recursive) newServer(addr string) *server {
+	p := proxy.NewProxy(pluginName, addr, transport.DNS)
+	p.SetReadTimeout(r.timeout)
+	p.Start(0) // no health checks, servers are selected on their round trip time
+	return &server{addr: addr, proxy: p, srtt: unknownRTT}
+}
+
+// server returns the server for addr, to look at its round trip time.
+func (r *Recursive) server(addr string) *server { return r.servers.get(addr, false) }
+
+// order returns the addresses of ips, joined with the port to use, in the order they should be tried:
+// the fastest server first. Servers with equal round trip times are shuffled.
+func (r *Recursive) order(ips []string) []string {
+	addrs := make([]string, 0, len(ips))
+	for _, i := range r.rand.Perm(len(ips)) {
+		if r.noIPv6 && strings.Contains(ips[i], ":") {
+			continue
+		}
+		addrs = append(addrs, net.JoinHostPort(ips[i], r.port))
+	}
+
+	rtts := make(map[string]time.Duration, len(addrs))
+	for _, a := range addrs {
+		rtts[a] = r.server(a).rtt()
+	}
+	sort.SliceStable(addrs, func(i, j int) bool { return rtts[addrs[i]] < rtts[addrs[j]] })
+	return addrs
+}
+
+// OnShutdown closes the proxies of all known servers.
+func (r *Recursive) OnShutdown() error {
+	r.servers.close()
+	return nil
+}
+
+const (
+	unknownRTT = 376 * time.Millisecond // initial round trip time of a server, unknown servers are tried before slow ones
+	maxRTT     = 120 * time.Second
+)
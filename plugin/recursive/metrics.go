@@ -0,0 +1,18 @@
+package recursive
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// RequestsCount is the number of queries sent to authoritative name servers, per rcode of the response.
+	RequestsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "requests_total",
+		Help:      "Counter of queries sent to authoritative name servers per response rcode.",
+	}, []string{"server", "zone", "view", "rcode"})
+)
@@ -0,0 +1,131 @@
+// Package recursive implements a plugin that resolves queries iteratively, starting at the root name servers.
+package recursive
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/rand"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// Recursive is a recursive resolver: it follows the referrals from the root name servers down to the
+// name servers that are authoritative for the query name.
+type Recursive struct {
+	Next  plugin.Handler
+	Zones []string
+
+	hints *delegation // the root name servers
+
+	cuts    *cache.Cache // delegations, keyed by zone, see closest
+	hosts   *cache.Cache // addresses of name servers without glue, keyed by name
+	servers *servers     // servers, keyed by address
+
+	port       string // port name servers listen on, only changed in tests
+	timeout    time.Duration
+	qmin       bool // QNAME minimisation (RFC 9156)
+	use0x20    bool // randomise the case of the query name
+	noIPv6     bool
+	maxDepth   int // maximum number of referrals and CNAMEs to follow
+	maxQueries int // maximum number of queries sent for a single client query
+
+	rand *rand.Rand
+	now  func() time.Time
+}
+
+// New returns a new Recursive that starts resolving at the root name servers.
+func New() *Recursive {
+	hints, err := parseHints(strings.NewReader(rootHints), "root hints")
+	if err != nil {
+		panic(err) // the built-in hints are known to parse
+	}
+	r := &Recursive{
+		hints:      hints,
+		cuts:       cache.New(defaultCacheSize),
+		hosts:      cache.New(defaultCacheSize),
+		port:       "53",
+		timeout:    defaultTimeout,
+		qmin:       true,
+		use0x20:    true,
+		maxDepth:   defaultMaxDepth,
+		maxQueries: defaultMaxQueries,
+		rand:       rand.New(time.Now().UnixNano()),
+		now:        time.Now,
+	}
+	r.servers = newServers(defaultCacheSize, r.newServer)
+	return r
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (r *Recursive) ServeDNS(ctx context.Context, w dns.ResponseWriter, req *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: req}
+
+	zone := plugin.Zones(r.Zones).Matches(state.Name())
+	if zone == "" || req.Opcode != dns.OpcodeQuery {
+		return plugin.NextOrFailure(r.Name(), r.Next, ctx, w, req)
+	}
+
+	res := &resolution{w: w, zone: zone, active: make(map[string]bool)}
+	m, err := r.resolve(ctx, res, strings.ToLower(state.Name()), state.QType())
+	if err != nil {
+		log.Debugf("Failed to resolve %s/%s: %s", state.Name(), state.Type(), err)
+		return dns.RcodeServerFailure, err
+	}
+
+	m.SetRcode(req, m.Rcode)
+	m.Authoritative = false
+	m.RecursionAvailable = true
+	m.AuthenticatedData = false
+	m.CheckingDisabled = req.CheckingDisabled
+	m.Extra = nil
+	if !state.Do() {
+		m.Answer = strip(m.Answer, state.QType())
+		m.Ns = strip(m.Ns, state.QType())
+	}
+	// Restore the case of the question in the owner names, resolve works on the lower cased name.
+	qname := req.Question[0].Name
+	for _, rr := range m.Answer {
+		if strings.EqualFold(rr.Header().Name, qname) {
+			rr.Header().Name = qname
+		}
+	}
+	state.SizeAndDo(m)
+	m = state.Scrub(m)
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (r *Recursive) Name() string { return pluginName }
+
+// strip removes the DNSSEC records, that the client didn't ask for, from rrs.
+func strip(rrs []dns.RR, qtype uint16) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if t != qtype {
+				continue
+			}
+		}
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
+
+const (
+	defaultCacheSize  = 10000
+	defaultTimeout    = 2 * time.Second
+	defaultMaxDepth   = 30
+	defaultMaxQueries = 100
+)
@@ -0,0 +1,427 @@
+package recursive
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// fakeAuth is an authoritative name server for a set of zones, it refers to the delegations in them.
+type fakeAuth struct {
+	zones map[string][]dns.RR
+
+	mu        sync.Mutex
+	qnames    []string
+	lowercase bool // reply with a lower cased question, as name servers that don't support 0x20 do
+}
+
+func (f *fakeAuth) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+	q := r.Question[0]
+	f.mu.Lock()
+	f.qnames = append(f.qnames, q.Name)
+	lowercase := f.lowercase
+	f.mu.Unlock()
+
+	qname := strings.ToLower(q.Name)
+	m := new(dns.Msg)
+	m.SetReply(r)
+	if lowercase {
+		m.Question[0].Name = qname
+	}
+
+	zone := ""
+	for z := range f.zones {
+		if dns.IsSubDomain(z, qname) && len(z) > len(zone) {
+			zone = z
+		}
+	}
+	if zone == "" {
+		m.Rcode = dns.RcodeRefused
+		w.WriteMsg(m)
+		return
+	}
+	rrs := f.zones[zone]
+
+	// Referral to a child zone.
+	for _, rr := range rrs {
+		h := rr.Header()
+		if h.Rrtype != dns.TypeNS || h.Name == zone || !dns.IsSubDomain(h.Name, qname) {
+			continue
+		}
+		if h.Name == qname && q.Qtype == dns.TypeDS {
+			continue
+		}
+		m.Ns = append(m.Ns, rr)
+	}
+	if len(m.Ns) > 0 {
+		for _, ns := range m.Ns {
+			for _, rr := range rrs {
+				if rr.Header().Rrtype == dns.TypeA && rr.Header().Name == ns.(*dns.NS).Ns {
+					m.Extra = append(m.Extra, rr)
+				}
+			}
+		}
+		w.WriteMsg(m)
+		return
+	}
+
+	m.Authoritative = true
+	exists := false
+	for _, rr := range rrs {
+		h := rr.Header()
+		if dns.IsSubDomain(qname, h.Name) {
+			exists = true
+		}
+		if h.Name != qname {
+			continue
+		}
+		if h.Rrtype == q.Qtype || h.Rrtype == dns.TypeCNAME {
+			m.Answer = append(m.Answer, rr)
+		}
+	}
+	if len(m.Answer) > 0 {
+		w.WriteMsg(m)
+		return
+	}
+	if !exists {
+		m.Rcode = dns.RcodeNameError
+	}
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			m.Ns = append(m.Ns, rr)
+		}
+	}
+	w.WriteMsg(m)
+}
+
+func (f *fakeAuth) seen() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.qnames...)
+}
+
+// hierarchy is a root, a TLD and a second level name server, listening on different loopback addresses
+// and the same port.
+type hierarchy struct {
+	root, org, example *fakeAuth
+	port               string
+	servers            []*dns.Server
+}
+
+func newHierarchy(t *testing.T) *hierarchy {
+	t.Helper()
+	h := &hierarchy{
+		root: &fakeAuth{zones: map[string][]dns.RR{
+			".": {
+				test.SOA(". 3600 IN SOA a.root-servers.test. hostmaster.root-servers.test. 1 7200 3600 1209600 3600"),
+				test.NS(". 3600 IN NS a.root-servers.test."),
+				test.NS("org. 3600 IN NS ns.org."),
+				test.A("ns.org. 3600 IN A 127.0.0.2"),
+			},
+		}},
+		org: &fakeAuth{zones: map[string][]dns.RR{
+			"org.": {
+				test.SOA("org. 3600 IN SOA ns.org. hostmaster.org. 1 7200 3600 1209600 3600"),
+				test.NS("org. 3600 IN NS ns.org."),
+				test.A("ns.org. 3600 IN A 127.0.0.2"),
+				test.NS("example.org. 3600 IN NS ns.example.org."),
+				test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+				// Delegation without glue, the name server is in another zone.
+				test.NS("glueless.org. 3600 IN NS ns.example.org."),
+				// Delegation to a name server that can only be resolved through itself.
+				test.NS("loop.org. 3600 IN NS ns.loop.org."),
+				// Delegation to a name server that refers back up.
+				test.NS("lame.org. 3600 IN NS ns.example.org."),
+			},
+		}},
+		example: &fakeAuth{zones: map[string][]dns.RR{
+			"example.org.": {
+				test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+				test.NS("example.org. 3600 IN NS ns.example.org."),
+				test.A("ns.example.org. 3600 IN A 127.0.0.3"),
+				test.A("www.example.org. 3600 IN A 127.0.0.10"),
+				test.A("a.b.c.example.org. 3600 IN A 127.0.0.11"),
+				test.CNAME("cname.example.org. 3600 IN CNAME www.glueless.org."),
+			},
+			"glueless.org.": {
+				test.SOA("glueless.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600"),
+				test.NS("glueless.org. 3600 IN NS ns.example.org."),
+				test.A("www.glueless.org. 3600 IN A 127.0.0.12"),
+			},
+			"lame.org.": {
+				test.NS("org. 3600 IN NS ns.org."),
+			},
+		}},
+	}
+
+	l, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, h.port, _ = net.SplitHostPort(l.LocalAddr().String())
+	h.serve(t, l, h.root)
+	for ip, f := range map[string]*fakeAuth{"127.0.0.2": h.org, "127.0.0.3": h.example} {
+		l, err := net.ListenPacket("udp", net.JoinHostPort(ip, h.port))
+		if err != nil {
+			h.close()
+			t.Skipf("Failed to listen on %s: %s", ip, err)
+		}
+		h.serve(t, l, f)
+	}
+	return h
+}
+
+func (h *hierarchy) serve(t *testing.T, l net.PacketConn, f *fakeAuth) {
+	started := make(chan struct{})
+	s := &dns.Server{PacketConn: l, Handler: f, NotifyStartedFunc: func() { close(started) }}
+	go s.ActivateAndServe()
+	<-started
+	h.servers = append(h.servers, s)
+}
+
+func (h *hierarchy) close() {
+	for _, s := range h.servers {
+		s.Shutdown()
+	}
+}
+
+// recursive returns a Recursive that uses the root name server of h.
+func (h *hierarchy) recursive() *Recursive {
+	r := New()
+	r.Zones = []string{"."}
+	r.port = h.port
+	r.timeout = 500 * time.Millisecond
+	r.hints = &delegation{zone: ".", ns: []string{"a.root-servers.test."}, addrs: map[string][]string{"a.root-servers.test.": {"127.0.0.1"}}}
+	return r
+}
+
+func TestRecursive(t *testing.T) {
+	h := newHierarchy(t)
+	defer h.close()
+	r := h.recursive()
+	defer r.OnShutdown()
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		rcode  int
+		answer []string
+	}{
+		{"www.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.10"}},
+		{"WWW.Example.ORG.", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.10"}},
+		{"a.b.c.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.11"}},
+		{"www.example.org.", dns.TypeMX, dns.RcodeSuccess, nil},
+		{"nx.example.org.", dns.TypeA, dns.RcodeNameError, nil},
+		{"www.glueless.org.", dns.TypeA, dns.RcodeSuccess, []string{"127.0.0.12"}},
+		{"cname.example.org.", dns.TypeA, dns.RcodeSuccess, []string{"www.glueless.org.", "127.0.0.12"}},
+		{"www.loop.org.", dns.TypeA, dns.RcodeServerFailure, nil},
+		{"www.lame.org.", dns.TypeA, dns.RcodeServerFailure, nil},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		rcode, err := r.ServeDNS(context.TODO(), rec, m)
+		if tc.rcode == dns.RcodeServerFailure {
+			if err == nil || rcode != dns.RcodeServerFailure {
+				t.Errorf("Test %d: expected SERVFAIL and an error, got %s and %v", i, dns.RcodeToString[rcode], err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if !rec.Msg.RecursionAvailable {
+			t.Errorf("Test %d: expected RA bit to be set", i)
+		}
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Fatalf("Test %d: expected %d RRs in the answer, got %d: %v", i, len(tc.answer), len(rec.Msg.Answer), rec.Msg.Answer)
+		}
+		for j, rr := range rec.Msg.Answer {
+			var got string
+			switch x := rr.(type) {
+			case *dns.A:
+				got = x.A.String()
+			case *dns.CNAME:
+				got = x.Target
+			}
+			if got != tc.answer[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.answer[j], got)
+			}
+		}
+		if rr := rec.Msg.Answer; len(rr) > 0 && rr[0].Header().Name != tc.qname {
+			t.Errorf("Test %d: expected owner name %s, got %s", i, tc.qname, rr[0].Header().Name)
+		}
+		if tc.rcode == dns.RcodeNameError && len(rec.Msg.Ns) != 1 {
+			t.Errorf("Test %d: expected the SOA in the authority section, got %v", i, rec.Msg.Ns)
+		}
+	}
+}
+
+func TestQnameMinimisation(t *testing.T) {
+	h := newHierarchy(t)
+	defer h.close()
+	r := h.recursive()
+	defer r.OnShutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("a.b.c.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(f *fakeAuth, qnames ...string) {
+		t.Helper()
+		seen := f.seen()
+		if len(seen) != len(qnames) {
+			t.Fatalf("Expected queries for %v, got %v", qnames, seen)
+		}
+		for i := range seen {
+			if !strings.EqualFold(seen[i], qnames[i]) {
+				t.Errorf("Expected query for %s, got %s", qnames[i], seen[i])
+			}
+		}
+	}
+	expect(h.root, "org.")
+	expect(h.org, "example.org.")
+	expect(h.example, "c.example.org.", "b.c.example.org.", "a.b.c.example.org.")
+
+	// The delegations are cached, the root and org name servers aren't asked again.
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatal(err)
+	}
+	expect(h.root, "org.")
+	expect(h.org, "example.org.")
+}
+
+func TestNoQnameMinimisation(t *testing.T) {
+	h := newHierarchy(t)
+	defer h.close()
+	r := h.recursive()
+	defer r.OnShutdown()
+	r.qmin = false
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatal(err)
+	}
+	if seen := h.root.seen(); len(seen) != 1 || !strings.EqualFold(seen[0], "www.example.org.") {
+		t.Errorf("Expected the root to see the full name, got %v", seen)
+	}
+}
+
+func Test0x20Fallback(t *testing.T) {
+	h := newHierarchy(t)
+	defer h.close()
+	h.example.mu.Lock()
+	h.example.lowercase = true
+	h.example.mu.Unlock()
+	r := h.recursive()
+	defer r.OnShutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	if _, err := r.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected 1 RR in the answer, got %d", len(rec.Msg.Answer))
+	}
+	seen := h.example.seen()
+	if last := seen[len(seen)-1]; last != "www.example.org." {
+		t.Errorf("Expected the final query without 0x20, got %s", last)
+	}
+}
+
+func TestOrder(t *testing.T) {
+	r := New()
+	defer r.OnShutdown()
+	r.server("127.0.0.1:53").srtt = 100 * time.Millisecond
+	r.server("127.0.0.2:53").srtt = 10 * time.Millisecond
+	r.server("127.0.0.3:53").srtt = 800 * time.Millisecond
+
+	addrs := r.order([]string{"127.0.0.1", "127.0.0.2", "127.0.0.3", "127.0.0.4"})
+	expect := []string{"127.0.0.2:53", "127.0.0.1:53", "127.0.0.4:53", "127.0.0.3:53"}
+	for i := range expect {
+		if addrs[i] != expect[i] {
+			t.Errorf("Expected %v, got %v", expect, addrs)
+			break
+		}
+	}
+
+	r.noIPv6 = true
+	if addrs := r.order([]string{"::1", "127.0.0.1"}); len(addrs) != 1 {
+		t.Errorf("Expected IPv6 addresses to be skipped, got %v", addrs)
+	}
+
+	s := r.server("127.0.0.2:53")
+	s.penalize()
+	if s.rtt() != 20*time.Millisecond {
+		t.Errorf("Expected penalized rtt of 20ms, got %s", s.rtt())
+	}
+}
+
+func TestServersEvict(t *testing.T) {
+	r := New()
+	r.servers = newServers(2, r.newServer)
+	defer r.OnShutdown()
+
+	idle := r.server("127.0.0.1:53")
+	busy := r.servers.get("127.0.0.2:53", true)
+	r.server("127.0.0.1:53") // now the most recently used
+	r.server("127.0.0.3:53")
+	r.server("127.0.0.4:53")
+
+	if !idle.evicted || !idle.closed {
+		t.Errorf("Expected the least recently used server to be dropped and closed")
+	}
+	if !busy.evicted || busy.closed {
+		t.Errorf("Expected the server in use to be dropped, but not closed")
+	}
+	r.servers.release(busy)
+	if !busy.closed {
+		t.Errorf("Expected the dropped server to be closed when released")
+	}
+	if s := r.server("127.0.0.2:53"); s == busy || s.closed {
+		t.Errorf("Expected a new server for a dropped address")
+	}
+}
+
+func TestChain(t *testing.T) {
+	rrs := []dns.RR{
+		test.CNAME("a.example.org. 300 IN CNAME b.example.org."),
+		test.CNAME("b.example.org. 300 IN CNAME c.example.net."),
+		test.A("c.example.net. 300 IN A 127.0.0.1"),
+		test.A("unrelated.example.org. 300 IN A 127.0.0.2"),
+	}
+
+	ret, target, done := chain(rrs, "a.example.org.", dns.TypeA)
+	if !done || target != "c.example.net." || len(ret) != 3 {
+		t.Errorf("Expected complete chain to c.example.net., got %t, %s, %v", done, target, ret)
+	}
+	ret, target, done = chain(rrs[:2], "a.example.org.", dns.TypeA)
+	if done || target != "c.example.net." || len(ret) != 2 {
+		t.Errorf("Expected incomplete chain to c.example.net., got %t, %s, %v", done, target, ret)
+	}
+	ret, target, done = chain(rrs, "a.example.org.", dns.TypeCNAME)
+	if !done || target != "a.example.org." || len(ret) != 1 {
+		t.Errorf("Expected the CNAME itself, got %t, %s, %v", done, target, ret)
+	}
+}
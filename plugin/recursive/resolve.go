@@ -0,0 +1,297 @@
+package recursive
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// resolution holds the state of the resolution of a single client query.
+type resolution struct {
+	w       dns.ResponseWriter // the client's writer, the proxies use it to learn about the client
+	zone    string             // the zone of the plugin, for the metrics
+	queries int                // the number of queries sent so far
+	active  map[string]bool    // the name servers whose addresses are being resolved
+}
+
+var (
+	errMaxDepth   = errors.New("too many referrals or CNAMEs")
+	errMaxQueries = errors.New("too many queries")
+	errLoop       = errors.New("loop while resolving the addresses of name servers")
+	errNoServers  = errors.New("no name servers with addresses")
+	errLame       = errors.New("lame delegation")
+)
+
+// resolve resolves name and type qtype, following CNAMEs to other zones. It returns the response of the
+// name servers, with the CNAMEs that were followed in the answer section.
+func (r *Recursive) resolve(ctx context.Context, res *resolution, name string, qtype uint16) (*dns.Msg, error) {
+	var answer []dns.RR
+	for cnames := 0; ; cnames++ {
+		if cnames > maxCNAMEs {
+			return nil, errMaxDepth
+		}
+
+		m, err := r.iterate(ctx, res, name, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		rrs, target, done := chain(m.Answer, name, qtype)
+		answer = append(answer, rrs...)
+		if m.Rcode != dns.RcodeSuccess || done || target == name {
+			m.Answer = answer
+			if done {
+				m.Ns = nil
+			}
+			return m, nil
+		}
+		name = target
+	}
+}
+
+// iterate resolves name and type qtype, by following the referrals from the closest known delegation.
+// When QNAME minimisation is enabled the name servers only see the labels of name they need to make a
+// referral, instead of the full name.
+func (r *Recursive) iterate(ctx context.Context, res *resolution, name string, qtype uint16) (*dns.Msg, error) {
+	var d *delegation
+	if qtype == dns.TypeDS && name != "." {
+		// The DS records live in the parent zone.
+		d = r.closest(parent(name))
+	} else {
+		d = r.closest(name)
+	}
+
+	labels := dns.CountLabel(d.zone) + 1
+	minimise := r.qmin
+	for referrals, steps := 0, 0; ; {
+		qname, qt := name, qtype
+		if minimise && steps < maxMinimise {
+			if qname = minimised(name, labels); qname != name {
+				qt = dns.TypeA
+			}
+		}
+
+		m, err := r.exchange(ctx, res, d, qname, qt)
+		if err != nil {
+			if qname != name {
+				// Broken name servers don't like the minimised query, fall back to the full name.
+				minimise = false
+				continue
+			}
+			return nil, err
+		}
+
+		if zone := referral(m); zone != "" {
+			if referrals++; referrals > r.maxDepth {
+				return nil, errMaxDepth
+			}
+			d = r.delegation(m, d.zone, zone)
+			labels = dns.CountLabel(d.zone) + 1
+			continue
+		}
+
+		if qname == name {
+			return m, nil
+		}
+		if m.Rcode == dns.RcodeNameError {
+			// Either the name doesn't exist (RFC 8020), or the name server is broken, ask for the full
+			// name to find out.
+			minimise = false
+			continue
+		}
+		// The name exists in the same zone, ask for one more label.
+		labels++
+		steps++
+	}
+}
+
+// delegation returns the delegation to zone in the referral m, sent by a name server for parent. The
+// delegation and the glue are cached.
+func (r *Recursive) delegation(m *dns.Msg, parent, zone string) *delegation {
+	d := &delegation{zone: zone, addrs: make(map[string][]string)}
+	ttl := uint32(maxDelegationTTL)
+	for _, rr := range m.Ns {
+		ns, ok := rr.(*dns.NS)
+		if !ok || ns.Hdr.Name != zone {
+			continue
+		}
+		d.ns = append(d.ns, strings.ToLower(ns.Ns))
+		if ns.Hdr.Ttl < ttl {
+			ttl = ns.Hdr.Ttl
+		}
+	}
+	if ttl < minDelegationTTL {
+		ttl = minDelegationTTL
+	}
+
+	for _, rr := range m.Extra {
+		// Only accept glue from the zone of the name server that sent it.
+		name := rr.Header().Name
+		if !dns.IsSubDomain(parent, name) || !contains(d.ns, name) {
+			continue
+		}
+		if ip := address(rr); ip != "" {
+			d.addrs[name] = append(d.addrs[name], ip)
+		}
+	}
+
+	d.expires = r.now().Add(time.Duration(ttl) * time.Second)
+	r.addDelegation(d)
+	return d
+}
+
+// addresses returns the addresses of the name servers of d. If there is no glue for any of them, the
+// addresses of the name servers are resolved.
+func (r *Recursive) addresses(ctx context.Context, res *resolution, d *delegation) ([]string, error) {
+	var ips []string
+	for _, ns := range d.ns {
+		if a, ok := d.addrs[ns]; ok {
+			ips = append(ips, a...)
+			continue
+		}
+		ips = append(ips, r.hostAddrs(ns)...)
+	}
+	if len(ips) > 0 {
+		return ips, nil
+	}
+
+	err := errNoServers
+	for _, i := range r.rand.Perm(len(d.ns)) {
+		ns := d.ns[i]
+		if res.active[ns] {
+			err = errLoop
+			continue
+		}
+		res.active[ns] = true
+		ips = r.lookup(ctx, res, ns)
+		delete(res.active, ns)
+		if len(ips) > 0 {
+			return ips, nil
+		}
+	}
+	return nil, err
+}
+
+// lookup resolves the addresses of the name server ns and caches them.
+func (r *Recursive) lookup(ctx context.Context, res *resolution, ns string) []string {
+	qtypes := []uint16{dns.TypeA, dns.TypeAAAA}
+	if r.noIPv6 {
+		qtypes = qtypes[:1]
+	}
+
+	var ips []string
+	ttl := uint32(maxDelegationTTL)
+	for _, qtype := range qtypes {
+		m, err := r.resolve(ctx, res, ns, qtype)
+		if err != nil {
+			log.Debugf("Failed to resolve the address of name server %s: %s", ns, err)
+			continue
+		}
+		for _, rr := range m.Answer {
+			if ip := address(rr); ip != "" {
+				ips = append(ips, ip)
+				if rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+		}
+	}
+	if len(ips) > 0 {
+		r.addHost(ns, ips, ttl)
+	}
+	return ips
+}
+
+// chain returns the records in rrs that answer name and type qtype: the CNAMEs (and the DNAMEs they are
+// synthesized from) that lead from name to target, and the records of type qtype at target, if there
+// are any, in which case done is true.
+func chain(rrs []dns.RR, name string, qtype uint16) (ret []dns.RR, target string, done bool) {
+	used := make([]bool, len(rrs))
+	seen := make(map[string]bool)
+	for !seen[name] {
+		seen[name] = true
+		cname := ""
+		for i, rr := range rrs {
+			if used[i] {
+				continue
+			}
+			h := rr.Header()
+			switch {
+			case h.Name != name:
+				if h.Rrtype == dns.TypeDNAME && dns.IsSubDomain(h.Name, name) {
+					ret = append(ret, rr)
+					used[i] = true
+				}
+				continue
+			case h.Rrtype == qtype || qtype == dns.TypeANY:
+				done = true
+			case h.Rrtype == dns.TypeCNAME:
+				cname = strings.ToLower(rr.(*dns.CNAME).Target)
+			case h.Rrtype == dns.TypeRRSIG:
+				if t := rr.(*dns.RRSIG).TypeCovered; t != qtype && t != dns.TypeCNAME {
+					continue
+				}
+			default:
+				continue
+			}
+			ret = append(ret, rr)
+			used[i] = true
+		}
+		if done || cname == "" {
+			return ret, name, done
+		}
+		name = cname
+	}
+	return ret, name, false
+}
+
+// referral returns the zone that m delegates to, or the empty string if m isn't a referral.
+func referral(m *dns.Msg) string {
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) > 0 {
+		return ""
+	}
+	zone := ""
+	for _, rr := range m.Ns {
+		switch rr.Header().Rrtype {
+		case dns.TypeSOA:
+			return ""
+		case dns.TypeNS:
+			zone = rr.Header().Name
+		}
+	}
+	return zone
+}
+
+// minimised returns the last labels labels of name.
+func minimised(name string, labels int) string {
+	idx := dns.Split(name)
+	if labels >= len(idx) {
+		return name
+	}
+	return name[idx[len(idx)-labels]:]
+}
+
+// parent returns the parent of name, which must not be the root.
+func parent(name string) string {
+	off, _ := dns.NextLabel(name, 0)
+	return name[off:]
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	maxCNAMEs        = 8
+	maxMinimise      = 10 // MAX_MINIMISE_COUNT from RFC 9156
+	minDelegationTTL = 5
+	maxDelegationTTL = 86400
+)
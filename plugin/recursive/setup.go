@@ -0,0 +1,117 @@
+package recursive
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+const pluginName = "recursive"
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	r, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	c.OnShutdown(r.OnShutdown)
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		r.Next = next
+		return r
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Recursive, error) {
+	r := New()
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		r.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "root_hints":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				fileName := args[0]
+				if !filepath.IsAbs(fileName) && config.Root != "" {
+					fileName = filepath.Join(config.Root, fileName)
+				}
+				reader, err := os.Open(filepath.Clean(fileName))
+				if err != nil {
+					return nil, c.Errf("unable to open root hints file %q: %s", fileName, err)
+				}
+				hints, err := parseHints(reader, fileName)
+				reader.Close()
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				r.hints = hints
+			case "no_qname_minimisation":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.qmin = false
+			case "no_0x20":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.use0x20 = false
+			case "no_ipv6":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				r.noIPv6 = true
+			case "max_depth":
+				n, err := positive(c)
+				if err != nil {
+					return nil, err
+				}
+				r.maxDepth = n
+			case "max_queries":
+				n, err := positive(c)
+				if err != nil {
+					return nil, err
+				}
+				r.maxQueries = n
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+	return r, nil
+}
+
+// positive parses the single positive integer argument of the current property.
+func positive(c *caddy.Controller) (int, error) {
+	name := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, c.Errf("%s must be positive: %d", name, n)
+	}
+	return n, nil
+}
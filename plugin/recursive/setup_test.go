@@ -0,0 +1,93 @@
+package recursive
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestSetup(t *testing.T) {
+	hints, rm, err := test.TempFile(".", `.  3600000 NS A.ROOT-SERVERS.TEST.
+A.ROOT-SERVERS.TEST. 3600000 A 127.0.0.1
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+	bad, rm, err := test.TempFile(".", "example.org. 3600 IN NS ns.example.org.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input      string
+		shouldErr  bool
+		roots      int
+		qmin       bool
+		use0x20    bool
+		maxQueries int
+	}{
+		{`recursive`, false, 13, true, true, defaultMaxQueries},
+		{`recursive example.org`, false, 13, true, true, defaultMaxQueries},
+		{`recursive {
+			root_hints ` + hints + `
+			no_qname_minimisation
+			no_0x20
+			no_ipv6
+			max_depth 10
+			max_queries 50
+		}`, false, 1, false, false, 50},
+		// negative
+		{`recursive {
+			root_hints
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			root_hints /does/not/exist
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			root_hints ` + bad + `
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			no_0x20 yes
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			max_queries 0
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			max_depth many
+		}`, true, 0, false, false, 0},
+		{`recursive {
+			blah
+		}`, true, 0, false, false, 0},
+		{`recursive
+		recursive`, true, 0, false, false, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		r, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if len(r.hints.ns) != tc.roots {
+			t.Errorf("Test %d: expected %d root name servers, got %d", i, tc.roots, len(r.hints.ns))
+		}
+		if r.qmin != tc.qmin {
+			t.Errorf("Test %d: expected qname minimisation %t, got %t", i, tc.qmin, r.qmin)
+		}
+		if r.use0x20 != tc.use0x20 {
+			t.Errorf("Test %d: expected 0x20 %t, got %t", i, tc.use0x20, r.use0x20)
+		}
+		if r.maxQueries != tc.maxQueries {
+			t.Errorf("Test %d: expected max queries %d, got %d", i, tc.maxQueries, r.maxQueries)
+		}
+	}
+}
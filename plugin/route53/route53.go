@@ -41,6 +41,8 @@ type zone struct {
 	id  string
 	z   *file.Zone
 	dns string
+
+	versions map[rrsetKey]string // The version of each RRset, as last listed.
 }
 
 type zones map[string][]*zone
@@ -53,6 +55,7 @@ type zones map[string][]*zone
 func New(ctx context.Context, c route53iface.Route53API, keys map[string][]string, refresh time.Duration) (*Route53, error) {
 	zones := make(map[string][]*zone, len(keys))
 	zoneNames := make([]string, 0, len(keys))
+	up := upstream.New()
 	for dns, hostedZoneIDs := range keys {
 		for _, hostedZoneID := range hostedZoneIDs {
 			_, err := c.ListHostedZonesByNameWithContext(ctx, &route53.ListHostedZonesByNameInput{
@@ -65,14 +68,16 @@ func New(ctx context.Context, c route53iface.Route53API, keys map[string][]strin
 			if _, ok := zones[dns]; !ok {
 				zoneNames = append(zoneNames, dns)
 			}
-			zones[dns] = append(zones[dns], &zone{id: hostedZoneID, dns: dns, z: file.NewZone(dns, "")})
+			z := file.NewZone(dns, "")
+			z.Upstream = up
+			zones[dns] = append(zones[dns], &zone{id: hostedZoneID, dns: dns, z: z})
 		}
 	}
 	return &Route53{
 		client:    c,
 		zoneNames: zoneNames,
 		zones:     zones,
-		upstream:  upstream.New(),
+		upstream:  up,
 		refresh:   refresh,
 	}, nil
 }
@@ -211,27 +216,61 @@ func maybeUnescape(s string) (string, error) {
 	}
 }
 
-func updateZoneFromRRS(rrs *route53.ResourceRecordSet, z *file.Zone) error {
+// rrsetKey identifies an RRset, the type is kept as a string as route53 may hold types not known to us.
+type rrsetKey struct {
+	name string // Unescaped, fully qualified and lower case.
+	typ  string
+}
+
+// listedRRset is an RRset as listed from route53. Several resource record sets with different set
+// identifiers, used by weighted and latency routing, can make up an RRset.
+type listedRRset struct {
+	sets    []*route53.ResourceRecordSet
+	version strings.Builder
+}
+
+func (l *listedRRset) add(rrs *route53.ResourceRecordSet) {
+	l.sets = append(l.sets, rrs)
+	fmt.Fprintf(&l.version, "%s/%d", aws.StringValue(rrs.SetIdentifier), aws.Int64Value(rrs.TTL))
+	for _, rr := range rrs.ResourceRecords {
+		fmt.Fprintf(&l.version, "/%s", aws.StringValue(rr.Value))
+	}
+	l.version.WriteByte(';')
+}
+
+func (l *listedRRset) rrs() ([]dns.RR, error) {
+	var rrs []dns.RR
+	for _, set := range l.sets {
+		rr, err := rrsFromRRS(set)
+		if err != nil {
+			return nil, err
+		}
+		rrs = append(rrs, rr...)
+	}
+	return rrs, nil
+}
+
+func rrsFromRRS(rrs *route53.ResourceRecordSet) ([]dns.RR, error) {
+	var out []dns.RR
 	for _, rr := range rrs.ResourceRecords {
 		n, err := maybeUnescape(aws.StringValue(rrs.Name))
 		if err != nil {
-			return fmt.Errorf("failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
+			return nil, fmt.Errorf("failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
 		}
 		v, err := maybeUnescape(aws.StringValue(rr.Value))
 		if err != nil {
-			return fmt.Errorf("failed to unescape `%s' value: %v", aws.StringValue(rr.Value), err)
+			return nil, fmt.Errorf("failed to unescape `%s' value: %v", aws.StringValue(rr.Value), err)
 		}
 
 		// Assemble RFC 1035 conforming record to pass into dns scanner.
 		rfc1035 := fmt.Sprintf("%s %d IN %s %s", n, aws.Int64Value(rrs.TTL), aws.StringValue(rrs.Type), v)
 		r, err := dns.NewRR(rfc1035)
 		if err != nil {
-			return fmt.Errorf("failed to parse resource record: %v", err)
+			return nil, fmt.Errorf("failed to parse resource record: %v", err)
 		}
-
-		z.Insert(r)
+		out = append(out, r)
 	}
-	return nil
+	return out, nil
 }
 
 // updateZones re-queries resource record sets for each zone and updates the
@@ -248,30 +287,11 @@ func (h *Route53) updateZones(ctx context.Context) error {
 				errc <- err
 			}()
 
-			for i, hostedZone := range z {
-				newZ := file.NewZone(zName, "")
-				newZ.Upstream = h.upstream
-				in := &route53.ListResourceRecordSetsInput{
-					HostedZoneId: aws.String(hostedZone.id),
-					MaxItems:     aws.String("1000"),
-				}
-				err = h.client.ListResourceRecordSetsPagesWithContext(ctx, in,
-					func(out *route53.ListResourceRecordSetsOutput, last bool) bool {
-						for _, rrs := range out.ResourceRecordSets {
-							if err := updateZoneFromRRS(rrs, newZ); err != nil {
-								// Maybe unsupported record type. Log and carry on.
-								log.Warningf("Failed to process resource record set: %v", err)
-							}
-						}
-						return true
-					})
-				if err != nil {
+			for _, hostedZone := range z {
+				if err = h.updateZone(ctx, hostedZone); err != nil {
 					err = fmt.Errorf("failed to list resource records for %v:%v from route53: %v", zName, hostedZone.id, err)
 					return
 				}
-				h.zMu.Lock()
-				(*z[i]).z = newZ
-				h.zMu.Unlock()
 			}
 		}(zName, z)
 	}
@@ -290,5 +310,73 @@ func (h *Route53) updateZones(ctx context.Context) error {
 	return nil
 }
 
+// updateZone lists the resource record sets of hostedZone. Route53 has no way to list only the
+// changes, so all sets are listed, but only the RRsets that changed since the previous listing are
+// converted and applied to the zone.
+func (h *Route53) updateZone(ctx context.Context, hostedZone *zone) error {
+	start := time.Now()
+	listed := map[rrsetKey]*listedRRset{}
+	records := 0
+	in := &route53.ListResourceRecordSetsInput{
+		HostedZoneId: aws.String(hostedZone.id),
+		MaxItems:     aws.String("1000"),
+	}
+	err := h.client.ListResourceRecordSetsPagesWithContext(ctx, in,
+		func(out *route53.ListResourceRecordSetsOutput, last bool) bool {
+			for _, rrs := range out.ResourceRecordSets {
+				n, err := maybeUnescape(aws.StringValue(rrs.Name))
+				if err != nil {
+					log.Warningf("Failed to process resource record set: failed to unescape `%s' name: %v", aws.StringValue(rrs.Name), err)
+					continue
+				}
+				key := rrsetKey{dns.CanonicalName(n), aws.StringValue(rrs.Type)}
+				if listed[key] == nil {
+					listed[key] = &listedRRset{}
+				}
+				listed[key].add(rrs)
+				records += len(rrs.ResourceRecords)
+			}
+			return true
+		})
+	if err != nil {
+		return err
+	}
+
+	changes := map[rrsetKey][]dns.RR{}
+	versions := make(map[rrsetKey]string, len(listed))
+	for key, l := range listed {
+		version := l.version.String()
+		if v, ok := hostedZone.versions[key]; ok && v == version {
+			versions[key] = version
+			continue
+		}
+		rrs, err := l.rrs()
+		if err != nil {
+			// Maybe unsupported record type. Log and carry on.
+			log.Warningf("Failed to process resource record set: %v", err)
+			continue
+		}
+		changes[key] = rrs
+		versions[key] = version
+	}
+	for key := range hostedZone.versions {
+		if _, ok := versions[key]; !ok {
+			changes[key] = nil
+		}
+	}
+
+	h.zMu.Lock()
+	for key, rrs := range changes {
+		hostedZone.z.SetRRset(key.name, dns.StringToType[key.typ], rrs)
+	}
+	h.zMu.Unlock()
+	hostedZone.versions = versions
+
+	refreshDuration.WithLabelValues(hostedZone.dns, hostedZone.id).Observe(time.Since(start).Seconds())
+	recordCount.WithLabelValues(hostedZone.dns, hostedZone.id).Set(float64(records))
+	rrsetChangeCount.WithLabelValues(hostedZone.dns, hostedZone.id).Add(float64(len(changes)))
+	return nil
+}
+
 // Name implements plugin.Handler.Name.
 func (h *Route53) Name() string { return "route53" }
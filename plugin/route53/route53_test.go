@@ -296,3 +296,83 @@ func TestMaybeUnescape(t *testing.T) {
 		}
 	}
 }
+
+type listRoute53 struct {
+	fakeRoute53
+	sets []*route53.ResourceRecordSet
+}
+
+func (l *listRoute53) ListResourceRecordSetsPagesWithContext(_ aws.Context, _ *route53.ListResourceRecordSetsInput, fn func(*route53.ListResourceRecordSetsOutput, bool) bool, _ ...request.Option) error {
+	fn(&route53.ListResourceRecordSetsOutput{ResourceRecordSets: l.sets}, true)
+	return nil
+}
+
+func resourceRecordSets(rrs ...string) []*route53.ResourceRecordSet {
+	var sets []*route53.ResourceRecordSet
+	for _, s := range rrs {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		sets = append(sets, resourceRecordSet(rr.Header().Name, rr.Header().Rrtype, rr.Header().Ttl, []dns.RR{rr}))
+	}
+	return sets
+}
+
+func TestUpdateZoneIncremental(t *testing.T) {
+	ctx := context.Background()
+	c := &listRoute53{sets: resourceRecordSets(
+		"example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 7200 900 1209600 86400",
+		"www.example.org. 300 IN A 10.0.0.1",
+		"mail.example.org. 300 IN A 10.0.0.2",
+		"txt.example.org. 300 IN TXT \"txt\"",
+	)}
+	// A second set with the same name and type, as used by weighted routing.
+	weighted := resourceRecordSets("www.example.org. 300 IN A 10.0.0.3")[0]
+	weighted.SetIdentifier = aws.String("second")
+	c.sets = append(c.sets, weighted)
+
+	r, err := New(ctx, c, map[string][]string{"example.org.": {"Z1"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create route53: %v", err)
+	}
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	hz := r.zones["example.org."][0]
+	z := hz.z
+	if rrs := z.RRset("www.example.org.", dns.TypeA); len(rrs) != 2 {
+		t.Fatalf("Expected 2 www.example.org. A records, got %v", rrs)
+	}
+	mail := z.RRset("mail.example.org.", dns.TypeA)[0]
+
+	// Change www, delete txt and add new.
+	c.sets = append(resourceRecordSets(
+		"example.org. 300 IN SOA ns.example.org. hostmaster.example.org. 1 7200 900 1209600 86400",
+		"www.example.org. 300 IN A 10.0.0.4",
+		"mail.example.org. 300 IN A 10.0.0.2",
+		"new.example.org. 300 IN AAAA ::1",
+	), weighted)
+	if err := r.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+	if hz.z != z {
+		t.Error("Expected the zone to be updated in place")
+	}
+	if rrs := z.RRset("www.example.org.", dns.TypeA); len(rrs) != 2 || (rrs[0].(*dns.A).A.String() != "10.0.0.4" && rrs[1].(*dns.A).A.String() != "10.0.0.4") {
+		t.Errorf("Expected www.example.org. A to be changed, got %v", rrs)
+	}
+	if rrs := z.RRset("txt.example.org.", dns.TypeTXT); len(rrs) != 0 {
+		t.Errorf("Expected txt.example.org. TXT to be deleted, got %v", rrs)
+	}
+	if rrs := z.RRset("new.example.org.", dns.TypeAAAA); len(rrs) != 1 {
+		t.Errorf("Expected new.example.org. AAAA to be added, got %v", rrs)
+	}
+	// Unchanged RRsets are left alone.
+	if rrs := z.RRset("mail.example.org.", dns.TypeA); len(rrs) != 1 || rrs[0] != mail {
+		t.Errorf("Expected mail.example.org. A to be left alone, got %v", rrs)
+	}
+	if len(hz.versions) != 4 {
+		t.Errorf("Expected 4 RRsets, got %d", len(hz.versions))
+	}
+}
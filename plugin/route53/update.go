@@ -0,0 +1,86 @@
+package route53
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coredns/coredns/plugin/admin"
+	"github.com/coredns/coredns/plugin/file"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// Update implements the admin.Updater interface. The hosted zone is selected by its id.
+func (h *Route53) Update(ctx context.Context, zName, hostedZone string, changes []admin.Change) error {
+	z, ok := h.zones[zName]
+	if !ok {
+		return admin.ErrNotAuthoritative
+	}
+	ids := make([]string, len(z))
+	for i, hz := range z {
+		ids[i] = hz.id
+	}
+	i, err := admin.HostedZone(zName, hostedZone, ids)
+	if err != nil {
+		return err
+	}
+
+	// The zone is changed in place when it is refreshed, read it under the lock.
+	h.zMu.RLock()
+	batch, err := changeBatch(zName, z[i].z, changes)
+	h.zMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(z[i].id),
+		ChangeBatch:  batch,
+	}); err != nil {
+		return fmt.Errorf("failed to change resource record sets of %v:%v in route53: %v", zName, z[i].id, err)
+	}
+
+	h.zMu.Lock()
+	z[i].z = admin.Apply(zName, z[i].z, changes)
+	h.zMu.Unlock()
+	return nil
+}
+
+// changeBatch returns the route53 changes that apply changes to the zone z.
+func changeBatch(zName string, z *file.Zone, changes []admin.Change) (*route53.ChangeBatch, error) {
+	batch := &route53.ChangeBatch{}
+	for _, c := range changes {
+		switch c.Action {
+		case admin.Upsert:
+			batch.Changes = append(batch.Changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionUpsert),
+				ResourceRecordSet: resourceRecordSet(c.Name, c.Type, c.TTL, c.RRs),
+			})
+		case admin.Delete:
+			// Route53 only deletes a record set that matches exactly.
+			rrs := admin.RRset(zName, z, c.Name, c.Type)
+			if len(rrs) == 0 {
+				return nil, fmt.Errorf("%w: %s %s doesn't exist", admin.ErrInvalid, c.Name, dns.TypeToString[c.Type])
+			}
+			batch.Changes = append(batch.Changes, &route53.Change{
+				Action:            aws.String(route53.ChangeActionDelete),
+				ResourceRecordSet: resourceRecordSet(c.Name, c.Type, rrs[0].Header().Ttl, rrs),
+			})
+		}
+	}
+	return batch, nil
+}
+
+func resourceRecordSet(name string, typ uint16, ttl uint32, rrs []dns.RR) *route53.ResourceRecordSet {
+	set := &route53.ResourceRecordSet{
+		Name: aws.String(name),
+		Type: aws.String(dns.TypeToString[typ]),
+		TTL:  aws.Int64(int64(ttl)),
+	}
+	for _, rr := range rrs {
+		set.ResourceRecords = append(set.ResourceRecords, &route53.ResourceRecord{Value: aws.String(admin.Rdata(rr))})
+	}
+	return set
+}
@@ -0,0 +1,84 @@
+package route53
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/admin"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+type changeRoute53 struct {
+	fakeRoute53
+	input *route53.ChangeResourceRecordSetsInput
+}
+
+func (c *changeRoute53) ChangeResourceRecordSetsWithContext(_ aws.Context, input *route53.ChangeResourceRecordSetsInput, _ ...request.Option) (*route53.ChangeResourceRecordSetsOutput, error) {
+	c.input = input
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := &changeRoute53{}
+	h, err := New(ctx, c, map[string][]string{"org.": {"1357986420", "1234567890"}}, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to create route53: %v", err)
+	}
+	if err := h.updateZones(ctx); err != nil {
+		t.Fatalf("Failed to update zones: %v", err)
+	}
+
+	changes := []admin.Change{
+		{Action: admin.Upsert, Name: "new.example.org.", Type: dns.TypeA, TTL: 60, RRs: []dns.RR{test.A("new.example.org. 60 IN A 10.0.0.1")}},
+		{Action: admin.Delete, Name: "example.org.", Type: dns.TypePTR},
+	}
+	if err := h.Update(ctx, "example.com.", "", changes); !errors.Is(err, admin.ErrNotAuthoritative) {
+		t.Errorf("Expected ErrNotAuthoritative, got %v", err)
+	}
+	if err := h.Update(ctx, "org.", "", changes); !errors.Is(err, admin.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for a zone in two hosted zones, got %v", err)
+	}
+	missing := []admin.Change{{Action: admin.Delete, Name: "missing.org.", Type: dns.TypeA}}
+	if err := h.Update(ctx, "org.", "1234567890", missing); !errors.Is(err, admin.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for deleting a missing RRset, got %v", err)
+	}
+
+	if err := h.Update(ctx, "org.", "1234567890", changes); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if aws.StringValue(c.input.HostedZoneId) != "1234567890" {
+		t.Errorf("Expected hosted zone 1234567890, got %s", aws.StringValue(c.input.HostedZoneId))
+	}
+	got := c.input.ChangeBatch.Changes
+	if len(got) != 2 {
+		t.Fatalf("Expected 2 changes, got %d", len(got))
+	}
+	if aws.StringValue(got[0].Action) != route53.ChangeActionUpsert || aws.StringValue(got[0].ResourceRecordSet.ResourceRecords[0].Value) != "10.0.0.1" {
+		t.Errorf("Unexpected upsert: %v", got[0])
+	}
+	// The deleted record set must match the existing one.
+	if aws.StringValue(got[1].Action) != route53.ChangeActionDelete || aws.StringValue(got[1].ResourceRecordSet.ResourceRecords[0].Value) != "ptr.example.org." ||
+		aws.Int64Value(got[1].ResourceRecordSet.TTL) != 300 {
+		t.Errorf("Unexpected delete: %v", got[1])
+	}
+
+	for _, hz := range h.zones["org."] {
+		if hz.id != "1234567890" {
+			continue
+		}
+		if rrs := admin.RRset("org.", hz.z, "new.example.org.", dns.TypeA); len(rrs) != 1 {
+			t.Errorf("Expected new.example.org. A in the zone, got %v", rrs)
+		}
+		if rrs := admin.RRset("org.", hz.z, "example.org.", dns.TypePTR); len(rrs) != 0 {
+			t.Errorf("Expected example.org. PTR to be deleted, got %v", rrs)
+		}
+	}
+}
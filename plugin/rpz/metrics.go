@@ -0,0 +1,18 @@
+package rpz
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// HitsCount is the number of queries that matched a rule in a policy zone, per policy zone, trigger and action.
+	HitsCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "hits_total",
+		Help:      "Counter of queries that matched a policy rule.",
+	}, []string{"server", "zone", "view", "policy", "trigger", "action"})
+)
@@ -0,0 +1,81 @@
+package rpz
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// nameservers looks up the name servers of the zone of the query name, and their addresses, with the
+// next plugin. They are only looked up when a NSDNAME or NSIP trigger needs them.
+type nameservers struct {
+	rp    *RPZ
+	ctx   context.Context
+	state request.Request
+
+	ns     []string
+	nsDone bool
+	ips    []net.IP
+	ipDone bool
+}
+
+// names returns the names of the name servers of the closest enclosing zone of the query name.
+func (n *nameservers) names() []string {
+	if n.nsDone {
+		return n.ns
+	}
+	n.nsDone = true
+
+	name := n.state.Name()
+	for {
+		if m := n.query(name, dns.TypeNS); m != nil {
+			for _, rr := range m.Answer {
+				if x, ok := rr.(*dns.NS); ok && strings.EqualFold(x.Hdr.Name, name) {
+					n.ns = append(n.ns, strings.ToLower(x.Ns))
+				}
+			}
+		}
+		if len(n.ns) > 0 || name == "." {
+			return n.ns
+		}
+		off, _ := dns.NextLabel(name, 0)
+		name = name[off:]
+	}
+}
+
+// addrs returns the addresses of the name servers.
+func (n *nameservers) addrs() []net.IP {
+	if n.ipDone {
+		return n.ips
+	}
+	n.ipDone = true
+
+	for _, ns := range n.names() {
+		for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			m := n.query(ns, qtype)
+			if m == nil {
+				continue
+			}
+			for _, rr := range m.Answer {
+				if ip := address(rr); ip != nil {
+					n.ips = append(n.ips, ip)
+				}
+			}
+		}
+	}
+	return n.ips
+}
+
+func (n *nameservers) query(name string, qtype uint16) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, qtype)
+	nw := nonwriter.New(n.state.W)
+	plugin.NextOrFailure(n.rp.Name(), n.rp.Next, n.ctx, nw, req)
+	return nw.Msg
+}
@@ -0,0 +1,319 @@
+package rpz
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// action is what to do when a rule is triggered.
+type action int
+
+const (
+	actionNXDOMAIN action = iota // CNAME .
+	actionNODATA                 // CNAME *.
+	actionPassthru               // CNAME rpz-passthru.
+	actionDrop                   // CNAME rpz-drop.
+	actionTCPOnly                // CNAME rpz-tcp-only.
+	actionLocal                  // any other data
+)
+
+func (a action) String() string {
+	switch a {
+	case actionNXDOMAIN:
+		return "nxdomain"
+	case actionNODATA:
+		return "nodata"
+	case actionPassthru:
+		return "passthru"
+	case actionDrop:
+		return "drop"
+	case actionTCPOnly:
+		return "tcp-only"
+	}
+	return "local"
+}
+
+// The kinds of triggers, the labels that mark them in a policy zone are in trigger.
+const (
+	triggerQname      = "qname"
+	triggerClientIP   = "client-ip"
+	triggerResponseIP = "response-ip"
+	triggerNSDname    = "nsdname"
+	triggerNSIP       = "nsip"
+)
+
+// rule is a single policy rule: the records of a name in a policy zone.
+type rule struct {
+	owner  string // owner name in the policy zone
+	action action
+	rrs    []dns.RR // local data, only for actionLocal
+}
+
+// ipRule is a rule that is triggered by an address in the network.
+type ipRule struct {
+	*rule
+	network *net.IPNet
+	ones    int
+}
+
+// rules are the compiled rules of a policy zone.
+type rules struct {
+	qname      map[string]*rule // keyed by name, wildcard triggers start with "*."
+	nsdname    map[string]*rule
+	clientIP   []ipRule // sorted on prefix length, longest first
+	responseIP []ipRule
+	nsIP       []ipRule
+}
+
+// response returns true if rs has triggers that need the response to the query.
+func (rs *rules) response() bool {
+	return len(rs.responseIP) > 0 || len(rs.nsdname) > 0 || len(rs.nsIP) > 0
+}
+
+// policy is a policy zone. The zone is loaded and kept up to date by the file plugin, the rules are
+// compiled from it whenever it changes.
+type policy struct {
+	name string
+	z    *file.Zone
+
+	mu    sync.RWMutex
+	tree  *tree.Tree // tree the rules are compiled from
+	rules *rules
+}
+
+func newPolicy(z *file.Zone, name string) *policy {
+	return &policy{name: name, z: z}
+}
+
+// get returns the rules of p, compiling them when the zone has changed.
+func (p *policy) get() *rules {
+	p.z.RLock()
+	defer p.z.RUnlock()
+
+	p.mu.RLock()
+	rs := p.rules
+	if rs != nil && p.tree == p.z.Tree {
+		p.mu.RUnlock()
+		return rs
+	}
+	p.mu.RUnlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Another query may have compiled the rules while we waited for the lock.
+	if p.rules == nil || p.tree != p.z.Tree {
+		p.rules = compile(p.z.Tree, p.name)
+		p.tree = p.z.Tree
+	}
+	return p.rules
+}
+
+// soa returns the SOA record of the policy zone, or nil.
+func (p *policy) soa() *dns.SOA {
+	p.z.RLock()
+	defer p.z.RUnlock()
+	return p.z.Apex.SOA
+}
+
+// compile compiles the rules in tree t of the policy zone origin.
+func compile(t *tree.Tree, origin string) *rules {
+	rs := &rules{qname: make(map[string]*rule), nsdname: make(map[string]*rule)}
+	if t == nil {
+		return rs
+	}
+
+	for _, e := range t.All() {
+		owner := e.Name()
+		name := relative(owner, origin)
+		if name == "" {
+			continue
+		}
+		r := newRule(owner, e.All())
+		if r == nil {
+			continue
+		}
+
+		kind, trigger := triggerOf(name)
+		switch kind {
+		case triggerQname:
+			rs.qname[trigger] = r
+		case triggerNSDname:
+			rs.nsdname[trigger] = r
+		default:
+			network, err := parseIP(trigger)
+			if err != nil {
+				log.Warningf("Skipping %s in policy zone %s: %s", owner, origin, err)
+				continue
+			}
+			ones, _ := network.Mask.Size()
+			ir := ipRule{rule: r, network: network, ones: ones}
+			switch kind {
+			case triggerClientIP:
+				rs.clientIP = append(rs.clientIP, ir)
+			case triggerResponseIP:
+				rs.responseIP = append(rs.responseIP, ir)
+			case triggerNSIP:
+				rs.nsIP = append(rs.nsIP, ir)
+			}
+		}
+	}
+
+	for _, irs := range [][]ipRule{rs.clientIP, rs.responseIP, rs.nsIP} {
+		sort.SliceStable(irs, func(i, j int) bool { return irs[i].ones > irs[j].ones })
+	}
+	return rs
+}
+
+// newRule returns the rule for the records rrs of owner, or nil if there are none.
+func newRule(owner string, rrs []dns.RR) *rule {
+	r := &rule{owner: owner, action: actionLocal}
+	for _, rr := range rrs {
+		switch rr.Header().Rrtype {
+		case dns.TypeRRSIG, dns.TypeNSEC:
+			continue
+		case dns.TypeCNAME:
+			switch rr.(*dns.CNAME).Target {
+			case ".":
+				r.action = actionNXDOMAIN
+				return r
+			case "*.":
+				r.action = actionNODATA
+				return r
+			case "rpz-passthru.":
+				r.action = actionPassthru
+				return r
+			case "rpz-drop.":
+				r.action = actionDrop
+				return r
+			case "rpz-tcp-only.":
+				r.action = actionTCPOnly
+				return r
+			}
+		}
+		r.rrs = append(r.rrs, rr)
+	}
+	if len(r.rrs) == 0 {
+		return nil
+	}
+	return r
+}
+
+// relative returns name without the origin, or the empty string if name is the origin or not below it.
+func relative(name, origin string) string {
+	if name == origin || !dns.IsSubDomain(origin, name) {
+		return ""
+	}
+	if origin == "." {
+		return name
+	}
+	return name[:len(name)-len(origin)]
+}
+
+// triggerOf returns the kind of trigger of the relative name in a policy zone and the trigger itself:
+// the name or the encoded address.
+func triggerOf(name string) (kind, trigger string) {
+	for _, t := range []struct{ kind, label string }{
+		{triggerClientIP, "rpz-client-ip."},
+		{triggerResponseIP, "rpz-ip."},
+		{triggerNSDname, "rpz-nsdname."},
+		{triggerNSIP, "rpz-nsip."},
+	} {
+		if strings.HasSuffix(name, "."+t.label) {
+			return t.kind, name[:len(name)-len(t.label)]
+		}
+	}
+	return triggerQname, name
+}
+
+// parseIP parses an address trigger: the prefix length followed by the labels of the address in
+// reverse order, with "zz" standing for "::" in IPv6 addresses. I.e. 24.0.2.0.192 is 192.0.2.0/24 and
+// 48.zz.db8.2001 is 2001:db8::/48.
+func parseIP(s string) (*net.IPNet, error) {
+	labels := dns.SplitDomainName(s)
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("invalid address trigger %q", s)
+	}
+	ones, err := strconv.Atoi(labels[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid prefix length in %q", s)
+	}
+
+	addr := labels[1:]
+	for i, j := 0, len(addr)-1; i < j; i, j = i+1, j-1 {
+		addr[i], addr[j] = addr[j], addr[i]
+	}
+
+	var (
+		ip   net.IP
+		bits int
+	)
+	if len(addr) == 4 && !strings.Contains(s, "zz") {
+		ip, bits = net.ParseIP(strings.Join(addr, ".")).To4(), 32
+	} else {
+		ip, bits = net.ParseIP(expand(strings.Join(addr, ":"))), 128
+		if ip != nil && ip.To4() != nil {
+			ip = nil // IPv4 addresses must use the IPv4 notation
+		}
+	}
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address in %q", s)
+	}
+	if ones < 1 || ones > bits {
+		return nil, fmt.Errorf("invalid prefix length in %q", s)
+	}
+
+	mask := net.CIDRMask(ones, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// expand replaces the "zz" in the IPv6 address s with "::".
+func expand(s string) string {
+	switch {
+	case s == "zz":
+		return "::"
+	case strings.HasPrefix(s, "zz:"):
+		return ":" + s[2:]
+	case strings.HasSuffix(s, ":zz"):
+		return s[:len(s)-2] + ":"
+	}
+	return strings.Replace(s, ":zz:", "::", 1)
+}
+
+// matchName returns the rule in m for name, an exact match takes precedence over the longest matching
+// wildcard.
+func matchName(m map[string]*rule, name string) *rule {
+	if len(m) == 0 {
+		return nil
+	}
+	if r, ok := m[name]; ok {
+		return r
+	}
+	for off, end := dns.NextLabel(name, 0); !end; off, end = dns.NextLabel(name, off) {
+		if r, ok := m["*."+name[off:]]; ok {
+			return r
+		}
+	}
+	if r, ok := m["*."]; ok && name != "." {
+		return r
+	}
+	return nil
+}
+
+// matchIP returns the rule in irs with the longest prefix that contains ip.
+func matchIP(irs []ipRule, ip net.IP) *rule {
+	for _, ir := range irs {
+		if ir.network.Contains(ip) {
+			return ir.rule
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package rpz
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+)
+
+func TestParseIP(t *testing.T) {
+	tests := []struct {
+		trigger   string
+		network   string
+		shouldErr bool
+	}{
+		{"32.1.0.0.127.", "127.0.0.1/32", false},
+		{"24.0.2.0.192.", "192.0.2.0/24", false},
+		{"8.1.2.3.10.", "10.0.0.0/8", false},
+		{"128.1.zz.db8.2001.", "2001:db8::1/128", false},
+		{"48.zz.db8.2001.", "2001:db8::/48", false},
+		{"128.1.zz.", "::1/128", false},
+		{"64.zz.1.0.0.0.2001.", "2001:0:0:0:1::/64", false},
+		// negative
+		{"33.1.0.0.127.", "", true},
+		{"0.1.0.0.127.", "", true},
+		{"x.1.0.0.127.", "", true},
+		{"32.1.0.127.", "", true},
+		{"32.", "", true},
+		{"129.1.zz.db8.2001.", "", true},
+	}
+
+	for i, tc := range tests {
+		n, err := parseIP(tc.trigger)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error for %s, got %s", i, tc.trigger, n)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error for %s, got %s", i, tc.trigger, err)
+			continue
+		}
+		_, expect, _ := net.ParseCIDR(tc.network)
+		if n.String() != expect.String() {
+			t.Errorf("Test %d: expected %s, got %s", i, expect, n)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	exact, wild, all := &rule{owner: "exact"}, &rule{owner: "wild"}, &rule{owner: "all"}
+	m := map[string]*rule{
+		"www.example.org.":   exact,
+		"*.example.org.":     wild,
+		"*.sub.example.org.": exact,
+	}
+
+	names := []struct {
+		name   string
+		expect *rule
+	}{
+		{"www.example.org.", exact},
+		{"a.www.example.org.", wild},
+		{"a.sub.example.org.", exact},
+		{"sub.example.org.", wild},
+		{"example.org.", nil},
+		{"example.net.", nil},
+	}
+	for i, tc := range names {
+		if got := matchName(m, tc.name); got != tc.expect {
+			t.Errorf("Test %d: expected %v for %s, got %v", i, tc.expect, tc.name, got)
+		}
+	}
+	m["*."] = all
+	if got := matchName(m, "example.net."); got != all {
+		t.Errorf("Expected the catch all rule, got %v", got)
+	}
+
+	var irs []ipRule
+	for _, n := range []struct {
+		cidr string
+		r    *rule
+	}{{"10.0.0.0/24", exact}, {"10.0.0.0/8", wild}} {
+		_, network, _ := net.ParseCIDR(n.cidr)
+		ones, _ := network.Mask.Size()
+		irs = append(irs, ipRule{rule: n.r, network: network, ones: ones})
+	}
+	if got := matchIP(irs, net.ParseIP("10.0.0.1")); got != exact {
+		t.Errorf("Expected longest prefix match, got %v", got)
+	}
+	if got := matchIP(irs, net.ParseIP("10.1.0.1")); got != wild {
+		t.Errorf("Expected /8 match, got %v", got)
+	}
+	if got := matchIP(irs, net.ParseIP("192.0.2.1")); got != nil {
+		t.Errorf("Expected no match, got %v", got)
+	}
+}
+
+func TestPolicyGet(t *testing.T) {
+	z, err := file.Parse(strings.NewReader(policyZone), "rpz.example.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newPolicy(z, "rpz.example.")
+
+	var wg sync.WaitGroup
+	got := make([]*rules, 10)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = p.get()
+		}(i)
+	}
+	wg.Wait()
+	for i, rs := range got {
+		if rs != got[0] {
+			t.Errorf("Query %d: expected the rules to be compiled once", i)
+		}
+	}
+
+	// A reload replaces the tree, the rules are then compiled again.
+	z1, err := file.Parse(strings.NewReader(policyZone), "rpz.example.", "stdin", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z.Lock()
+	z.Tree = z1.Tree
+	z.Unlock()
+	if p.get() == got[0] {
+		t.Errorf("Expected the rules to be compiled for the new tree")
+	}
+}
@@ -0,0 +1,257 @@
+// Package rpz implements a plugin that applies Response Policy Zones (RPZ) to queries and responses.
+package rpz
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// RPZ applies the rules in a list of policy zones. The first policy zone with a matching rule wins.
+type RPZ struct {
+	Next  plugin.Handler
+	Zones []string
+
+	policies []*policy
+}
+
+// hit is a rule that matched.
+type hit struct {
+	policy  *policy
+	trigger string
+	rule    *rule
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rp *RPZ) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(rp.Zones).Matches(state.Name())
+	if zone == "" || r.Opcode != dns.OpcodeQuery || len(rp.policies) == 0 {
+		return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+	}
+
+	rules := make([]*rules, len(rp.policies))
+	for i, p := range rp.policies {
+		rules[i] = p.get()
+	}
+
+	// Client IP and QNAME triggers are checked before resolving, if a rule matches we only need to
+	// resolve the query when an earlier policy zone has triggers on the response.
+	var h *hit
+	limit := len(rules)
+	ip := net.ParseIP(state.IP())
+	for i, rs := range rules {
+		if ru := matchIP(rs.clientIP, ip); ru != nil {
+			h = &hit{rp.policies[i], triggerClientIP, ru}
+		} else if ru := matchName(rs.qname, state.Name()); ru != nil {
+			h = &hit{rp.policies[i], triggerQname, ru}
+		}
+		if h != nil {
+			limit = i
+			break
+		}
+	}
+
+	resolve := false
+	for _, rs := range rules[:limit] {
+		if rs.response() {
+			resolve = true
+			break
+		}
+	}
+	if !resolve {
+		if h == nil {
+			return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, w, r)
+		}
+		return rp.apply(ctx, state, zone, h, nil)
+	}
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(rp.Name(), rp.Next, ctx, nw, r)
+	if nw.Msg == nil {
+		if h != nil {
+			return rp.apply(ctx, state, zone, h, nil)
+		}
+		return rcode, err
+	}
+
+	ns := &nameservers{rp: rp, ctx: ctx, state: state}
+	for i, rs := range rules[:limit] {
+		if x := rp.matchResponse(ns, rs, nw.Msg); x != nil {
+			x.policy = rp.policies[i]
+			h = x
+			break
+		}
+	}
+	if h == nil {
+		w.WriteMsg(nw.Msg)
+		return rcode, err
+	}
+	return rp.apply(ctx, state, zone, h, nw.Msg)
+}
+
+// matchResponse returns the hit for the triggers in rs that look at the response m: the addresses in
+// the answer, and the names and addresses of the name servers of the query name.
+func (rp *RPZ) matchResponse(ns *nameservers, rs *rules, m *dns.Msg) *hit {
+	if len(rs.responseIP) > 0 {
+		for _, rr := range m.Answer {
+			if ip := address(rr); ip != nil {
+				if ru := matchIP(rs.responseIP, ip); ru != nil {
+					return &hit{trigger: triggerResponseIP, rule: ru}
+				}
+			}
+		}
+	}
+	if len(rs.nsdname) > 0 {
+		for _, n := range ns.names() {
+			if ru := matchName(rs.nsdname, n); ru != nil {
+				return &hit{trigger: triggerNSDname, rule: ru}
+			}
+		}
+	}
+	if len(rs.nsIP) > 0 {
+		for _, ip := range ns.addrs() {
+			if ru := matchIP(rs.nsIP, ip); ru != nil {
+				return &hit{trigger: triggerNSIP, rule: ru}
+			}
+		}
+	}
+	return nil
+}
+
+// apply applies the action of the rule in h. The response m is the response of the next plugin, if the
+// query has been resolved.
+func (rp *RPZ) apply(ctx context.Context, state request.Request, zone string, h *hit, m *dns.Msg) (int, error) {
+	act := h.rule.action.String()
+	HitsCount.WithLabelValues(metrics.WithServer(ctx), zone, metrics.WithView(ctx), h.policy.name, h.trigger, act).Inc()
+	log.Infof("%s %s/%s matched %s trigger %s in %s: %s", state.IP(), state.Name(), state.Type(), h.trigger, h.rule.owner, h.policy.name, act)
+	metadata.SetValueFunc(ctx, "rpz/policy", func() string { return h.policy.name })
+	metadata.SetValueFunc(ctx, "rpz/trigger", func() string { return h.trigger })
+	metadata.SetValueFunc(ctx, "rpz/rule", func() string { return h.rule.owner })
+	metadata.SetValueFunc(ctx, "rpz/action", func() string { return act })
+
+	switch h.rule.action {
+	case actionDrop:
+		return dns.RcodeSuccess, nil
+
+	case actionTCPOnly:
+		if state.Proto() == "udp" {
+			ret := new(dns.Msg)
+			ret.SetReply(state.Req)
+			ret.Truncated = true
+			state.SizeAndDo(ret)
+			state.W.WriteMsg(ret)
+			return dns.RcodeSuccess, nil
+		}
+		fallthrough
+
+	case actionPassthru:
+		if m != nil {
+			state.W.WriteMsg(m)
+			return dns.RcodeSuccess, nil
+		}
+		return plugin.NextOrFailure(rp.Name(), rp.Next, ctx, state.W, state.Req)
+
+	case actionNXDOMAIN:
+		state.W.WriteMsg(rp.negative(state, h.policy, dns.RcodeNameError))
+		return dns.RcodeSuccess, nil
+
+	case actionNODATA:
+		state.W.WriteMsg(rp.negative(state, h.policy, dns.RcodeSuccess))
+		return dns.RcodeSuccess, nil
+	}
+
+	return rp.local(ctx, state, h)
+}
+
+// local replies with the local data of the rule in h. A CNAME is followed with the next plugin.
+func (rp *RPZ) local(ctx context.Context, state request.Request, h *hit) (int, error) {
+	ret := new(dns.Msg)
+	ret.SetReply(state.Req)
+	ret.Authoritative, ret.RecursionAvailable = true, true
+
+	qname, qtype := state.QName(), state.QType()
+	var target string
+	for _, rr := range h.rule.rrs {
+		if rr.Header().Rrtype != qtype && rr.Header().Rrtype != dns.TypeCNAME {
+			continue
+		}
+		rr = dns.Copy(rr)
+		rr.Header().Name = qname
+		if c, ok := rr.(*dns.CNAME); ok {
+			if strings.HasPrefix(c.Target, "*.") {
+				// A wildcard target is replaced by the query name.
+				c.Target = dns.Fqdn(strings.TrimSuffix(qname, ".") + c.Target[1:])
+			}
+			target = c.Target
+		}
+		ret.Answer = append(ret.Answer, rr)
+	}
+
+	if len(ret.Answer) == 0 {
+		state.W.WriteMsg(rp.negative(state, h.policy, dns.RcodeSuccess))
+		return dns.RcodeSuccess, nil
+	}
+
+	if target != "" && qtype != dns.TypeCNAME {
+		ret.Answer = ret.Answer[:1] // a CNAME can't have other data
+		req := new(dns.Msg)
+		req.SetQuestion(target, qtype)
+		req.RecursionDesired = state.Req.RecursionDesired
+		if o := state.Req.IsEdns0(); o != nil {
+			req.SetEdns0(o.UDPSize(), o.Do())
+		}
+		nw := nonwriter.New(state.W)
+		plugin.NextOrFailure(rp.Name(), rp.Next, ctx, nw, req)
+		if nw.Msg != nil {
+			ret.Answer = append(ret.Answer, nw.Msg.Answer...)
+			ret.Ns = nw.Msg.Ns
+			ret.Rcode = nw.Msg.Rcode
+		}
+	}
+
+	state.SizeAndDo(ret)
+	ret = state.Scrub(ret)
+	state.W.WriteMsg(ret)
+	return dns.RcodeSuccess, nil
+}
+
+// negative returns an NXDOMAIN or NODATA reply with the SOA of the policy zone p.
+func (rp *RPZ) negative(state request.Request, p *policy, rcode int) *dns.Msg {
+	ret := new(dns.Msg)
+	ret.SetRcode(state.Req, rcode)
+	ret.Authoritative, ret.RecursionAvailable = true, true
+	if soa := p.soa(); soa != nil {
+		s := dns.Copy(soa).(*dns.SOA)
+		s.Hdr.Ttl = s.Minttl
+		ret.Ns = []dns.RR{s}
+	}
+	state.SizeAndDo(ret)
+	return ret
+}
+
+// Name implements the plugin.Handler interface.
+func (rp *RPZ) Name() string { return pluginName }
+
+// address returns the address in rr, which is an A or AAAA record, or nil.
+func address(rr dns.RR) net.IP {
+	switch x := rr.(type) {
+	case *dns.A:
+		return x.A
+	case *dns.AAAA:
+		return x.AAAA
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package rpz
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/metadata"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+const policyZone = `$ORIGIN rpz.example.
+@	3600 IN SOA localhost. hostmaster.localhost. 1 3600 600 86400 60
+@	3600 IN NS localhost.
+
+nx.example.org		CNAME .
+nodata.example.org	CNAME *.
+*.wild.example.org	CNAME .
+ok.wild.example.org	CNAME rpz-passthru.
+drop.example.org	CNAME rpz-drop.
+tcp.example.org		CNAME rpz-tcp-only.
+local.example.org	A 127.0.0.1
+local.example.org	TXT "blocked"
+cname.example.org	CNAME www.example.com.
+garden.example.org	CNAME *.walled.example.
+
+32.6.6.6.10.rpz-ip		CNAME .
+ns.evil.net.rpz-nsdname		CNAME .
+32.53.113.0.203.rpz-nsip	CNAME *.
+`
+
+// upstream answers queries from a fixed set of records, keyed by name/type.
+type upstream map[string][]dns.RR
+
+func (u upstream) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Answer = u[state.Name()+"/"+state.Type()]
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+func (u upstream) Name() string { return "upstream" }
+
+func newTestRPZ(t *testing.T, zones ...string) *RPZ {
+	t.Helper()
+	rp := &RPZ{Zones: []string{"."}}
+	for i, zone := range zones {
+		name := []string{"rpz.example.", "second.example."}[i]
+		z, err := file.Parse(strings.NewReader(zone), name, "stdin", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rp.policies = append(rp.policies, newPolicy(z, name))
+	}
+	rp.Next = upstream{
+		"www.example.com./A":     {test.A("www.example.com. 300 IN A 192.0.2.1")},
+		"example.com./NS":        {test.NS("example.com. 300 IN NS ns.example.com.")},
+		"ns.example.com./A":      {test.A("ns.example.com. 300 IN A 192.0.2.53")},
+		"bad-ip.example.com./A":  {test.A("bad-ip.example.com. 300 IN A 10.6.6.6")},
+		"www.evil.com./A":        {test.A("www.evil.com. 300 IN A 192.0.2.2")},
+		"evil.com./NS":           {test.NS("evil.com. 300 IN NS ns.evil.net.")},
+		"www.example.net./A":     {test.A("www.example.net. 300 IN A 192.0.2.3")},
+		"example.net./NS":        {test.NS("example.net. 300 IN NS ns.example.net.")},
+		"ns.example.net./A":      {test.A("ns.example.net. 300 IN A 203.0.113.53")},
+		"ok.wild.example.org./A": {test.A("ok.wild.example.org. 300 IN A 192.0.2.4")},
+		"tcp.example.org./A":     {test.A("tcp.example.org. 300 IN A 192.0.2.5")},
+	}
+	return rp
+}
+
+func TestRPZ(t *testing.T) {
+	rp := newTestRPZ(t, policyZone)
+
+	tests := []struct {
+		qname     string
+		qtype     uint16
+		tcp       bool
+		rcode     int
+		answer    []string
+		dropped   bool
+		truncated bool
+	}{
+		{qname: "www.example.com.", qtype: dns.TypeA, answer: []string{"192.0.2.1"}},
+		{qname: "nx.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "NX.Example.ORG.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "nodata.example.org.", qtype: dns.TypeA},
+		{qname: "a.b.wild.example.org.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		{qname: "ok.wild.example.org.", qtype: dns.TypeA, answer: []string{"192.0.2.4"}},
+		{qname: "drop.example.org.", qtype: dns.TypeA, dropped: true},
+		{qname: "tcp.example.org.", qtype: dns.TypeA, truncated: true},
+		{qname: "tcp.example.org.", qtype: dns.TypeA, tcp: true, answer: []string{"192.0.2.5"}},
+		{qname: "local.example.org.", qtype: dns.TypeA, answer: []string{"127.0.0.1"}},
+		{qname: "local.example.org.", qtype: dns.TypeTXT, answer: []string{"blocked"}},
+		{qname: "local.example.org.", qtype: dns.TypeMX},
+		{qname: "cname.example.org.", qtype: dns.TypeA, answer: []string{"www.example.com.", "192.0.2.1"}},
+		{qname: "garden.example.org.", qtype: dns.TypeA, answer: []string{"garden.example.org.walled.example."}},
+		// Response IP.
+		{qname: "bad-ip.example.com.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		// NSDNAME.
+		{qname: "www.evil.com.", qtype: dns.TypeA, rcode: dns.RcodeNameError},
+		// NSIP.
+		{qname: "www.example.net.", qtype: dns.TypeA},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		rec := dnstest.NewRecorder(&test.ResponseWriter{TCP: tc.tcp})
+		if _, err := rp.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if tc.dropped {
+			if rec.Msg != nil {
+				t.Errorf("Test %d: expected the query to be dropped, got %v", i, rec.Msg)
+			}
+			continue
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected a response", i)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if rec.Msg.Truncated != tc.truncated {
+			t.Errorf("Test %d: expected TC bit %t, got %t", i, tc.truncated, rec.Msg.Truncated)
+		}
+		if len(rec.Msg.Answer) != len(tc.answer) {
+			t.Fatalf("Test %d: expected %d RRs in the answer, got %v", i, len(tc.answer), rec.Msg.Answer)
+		}
+		for j, rr := range rec.Msg.Answer {
+			var got string
+			switch x := rr.(type) {
+			case *dns.A:
+				got = x.A.String()
+			case *dns.CNAME:
+				got = x.Target
+			case *dns.TXT:
+				got = x.Txt[0]
+			}
+			if got != tc.answer[j] {
+				t.Errorf("Test %d: expected %s, got %s", i, tc.answer[j], got)
+			}
+		}
+		if len(tc.answer) == 0 && !tc.truncated && (len(rec.Msg.Ns) != 1 || rec.Msg.Ns[0].Header().Name != "rpz.example.") {
+			t.Errorf("Test %d: expected the SOA of the policy zone, got %v", i, rec.Msg.Ns)
+		}
+	}
+}
+
+func TestRPZPrecedence(t *testing.T) {
+	second := `$ORIGIN second.example.
+@	3600 IN SOA localhost. hostmaster.localhost. 1 3600 600 86400 60
+24.0.0.240.10.rpz-client-ip	CNAME rpz-drop.
+www.example.com			CNAME .
+`
+	rp := newTestRPZ(t, policyZone, second)
+
+	// The client IP rule in the second zone is only used when nothing in the first zone matches.
+	m := new(dns.Msg)
+	m.SetQuestion("local.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	rp.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg == nil || len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected the local data of the first policy zone, got %v", rec.Msg)
+	}
+
+	m.SetQuestion("www.example.com.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	rp.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg != nil {
+		t.Errorf("Expected the query to be dropped, got %v", rec.Msg)
+	}
+
+	// A response IP trigger in the first zone wins over a QNAME trigger in the second.
+	rp = newTestRPZ(t, policyZone, strings.Replace(second, "24.0.0.240.10.rpz-client-ip", "bad-ip.example.com", 1))
+	m.SetQuestion("bad-ip.example.com.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	rp.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg == nil || rec.Msg.Rcode != dns.RcodeNameError || rec.Msg.Ns[0].Header().Name != "rpz.example." {
+		t.Errorf("Expected NXDOMAIN from the first policy zone, got %v", rec.Msg)
+	}
+}
+
+func TestRPZMetadata(t *testing.T) {
+	rp := newTestRPZ(t, policyZone)
+
+	ctx := metadata.ContextWithMetadata(context.TODO())
+	m := new(dns.Msg)
+	m.SetQuestion("a.wild.example.org.", dns.TypeA)
+	rp.ServeDNS(ctx, dnstest.NewRecorder(&test.ResponseWriter{}), m)
+
+	for label, expect := range map[string]string{
+		"rpz/policy":  "rpz.example.",
+		"rpz/trigger": "qname",
+		"rpz/rule":    "*.wild.example.org.rpz.example.",
+		"rpz/action":  "nxdomain",
+	} {
+		f := metadata.ValueFunc(ctx, label)
+		if f == nil {
+			t.Errorf("Expected metadata %s", label)
+			continue
+		}
+		if got := f(); got != expect {
+			t.Errorf("Expected %s for %s, got %s", expect, label, got)
+		}
+	}
+}
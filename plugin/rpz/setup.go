@@ -0,0 +1,155 @@
+package rpz
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/pkg/parse"
+)
+
+const pluginName = "rpz"
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	rp, err := rpzParse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	for _, p := range rp.policies {
+		z, name := p.z, p.name
+		if len(z.TransferFrom) == 0 {
+			c.OnShutdown(z.OnShutdown)
+			c.OnStartup(func() error {
+				z.StartupOnce.Do(func() { z.Reload(nil) })
+				return nil
+			})
+			continue
+		}
+
+		// Retrieve the zone from the feed and keep it up to date, like the secondary plugin does.
+		c.OnStartup(func() error {
+			z.StartupOnce.Do(func() {
+				go func() {
+					dur := time.Millisecond * 250
+					step := time.Duration(2)
+					max := time.Second * 10
+					for {
+						err := z.TransferIn()
+						if err == nil {
+							break
+						}
+						log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", name, dur.String(), err)
+						time.Sleep(dur)
+						dur = step * dur
+						if dur > max {
+							dur = max
+						}
+					}
+					z.Update()
+				}()
+			})
+			return nil
+		})
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rp.Next = next
+		return rp
+	})
+
+	return nil
+}
+
+func rpzParse(c *caddy.Controller) (*RPZ, error) {
+	rp := &RPZ{}
+	config := dnsserver.GetConfig(c)
+	reload := 1 * time.Minute
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rp.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		seen := make(map[string]bool)
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "file":
+				args := c.RemainingArgs()
+				if len(args) != 2 {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Name(args[0]).Normalize()
+				fileName := args[1]
+				if !filepath.IsAbs(fileName) && config.Root != "" {
+					fileName = filepath.Join(config.Root, fileName)
+				}
+				reader, err := os.Open(filepath.Clean(fileName))
+				if err != nil {
+					return nil, c.Errf("unable to open policy zone %q: %s", fileName, err)
+				}
+				z, err := file.Parse(reader, name, fileName, 0)
+				reader.Close()
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				if seen[name] {
+					return nil, c.Errf("duplicate policy zone %s", name)
+				}
+				seen[name] = true
+				rp.policies = append(rp.policies, newPolicy(z, name))
+
+			case "transfer":
+				if !c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				name := plugin.Name(c.Val()).Normalize()
+				froms, err := parse.TransferIn(c)
+				if err != nil {
+					return nil, err
+				}
+				if seen[name] {
+					return nil, c.Errf("duplicate policy zone %s", name)
+				}
+				seen[name] = true
+				z := file.NewZone(name, "stdin")
+				z.TransferFrom = froms
+				rp.policies = append(rp.policies, newPolicy(z, name))
+
+			case "reload":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				d, err := time.ParseDuration(args[0])
+				if err != nil {
+					return nil, c.Errf("invalid reload duration %q: %s", args[0], err)
+				}
+				reload = d
+
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+	}
+
+	if len(rp.policies) == 0 {
+		return nil, c.Err("no policy zones")
+	}
+	for _, p := range rp.policies {
+		if len(p.z.TransferFrom) == 0 {
+			p.z.ReloadInterval = reload
+		}
+	}
+	return rp, nil
+}
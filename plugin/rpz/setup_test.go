@@ -0,0 +1,81 @@
+package rpz
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestSetup(t *testing.T) {
+	policy, rm, err := test.TempFile(".", policyZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		policies  []string
+	}{
+		{`rpz {
+			file rpz.example ` + policy + `
+		}`, false, []string{"rpz.example."}},
+		{`rpz example.org {
+			transfer feed.example from 10.0.0.1
+			file RPZ.Example. ` + policy + `
+			reload 10s
+		}`, false, []string{"feed.example.", "rpz.example."}},
+		// negative
+		{`rpz`, true, nil},
+		{`rpz {
+			file rpz.example
+		}`, true, nil},
+		{`rpz {
+			file rpz.example /does/not/exist
+		}`, true, nil},
+		{`rpz {
+			transfer feed.example to 10.0.0.1
+		}`, true, nil},
+		{`rpz {
+			transfer feed.example from 10.0.0.1
+			transfer feed.example from 10.0.0.2
+		}`, true, nil},
+		{`rpz {
+			file rpz.example ` + policy + `
+			reload soon
+		}`, true, nil},
+		{`rpz {
+			blah
+		}`, true, nil},
+		{`rpz {
+			file rpz.example ` + policy + `
+		}
+		rpz {
+			file rpz.example ` + policy + `
+		}`, true, nil},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rp, err := rpzParse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if len(rp.policies) != len(tc.policies) {
+			t.Fatalf("Test %d: expected %d policy zones, got %d", i, len(tc.policies), len(rp.policies))
+		}
+		for j, p := range rp.policies {
+			if p.name != tc.policies[j] {
+				t.Errorf("Test %d: expected policy zone %s, got %s", i, tc.policies[j], p.name)
+			}
+		}
+	}
+}
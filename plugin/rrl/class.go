@@ -0,0 +1,90 @@
+package rrl
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+// class is the response class a response is accounted under.
+type class int
+
+const (
+	classResponse class = iota // positive answers and NODATA
+	classNXDomain
+	classReferral
+	classError
+	classCount // keep this last
+)
+
+var classToString = [classCount]string{
+	classResponse: "response",
+	classNXDomain: "nxdomain",
+	classReferral: "referral",
+	classError:    "error",
+}
+
+func (c class) String() string { return classToString[c] }
+
+// classify returns the class of res and the name that identifies the response within that class.
+// Answers are identified by the query name, NXDOMAIN and referrals by the owner name in the
+// authority section, i.e. the zone or delegation point, so that random subdomains still share an account.
+func classify(res *dns.Msg) (class, string) {
+	qname := ""
+	if len(res.Question) > 0 {
+		qname = strings.ToLower(res.Question[0].Name)
+	}
+
+	switch res.Rcode {
+	case dns.RcodeSuccess:
+		if len(res.Answer) == 0 && !res.Authoritative {
+			for _, rr := range res.Ns {
+				if rr.Header().Rrtype == dns.TypeNS {
+					return classReferral, strings.ToLower(rr.Header().Name)
+				}
+			}
+		}
+		return classResponse, qname
+	case dns.RcodeNameError:
+		for _, rr := range res.Ns {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				return classNXDomain, strings.ToLower(rr.Header().Name)
+			}
+		}
+		return classNXDomain, qname
+	}
+	return classError, ""
+}
+
+// key returns the account key for a response of class c for owner to the client in state.
+func (rl *RRL) key(state request.Request, c class, owner string) uint64 {
+	addr := state.IP()
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	ip := net.ParseIP(addr)
+	prefix := ""
+	if ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			prefix = ip4.Mask(net.CIDRMask(rl.ipv4Prefix, 32)).String()
+		} else {
+			prefix = ip.Mask(net.CIDRMask(rl.ipv6Prefix, 128)).String()
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteByte('/')
+	b.WriteString(strconv.Itoa(int(c)))
+	b.WriteByte('/')
+	if c == classResponse {
+		b.WriteString(strconv.Itoa(int(state.QType())))
+		b.WriteByte('/')
+	}
+	b.WriteString(owner)
+	return hash(b.String())
+}
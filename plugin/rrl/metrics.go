@@ -0,0 +1,32 @@
+package rrl
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// DroppedCount is the number of responses dropped because of rate limiting.
+	DroppedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "dropped_responses_total",
+		Help:      "Counter of responses dropped because of rate limiting.",
+	}, []string{"server", "zone", "class"})
+	// SlippedCount is the number of truncated responses sent instead of a rate limited response.
+	SlippedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "slipped_responses_total",
+		Help:      "Counter of truncated responses sent instead of rate limited responses.",
+	}, []string{"server", "zone", "class"})
+	// ReportOnlyCount is the number of responses that would have been rate limited in report only mode.
+	ReportOnlyCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "report_only_responses_total",
+		Help:      "Counter of responses that would have been rate limited, when running in report only mode.",
+	}, []string{"server", "zone", "class"})
+)
@@ -0,0 +1,157 @@
+// Package rrl implements BIND style response rate limiting.
+package rrl
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// RRL limits the rate of identical responses sent to a client network.
+type RRL struct {
+	Next  plugin.Handler
+	Zones []string
+
+	window     float64 // seconds
+	ipv4Prefix int
+	ipv6Prefix int
+	rates      [classCount]float64 // allowed responses per second, per response class
+	slip       int
+	reportOnly bool
+
+	table *table
+	now   func() time.Time
+}
+
+// New returns a new RRL with the default settings.
+func New() *RRL {
+	return &RRL{
+		window:     defaultWindow,
+		ipv4Prefix: defaultIPv4Prefix,
+		ipv6Prefix: defaultIPv6Prefix,
+		slip:       defaultSlip,
+		table:      newTable(defaultMaxTableSize),
+		now:        time.Now,
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (rl *RRL) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(rl.Zones).Matches(state.Name())
+	// Only UDP is spoofable, so TCP is never rate limited.
+	if zone == "" || state.Proto() != "udp" {
+		return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, w, r)
+	}
+
+	rw := &ResponseWriter{ResponseWriter: w, ctx: ctx, rrl: rl, state: state, zone: zone}
+	return plugin.NextOrFailure(rl.Name(), rl.Next, ctx, rw, r)
+}
+
+// Name implements the plugin.Handler interface.
+func (rl *RRL) Name() string { return pluginName }
+
+// ResponseWriter accounts each response before it is written and drops or truncates it
+// when the client network exceeds its allowance.
+type ResponseWriter struct {
+	dns.ResponseWriter
+	ctx   context.Context
+	rrl   *RRL
+	state request.Request
+	zone  string
+}
+
+// WriteMsg implements the dns.ResponseWriter interface.
+func (w *ResponseWriter) WriteMsg(res *dns.Msg) error {
+	class, owner := classify(res)
+	if w.rrl.rates[class] == 0 {
+		return w.ResponseWriter.WriteMsg(res)
+	}
+
+	k := w.rrl.key(w.state, class, owner)
+	switch act := w.rrl.debit(k, class); act {
+	case actionSend:
+		return w.ResponseWriter.WriteMsg(res)
+
+	case actionDrop, actionSlip:
+		if w.rrl.reportOnly {
+			log.Infof("Would rate limit %s response to %s for %s", class, w.state.IP(), owner)
+			ReportOnlyCount.WithLabelValues(metrics.WithServer(w.ctx), w.zone, class.String()).Inc()
+			return w.ResponseWriter.WriteMsg(res)
+		}
+		if act == actionDrop {
+			DroppedCount.WithLabelValues(metrics.WithServer(w.ctx), w.zone, class.String()).Inc()
+			return nil
+		}
+		SlippedCount.WithLabelValues(metrics.WithServer(w.ctx), w.zone, class.String()).Inc()
+		tc := new(dns.Msg)
+		tc.SetReply(w.state.Req)
+		tc.Truncated = true
+		return w.ResponseWriter.WriteMsg(tc)
+	}
+	return nil
+}
+
+// action is what to do with a response.
+type action int
+
+const (
+	actionSend action = iota
+	actionDrop
+	actionSlip
+)
+
+// debit charges one response to the account k and returns what to do with the response.
+func (rl *RRL) debit(k uint64, class class) action {
+	rate := rl.rates[class]
+	now := rl.now()
+
+	b := rl.table.get(k, func() *bucket { return &bucket{balance: rate, last: now} })
+
+	b.Lock()
+	defer b.Unlock()
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.last = now
+	if elapsed > 0 {
+		b.balance += elapsed * rate
+		if b.balance > rate {
+			b.balance = rate
+		}
+	}
+
+	b.balance--
+	// The window bounds how far into debt an account can go, i.e. for how long a client
+	// stays limited after it stopped sending.
+	if floor := -rl.window * rate; b.balance < floor {
+		b.balance = floor
+	}
+	if b.balance >= 0 {
+		return actionSend
+	}
+
+	b.dropped++
+	if rl.slip > 0 && b.dropped%rl.slip == 0 {
+		return actionSlip
+	}
+	return actionDrop
+}
+
+const (
+	pluginName = "rrl"
+
+	defaultWindow       = 15.0
+	defaultIPv4Prefix   = 24
+	defaultIPv6Prefix   = 56
+	defaultSlip         = 2
+	defaultMaxTableSize = 100000
+)
@@ -0,0 +1,186 @@
+package rrl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// answer is a backend that answers every query authoritatively, and NXDOMAIN for nx.example.org.
+var answer = plugin.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	m.Authoritative = true
+	if r.Question[0].Name == "nx.example.org." {
+		m.Rcode = dns.RcodeNameError
+		m.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 60")}
+	} else {
+		m.Answer = []dns.RR{test.A(r.Question[0].Name + " 3600 IN A 127.0.0.1")}
+	}
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+})
+
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+func newTestRRL(rps float64, slip int) (*RRL, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	rl := New()
+	rl.Zones = []string{"example.org."}
+	rl.Next = answer
+	rl.slip = slip
+	for cl := range rl.rates {
+		rl.rates[cl] = rps
+	}
+	rl.now = clock.now
+	return rl, clock
+}
+
+// query sends qname from the client at remote and returns the written reply, if any.
+func query(t *testing.T, rl *RRL, qname, remote string, tcp bool) *dns.Msg {
+	t.Helper()
+	m := new(dns.Msg)
+	m.SetQuestion(qname, dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{RemoteIP: remote, TCP: tcp})
+	if _, err := rl.ServeDNS(context.TODO(), rec, m); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	return rec.Msg
+}
+
+func TestRRLLimit(t *testing.T) {
+	rl, clock := newTestRRL(2, 0)
+
+	for i := 0; i < 2; i++ {
+		if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m == nil {
+			t.Fatalf("Expected response %d to be sent", i)
+		}
+	}
+	if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m != nil {
+		t.Errorf("Expected response to be dropped")
+	}
+	// Same /24, so the same account.
+	if m := query(t, rl, "a.example.org.", "10.0.0.2", false); m != nil {
+		t.Errorf("Expected response to be dropped for client in same prefix")
+	}
+	// Other network, other name, or TCP are not limited.
+	if m := query(t, rl, "a.example.org.", "10.0.1.1", false); m == nil {
+		t.Errorf("Expected response to other network to be sent")
+	}
+	if m := query(t, rl, "b.example.org.", "10.0.0.1", false); m == nil {
+		t.Errorf("Expected response for other name to be sent")
+	}
+	if m := query(t, rl, "a.example.org.", "10.0.0.1", true); m == nil {
+		t.Errorf("Expected response over TCP to be sent")
+	}
+
+	// The account is in debt for 2 responses, after 1.5s the balance is positive again.
+	clock.advance(1500 * time.Millisecond)
+	if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m == nil {
+		t.Errorf("Expected response to be sent after the account recovered")
+	}
+}
+
+func TestRRLSlip(t *testing.T) {
+	rl, _ := newTestRRL(1, 2)
+
+	query(t, rl, "a.example.org.", "10.0.0.1", false)
+
+	truncated, dropped := 0, 0
+	for i := 0; i < 10; i++ {
+		m := query(t, rl, "a.example.org.", "10.0.0.1", false)
+		if m == nil {
+			dropped++
+			continue
+		}
+		if !m.Truncated || len(m.Answer) != 0 {
+			t.Errorf("Expected empty truncated response, got %v", m)
+		}
+		truncated++
+	}
+	if truncated != 5 || dropped != 5 {
+		t.Errorf("Expected 5 truncated and 5 dropped responses, got %d and %d", truncated, dropped)
+	}
+}
+
+func TestRRLNXDomain(t *testing.T) {
+	rl, _ := newTestRRL(1, 0)
+	rl.rates[classResponse] = 0 // only limit NXDOMAIN
+
+	if m := query(t, rl, "nx.example.org.", "10.0.0.1", false); m == nil {
+		t.Fatalf("Expected first NXDOMAIN to be sent")
+	}
+	if m := query(t, rl, "nx.example.org.", "10.0.0.1", false); m != nil {
+		t.Errorf("Expected second NXDOMAIN to be dropped")
+	}
+	for i := 0; i < 3; i++ {
+		if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m == nil {
+			t.Errorf("Expected answers not to be rate limited")
+		}
+	}
+}
+
+func TestRRLReportOnly(t *testing.T) {
+	rl, _ := newTestRRL(1, 0)
+	rl.reportOnly = true
+
+	for i := 0; i < 3; i++ {
+		if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m == nil {
+			t.Errorf("Expected response %d to be sent in report only mode", i)
+		}
+	}
+}
+
+func TestRRLWindow(t *testing.T) {
+	rl, clock := newTestRRL(1, 0)
+	rl.window = 2
+
+	for i := 0; i < 100; i++ {
+		query(t, rl, "a.example.org.", "10.0.0.1", false)
+	}
+	// The debt is capped at window * rate, so after 3s the client is allowed again.
+	clock.advance(3 * time.Second)
+	if m := query(t, rl, "a.example.org.", "10.0.0.1", false); m == nil {
+		t.Errorf("Expected response to be sent after the window passed")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	referral := new(dns.Msg)
+	referral.SetQuestion("www.sub.example.org.", dns.TypeA)
+	referral.Ns = []dns.RR{test.NS("sub.example.org. 3600 IN NS ns.sub.example.org.")}
+
+	servfail := new(dns.Msg)
+	servfail.SetQuestion("www.example.org.", dns.TypeA)
+	servfail.Rcode = dns.RcodeServerFailure
+
+	nx := new(dns.Msg)
+	nx.SetQuestion("random.example.org.", dns.TypeA)
+	nx.Rcode = dns.RcodeNameError
+	nx.Ns = []dns.RR{test.SOA("example.org. 3600 IN SOA ns.example.org. admin.example.org. 1 3600 600 86400 60")}
+
+	tests := []struct {
+		msg   *dns.Msg
+		class class
+		owner string
+	}{
+		{referral, classReferral, "sub.example.org."},
+		{servfail, classError, ""},
+		{nx, classNXDomain, "example.org."},
+	}
+	for i, tc := range tests {
+		cl, owner := classify(tc.msg)
+		if cl != tc.class || owner != tc.owner {
+			t.Errorf("Test %d: expected %s %q, got %s %q", i, tc.class, tc.owner, cl, owner)
+		}
+	}
+}
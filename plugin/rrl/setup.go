@@ -0,0 +1,121 @@
+package rrl
+
+import (
+	"strconv"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+)
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	rl, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		rl.Next = next
+		return rl
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*RRL, error) {
+	rl := New()
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		rl.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		// The other classes default to the responses-per-second allowance, if not set explicitly.
+		set := [classCount]bool{}
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "window":
+				n, err := intArg(c, 1, 3600)
+				if err != nil {
+					return nil, err
+				}
+				rl.window = float64(n)
+			case "ipv4-prefix-length":
+				n, err := intArg(c, 0, 32)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv4Prefix = n
+			case "ipv6-prefix-length":
+				n, err := intArg(c, 0, 128)
+				if err != nil {
+					return nil, err
+				}
+				rl.ipv6Prefix = n
+			case "responses-per-second", "nxdomains-per-second", "referrals-per-second", "errors-per-second":
+				n, err := intArg(c, 0, 1<<20)
+				if err != nil {
+					return nil, err
+				}
+				cl := perSecond[x]
+				rl.rates[cl] = float64(n)
+				set[cl] = true
+			case "slip":
+				n, err := intArg(c, 0, 10)
+				if err != nil {
+					return nil, err
+				}
+				rl.slip = n
+			case "max-table-size":
+				n, err := intArg(c, 1, 1<<30)
+				if err != nil {
+					return nil, err
+				}
+				rl.table = newTable(n)
+			case "report-only":
+				if c.NextArg() {
+					return nil, c.ArgErr()
+				}
+				rl.reportOnly = true
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+		for cl := classNXDomain; cl < classCount; cl++ {
+			if !set[cl] {
+				rl.rates[cl] = rl.rates[classResponse]
+			}
+		}
+	}
+	return rl, nil
+}
+
+var perSecond = map[string]class{
+	"responses-per-second": classResponse,
+	"nxdomains-per-second": classNXDomain,
+	"referrals-per-second": classReferral,
+	"errors-per-second":    classError,
+}
+
+// intArg parses the single integer argument of the current property, which must be within [lo, hi].
+func intArg(c *caddy.Controller, lo, hi int) (int, error) {
+	prop := c.Val()
+	args := c.RemainingArgs()
+	if len(args) != 1 {
+		return 0, c.ArgErr()
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return 0, c.Errf("invalid value for %s: %s", prop, args[0])
+	}
+	if n < lo || n > hi {
+		return 0, c.Errf("%s must be between %d and %d: %d", prop, lo, hi, n)
+	}
+	return n, nil
+}
@@ -0,0 +1,77 @@
+package rrl
+
+import (
+	"testing"
+
+	"github.com/coredns/caddy"
+)
+
+func TestSetup(t *testing.T) {
+	tests := []struct {
+		input     string
+		shouldErr bool
+		rates     [classCount]float64
+		slip      int
+		window    float64
+	}{
+		{`rrl`, false, [classCount]float64{0, 0, 0, 0}, defaultSlip, defaultWindow},
+		{`rrl example.org {
+			responses-per-second 10
+		}`, false, [classCount]float64{10, 10, 10, 10}, defaultSlip, defaultWindow},
+		{`rrl {
+			responses-per-second 10
+			nxdomains-per-second 5
+			errors-per-second 0
+			slip 0
+			window 5
+		}`, false, [classCount]float64{10, 5, 10, 0}, 0, 5},
+		{`rrl {
+			ipv4-prefix-length 32
+			ipv6-prefix-length 64
+			max-table-size 1000
+			report-only
+		}`, false, [classCount]float64{}, defaultSlip, defaultWindow},
+		// negative
+		{`rrl {
+			responses-per-second
+		}`, true, [classCount]float64{}, 0, 0},
+		{`rrl {
+			ipv4-prefix-length 33
+		}`, true, [classCount]float64{}, 0, 0},
+		{`rrl {
+			slip -1
+		}`, true, [classCount]float64{}, 0, 0},
+		{`rrl {
+			report-only yes
+		}`, true, [classCount]float64{}, 0, 0},
+		{`rrl {
+			blah
+		}`, true, [classCount]float64{}, 0, 0},
+		{`rrl
+		rrl`, true, [classCount]float64{}, 0, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		rl, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d: expected no error, got %s", i, err)
+			continue
+		}
+		if rl.rates != tc.rates {
+			t.Errorf("Test %d: expected rates %v, got %v", i, tc.rates, rl.rates)
+		}
+		if rl.slip != tc.slip {
+			t.Errorf("Test %d: expected slip %d, got %d", i, tc.slip, rl.slip)
+		}
+		if rl.window != tc.window {
+			t.Errorf("Test %d: expected window %f, got %f", i, tc.window, rl.window)
+		}
+	}
+}
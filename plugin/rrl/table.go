@@ -0,0 +1,44 @@
+package rrl
+
+import (
+	"sync"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/cache"
+)
+
+// bucket is the token bucket of one account.
+type bucket struct {
+	balance float64
+	last    time.Time
+	dropped int
+
+	sync.Mutex
+}
+
+// table holds the accounts. It is bounded in size, when full random accounts are evicted.
+type table struct {
+	c *cache.Cache
+	sync.Mutex
+}
+
+func newTable(size int) *table { return &table{c: cache.New(size)} }
+
+// get returns the bucket for key k, if there is none a new one is created with newBucket.
+func (t *table) get(k uint64, newBucket func() *bucket) *bucket {
+	if b, ok := t.c.Get(k); ok {
+		return b.(*bucket)
+	}
+
+	t.Lock()
+	defer t.Unlock()
+	// Someone may have beaten us to it.
+	if b, ok := t.c.Get(k); ok {
+		return b.(*bucket)
+	}
+	b := newBucket()
+	t.c.Add(k, b)
+	return b
+}
+
+func hash(s string) uint64 { return cache.Hash([]byte(s)) }
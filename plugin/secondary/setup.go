@@ -1,6 +1,9 @@
 package secondary
 
 import (
+	"crypto/tls"
+	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/coredns/caddy"
@@ -9,6 +12,7 @@ import (
 	"github.com/coredns/coredns/plugin/file"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
 	"github.com/coredns/coredns/plugin/pkg/parse"
+	pkgtls "github.com/coredns/coredns/plugin/pkg/tls"
 	"github.com/coredns/coredns/plugin/pkg/upstream"
 )
 
@@ -28,8 +32,24 @@ func setup(c *caddy.Controller) error {
 		z := zones.Z[n]
 		if len(z.TransferFrom) > 0 {
 			c.OnStartup(func() error {
+				// The TSIG keys are defined by the tsig plugin.
+				z.TsigSecret = dnsserver.GetConfig(c).TsigSecret
+				z.TsigAlgorithm = dnsserver.GetConfig(c).TsigAlgorithm
+				for _, key := range z.TransferKeys {
+					if _, ok := z.TsigSecret[key]; !ok {
+						return plugin.Error("secondary", fmt.Errorf("TSIG key %q for %s is not defined with the tsig plugin", key, n))
+					}
+				}
+
 				z.StartupOnce.Do(func() {
 					go func() {
+						expire, err := z.LoadTransferFile()
+						if err != nil {
+							log.Warningf("Failed to load '%s' from %q: %s", n, z.TransferFile, err)
+						} else if !expire.IsZero() {
+							log.Infof("Loaded '%s' from %q, it expires at %s", n, z.TransferFile, expire.Format(time.RFC3339))
+						}
+
 						dur := time.Millisecond * 250
 						step := time.Duration(2)
 						max := time.Second * 10
@@ -38,6 +58,15 @@ func setup(c *caddy.Controller) error {
 							if err == nil {
 								break
 							}
+							if !expire.IsZero() && time.Now().After(expire) {
+								z.Lock()
+								expired := z.Expired
+								z.Expired = true
+								z.Unlock()
+								if !expired {
+									log.Warningf("Zone '%s' loaded from %q has expired, all masters failed to transfer it", n, z.TransferFile)
+								}
+							}
 							log.Warningf("All '%s' masters failed to transfer, retrying in %s: %s", n, dur.String(), err)
 							time.Sleep(dur)
 							dur = step * dur
@@ -61,6 +90,7 @@ func setup(c *caddy.Controller) error {
 }
 
 func secondaryParse(c *caddy.Controller) (file.Zones, error) {
+	config := dnsserver.GetConfig(c)
 	z := make(map[string]*file.Zone)
 	names := []string{}
 	for c.Next() {
@@ -72,16 +102,58 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 				names = append(names, origins[i])
 			}
 
+			var (
+				tlsConfig     *tls.Config
+				tlsServerName string
+			)
 			for c.NextBlock() {
-				var f []string
+				var (
+					f   []string
+					key string
+				)
 
 				switch c.Val() {
 				case "transfer":
 					var err error
-					f, err = parse.TransferIn(c)
+					f, key, err = parse.TransferInWithKey(c)
+					if err != nil {
+						return file.Zones{}, err
+					}
+				case "file":
+					if !c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					if len(origins) != 1 {
+						return file.Zones{}, c.Errf("file can only be used with a single zone, got %d", len(origins))
+					}
+					fileName := c.Val()
+					if !filepath.IsAbs(fileName) && config.Root != "" {
+						fileName = filepath.Join(config.Root, fileName)
+					}
+					z[origins[0]].TransferFile = fileName
+					if c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+				case "tls":
+					args := c.RemainingArgs()
+					if len(args) > 3 {
+						return file.Zones{}, c.ArgErr()
+					}
+					for i := range args {
+						if !filepath.IsAbs(args[i]) && config.Root != "" {
+							args[i] = filepath.Join(config.Root, args[i])
+						}
+					}
+					var err error
+					tlsConfig, err = pkgtls.NewTLSConfigFromArgs(args...)
 					if err != nil {
 						return file.Zones{}, err
 					}
+				case "tls_servername":
+					if !c.NextArg() {
+						return file.Zones{}, c.ArgErr()
+					}
+					tlsServerName = c.Val()
 				default:
 					return file.Zones{}, c.Errf("unknown property '%s'", c.Val())
 				}
@@ -90,9 +162,31 @@ func secondaryParse(c *caddy.Controller) (file.Zones, error) {
 					if f != nil {
 						z[origin].TransferFrom = append(z[origin].TransferFrom, f...)
 					}
+					if key != "" {
+						if z[origin].TransferKeys == nil {
+							z[origin].TransferKeys = map[string]string{}
+						}
+						for _, from := range f {
+							z[origin].TransferKeys[from] = key
+						}
+					}
 					z[origin].Upstream = upstream.New()
 				}
 			}
+
+			// The TLS configuration is used for the primaries with the tls:// prefix.
+			if tlsConfig == nil {
+				var err error
+				if tlsConfig, err = pkgtls.NewTLSClientConfig(""); err != nil {
+					return file.Zones{}, err
+				}
+			}
+			if tlsServerName != "" {
+				tlsConfig.ServerName = tlsServerName
+			}
+			for _, origin := range origins {
+				z[origin].TransferTLS = tlsConfig
+			}
 		}
 	}
 	return file.Zones{Z: z, Names: names}, nil
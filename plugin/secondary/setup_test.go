@@ -61,3 +61,99 @@ func TestSecondaryParse(t *testing.T) {
 		}
 	}
 }
+
+func TestSecondaryParseFile(t *testing.T) {
+	tests := []struct {
+		input        string
+		shouldErr    bool
+		transferFile string
+	}{
+		{`secondary example.org {
+			transfer from 127.0.0.1
+			file /var/lib/coredns/db.example.org
+		}`, false, "/var/lib/coredns/db.example.org"},
+		{`secondary example.org {
+			transfer from 127.0.0.1
+		}`, false, ""},
+		{`secondary example.org {
+			file
+		}`, true, ""},
+		{`secondary example.org {
+			file a b
+		}`, true, ""},
+		{`secondary example.org example.net {
+			file db.example.org
+		}`, true, ""},
+	}
+
+	for i, test := range tests {
+		c := caddy.NewTestController("dns", test.input)
+		s, err := secondaryParse(c)
+		if test.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d expected errors, but got no error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+			continue
+		}
+		if x := s.Z["example.org."].TransferFile; x != test.transferFile {
+			t.Errorf("Test %d expected file %q, but got %q", i, test.transferFile, x)
+		}
+	}
+}
+
+func TestSecondaryParseKey(t *testing.T) {
+	c := caddy.NewTestController("dns", `secondary example.org {
+		transfer from 127.0.0.1 10.0.0.1 key Transfer.Key
+		transfer from 10.0.0.2
+	}`)
+	s, err := secondaryParse(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got '%v'", err)
+	}
+	z := s.Z["example.org."]
+	want := map[string]string{"127.0.0.1:53": "transfer.key.", "10.0.0.1:53": "transfer.key."}
+	if len(z.TransferKeys) != len(want) {
+		t.Fatalf("Expected keys %v, got %v", want, z.TransferKeys)
+	}
+	for from, key := range want {
+		if x := z.TransferKeys[from]; x != key {
+			t.Errorf("Expected key %q for %s, got %q", key, from, x)
+		}
+	}
+	if len(z.TransferFrom) != 3 {
+		t.Errorf("Expected 3 primaries, got %v", z.TransferFrom)
+	}
+}
+
+func TestSecondaryParseTLS(t *testing.T) {
+	c := caddy.NewTestController("dns", `secondary example.org {
+		transfer from tls://127.0.0.1
+		tls_servername primary.example.org
+	}`)
+	s, err := secondaryParse(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got '%v'", err)
+	}
+	z := s.Z["example.org."]
+	if x := z.TransferFrom[0]; x != "tls://127.0.0.1:853" {
+		t.Errorf("Expected transfer from %q, got %q", "tls://127.0.0.1:853", x)
+	}
+	if z.TransferTLS == nil || z.TransferTLS.ServerName != "primary.example.org" {
+		t.Errorf("Expected TLS configuration with server name %q, got %v", "primary.example.org", z.TransferTLS)
+	}
+
+	for _, input := range []string{
+		"secondary example.org {\n transfer from tls://127.0.0.1\n tls a b c d\n}",
+		"secondary example.org {\n transfer from tls://127.0.0.1\n tls_servername\n}",
+		"secondary example.org {\n transfer from tls://127.0.0.1\n tls /does/not/exist.pem\n}",
+	} {
+		c := caddy.NewTestController("dns", input)
+		if _, err := secondaryParse(c); err == nil {
+			t.Errorf("Expected errors for %q, but got nil", input)
+		}
+	}
+}
@@ -45,20 +45,26 @@ func write(w io.Writer, z *file.Zone) error {
 		io.WriteString(w, rr.String())
 		w.Write([]byte("\n"))
 	}
-	err := z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+	writeElem := func(e *tree.Elem, _ map[uint16][]dns.RR) error {
 		for _, r := range e.All() {
 			io.WriteString(w, r.String())
 			w.Write([]byte("\n"))
 		}
 		return nil
-	})
-	return err
+	}
+	if err := z.Walk(writeElem); err != nil {
+		return err
+	}
+	if z.Apex.NSEC3 != nil {
+		return z.Apex.NSEC3.Walk(writeElem)
+	}
+	return nil
 }
 
 // Parse parses the zone in filename and returns a new Zone or an error. This
 // is similar to the Parse function in the *file* plugin. However when parsing
-// the record types DNSKEY, RRSIG, CDNSKEY and CDS are *not* included in the returned
-// zone (if encountered).
+// the record types DNSKEY, RRSIG, CDNSKEY, CDS, NSEC, NSEC3 and NSEC3PARAM are *not* included
+// in the returned zone (if encountered).
 func Parse(f io.Reader, origin, fileName string) (*file.Zone, error) {
 	zp := dns.NewZoneParser(f, dns.Fqdn(origin), fileName)
 	zp.SetIncludeAllowed(true)
@@ -71,7 +77,7 @@ func Parse(f io.Reader, origin, fileName string) (*file.Zone, error) {
 		}
 
 		switch rr.(type) {
-		case *dns.DNSKEY, *dns.RRSIG, *dns.CDNSKEY, *dns.CDS:
+		case *dns.DNSKEY, *dns.RRSIG, *dns.CDNSKEY, *dns.CDS, *dns.NSEC, *dns.NSEC3, *dns.NSEC3PARAM:
 			continue
 		case *dns.SOA:
 			seenSOA = true
@@ -0,0 +1,94 @@
+package sign
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+
+	"github.com/miekg/dns"
+)
+
+// nsec3 adds the NSEC3 records (RFC 5155) for all authoritative names in z, including the empty non-terminals,
+// and signs them. The other records in z must have been signed already, so the type bitmaps include RRSIG
+// where needed. With opt-out, delegations without DS records don't get an NSEC3 record.
+func (s *Signer) nsec3(z *file.Zone, ttl, inception, expiration uint32) error {
+	bitmaps := map[string][]uint16{}
+	z.AuthWalk(func(e *tree.Elem, _ map[uint16][]dns.RR, auth bool) error {
+		if !auth {
+			return nil
+		}
+		name := e.Name()
+		delegation := name != s.origin && e.Type(dns.TypeNS) != nil
+		if s.optOut && delegation && e.Type(dns.TypeDS) == nil {
+			return nil
+		}
+		if name == s.origin {
+			bitmaps[name] = append(e.Types(), dns.TypeNS, dns.TypeSOA)
+		} else {
+			bitmaps[name] = e.Types()
+		}
+
+		// The tree is walked in canonical order, so the parents of name have been seen already.
+		for n := name; n != s.origin; {
+			i, end := dns.NextLabel(n, 0)
+			if end {
+				break
+			}
+			n = n[i:]
+			if _, ok := bitmaps[n]; ok {
+				break
+			}
+			if _, ok := z.Search(n); !ok {
+				bitmaps[n] = nil // empty non-terminal
+			}
+		}
+		return nil
+	})
+
+	names := make(map[string]string, len(bitmaps))
+	hashes := make([]string, 0, len(bitmaps))
+	for name := range bitmaps {
+		h := dns.HashName(name, s.nsec3param.Hash, s.nsec3param.Iterations, s.nsec3param.Salt)
+		names[h] = name
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+
+	for i, h := range hashes {
+		next := hashes[(i+1)%len(hashes)]
+		nsec3 := NSEC3(strings.ToLower(h)+"."+s.origin, next, ttl, s.nsec3param, s.optOut, bitmaps[names[h]])
+		z.Insert(nsec3)
+		for _, pair := range s.keys {
+			rrsig, err := pair.signRRs([]dns.RR{nsec3}, s.origin, ttl, inception, expiration)
+			if err != nil {
+				return err
+			}
+			z.Insert(rrsig)
+		}
+	}
+	return nil
+}
+
+// NSEC3 returns an NSEC3 record according to the hashed owner name, next hashed owner name, ttl, the NSEC3
+// parameters, opt-out flag and bitmap. Note that the bitmap is sorted before use.
+func NSEC3(name, next string, ttl uint32, param *dns.NSEC3PARAM, optOut bool, bitmap []uint16) *dns.NSEC3 {
+	sort.Slice(bitmap, func(i, j int) bool { return bitmap[i] < bitmap[j] })
+
+	var flags uint8
+	if optOut {
+		flags = 1 // RFC 5155, Section 3.1.2.1.
+	}
+	return &dns.NSEC3{
+		Hdr:        dns.RR_Header{Name: name, Ttl: ttl, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET},
+		Hash:       param.Hash,
+		Flags:      flags,
+		Iterations: param.Iterations,
+		SaltLength: param.SaltLength,
+		Salt:       param.Salt,
+		HashLength: 20, // SHA1
+		NextDomain: next,
+		TypeBitMap: bitmap,
+	}
+}
@@ -0,0 +1,228 @@
+package sign
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/file"
+	"github.com/coredns/coredns/plugin/file/tree"
+	"github.com/coredns/coredns/plugin/test"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+func signNSEC3(t *testing.T, nsec3 string) *file.Zone {
+	t.Helper()
+	input := `sign testdata/db.miek.nl_nsec3 miek.nl {
+		key file testdata/Kmiek.nl.+013+59725
+		directory testdata
+		` + nsec3 + `
+	}`
+	c := caddy.NewTestController("dns", input)
+	sign, err := parse(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := sign.signers[0].Sign(time.Now().UTC())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return z
+}
+
+// hashed returns the NSEC3 records of z by the names they are for.
+func hashed(t *testing.T, z *file.Zone, names ...string) map[string]*dns.NSEC3 {
+	t.Helper()
+	nsec3s := map[string]*dns.NSEC3{}
+	z.Apex.NSEC3.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		for _, rr := range e.Type(dns.TypeNSEC3) {
+			x := rr.(*dns.NSEC3)
+			for _, name := range names {
+				if x.Match(name) {
+					nsec3s[name] = x
+				}
+			}
+		}
+		return nil
+	})
+	return nsec3s
+}
+
+func TestSignNSEC3(t *testing.T) {
+	z := signNSEC3(t, "nsec3 salt AABBCCDD iterations 2")
+
+	apex, _ := z.Search("miek.nl.")
+	param := apex.Type(dns.TypeNSEC3PARAM)
+	if len(param) != 1 {
+		t.Fatalf("Expected 1 NSEC3PARAM, got %d", len(param))
+	}
+	if x := param[0].(*dns.NSEC3PARAM); x.Iterations != 2 || x.Salt != "AABBCCDD" || x.Flags != 0 {
+		t.Errorf("Expected NSEC3PARAM with 2 iterations and salt AABBCCDD, got %s", x)
+	}
+	z.Walk(func(e *tree.Elem, _ map[uint16][]dns.RR) error {
+		if len(e.Type(dns.TypeNSEC)) > 0 {
+			t.Errorf("Expected no NSEC records, got one for %s", e.Name())
+		}
+		return nil
+	})
+
+	// All authoritative names and the empty non-terminal, but not the glue.
+	names := []string{"miek.nl.", "a.miek.nl.", "www.miek.nl.", "*.wild.miek.nl.", "wild.miek.nl.", "b.ent.miek.nl.",
+		"ent.miek.nl.", "child.miek.nl.", "insecure.miek.nl."}
+	if x := z.Apex.NSEC3.Len(); x != len(names) {
+		t.Errorf("Expected %d NSEC3 records, got %d", len(names), x)
+	}
+	nsec3s := hashed(t, z, append(names, "ns.child.miek.nl.")...)
+	for _, name := range names {
+		if nsec3s[name] == nil {
+			t.Errorf("Expected an NSEC3 record for %s", name)
+		}
+	}
+	if nsec3s["ns.child.miek.nl."] != nil {
+		t.Errorf("Expected no NSEC3 record for the glue")
+	}
+
+	tests := []struct {
+		name   string
+		bitmap []uint16
+	}{
+		{"miek.nl.", []uint16{dns.TypeNS, dns.TypeSOA, dns.TypeMX, dns.TypeRRSIG, dns.TypeDNSKEY, dns.TypeNSEC3PARAM, dns.TypeCDS, dns.TypeCDNSKEY}},
+		{"ent.miek.nl.", nil},
+		{"child.miek.nl.", []uint16{dns.TypeNS, dns.TypeDS, dns.TypeRRSIG}},
+		{"insecure.miek.nl.", []uint16{dns.TypeNS}},
+	}
+	for _, tc := range tests {
+		x := nsec3s[tc.name]
+		if x == nil {
+			continue
+		}
+		if len(x.TypeBitMap) != len(tc.bitmap) {
+			t.Errorf("Expected bitmap %v for %s, got %v", tc.bitmap, tc.name, x.TypeBitMap)
+			continue
+		}
+		for i := range tc.bitmap {
+			if x.TypeBitMap[i] != tc.bitmap[i] {
+				t.Errorf("Expected bitmap %v for %s, got %v", tc.bitmap, tc.name, x.TypeBitMap)
+				break
+			}
+		}
+	}
+
+	// The NSEC3 records form a single chain, and are signed.
+	dnskey := apex.Type(dns.TypeDNSKEY)[0].(*dns.DNSKEY)
+	seen := map[string]bool{}
+	e := z.Apex.NSEC3.Min()
+	for i := 0; i < len(names); i++ {
+		sigs := e.Type(dns.TypeRRSIG)
+		if len(sigs) != 1 {
+			t.Fatalf("Expected the NSEC3 record of %s to be signed", e.Name())
+		}
+		if err := sigs[0].(*dns.RRSIG).Verify(dnskey, e.Type(dns.TypeNSEC3)); err != nil {
+			t.Errorf("Expected a valid signature for the NSEC3 record of %s, got %s", e.Name(), err)
+		}
+		seen[e.Name()] = true
+		next := e.Type(dns.TypeNSEC3)[0].(*dns.NSEC3).NextDomain
+		n, ok := z.Apex.NSEC3.Search(dns.CanonicalName(next + ".miek.nl."))
+		if !ok {
+			t.Fatalf("Expected next hashed owner name %s to exist", next)
+		}
+		e = n
+	}
+	if len(seen) != len(names) || e != z.Apex.NSEC3.Min() {
+		t.Errorf("Expected a chain of %d NSEC3 records, got %d", len(names), len(seen))
+	}
+}
+
+func TestSignNSEC3OptOut(t *testing.T) {
+	z := signNSEC3(t, "nsec3 optout")
+
+	nsec3s := hashed(t, z, "miek.nl.", "child.miek.nl.", "insecure.miek.nl.")
+	if nsec3s["insecure.miek.nl."] != nil {
+		t.Errorf("Expected no NSEC3 record for the unsigned delegation")
+	}
+	if nsec3s["child.miek.nl."] == nil {
+		t.Errorf("Expected an NSEC3 record for the signed delegation")
+	}
+	if x := nsec3s["miek.nl."]; x == nil || x.Flags != 1 || x.Iterations != 0 || x.SaltLength != 0 {
+		t.Errorf("Expected an NSEC3 record with the opt-out flag, no salt and no iterations, got %v", x)
+	}
+}
+
+// TestLookupNSEC3 checks the denial of existence proofs the file plugin serves from a zone signed with NSEC3.
+func TestLookupNSEC3(t *testing.T) {
+	for _, opt := range []string{"nsec3", "nsec3 optout"} {
+		buf := &bytes.Buffer{}
+		if err := write(buf, signNSEC3(t, opt)); err != nil {
+			t.Fatal(err)
+		}
+		z, err := file.Parse(buf, "miek.nl.", "stdin", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			qname string
+			qtype uint16
+			// names that must be proven to exist, or to not exist.
+			match, cover []string
+			optOut       bool
+		}{
+			// NXDOMAIN: closest encloser, next closer and wildcard.
+			{"nope.miek.nl.", dns.TypeA, []string{"miek.nl."}, []string{"nope.miek.nl.", "*.miek.nl."}, false},
+			{"x.nope.a.miek.nl.", dns.TypeA, []string{"a.miek.nl."}, []string{"nope.a.miek.nl.", "*.a.miek.nl."}, false},
+			// NODATA
+			{"a.miek.nl.", dns.TypeA, []string{"a.miek.nl."}, nil, false},
+			{"ent.miek.nl.", dns.TypeA, []string{"ent.miek.nl."}, nil, false},
+			// Wildcard NODATA and answer.
+			{"x.wild.miek.nl.", dns.TypeA, []string{"wild.miek.nl.", "*.wild.miek.nl."}, []string{"x.wild.miek.nl."}, false},
+			{"x.wild.miek.nl.", dns.TypeTXT, nil, []string{"x.wild.miek.nl."}, false},
+			// Delegations, the secure one has a DS record.
+			{"www.child.miek.nl.", dns.TypeA, nil, nil, false},
+			{"www.insecure.miek.nl.", dns.TypeA, []string{"insecure.miek.nl."}, nil, true},
+		}
+		for _, tc := range tests {
+			m := new(dns.Msg)
+			m.SetQuestion(tc.qname, tc.qtype)
+			m.SetEdns0(4096, true)
+			state := request.Request{W: &test.ResponseWriter{}, Req: m}
+			_, ns, _, _ := z.Lookup(context.TODO(), state, tc.qname)
+
+			nsec3s := []*dns.NSEC3{}
+			for _, rr := range ns {
+				if x, ok := rr.(*dns.NSEC3); ok {
+					nsec3s = append(nsec3s, x)
+				}
+			}
+			if tc.optOut && opt == "nsec3 optout" {
+				// The unsigned delegation is covered by an opt-out NSEC3 record instead.
+				tc.match, tc.cover = []string{"miek.nl."}, []string{"insecure.miek.nl."}
+			}
+			for _, name := range tc.match {
+				if !proves(nsec3s, name, (*dns.NSEC3).Match) {
+					t.Errorf("%s: expected an NSEC3 record matching %s for %s/%s, got %v", opt, name, tc.qname, dns.TypeToString[tc.qtype], nsec3s)
+				}
+			}
+			for _, name := range tc.cover {
+				if !proves(nsec3s, name, (*dns.NSEC3).Cover) {
+					t.Errorf("%s: expected an NSEC3 record covering %s for %s/%s, got %v", opt, name, tc.qname, dns.TypeToString[tc.qtype], nsec3s)
+				}
+			}
+			if x := len(tc.match) + len(tc.cover); len(nsec3s) > x {
+				t.Errorf("%s: expected at most %d NSEC3 records for %s/%s, got %d", opt, x, tc.qname, dns.TypeToString[tc.qtype], len(nsec3s))
+			}
+		}
+	}
+}
+
+func proves(nsec3s []*dns.NSEC3, name string, f func(*dns.NSEC3, string) bool) bool {
+	for _, x := range nsec3s {
+		if f(x, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,18 +4,20 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/miekg/dns"
 )
 
 func TestResignInception(t *testing.T) {
 	then := time.Date(2019, 7, 18, 22, 50, 0, 0, time.UTC)
 	// signed yesterday
 	zr := strings.NewReader(`miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190808191936 20190717161936 59725 miek.nl. eU6gI1OkSEbyt`)
-	if x := resign(zr, then); x != nil {
+	if x := resign(zr, then, nil); x != nil {
 		t.Errorf("Expected RRSIG to be valid for %s, got invalid: %s", then.Format(timeFmt), x)
 	}
 	// inception starts after this date.
 	zr = strings.NewReader(`miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190808191936 20190731161936 59725 miek.nl. eU6gI1OkSEbyt`)
-	if x := resign(zr, then); x == nil {
+	if x := resign(zr, then, nil); x == nil {
 		t.Errorf("Expected RRSIG to be invalid for %s, got valid", then.Format(timeFmt))
 	}
 }
@@ -24,17 +26,45 @@ func TestResignExpire(t *testing.T) {
 	then := time.Date(2019, 7, 18, 22, 50, 0, 0, time.UTC)
 	// expires tomorrow
 	zr := strings.NewReader(`miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190717191936 20190717161936 59725 miek.nl. eU6gI1OkSEbyt`)
-	if x := resign(zr, then); x == nil {
+	if x := resign(zr, then, nil); x == nil {
 		t.Errorf("Expected RRSIG to be invalid for %s, got valid", then.Format(timeFmt))
 	}
 	// expire too far away
 	zr = strings.NewReader(`miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190731191936 20190717161936 59725 miek.nl. eU6gI1OkSEbyt`)
-	if x := resign(zr, then); x != nil {
+	if x := resign(zr, then, nil); x != nil {
 		t.Errorf("Expected RRSIG to be valid for %s, got invalid: %s", then.Format(timeFmt), x)
 	}
 	// expired yesterday
 	zr = strings.NewReader(`miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190721191936 20190717161936 59725 miek.nl. eU6gI1OkSEbyt`)
-	if x := resign(zr, then); x == nil {
+	if x := resign(zr, then, nil); x == nil {
 		t.Errorf("Expected RRSIG to be invalid for %s, got valid", then.Format(timeFmt))
 	}
 }
+
+func TestResignNSEC3Param(t *testing.T) {
+	then := time.Date(2019, 7, 18, 22, 50, 0, 0, time.UTC)
+	rrsig := "miek.nl.	1800	IN	RRSIG	SOA 13 2 1800 20190808191936 20190717161936 59725 miek.nl. eU6gI1OkSEbyt\n"
+	param := "miek.nl.	0	IN	NSEC3PARAM	1 0 10 ABCD\n"
+
+	tests := []struct {
+		zone  string
+		param *dns.NSEC3PARAM
+		valid bool
+	}{
+		{rrsig, nil, true},
+		{rrsig + param, &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: 10, Salt: "abcd"}, true},
+		{rrsig + param, nil, false},
+		{rrsig, &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: 10, Salt: "ABCD"}, false},
+		{rrsig + param, &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: 0, Salt: "ABCD"}, false},
+		{rrsig + param, &dns.NSEC3PARAM{Hash: dns.SHA1, Iterations: 10, Salt: ""}, false},
+	}
+	for i, tc := range tests {
+		x := resign(strings.NewReader(tc.zone), then, tc.param)
+		if tc.valid && x != nil {
+			t.Errorf("Test %d: expected zone to be valid, got invalid: %s", i, x)
+		}
+		if !tc.valid && x == nil {
+			t.Errorf("Test %d: expected zone to be invalid, got valid", i)
+		}
+	}
+}
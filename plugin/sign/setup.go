@@ -1,14 +1,19 @@
 package sign
 
 import (
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
 )
 
 func init() { plugin.Register("sign", setup) }
@@ -89,6 +94,15 @@ func parse(c *caddy.Controller) (*Sign, error) {
 					signers[i].directory = dir[0]
 					signers[i].signedfile = fmt.Sprintf("db.%ssigned", signers[i].origin)
 				}
+			case "nsec3":
+				param, optOut, err := nsec3Parse(c)
+				if err != nil {
+					return sign, err
+				}
+				for i := range signers {
+					signers[i].nsec3param = param
+					signers[i].optOut = optOut
+				}
 			default:
 				return nil, c.Errf("unknown property '%s'", c.Val())
 			}
@@ -98,3 +112,46 @@ func parse(c *caddy.Controller) (*Sign, error) {
 
 	return sign, nil
 }
+
+// nsec3Parse parses the arguments of nsec3: [salt SALT] [iterations ITERATIONS] [optout].
+func nsec3Parse(c *caddy.Controller) (*dns.NSEC3PARAM, bool, error) {
+	// RFC 9276 recommends no salt and no extra iterations.
+	param := &dns.NSEC3PARAM{Hash: dns.SHA1}
+	optOut := false
+
+	args := c.RemainingArgs()
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "salt":
+			i++
+			if i == len(args) {
+				return nil, false, c.ArgErr()
+			}
+			salt := args[i]
+			if salt == "-" {
+				salt = ""
+			}
+			b, err := hex.DecodeString(salt)
+			if err != nil || len(b) > 255 {
+				return nil, false, c.Errf("invalid salt %q, it must be hexadecimal, of at most 255 bytes, or '-'", args[i])
+			}
+			param.Salt = strings.ToUpper(salt)
+			param.SaltLength = uint8(len(b))
+		case "iterations":
+			i++
+			if i == len(args) {
+				return nil, false, c.ArgErr()
+			}
+			n, err := strconv.ParseUint(args[i], 10, 16)
+			if err != nil {
+				return nil, false, c.Errf("invalid iterations %q: %s", args[i], err)
+			}
+			param.Iterations = uint16(n)
+		case "optout":
+			optOut = true
+		default:
+			return nil, false, c.Errf("unknown nsec3 argument '%s'", args[i])
+		}
+	}
+	return param, optOut, nil
+}
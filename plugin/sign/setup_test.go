@@ -73,3 +73,57 @@ func TestParse(t *testing.T) {
 		}
 	}
 }
+
+func TestParseNSEC3(t *testing.T) {
+	tests := []struct {
+		nsec3      string
+		shouldErr  bool
+		salt       string
+		iterations uint16
+		optOut     bool
+	}{
+		{"nsec3", false, "", 0, false},
+		{"nsec3 salt aabbccdd iterations 5 optout", false, "AABBCCDD", 5, true},
+		{"nsec3 salt - optout", false, "", 0, true},
+		{"nsec3 salt", true, "", 0, false},
+		{"nsec3 salt xyz", true, "", 0, false},
+		{"nsec3 salt abc", true, "", 0, false},
+		{"nsec3 iterations", true, "", 0, false},
+		{"nsec3 iterations 65536", true, "", 0, false},
+		{"nsec3 optin", true, "", 0, false},
+	}
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", `sign testdata/db.miek.nl miek.nl {
+			key file testdata/Kmiek.nl.+013+59725
+			`+tc.nsec3+`
+		}`)
+		sign, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d expected errors, but got no error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test %d expected no errors, but got '%v'", i, err)
+			continue
+		}
+		signer := sign.signers[0]
+		if signer.nsec3param == nil {
+			t.Errorf("Test %d expected NSEC3 parameters", i)
+			continue
+		}
+		if x := signer.nsec3param.Salt; x != tc.salt {
+			t.Errorf("Test %d expected salt %q, got %q", i, tc.salt, x)
+		}
+		if x := signer.nsec3param.SaltLength; int(x) != len(tc.salt)/2 {
+			t.Errorf("Test %d expected salt length %d, got %d", i, len(tc.salt)/2, x)
+		}
+		if x := signer.nsec3param.Iterations; x != tc.iterations {
+			t.Errorf("Test %d expected %d iterations, got %d", i, tc.iterations, x)
+		}
+		if x := signer.optOut; x != tc.optOut {
+			t.Errorf("Test %d expected opt-out %t, got %t", i, tc.optOut, x)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/coredns/coredns/plugin/file"
@@ -24,6 +25,8 @@ type Signer struct {
 	directory   string
 	jitterIncep time.Duration
 	jitterExpir time.Duration
+	nsec3param  *dns.NSEC3PARAM // If not nil, the zone is signed with NSEC3 instead of NSEC.
+	optOut      bool            // NSEC3 opt-out, delegations without DS records are left out.
 
 	signedfile string
 	stop       chan struct{}
@@ -53,6 +56,11 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 		z.Insert(pair.Public.ToDS(dns.SHA256).ToCDS())
 		z.Insert(pair.Public.ToCDNSKEY())
 	}
+	if s.nsec3param != nil {
+		param := *s.nsec3param
+		param.Hdr = dns.RR_Header{Name: s.origin, Rrtype: dns.TypeNSEC3PARAM, Class: dns.ClassINET, Ttl: 0}
+		z.Insert(&param)
+	}
 
 	names := names(s.origin, z)
 	ln := len(names)
@@ -80,10 +88,13 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 			return nil
 		}
 
-		if e.Name() == s.origin {
+		switch {
+		case s.nsec3param != nil:
+			// NSEC3 records are added once all names are signed.
+		case e.Name() == s.origin:
 			nsec := NSEC(e.Name(), names[(ln+i)%ln], mttl, append(e.Types(), dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC))
 			z.Insert(nsec)
-		} else {
+		default:
 			nsec := NSEC(e.Name(), names[(ln+i)%ln], mttl, append(e.Types(), dns.TypeRRSIG, dns.TypeNSEC))
 			z.Insert(nsec)
 		}
@@ -105,7 +116,10 @@ func (s *Signer) Sign(now time.Time) (*file.Zone, error) {
 		i++
 		return nil
 	})
-	return z, err
+	if err != nil || s.nsec3param == nil {
+		return z, err
+	}
+	return z, s.nsec3(z, mttl, inception, expiration)
 }
 
 // resign checks if the signed zone exists, or needs resigning.
@@ -117,20 +131,22 @@ func (s *Signer) resign() error {
 	}
 
 	now := time.Now().UTC()
-	return resign(rd, now)
+	return resign(rd, now, s.nsec3param)
 }
 
 // resign will scan rd and check the signature on the SOA record. We will resign on the basis
-// of 2 conditions:
+// of 3 conditions:
 // * either the inception is more than 6 days ago, or
-// * we only have 1 week left on the signature
+// * we only have 1 week left on the signature, or
+// * the NSEC3PARAM record in rd doesn't match param, which is nil when signing with NSEC.
 //
 // All SOA signatures will be checked. If the SOA isn't found in the first 100
 // records, we will resign the zone.
-func resign(rd io.Reader, now time.Time) (why error) {
+func resign(rd io.Reader, now time.Time, param *dns.NSEC3PARAM) (why error) {
 	zp := dns.NewZoneParser(rd, ".", "resign")
 	zp.SetIncludeAllowed(true)
 	i := 0
+	var signed *dns.NSEC3PARAM
 
 	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
 		if err := zp.Err(); err != nil {
@@ -138,6 +154,8 @@ func resign(rd io.Reader, now time.Time) (why error) {
 		}
 
 		switch x := rr.(type) {
+		case *dns.NSEC3PARAM:
+			signed = x
 		case *dns.RRSIG:
 			if x.TypeCovered != dns.TypeSOA {
 				continue
@@ -166,6 +184,24 @@ func resign(rd io.Reader, now time.Time) (why error) {
 		}
 	}
 
+	return nsec3paramChanged(signed, param)
+}
+
+// nsec3paramChanged returns an error when the NSEC3PARAM record of the signed zone differs from the
+// configured one, or only one of them exists.
+func nsec3paramChanged(signed, param *dns.NSEC3PARAM) error {
+	switch {
+	case signed == nil && param == nil:
+		return nil
+	case signed == nil:
+		return fmt.Errorf("zone is signed with NSEC, configured with NSEC3")
+	case param == nil:
+		return fmt.Errorf("zone is signed with NSEC3, configured with NSEC")
+	}
+	if signed.Hash != param.Hash || signed.Iterations != param.Iterations || !strings.EqualFold(signed.Salt, param.Salt) {
+		return fmt.Errorf("NSEC3 parameters (hash %d, iterations %d, salt %q) differ from the configured (hash %d, iterations %d, salt %q)",
+			signed.Hash, signed.Iterations, signed.Salt, param.Hash, param.Iterations, param.Salt)
+	}
 	return nil
 }
 
@@ -2,8 +2,12 @@ package transfer
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/coredns/coredns/plugin/pkg/rcode"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/tsig"
 
 	"github.com/miekg/dns"
 )
@@ -14,9 +18,8 @@ func (t *Transfer) Notify(zone string) error {
 		return nil
 	}
 
-	m := new(dns.Msg)
-	m.SetNotify(zone)
 	c := new(dns.Client)
+	c.TsigSecret = t.tsigSecret
 
 	x := longestMatch(t.xfrs, zone)
 	if x == nil {
@@ -25,11 +28,19 @@ func (t *Transfer) Notify(zone string) error {
 	}
 
 	var err1 error
-	for _, t := range x.to {
-		if t == "*" {
+	for _, to := range x.to {
+		// Notifies are sent over UDP, also to the addresses that are transferred to over TLS.
+		addr := strings.TrimPrefix(to, transport.TLS+"://")
+		if addr == "*" {
 			continue
 		}
-		if err := sendNotify(c, m, t); err != nil {
+		m := new(dns.Msg)
+		m.SetNotify(zone)
+		// Sign the notify with the key transfers to this address are signed with.
+		if key := x.keys[to]; key != "" {
+			m.SetTsig(key, tsig.Algorithm(t.tsigAlgorithm, key), tsig.Fudge, time.Now().Unix())
+		}
+		if err := sendNotify(c, m, addr); err != nil {
 			err1 = err
 		}
 	}
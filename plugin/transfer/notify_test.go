@@ -0,0 +1,44 @@
+package transfer
+
+import (
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestNotifyTsigAlgorithm(t *testing.T) {
+	const (
+		key    = "notify.example.org."
+		secret = "so6ZGir4GPAqINNh9U5c3A=="
+	)
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	s := &dns.Server{PacketConn: pc, Net: "udp", TsigSecret: map[string]string{key: secret},
+		NotifyStartedFunc: func() { close(started) },
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			if tsig := r.IsTsig(); tsig == nil || tsig.Algorithm != dns.HmacSHA512 || w.TsigStatus() != nil {
+				m.Rcode = dns.RcodeNotAuth
+			}
+			w.WriteMsg(m)
+		})}
+	go s.ActivateAndServe()
+	<-started
+	defer s.Shutdown()
+
+	addr := pc.LocalAddr().String()
+	tr := &Transfer{
+		xfrs:          []*xfr{{Zones: []string{"example.org."}, to: []string{addr}, keys: map[string]string{addr: key}}},
+		tsigSecret:    map[string]string{key: secret},
+		tsigAlgorithm: map[string]string{key: dns.HmacSHA512},
+	}
+	if err := tr.Notify("example.org."); err != nil {
+		t.Errorf("Expected notify signed with %s to be accepted, got %s", dns.HmacSHA512, err)
+	}
+}
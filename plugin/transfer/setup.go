@@ -1,6 +1,8 @@
 package transfer
 
 import (
+	"fmt"
+
 	"github.com/coredns/caddy"
 	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
@@ -30,6 +32,14 @@ func setup(c *caddy.Controller) error {
 	c.OnStartup(func() error {
 		config := dnsserver.GetConfig(c)
 		t.tsigSecret = config.TsigSecret
+		t.tsigAlgorithm = config.TsigAlgorithm
+		for _, x := range t.xfrs {
+			for _, key := range x.keys {
+				if _, ok := t.tsigSecret[key]; !ok {
+					return plugin.Error("transfer", fmt.Errorf("TSIG key %q is not defined with the tsig plugin", key))
+				}
+			}
+		}
 		// find all plugins that implement Transferer and add them to Transferers
 		plugins := config.Handlers()
 		for _, pl := range plugins {
@@ -53,20 +63,36 @@ func parseTransfer(c *caddy.Controller) (*Transfer, error) {
 		for c.NextBlock() {
 			switch c.Val() {
 			case "to":
-				args := c.RemainingArgs()
+				args, key, err := parse.TSIGKey(c.RemainingArgs())
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
 				if len(args) == 0 {
 					return nil, c.ArgErr()
 				}
 				for _, host := range args {
-					if host == "*" {
-						x.to = append(x.to, host)
-						continue
+					trans, addr := parse.Transport(host)
+					if trans != transport.DNS && trans != transport.TLS {
+						return nil, c.Errf("transport %s is not supported in to", trans)
+					}
+					if addr != "*" {
+						normalized, err := parse.HostPort(addr, transport.Port)
+						if err != nil {
+							return nil, err
+						}
+						addr = normalized
+					}
+					host = addr
+					if trans == transport.TLS {
+						host = transport.TLS + "://" + addr
 					}
-					normalized, err := parse.HostPort(host, transport.Port)
-					if err != nil {
-						return nil, err
+					x.to = append(x.to, host)
+					if key != "" {
+						if x.keys == nil {
+							x.keys = map[string]string{}
+						}
+						x.keys[host] = key
 					}
-					x.to = append(x.to, normalized)
 				}
 			default:
 				return nil, plugin.Error("transfer", c.Errf("unknown property %q", c.Val()))
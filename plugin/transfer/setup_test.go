@@ -1,6 +1,7 @@
 package transfer
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/coredns/caddy"
@@ -113,6 +114,62 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestParseKey(t *testing.T) {
+	c := caddy.NewTestController("dns", `transfer example.org {
+		to 1.2.3.4 * key Transfer.Key
+		to 5.6.7.8
+	}`)
+	transfer, err := parseTransfer(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got '%v'", err)
+	}
+	x := transfer.xfrs[0]
+	if len(x.to) != 3 {
+		t.Fatalf("Expected 3 'to' values, got %v", x.to)
+	}
+	want := map[string]string{"1.2.3.4:53": "transfer.key.", "*": "transfer.key."}
+	if len(x.keys) != len(want) {
+		t.Fatalf("Expected keys %v, got %v", want, x.keys)
+	}
+	for to, key := range want {
+		if x.keys[to] != key {
+			t.Errorf("Expected key %q for %s, got %q", key, to, x.keys[to])
+		}
+	}
+
+	for _, input := range []string{
+		"transfer example.org {\n to 1.2.3.4 key\n}",
+		"transfer example.org {\n to key transfer.key\n}",
+		"transfer example.org {\n to 1.2.3.4 key a b\n}",
+	} {
+		c := caddy.NewTestController("dns", input)
+		if _, err := parseTransfer(c); err == nil {
+			t.Errorf("Expected errors for %q, but got nil", input)
+		}
+	}
+}
+
+func TestParseTLS(t *testing.T) {
+	c := caddy.NewTestController("dns", `transfer example.org {
+		to tls://1.2.3.4 tls://* 5.6.7.8
+	}`)
+	transfer, err := parseTransfer(c)
+	if err != nil {
+		t.Fatalf("Expected no errors, but got '%v'", err)
+	}
+	want := []string{"tls://1.2.3.4:53", "tls://*", "5.6.7.8:53"}
+	if got := transfer.xfrs[0].to; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected 'to' values %v, got %v", want, got)
+	}
+
+	c = caddy.NewTestController("dns", `transfer example.org {
+		to https://1.2.3.4
+	}`)
+	if _, err := parseTransfer(c); err == nil {
+		t.Errorf("Expected errors for an unsupported transport, but got nil")
+	}
+}
+
 func TestSetup(t *testing.T) {
 	c := caddy.NewTestController("dns", "transfer")
 	if err := setup(c); err == nil {
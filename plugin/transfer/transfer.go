@@ -2,11 +2,16 @@ package transfer
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net"
+	"strings"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/transport"
+	"github.com/coredns/coredns/plugin/tsig"
 	"github.com/coredns/coredns/request"
 
 	"github.com/miekg/dns"
@@ -16,15 +21,17 @@ var log = clog.NewWithPlugin("transfer")
 
 // Transfer is a plugin that handles zone transfers.
 type Transfer struct {
-	Transferers []Transferer // List of plugins that implement Transferer
-	xfrs        []*xfr
-	tsigSecret  map[string]string
-	Next        plugin.Handler
+	Transferers   []Transferer // List of plugins that implement Transferer
+	xfrs          []*xfr
+	tsigSecret    map[string]string
+	tsigAlgorithm map[string]string
+	Next          plugin.Handler
 }
 
 type xfr struct {
 	Zones []string
-	to    []string
+	to    []string          // Addresses with the tls:// prefix only receive transfers over mutually authenticated TLS.
+	keys  map[string]string // Names of the TSIG keys that transfers to the addresses in to must be signed with.
 }
 
 // Transferer may be implemented by plugins to enable zone transfers
@@ -42,8 +49,10 @@ type Transferer interface {
 	//
 	// If serial is not 0, it will be handled as an IXFR request. If the serial is equal to or greater (newer) than
 	// the current serial for the zone, send a single SOA record to the channel and then close it.
-	// If the serial is less (older) than the current serial for the zone, perform an AXFR fallback
-	// by proceeding as if an AXFR was requested (as above).
+	// If the serial is less (older) than the current serial for the zone, the implementation may send the
+	// differences since serial (RFC 1995): the current SOA, then for each change the old SOA, the deleted
+	// records, the new SOA and the added records, and the current SOA again. Otherwise perform an AXFR
+	// fallback by proceeding as if an AXFR was requested (as above).
 	Transfer(zone string, serial uint32) (<-chan []dns.RR, error)
 }
 
@@ -68,7 +77,17 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 		return plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 	}
 
-	if !x.allowed(state) {
+	// The tsig plugin strips the TSIG record of the requests it verified.
+	key := tsig.KeyName(ctx)
+	if t := r.IsTsig(); key == "" && t != nil && w.TsigStatus() == nil {
+		key = dns.CanonicalName(t.Hdr.Name)
+	}
+
+	// Transfers to addresses with the tls:// prefix must be done over TLS, to a client with a verified certificate.
+	cs, _ := ctx.Value(dnsserver.TLSConnectionStateKey{}).(*tls.ConnectionState)
+	verified := cs != nil && len(cs.VerifiedChains) > 0
+
+	if !x.allowed(state, key, verified) {
 		// write msg here, so logging will pick it up
 		m := new(dns.Msg)
 		m.SetRcode(r, dns.RcodeRefused)
@@ -125,9 +144,15 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	rrs := []dns.RR{}
 	l := 0
 	var soa *dns.SOA
+	incremental := false
 	for records := range pchan {
-		if x, ok := records[0].(*dns.SOA); ok && soa == nil {
-			soa = x
+		if x, ok := records[0].(*dns.SOA); ok {
+			if soa == nil {
+				soa = x
+			} else if x.Serial != soa.Serial {
+				// a difference sequence, that starts with an older SOA
+				incremental = true
+			}
 		}
 		rrs = append(rrs, records...)
 		if len(rrs) > 500 {
@@ -179,12 +204,26 @@ func (t *Transfer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Ms
 	if soa != nil {
 		logserial = soa.Serial
 	}
+	if incremental {
+		log.Infof("Outgoing incremental transfer of %d records of zone %q to %s from %d to %d SOA serial", l, state.QName(), state.IP(), serial, logserial)
+		return 0, nil
+	}
 	log.Infof("Outgoing transfer of %d records of zone %q to %s for %d SOA serial", l, state.QName(), state.IP(), logserial)
 	return 0, nil
 }
 
-func (x xfr) allowed(state request.Request) bool {
+// allowed returns true if a transfer to state is allowed, key is the name of the TSIG key the request
+// was verified with, or empty if it wasn't signed. Verified is true if the request was received over TLS
+// from a client with a verified certificate.
+func (x xfr) allowed(state request.Request, key string, verified bool) bool {
 	for _, h := range x.to {
+		if k := x.keys[h]; k != "" && k != key {
+			continue
+		}
+		h, tlsOnly := strings.CutPrefix(h, transport.TLS+"://")
+		if tlsOnly && !verified {
+			continue
+		}
 		if h == "*" {
 			return true
 		}
@@ -2,9 +2,12 @@ package transfer
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"testing"
 
+	"github.com/coredns/coredns/core/dnsserver"
 	"github.com/coredns/coredns/plugin"
 	"github.com/coredns/coredns/plugin/pkg/dnstest"
 	"github.com/coredns/coredns/plugin/test"
@@ -217,6 +220,54 @@ func TestTransferIXFRFallback(t *testing.T) {
 	validateAXFRResponse(t, w)
 }
 
+// ixfrPlugin implements transfer.Transferer, it returns the differences from serial 1 to 2.
+type ixfrPlugin struct{}
+
+func (ixfrPlugin) Transfer(zone string, serial uint32) (<-chan []dns.RR, error) {
+	if zone != "example.org." {
+		return nil, ErrNotAuthoritative
+	}
+	ch := make(chan []dns.RR, 3)
+	defer close(ch)
+	ch <- []dns.RR{test.SOA("example.org. 100 IN SOA ns.dns.example.org. hostmaster.example.org. 2 7200 1800 86400 100")}
+	ch <- []dns.RR{
+		test.SOA("example.org. 100 IN SOA ns.dns.example.org. hostmaster.example.org. 1 7200 1800 86400 100"),
+		test.A("a.example.org. 100 IN A 1.2.3.4"),
+		test.SOA("example.org. 100 IN SOA ns.dns.example.org. hostmaster.example.org. 2 7200 1800 86400 100"),
+		test.A("a.example.org. 100 IN A 1.2.3.5"),
+	}
+	ch <- []dns.RR{test.SOA("example.org. 100 IN SOA ns.dns.example.org. hostmaster.example.org. 2 7200 1800 86400 100")}
+	return ch, nil
+}
+
+func TestTransferIXFRIncremental(t *testing.T) {
+	transfer := &Transfer{
+		Transferers: []Transferer{ixfrPlugin{}},
+		xfrs:        []*xfr{{Zones: []string{"example.org."}, to: []string{"*"}}},
+	}
+
+	w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+	m := &dns.Msg{}
+	m.SetIxfr("example.org.", 1, "ns.dns.example.org.", "hostmaster.example.org.")
+	if _, err := transfer.ServeDNS(context.TODO(), w, m); err != nil {
+		t.Fatal(err)
+	}
+
+	rrs := []dns.RR{}
+	for _, m := range w.Msgs {
+		rrs = append(rrs, m.Answer...)
+	}
+	if len(rrs) != 6 {
+		t.Fatalf("Expected 6 records, got %d", len(rrs))
+	}
+	for i, serial := range map[int]uint32{0: 2, 1: 1, 3: 2, 5: 2} {
+		soa, ok := rrs[i].(*dns.SOA)
+		if !ok || soa.Serial != serial {
+			t.Errorf("Expected record %d to be the SOA with serial %d, got %s", i, serial, rrs[i])
+		}
+	}
+}
+
 func validateAXFRResponse(t *testing.T, w *dnstest.MultiRecorder) {
 	if len(w.Msgs) == 0 {
 		t.Fatal("Did not get back a zone response")
@@ -276,3 +327,91 @@ func TestTransferNotAllowed(t *testing.T) {
 		t.Errorf("Expected REFUSED response code, got %s", dns.RcodeToString[w.Msg.Rcode])
 	}
 }
+
+func TestTransferKeyRequired(t *testing.T) {
+	nextPlugin := transfererPlugin{Zone: "example.org.", Serial: 12345}
+
+	transfer := Transfer{
+		Transferers: []Transferer{&nextPlugin},
+		xfrs: []*xfr{
+			{
+				Zones: []string{"example.org."},
+				to:    []string{"*"},
+				keys:  map[string]string{"*": "transfer.key."},
+			},
+		},
+		Next: &nextPlugin,
+	}
+
+	tests := []struct {
+		key   string
+		rcode int
+	}{
+		{"", dns.RcodeRefused},
+		{"other.key.", dns.RcodeRefused},
+		{"transfer.key.", dns.RcodeSuccess},
+	}
+	for i, tc := range tests {
+		w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+		m := &dns.Msg{}
+		m.SetAxfr("example.org.")
+		if tc.key != "" {
+			m.SetTsig(tc.key, dns.HmacSHA256, 300, 0)
+		}
+		if _, err := transfer.ServeDNS(context.TODO(), w, m); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if len(w.Msgs) == 0 {
+			t.Errorf("Test %d: no response", i)
+			continue
+		}
+		if rcode := w.Msgs[0].Rcode; rcode != tc.rcode {
+			t.Errorf("Test %d: expected %s response code, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+	}
+}
+
+func TestTransferTLSRequired(t *testing.T) {
+	nextPlugin := transfererPlugin{Zone: "example.org.", Serial: 12345}
+
+	transfer := Transfer{
+		Transferers: []Transferer{&nextPlugin},
+		xfrs: []*xfr{
+			{
+				Zones: []string{"example.org."},
+				to:    []string{"tls://*"},
+			},
+		},
+		Next: &nextPlugin,
+	}
+
+	tests := []struct {
+		cs    *tls.ConnectionState
+		rcode int
+	}{
+		{nil, dns.RcodeRefused},
+		{&tls.ConnectionState{}, dns.RcodeRefused},
+		{&tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}}, dns.RcodeSuccess},
+	}
+	for i, tc := range tests {
+		ctx := context.TODO()
+		if tc.cs != nil {
+			ctx = context.WithValue(ctx, dnsserver.TLSConnectionStateKey{}, tc.cs)
+		}
+		w := dnstest.NewMultiRecorder(&test.ResponseWriter{TCP: true})
+		m := &dns.Msg{}
+		m.SetAxfr("example.org.")
+		if _, err := transfer.ServeDNS(ctx, w, m); err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if len(w.Msgs) == 0 {
+			t.Errorf("Test %d: no response", i)
+			continue
+		}
+		if rcode := w.Msgs[0].Rcode; rcode != tc.rcode {
+			t.Errorf("Test %d: expected %s response code, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rcode])
+		}
+	}
+}
@@ -30,6 +30,7 @@ func setup(c *caddy.Controller) error {
 	config := dnsserver.GetConfig(c)
 
 	config.TsigSecret = t.secrets
+	config.TsigAlgorithm = t.algs
 
 	config.AddPlugin(func(next plugin.Handler) plugin.Handler {
 		t.Next = next
@@ -42,6 +43,7 @@ func setup(c *caddy.Controller) error {
 func parse(c *caddy.Controller) (*TSIGServer, error) {
 	t := &TSIGServer{
 		secrets: make(map[string]string),
+		algs:    make(map[string]string),
 		types:   defaultQTypes,
 	}
 
@@ -72,7 +74,7 @@ func parse(c *caddy.Controller) (*TSIGServer, error) {
 				if err != nil {
 					return nil, err
 				}
-				secrets, err := parseKeyFile(f)
+				secrets, algs, err := parseKeyFile(f)
 				if err != nil {
 					return nil, err
 				}
@@ -82,6 +84,9 @@ func parse(c *caddy.Controller) (*TSIGServer, error) {
 					}
 					t.secrets[k] = s
 				}
+				for k, a := range algs {
+					t.algs[k] = a
+				}
 			case "require":
 				t.types = qTypes{}
 				args := c.RemainingArgs()
@@ -110,8 +115,10 @@ func parse(c *caddy.Controller) (*TSIGServer, error) {
 	return t, nil
 }
 
-func parseKeyFile(f io.Reader) (map[string]string, error) {
+// parseKeyFile parses the keys in f, it returns their secrets and the algorithms of the keys that define one.
+func parseKeyFile(f io.Reader) (map[string]string, map[string]string, error) {
 	secrets := make(map[string]string)
+	algs := make(map[string]string)
 	s := bufio.NewScanner(f)
 	for s.Scan() {
 		fields := strings.Fields(s.Text())
@@ -119,18 +126,18 @@ func parseKeyFile(f io.Reader) (map[string]string, error) {
 			continue
 		}
 		if fields[0] != "key" {
-			return nil, fmt.Errorf("unexpected token %q", fields[0])
+			return nil, nil, fmt.Errorf("unexpected token %q", fields[0])
 		}
 		if len(fields) < 2 {
-			return nil, fmt.Errorf("expected key name %q", s.Text())
+			return nil, nil, fmt.Errorf("expected key name %q", s.Text())
 		}
 		key := strings.Trim(fields[1], "\"{")
 		if len(key) == 0 {
-			return nil, fmt.Errorf("expected key name %q", s.Text())
+			return nil, nil, fmt.Errorf("expected key name %q", s.Text())
 		}
 		key = plugin.Name(key).Normalize()
 		if _, ok := secrets[key]; ok {
-			return nil, fmt.Errorf("key %q redefined", key)
+			return nil, nil, fmt.Errorf("key %q redefined", key)
 		}
 	key:
 		for s.Scan() {
@@ -140,14 +147,24 @@ func parseKeyFile(f io.Reader) (map[string]string, error) {
 			}
 			switch fields[0] {
 			case "algorithm":
-				continue
+				if len(fields) < 2 {
+					return nil, nil, fmt.Errorf("expected algorithm %q", s.Text())
+				}
+				name := strings.Trim(fields[1], "\";")
+				alg := dns.Fqdn(strings.ToLower(name))
+				switch alg {
+				case dns.HmacSHA1, dns.HmacSHA224, dns.HmacSHA256, dns.HmacSHA384, dns.HmacSHA512:
+				default:
+					return nil, nil, fmt.Errorf("unsupported algorithm %q for key %q", name, key)
+				}
+				algs[key] = alg
 			case "secret":
 				if len(fields) < 2 {
-					return nil, fmt.Errorf("expected secret key %q", s.Text())
+					return nil, nil, fmt.Errorf("expected secret key %q", s.Text())
 				}
 				secret := strings.Trim(fields[1], "\";")
 				if len(secret) == 0 {
-					return nil, fmt.Errorf("expected secret key %q", s.Text())
+					return nil, nil, fmt.Errorf("expected secret key %q", s.Text())
 				}
 				secrets[key] = secret
 			case "}":
@@ -155,14 +172,14 @@ func parseKeyFile(f io.Reader) (map[string]string, error) {
 			case "};":
 				break key
 			default:
-				return nil, fmt.Errorf("unexpected token %q", fields[0])
+				return nil, nil, fmt.Errorf("unexpected token %q", fields[0])
 			}
 		}
 		if _, ok := secrets[key]; !ok {
-			return nil, fmt.Errorf("expected secret for key %q", key)
+			return nil, nil, fmt.Errorf("expected secret for key %q", key)
 		}
 	}
-	return secrets, nil
+	return secrets, algs, nil
 }
 
 var defaultQTypes = qTypes{}
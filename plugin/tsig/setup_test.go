@@ -154,7 +154,7 @@ key "name2.key." {
 
 func TestParseKeyFile(t *testing.T) {
 	var reader = strings.NewReader(`key "foo" {
-	algorithm hmac-sha256;
+	algorithm hmac-sha512;
 	secret "36eowrtmxceNA3T5AdE+JNUOWFCw3amtcyHACnrDVgQ=";
 };
 key "bar" {
@@ -165,7 +165,7 @@ key "baz" {
 	secret "BycDPXSx/5YCD44Q4g5Nd2QNxNRDKwWTXddrU/zpIQM=";
 };`)
 
-	secrets, err := parseKeyFile(reader)
+	secrets, algs, err := parseKeyFile(reader)
 	if err != nil {
 		t.Fatalf("Unexpected error: %q", err)
 	}
@@ -189,6 +189,22 @@ key "baz" {
 			t.Errorf("incorrect secret in result for key %q. expected %q got %q ", k, expectedSec, sec)
 		}
 	}
+
+	expectedAlgs := map[string]string{
+		"foo.": dns.HmacSHA512,
+		"bar.": dns.HmacSHA256,
+	}
+	if len(algs) != len(expectedAlgs) {
+		t.Fatalf("result has %d algorithms. expected %d", len(algs), len(expectedAlgs))
+	}
+	for k, alg := range expectedAlgs {
+		if algs[k] != alg {
+			t.Errorf("incorrect algorithm in result for key %q. expected %q got %q ", k, alg, algs[k])
+		}
+	}
+	if alg := Algorithm(algs, "baz."); alg != dns.HmacSHA256 {
+		t.Errorf("expected key without algorithm to use %q, got %q", dns.HmacSHA256, alg)
+	}
 }
 
 func TestParseKeyFileErrors(t *testing.T) {
@@ -208,6 +224,10 @@ func TestParseKeyFileErrors(t *testing.T) {
 			err: "key \"foo.\" redefined",
 		},
 		{in: `key "foo" {
+	algorithm hmac-md5;`,
+			err: "unsupported algorithm \"hmac-md5\" for key \"foo.\"",
+		},
+		{in: `key "foo" {
 	schmalgorithm hmac-sha256;`,
 			err: "unexpected token \"schmalgorithm\"",
 		},
@@ -233,7 +253,7 @@ func TestParseKeyFileErrors(t *testing.T) {
 		},
 	}
 	for i, testcase := range tests {
-		_, err := parseKeyFile(strings.NewReader(testcase.in))
+		_, _, err := parseKeyFile(strings.NewReader(testcase.in))
 		if err == nil {
 			t.Errorf("Test %d: expected error, got no error", i)
 			continue
@@ -17,6 +17,7 @@ import (
 type TSIGServer struct {
 	Zones   []string
 	secrets map[string]string // [key-name]secret
+	algs    map[string]string // [key-name]algorithm, for the keys that define one
 	types   qTypes
 	all     bool
 	Next    plugin.Handler
@@ -75,6 +76,9 @@ func (t *TSIGServer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.
 	}
 
 	if rcode == dns.RcodeSuccess {
+		if tsigRR != nil {
+			ctx = context.WithValue(ctx, keyNameKey{}, dns.CanonicalName(tsigRR.Hdr.Name))
+		}
 		rcode, err = plugin.NextOrFailure(t.Name(), t.Next, ctx, w, r)
 		if err != nil {
 			log.Errorf("request handler returned an error: %v\n", err)
@@ -88,6 +92,28 @@ func (t *TSIGServer) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.
 	return dns.RcodeSuccess, nil
 }
 
+// keyNameKey is the context key for the name of the TSIG key a request was verified with.
+type keyNameKey struct{}
+
+// KeyName returns the (lower cased) name of the TSIG key the request was successfully verified with.
+// If the request wasn't TSIG signed, or didn't pass through the tsig plugin, the empty string is returned.
+func KeyName(ctx context.Context) string {
+	name, _ := ctx.Value(keyNameKey{}).(string)
+	return name
+}
+
+// Fudge is the fudge used in TSIG signed requests, as recommended by RFC 8945.
+const Fudge = 300
+
+// Algorithm returns the algorithm of the TSIG key name in algs, as defined with the tsig plugin (see
+// dnsserver.Config.TsigAlgorithm). Keys that don't define one use hmac-sha256.
+func Algorithm(algs map[string]string, name string) string {
+	if alg, ok := algs[name]; ok {
+		return alg
+	}
+	return dns.HmacSHA256
+}
+
 func (t *TSIGServer) tsigRequired(qtype uint16) bool {
 	if t.all {
 		return true
@@ -253,3 +253,33 @@ type ErrWriter struct {
 
 // TsigStatus always returns an error.
 func (t *ErrWriter) TsigStatus() error { return t.err }
+
+func TestKeyName(t *testing.T) {
+	var keyName string
+	tsig := TSIGServer{
+		Zones: []string{"."},
+		Next: test.HandlerFunc(func(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+			keyName = KeyName(ctx)
+			return dns.RcodeSuccess, nil
+		}),
+	}
+
+	r := new(dns.Msg)
+	r.SetQuestion("test.example.", dns.TypeA)
+	r.SetTsig("Test.Key.", dns.HmacSHA256, 300, time.Now().Unix())
+	if _, err := tsig.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r); err != nil {
+		t.Fatal(err)
+	}
+	if keyName != "test.key." {
+		t.Errorf("Expected key name %q, got %q", "test.key.", keyName)
+	}
+
+	r = new(dns.Msg)
+	r.SetQuestion("test.example.", dns.TypeA)
+	if _, err := tsig.ServeDNS(context.TODO(), dnstest.NewRecorder(&test.ResponseWriter{}), r); err != nil {
+		t.Fatal(err)
+	}
+	if keyName != "" {
+		t.Errorf("Expected no key name, got %q", keyName)
+	}
+}
@@ -0,0 +1,83 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// rootKSKs are the DS records of the root zone key signing keys, KSK-2017 and KSK-2024, as published
+// by IANA in https://data.iana.org/root-anchors/root-anchors.xml.
+var rootKSKs = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// rootAnchors returns the root zone trust anchors.
+func rootAnchors() []*dns.DS {
+	ds := make([]*dns.DS, len(rootKSKs))
+	for i, s := range rootKSKs {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			panic(err)
+		}
+		ds[i] = rr.(*dns.DS)
+	}
+	return ds
+}
+
+// parseAnchors reads the trust anchors from r, which holds DS or DNSKEY records in the presentation
+// format. DNSKEY records are converted to a DS record with a SHA-256 digest.
+func parseAnchors(r io.Reader, file string) ([]*dns.DS, error) {
+	var ds []*dns.DS
+	zp := dns.NewZoneParser(r, ".", file)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch x := rr.(type) {
+		case *dns.DS:
+			ds = append(ds, x)
+		case *dns.DNSKEY:
+			if x.Flags&dns.ZONE == 0 {
+				return nil, fmt.Errorf("DNSKEY for %s is not a zone key", x.Hdr.Name)
+			}
+			ds = append(ds, x.ToDS(dns.SHA256))
+		default:
+			return nil, fmt.Errorf("trust anchor must be a DS or DNSKEY record, got %s", dns.TypeToString[rr.Header().Rrtype])
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if len(ds) == 0 {
+		return nil, fmt.Errorf("no trust anchors found in %s", file)
+	}
+	return ds, nil
+}
+
+// supportedAlgorithm returns true if alg is a DNSKEY algorithm that can be validated.
+func supportedAlgorithm(alg uint8) bool {
+	switch alg {
+	case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512, dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+		return true
+	}
+	return false
+}
+
+// supportedDigest returns true if digest is a DS digest type that can be validated.
+func supportedDigest(digest uint8) bool {
+	switch digest {
+	case dns.SHA1, dns.SHA256, dns.SHA384:
+		return true
+	}
+	return false
+}
+
+// matchDS returns true if key is the key referenced by ds.
+func matchDS(key *dns.DNSKEY, ds *dns.DS) bool {
+	if key.KeyTag() != ds.KeyTag || key.Algorithm != ds.Algorithm {
+		return false
+	}
+	kds := key.ToDS(ds.DigestType)
+	return kds != nil && strings.EqualFold(kds.Digest, ds.Digest)
+}
@@ -0,0 +1,239 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+
+	"github.com/miekg/dns"
+)
+
+// zoneKeys is the result of chasing the chain of trust down to a name: the deepest zone enclosing the name
+// and its validated zone keys. If keys is nil, the name is insecure, i.e. not covered by a trust anchor,
+// below a negative trust anchor or below a delegation that has no (usable) DS records.
+type zoneKeys struct {
+	name    string
+	zone    string
+	keys    []*dns.DNSKEY
+	expires time.Time
+}
+
+func (zk *zoneKeys) insecure() bool { return zk.keys == nil }
+
+// below returns the zoneKeys for name, that lies below zk.name in the same zone.
+func (zk *zoneKeys) below(name string) *zoneKeys {
+	return &zoneKeys{name: name, zone: zk.zone, keys: zk.keys, expires: zk.expires}
+}
+
+// chase returns the zoneKeys for name, which must be a canonical name. Results are cached until their
+// TTL expires.
+func (v *Validate) chase(ctx context.Context, w dns.ResponseWriter, name string) (*zoneKeys, error) {
+	k := cache.Hash([]byte(name))
+	if e, ok := v.keys.Get(k); ok {
+		if zk := e.(*zoneKeys); zk.name == name && v.now().Before(zk.expires) {
+			return zk, nil
+		}
+	}
+
+	e, err := v.inflight.Do(k, func() (interface{}, error) { return v.chaseName(ctx, w, name) })
+	if err != nil {
+		return nil, err
+	}
+	zk := e.(*zoneKeys)
+	v.keys.Add(k, zk)
+	return zk, nil
+}
+
+// chaseName does the work for chase: it extends the chain of trust for the parent of name with the DS
+// records for name.
+func (v *Validate) chaseName(ctx context.Context, w dns.ResponseWriter, name string) (*zoneKeys, error) {
+	now := v.now()
+	if plugin.Zones(v.insecure).Matches(name) != "" || plugin.Zones(v.anchorZones).Matches(name) == "" {
+		return &zoneKeys{name: name, zone: name, expires: now.Add(maxKeyTTL)}, nil
+	}
+	if ds, ok := v.anchors[name]; ok {
+		return v.dnskeys(ctx, w, name, ds, maxKeyTTL)
+	}
+
+	parent, err := v.chase(ctx, w, parentName(name))
+	if err != nil {
+		return nil, err
+	}
+	if parent.insecure() {
+		return parent.below(name), nil
+	}
+
+	m, err := v.exchange(ctx, w, name, dns.TypeDS)
+	if err != nil {
+		return nil, err
+	}
+	switch m.Rcode {
+	case dns.RcodeSuccess:
+	case dns.RcodeNameError:
+		// The name doesn't exist, so it can't be a zone cut.
+		if _, err := v.verifyDenial(m, name, dns.TypeDS, parent); err != nil {
+			return nil, err
+		}
+		return parent.below(name), nil
+	default:
+		return nil, bogus(dns.ExtendedErrorCodeDNSBogus, "DS lookup for %s failed with %s", name, dns.RcodeToString[m.Rcode])
+	}
+
+	for _, s := range rrSets(m.Answer) {
+		if s.name != name {
+			continue
+		}
+		switch s.qtype {
+		case dns.TypeDS:
+			sig, err := verifyRRset(s, parent, now)
+			if err != nil {
+				return nil, err
+			}
+			capTTL(s, sig, now)
+			ds := make([]*dns.DS, 0, len(s.rrs))
+			for _, rr := range s.rrs {
+				ds = append(ds, rr.(*dns.DS))
+			}
+			return v.dnskeys(ctx, w, name, ds, time.Duration(minTTL(s.rrs))*time.Second)
+		case dns.TypeCNAME:
+			// An alias is never a zone cut.
+			return parent.below(name), nil
+		}
+	}
+
+	optout, err := v.verifyDenial(m, name, dns.TypeDS, parent)
+	if err != nil {
+		return nil, err
+	}
+	if optout || v.delegation(m, name) {
+		// An insecure delegation, everything below name is insecure.
+		return &zoneKeys{name: name, zone: name, expires: parent.expires}, nil
+	}
+	return parent.below(name), nil
+}
+
+// dnskeys fetches the DNSKEY RRset for zone and validates it with ds. The zone keys are returned, or an insecure
+// zoneKeys when none of the DS records can be used. ttl is the TTL of the DS records.
+func (v *Validate) dnskeys(ctx context.Context, w dns.ResponseWriter, zone string, ds []*dns.DS, ttl time.Duration) (*zoneKeys, error) {
+	now := v.now()
+
+	usable := ds[:0:0]
+	for _, d := range ds {
+		if supportedAlgorithm(d.Algorithm) && supportedDigest(d.DigestType) {
+			usable = append(usable, d)
+		}
+	}
+	if len(usable) == 0 {
+		// RFC 4035, section 5.2: treat the zone as unsigned.
+		return &zoneKeys{name: zone, zone: zone, expires: now.Add(clampTTL(ttl))}, nil
+	}
+
+	m, err := v.exchange(ctx, w, zone, dns.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	var set *rrSet
+	for _, s := range rrSets(m.Answer) {
+		if s.name == zone && s.qtype == dns.TypeDNSKEY {
+			set = s
+		}
+	}
+	if set == nil {
+		return nil, bogus(dns.ExtendedErrorCodeDNSKEYMissing, "no DNSKEY records for %s", zone)
+	}
+
+	var keys []*dns.DNSKEY
+	for _, rr := range set.rrs {
+		if k := rr.(*dns.DNSKEY); k.Flags&dns.ZONE != 0 && k.Protocol == 3 {
+			keys = append(keys, k)
+		}
+	}
+
+	// The DNSKEY RRset must be signed by a key that is referenced by one of the DS records.
+	var sep []*dns.DNSKEY
+	for _, k := range keys {
+		for _, d := range usable {
+			if matchDS(k, d) {
+				sep = append(sep, k)
+				break
+			}
+		}
+	}
+	if len(sep) == 0 {
+		return nil, bogus(dns.ExtendedErrorCodeDNSKEYMissing, "no DNSKEY for %s matches its DS records", zone)
+	}
+	sig, err := verifyRRset(set, &zoneKeys{zone: zone, keys: sep}, now)
+	if err != nil {
+		return nil, err
+	}
+	capTTL(set, sig, now)
+
+	if t := time.Duration(minTTL(set.rrs)) * time.Second; t < ttl {
+		ttl = t
+	}
+	return &zoneKeys{name: zone, zone: zone, keys: keys, expires: now.Add(clampTTL(ttl))}, nil
+}
+
+// delegation returns true if the verified denial in m shows that name has NS records, and thus is a
+// delegation.
+func (v *Validate) delegation(m *dns.Msg, name string) bool {
+	for _, rr := range m.Ns {
+		switch x := rr.(type) {
+		case *dns.NSEC:
+			if dns.CanonicalName(x.Hdr.Name) == name {
+				return hasType(x.TypeBitMap, dns.TypeNS) && !hasType(x.TypeBitMap, dns.TypeSOA)
+			}
+		case *dns.NSEC3:
+			if x.Match(name) {
+				return hasType(x.TypeBitMap, dns.TypeNS) && !hasType(x.TypeBitMap, dns.TypeSOA)
+			}
+		}
+	}
+	return false
+}
+
+// exchange sends a query for name and qtype to the next plugin and returns the response.
+func (v *Validate) exchange(ctx context.Context, w dns.ResponseWriter, name string, qtype uint16) (*dns.Msg, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(name, qtype)
+	m.SetEdns0(4096, true)
+	m.CheckingDisabled = true
+
+	nw := nonwriter.New(w)
+	if _, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, m); err != nil {
+		return nil, err
+	}
+	if nw.Msg == nil {
+		return nil, fmt.Errorf("no response for %s/%s", name, dns.TypeToString[qtype])
+	}
+	return nw.Msg, nil
+}
+
+// parentName returns the parent of name, which must not be the root.
+func parentName(name string) string {
+	off, end := dns.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[off:]
+}
+
+// clampTTL keeps ttl within the bounds of how long validated keys are cached.
+func clampTTL(ttl time.Duration) time.Duration {
+	if ttl < minKeyTTL {
+		return minKeyTTL
+	}
+	if ttl > maxKeyTTL {
+		return maxKeyTTL
+	}
+	return ttl
+}
+
+const (
+	minKeyTTL = 5 * time.Second
+	maxKeyTTL = 1 * time.Hour
+)
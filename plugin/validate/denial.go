@@ -0,0 +1,335 @@
+package validate
+
+import (
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// maxNSEC3Iterations is the highest number of NSEC3 iterations that is validated, responses using more are
+// treated as insecure (RFC 9276, section 3.2).
+const maxNSEC3Iterations = 150
+
+// denialRecords verifies the SOA, NSEC and NSEC3 RRsets in the authority section of m with zk and returns
+// the NSEC and NSEC3 records.
+func denialRecords(m *dns.Msg, zk *zoneKeys, now time.Time) ([]*dns.NSEC, []*dns.NSEC3, error) {
+	var (
+		nsecs  []*dns.NSEC
+		nsec3s []*dns.NSEC3
+	)
+	for _, s := range rrSets(m.Ns) {
+		switch s.qtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+		default:
+			continue
+		}
+		sig, err := verifyRRset(s, zk, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		capTTL(s, sig, now)
+		for _, rr := range s.rrs {
+			switch x := rr.(type) {
+			case *dns.NSEC:
+				nsecs = append(nsecs, x)
+			case *dns.NSEC3:
+				nsec3s = append(nsec3s, x)
+			}
+		}
+	}
+	return nsecs, nsec3s, nil
+}
+
+// verifyDenial verifies the proof in m that name doesn't exist, or doesn't have type qtype, depending on the
+// rcode of m. The returned bool is true when the proof shows that the response is insecure, because of NSEC3
+// opt-out, or because the NSEC3 parameters are too expensive to check.
+func (v *Validate) verifyDenial(m *dns.Msg, name string, qtype uint16, zk *zoneKeys) (bool, error) {
+	nsecs, nsec3s, err := denialRecords(m, zk, v.now())
+	if err != nil {
+		return false, err
+	}
+
+	nxdomain := m.Rcode == dns.RcodeNameError
+	if len(nsecs) > 0 {
+		if nxdomain && nsecNameError(nsecs, name) || !nxdomain && nsecNoData(nsecs, name, qtype) {
+			return false, nil
+		}
+	}
+	if len(nsec3s) > 0 {
+		for _, n := range nsec3s {
+			if n.Hash != dns.SHA1 || n.Iterations > maxNSEC3Iterations {
+				return true, nil
+			}
+		}
+		var ok, optout bool
+		if nxdomain {
+			ok, optout = nsec3NameError(nsec3s, name, zk.zone)
+		} else {
+			ok, optout = nsec3NoData(nsec3s, name, qtype, zk.zone)
+		}
+		if ok {
+			return optout, nil
+		}
+	}
+	if nxdomain {
+		return false, bogus(dns.ExtendedErrorCodeNSECMissing, "no proof that %s doesn't exist", name)
+	}
+	return false, bogus(dns.ExtendedErrorCodeNSECMissing, "no proof that %s has no %s records", name, dns.TypeToString[qtype])
+}
+
+// verifyWildcard verifies the proof in m that name, which was answered by a wildcard with the given number of
+// labels, doesn't exist itself (RFC 4035, section 5.3.4).
+func (v *Validate) verifyWildcard(m *dns.Msg, name string, labels int, zk *zoneKeys) error {
+	nsecs, nsec3s, err := denialRecords(m, zk, v.now())
+	if err != nil {
+		return err
+	}
+	for _, n := range nsecs {
+		if nsecCovers(n, name) {
+			return nil
+		}
+	}
+	// The next closer name is the name with one label more than the wildcard's closest encloser.
+	if idx := dns.Split(name); len(idx) > labels {
+		nc := name[idx[len(idx)-labels-1]:]
+		for _, n := range nsec3s {
+			if n.Cover(nc) {
+				return nil
+			}
+		}
+	}
+	return bogus(dns.ExtendedErrorCodeNSECMissing, "no proof that %s doesn't exist for its wildcard answer", name)
+}
+
+// nsecCovers returns true if name falls between the owner and the next name of n, i.e. n proves
+// that name doesn't exist.
+func nsecCovers(n *dns.NSEC, name string) bool {
+	owner, next := dns.CanonicalName(n.Hdr.Name), dns.CanonicalName(n.NextDomain)
+	if compare(owner, name) >= 0 {
+		return false
+	}
+	// The NSEC of a delegation or DNAME does not prove anything about the names below it (RFC 6840, section 4.1).
+	if dns.IsSubDomain(owner, name) {
+		if hasType(n.TypeBitMap, dns.TypeDNAME) || hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA) {
+			return false
+		}
+	}
+	if compare(owner, next) >= 0 {
+		// The last NSEC in the zone, its next name is the apex.
+		return dns.IsSubDomain(next, name)
+	}
+	return compare(name, next) < 0
+}
+
+// nsecNameError returns true if nsecs prove that name doesn't exist, and that there is no wildcard that
+// could have matched it.
+func nsecNameError(nsecs []*dns.NSEC, name string) bool {
+	for _, n := range nsecs {
+		if !nsecCovers(n, name) {
+			continue
+		}
+		wc := wildcard(nsecEncloser(n, name))
+		for _, w := range nsecs {
+			if nsecCovers(w, wc) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nsecNoData returns true if nsecs prove that name exists, but has no records of type qtype.
+func nsecNoData(nsecs []*dns.NSEC, name string, qtype uint16) bool {
+	for _, n := range nsecs {
+		if dns.CanonicalName(n.Hdr.Name) != name {
+			continue
+		}
+		if hasType(n.TypeBitMap, qtype) || hasType(n.TypeBitMap, dns.TypeCNAME) {
+			return false
+		}
+		// The parent side NSEC of a delegation only proves the absence of the DS record (RFC 6840, section 4.4).
+		if qtype != dns.TypeDS && hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA) {
+			return false
+		}
+		return true
+	}
+
+	for _, n := range nsecs {
+		if !nsecCovers(n, name) {
+			continue
+		}
+		// An empty non-terminal, the next name lies below name.
+		if next := dns.CanonicalName(n.NextDomain); next != name && dns.IsSubDomain(name, next) {
+			return true
+		}
+		// A wildcard without qtype records matched.
+		wc := wildcard(nsecEncloser(n, name))
+		for _, w := range nsecs {
+			if dns.CanonicalName(w.Hdr.Name) == wc && !hasType(w.TypeBitMap, qtype) && !hasType(w.TypeBitMap, dns.TypeCNAME) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nsecEncloser returns the closest encloser of name, that is covered by n.
+func nsecEncloser(n *dns.NSEC, name string) string {
+	a, b := ancestor(name, n.Hdr.Name), ancestor(name, n.NextDomain)
+	if dns.CountLabel(a) > dns.CountLabel(b) {
+		return a
+	}
+	return b
+}
+
+// nsec3NameError returns true if nsec3s prove that name doesn't exist, and that there is no wildcard that
+// could have matched it. The second bool is true if the next closer name is covered by an opt-out NSEC3.
+func nsec3NameError(nsec3s []*dns.NSEC3, name, zone string) (bool, bool) {
+	ce, nc, optout := closestEncloser(nsec3s, name, zone)
+	if nc == "" {
+		return false, false
+	}
+	if coverNSEC3(nsec3s, wildcard(ce)) == nil {
+		return false, false
+	}
+	return true, optout
+}
+
+// nsec3NoData returns true if nsec3s prove that name has no records of type qtype. The second bool is true
+// if this is shown by an opt-out NSEC3 (RFC 5155, section 8.6).
+func nsec3NoData(nsec3s []*dns.NSEC3, name string, qtype uint16, zone string) (bool, bool) {
+	if n := matchNSEC3(nsec3s, name); n != nil {
+		if hasType(n.TypeBitMap, qtype) || hasType(n.TypeBitMap, dns.TypeCNAME) {
+			return false, false
+		}
+		if qtype != dns.TypeDS && hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA) {
+			return false, false
+		}
+		return true, false
+	}
+
+	ce, nc, optout := closestEncloser(nsec3s, name, zone)
+	if nc == "" {
+		return false, false
+	}
+	if qtype == dns.TypeDS && optout {
+		return true, true
+	}
+	if n := matchNSEC3(nsec3s, wildcard(ce)); n != nil {
+		return !hasType(n.TypeBitMap, qtype) && !hasType(n.TypeBitMap, dns.TypeCNAME), false
+	}
+	return false, false
+}
+
+// closestEncloser returns the closest encloser of name in zone and the next closer name, as proven by nsec3s
+// (RFC 5155, section 8.3). The next closer name is empty when there is no proof. The bool is true when the
+// NSEC3 covering the next closer name has the opt-out flag set.
+func closestEncloser(nsec3s []*dns.NSEC3, name, zone string) (string, string, bool) {
+	nc := ""
+	for c := name; dns.IsSubDomain(zone, c); c = parentName(c) {
+		if n := matchNSEC3(nsec3s, c); n != nil {
+			if nc == "" || hasType(n.TypeBitMap, dns.TypeDNAME) || hasType(n.TypeBitMap, dns.TypeNS) && !hasType(n.TypeBitMap, dns.TypeSOA) {
+				return "", "", false
+			}
+			cover := coverNSEC3(nsec3s, nc)
+			if cover == nil {
+				return "", "", false
+			}
+			return c, nc, cover.Flags&0x01 != 0
+		}
+		if c == "." {
+			break
+		}
+		nc = c
+	}
+	return "", "", false
+}
+
+func matchNSEC3(nsec3s []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, n := range nsec3s {
+		if n.Match(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+func coverNSEC3(nsec3s []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, n := range nsec3s {
+		if n.Cover(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+func hasType(bitmap []uint16, qtype uint16) bool {
+	for _, t := range bitmap {
+		if t == qtype {
+			return true
+		}
+	}
+	return false
+}
+
+// wildcard returns the wildcard name directly below ce.
+func wildcard(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+// ancestor returns the longest common ancestor of the names a and b.
+func ancestor(a, b string) string {
+	a = dns.CanonicalName(a)
+	n := dns.CompareDomainName(a, b)
+	if n == 0 {
+		return "."
+	}
+	idx := dns.Split(a)
+	return a[idx[len(idx)-n]:]
+}
+
+// compare compares the names a and b in the canonical DNS name order (RFC 4034, section 6.1).
+func compare(a, b string) int {
+	la, lb := dns.SplitDomainName(strings.ToLower(a)), dns.SplitDomainName(strings.ToLower(b))
+	i, j := len(la)-1, len(lb)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(unescape(la[i]), unescape(lb[j])); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case i < 0 && j < 0:
+		return 0
+	case i < 0:
+		return -1
+	}
+	return 1
+}
+
+// unescape returns the octets of the presentation format label l.
+func unescape(l string) string {
+	if !strings.Contains(l, "\\") {
+		return l
+	}
+	b := make([]byte, 0, len(l))
+	for i := 0; i < len(l); i++ {
+		if l[i] != '\\' || i+1 == len(l) {
+			b = append(b, l[i])
+			continue
+		}
+		if i+3 < len(l) && isDigit(l[i+1]) && isDigit(l[i+2]) && isDigit(l[i+3]) {
+			b = append(b, (l[i+1]-'0')*100+(l[i+2]-'0')*10+(l[i+3]-'0'))
+			i += 3
+			continue
+		}
+		b = append(b, l[i+1])
+		i++
+	}
+	return string(b)
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
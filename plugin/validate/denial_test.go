@@ -0,0 +1,130 @@
+package validate
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestCompare(t *testing.T) {
+	// Canonical order from RFC 4034, section 6.1.
+	names := []string{
+		"example.",
+		"a.example.",
+		"yljkjljk.a.example.",
+		"Z.a.example.",
+		"zABC.a.EXAMPLE.",
+		"z.example.",
+		"\\001.z.example.",
+		"*.z.example.",
+		"\\200.z.example.",
+	}
+	for i := 0; i < len(names)-1; i++ {
+		if c := compare(names[i], names[i+1]); c >= 0 {
+			t.Errorf("Expected %s to sort before %s, got %d", names[i], names[i+1], c)
+		}
+		if c := compare(names[i+1], names[i]); c <= 0 {
+			t.Errorf("Expected %s to sort after %s, got %d", names[i+1], names[i], c)
+		}
+	}
+	if c := compare("Example.", "example."); c != 0 {
+		t.Errorf("Expected names to be equal, got %d", c)
+	}
+}
+
+// nsec3Chain returns the NSEC3 chain for the names in zone, without opt-out.
+func nsec3Chain(zone string, names map[string][]uint16, optout bool) []*dns.NSEC3 {
+	type hashed struct {
+		hash  string
+		types []uint16
+	}
+	var hs []hashed
+	for n, types := range names {
+		hs = append(hs, hashed{dns.HashName(n, dns.SHA1, 0, ""), types})
+	}
+	sort.Slice(hs, func(i, j int) bool { return hs[i].hash < hs[j].hash })
+
+	flags := uint8(0)
+	if optout {
+		flags = 1
+	}
+	chain := make([]*dns.NSEC3, len(hs))
+	for i, h := range hs {
+		chain[i] = &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h.hash) + "." + zone, Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			Flags:      flags,
+			Salt:       "",
+			NextDomain: hs[(i+1)%len(hs)].hash,
+			TypeBitMap: h.types,
+		}
+	}
+	return chain
+}
+
+func TestNSEC3(t *testing.T) {
+	names := map[string][]uint16{
+		"example.org.":           {dns.TypeNS, dns.TypeSOA, dns.TypeDNSKEY, dns.TypeNSEC3PARAM, dns.TypeRRSIG},
+		"www.example.org.":       {dns.TypeA, dns.TypeRRSIG},
+		"delegated.example.org.": {dns.TypeNS},
+	}
+	chain := nsec3Chain("example.org.", names, false)
+
+	if ok, _ := nsec3NameError(chain, "nx.example.org.", "example.org."); !ok {
+		t.Errorf("Expected NXDOMAIN proof for nx.example.org.")
+	}
+	if ok, _ := nsec3NameError(chain, "www.example.org.", "example.org."); ok {
+		t.Errorf("Expected no NXDOMAIN proof for www.example.org.")
+	}
+	if ok, _ := nsec3NameError(chain, "a.delegated.example.org.", "example.org."); ok {
+		t.Errorf("Expected no NXDOMAIN proof below a delegation")
+	}
+	if ok, _ := nsec3NoData(chain, "www.example.org.", dns.TypeAAAA, "example.org."); !ok {
+		t.Errorf("Expected NODATA proof for www.example.org./AAAA")
+	}
+	if ok, _ := nsec3NoData(chain, "www.example.org.", dns.TypeA, "example.org."); ok {
+		t.Errorf("Expected no NODATA proof for www.example.org./A")
+	}
+	if ok, _ := nsec3NoData(chain, "delegated.example.org.", dns.TypeDS, "example.org."); !ok {
+		t.Errorf("Expected NODATA proof for delegated.example.org./DS")
+	}
+	if ok, _ := nsec3NoData(chain, "delegated.example.org.", dns.TypeA, "example.org."); ok {
+		t.Errorf("Expected no NODATA proof from the parent side of a delegation")
+	}
+
+	// With opt-out the absence of a DS record for an unsigned delegation is shown by covering the name.
+	chain = nsec3Chain("example.org.", names, true)
+	ok, optout := nsec3NoData(chain, "unsigned.example.org.", dns.TypeDS, "example.org.")
+	if !ok || !optout {
+		t.Errorf("Expected opt-out NODATA proof for unsigned.example.org./DS, got %t, %t", ok, optout)
+	}
+}
+
+func TestNSEC(t *testing.T) {
+	nsec := func(owner, next string, types ...uint16) *dns.NSEC {
+		return &dns.NSEC{Hdr: dns.RR_Header{Name: owner, Rrtype: dns.TypeNSEC, Class: dns.ClassINET}, NextDomain: next, TypeBitMap: types}
+	}
+	nsecs := []*dns.NSEC{
+		nsec("example.org.", "a.example.org.", dns.TypeSOA, dns.TypeNS),
+		nsec("a.example.org.", "x.b.example.org.", dns.TypeA),
+		nsec("x.b.example.org.", "example.org.", dns.TypeA),
+	}
+
+	if !nsecNameError(nsecs, "aa.example.org.") {
+		t.Errorf("Expected NXDOMAIN proof for aa.example.org.")
+	}
+	if nsecNameError(nsecs, "a.example.org.") {
+		t.Errorf("Expected no NXDOMAIN proof for a.example.org.")
+	}
+	if !nsecNoData(nsecs, "a.example.org.", dns.TypeAAAA) {
+		t.Errorf("Expected NODATA proof for a.example.org./AAAA")
+	}
+	if !nsecNoData(nsecs, "b.example.org.", dns.TypeA) {
+		t.Errorf("Expected NODATA proof for the empty non-terminal b.example.org.")
+	}
+	if !nsecNameError(nsecs, "z.example.org.") {
+		t.Errorf("Expected NXDOMAIN proof from the last NSEC in the zone")
+	}
+}
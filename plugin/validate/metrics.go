@@ -0,0 +1,18 @@
+package validate
+
+import (
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// ResponsesCount is the number of validated responses, per result: secure, insecure or bogus.
+	ResponsesCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: plugin.Namespace,
+		Subsystem: pluginName,
+		Name:      "responses_total",
+		Help:      "Counter of validated responses per result.",
+	}, []string{"server", "zone", "view", "result"})
+)
@@ -0,0 +1,152 @@
+package validate
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+)
+
+// verify validates m, the response for qname and qtype. It returns true if the response is secure and false
+// if it is insecure. An error is returned if the response is bogus.
+func (v *Validate) verify(ctx context.Context, w dns.ResponseWriter, m *dns.Msg, qname string, qtype uint16) (bool, error) {
+	switch m.Rcode {
+	case dns.RcodeSuccess, dns.RcodeNameError:
+	default:
+		// There is no data to validate.
+		return false, nil
+	}
+
+	now := v.now()
+	secure := true
+
+	answer := rrSets(m.Answer)
+	for _, s := range answer {
+		if s.qtype == dns.TypeCNAME && len(s.sigs) == 0 && synthesized(answer, s.name) {
+			// A CNAME synthesized from a DNAME is not signed, the DNAME is.
+			continue
+		}
+		zk, err := v.signerKeys(ctx, w, s)
+		if err != nil {
+			return false, err
+		}
+		if zk.insecure() {
+			secure = false
+			continue
+		}
+		sig, err := verifyRRset(s, zk, now)
+		if err != nil {
+			return false, err
+		}
+		capTTL(s, sig, now)
+		if labels := int(sig.Labels); labels < dns.CountLabel(s.name) {
+			if err := v.verifyWildcard(m, s.name, labels, zk); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	target := qname
+	if qtype != dns.TypeCNAME && qtype != dns.TypeANY {
+		target = follow(answer, qname)
+	}
+	if m.Rcode == dns.RcodeSuccess {
+		for _, s := range answer {
+			if s.name == target && (s.qtype == qtype || qtype == dns.TypeANY) {
+				return secure, nil
+			}
+		}
+	}
+
+	// A negative answer for target, the authority section must prove it.
+	zk, err := v.denialKeys(ctx, w, m, target)
+	if err != nil {
+		return false, err
+	}
+	if zk.insecure() {
+		return false, nil
+	}
+	insecure, err := v.verifyDenial(m, target, qtype, zk)
+	if err != nil {
+		return false, err
+	}
+	return secure && !insecure, nil
+}
+
+// signerKeys returns the keys of the zone that signed s. If s is not signed, it must be in an insecure zone.
+func (v *Validate) signerKeys(ctx context.Context, w dns.ResponseWriter, s *rrSet) (*zoneKeys, error) {
+	signer := s.signer()
+	if signer == "" {
+		zk, err := v.chase(ctx, w, s.name)
+		if err != nil {
+			return nil, err
+		}
+		if !zk.insecure() {
+			return nil, bogus(dns.ExtendedErrorCodeRRSIGsMissing, "no signatures for %s/%s", s.name, dns.TypeToString[s.qtype])
+		}
+		return zk, nil
+	}
+	return v.signerZone(ctx, w, signer, s.name)
+}
+
+// denialKeys returns the keys of the zone that signed the negative answer for name in m.
+func (v *Validate) denialKeys(ctx context.Context, w dns.ResponseWriter, m *dns.Msg, name string) (*zoneKeys, error) {
+	for _, s := range rrSets(m.Ns) {
+		switch s.qtype {
+		case dns.TypeSOA, dns.TypeNSEC, dns.TypeNSEC3:
+			if signer := s.signer(); signer != "" {
+				return v.signerZone(ctx, w, signer, name)
+			}
+		}
+	}
+
+	zk, err := v.chase(ctx, w, name)
+	if err != nil {
+		return nil, err
+	}
+	if !zk.insecure() {
+		return nil, bogus(dns.ExtendedErrorCodeNSECMissing, "no signed proof for the negative answer for %s", name)
+	}
+	return zk, nil
+}
+
+// signerZone returns the keys of the zone signer, which must be authoritative for name.
+func (v *Validate) signerZone(ctx context.Context, w dns.ResponseWriter, signer, name string) (*zoneKeys, error) {
+	if !dns.IsSubDomain(signer, name) {
+		return nil, bogus(dns.ExtendedErrorCodeDNSBogus, "signer %s is not authoritative for %s", signer, name)
+	}
+	zk, err := v.chase(ctx, w, signer)
+	if err != nil {
+		return nil, err
+	}
+	if !zk.insecure() && zk.zone != signer {
+		return nil, bogus(dns.ExtendedErrorCodeDNSKEYMissing, "signer %s is not a secure zone", signer)
+	}
+	return zk, nil
+}
+
+// follow follows the CNAME chain from name in answer and returns the name it ends in.
+func follow(answer []*rrSet, name string) string {
+	for i := 0; i < len(answer); i++ {
+		next := ""
+		for _, s := range answer {
+			if s.name == name && s.qtype == dns.TypeCNAME {
+				next = dns.CanonicalName(s.rrs[0].(*dns.CNAME).Target)
+			}
+		}
+		if next == "" {
+			break
+		}
+		name = next
+	}
+	return name
+}
+
+// synthesized returns true if name lies below a DNAME in answer.
+func synthesized(answer []*rrSet, name string) bool {
+	for _, s := range answer {
+		if s.qtype == dns.TypeDNAME && s.name != name && dns.IsSubDomain(s.name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/core/dnsserver"
+	"github.com/coredns/coredns/plugin"
+
+	"github.com/miekg/dns"
+)
+
+const pluginName = "validate"
+
+func init() { plugin.Register(pluginName, setup) }
+
+func setup(c *caddy.Controller) error {
+	v, err := parse(c)
+	if err != nil {
+		return plugin.Error(pluginName, err)
+	}
+
+	dnsserver.GetConfig(c).AddPlugin(func(next plugin.Handler) plugin.Handler {
+		v.Next = next
+		return v
+	})
+
+	return nil
+}
+
+func parse(c *caddy.Controller) (*Validate, error) {
+	v := New()
+	config := dnsserver.GetConfig(c)
+
+	i := 0
+	for c.Next() {
+		if i > 0 {
+			return nil, plugin.ErrOnce
+		}
+		i++
+
+		v.Zones = plugin.OriginsFromArgsOrServerBlock(c.RemainingArgs(), c.ServerBlockKeys)
+
+		var anchors []*dns.DS
+		for c.NextBlock() {
+			switch x := c.Val(); x {
+			case "trust_anchor":
+				args := c.RemainingArgs()
+				if len(args) != 1 {
+					return nil, c.ArgErr()
+				}
+				fileName := args[0]
+				if !filepath.IsAbs(fileName) && config.Root != "" {
+					fileName = filepath.Join(config.Root, fileName)
+				}
+				reader, err := os.Open(filepath.Clean(fileName))
+				if err != nil {
+					return nil, c.Errf("unable to open trust anchor file %q: %s", fileName, err)
+				}
+				ds, err := parseAnchors(reader, fileName)
+				reader.Close()
+				if err != nil {
+					return nil, c.Err(err.Error())
+				}
+				anchors = append(anchors, ds...)
+			case "insecure":
+				args := c.RemainingArgs()
+				if len(args) == 0 {
+					return nil, c.ArgErr()
+				}
+				for _, a := range args {
+					v.insecure = append(v.insecure, plugin.Host(a).NormalizeExact()...)
+				}
+			default:
+				return nil, c.Errf("unknown property '%s'", x)
+			}
+		}
+		if len(anchors) > 0 {
+			v.setAnchors(anchors)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,76 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coredns/caddy"
+	"github.com/coredns/coredns/plugin/test"
+)
+
+func TestSetup(t *testing.T) {
+	anchors, rm, err := test.TempFile(".", `example.org. 3600 IN DNSKEY 257 3 13 LWnEVtFEbchINbZPSjKXDrtTZ9Wh4BpyVZ2QFqZOmYkr8u8qW9V82v1sCzH/TgMzBcw9U9aW4cgaw1XwIYPnKw==
+example.net. IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+	bad, rm, err := test.TempFile(".", "example.org. IN A 127.0.0.1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rm()
+
+	tests := []struct {
+		input     string
+		shouldErr bool
+		anchors   []string
+		insecure  int
+	}{
+		{`validate`, false, []string{"."}, 0},
+		{`validate example.org`, false, []string{"."}, 0},
+		{`validate {
+			trust_anchor ` + anchors + `
+			insecure example.org.test 10.0.0.0/8
+		}`, false, []string{"example.org.", "example.net."}, 2},
+		// negative
+		{`validate {
+			trust_anchor
+		}`, true, nil, 0},
+		{`validate {
+			trust_anchor /does/not/exist
+		}`, true, nil, 0},
+		{`validate {
+			trust_anchor ` + bad + `
+		}`, true, nil, 0},
+		{`validate {
+			insecure
+		}`, true, nil, 0},
+		{`validate {
+			blah
+		}`, true, nil, 0},
+		{`validate
+		validate`, true, nil, 0},
+	}
+
+	for i, tc := range tests {
+		c := caddy.NewTestController("dns", tc.input)
+		v, err := parse(c)
+		if tc.shouldErr {
+			if err == nil {
+				t.Errorf("Test %d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if got := strings.Join(v.anchorZones, " "); got != strings.Join(tc.anchors, " ") {
+			t.Errorf("Test %d: expected trust anchors for %v, got %v", i, tc.anchors, v.anchorZones)
+		}
+		if len(v.insecure) != tc.insecure {
+			t.Errorf("Test %d: expected %d insecure zones, got %d", i, tc.insecure, len(v.insecure))
+		}
+	}
+}
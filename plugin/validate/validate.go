@@ -0,0 +1,168 @@
+// Package validate implements a plugin that performs DNSSEC validation of the responses of the next plugin.
+package validate
+
+import (
+	"context"
+	"time"
+
+	"github.com/coredns/coredns/plugin"
+	"github.com/coredns/coredns/plugin/metrics"
+	"github.com/coredns/coredns/plugin/pkg/cache"
+	clog "github.com/coredns/coredns/plugin/pkg/log"
+	"github.com/coredns/coredns/plugin/pkg/nonwriter"
+	"github.com/coredns/coredns/plugin/pkg/singleflight"
+	"github.com/coredns/coredns/request"
+
+	"github.com/miekg/dns"
+)
+
+var log = clog.NewWithPlugin(pluginName)
+
+// Validate validates the DNSSEC signatures in the responses of the next plugin, starting from a set
+// of trust anchors. Secure responses get the AD bit, bogus responses are replaced by a SERVFAIL.
+type Validate struct {
+	Next  plugin.Handler
+	Zones []string
+
+	anchors     map[string][]*dns.DS // trust anchors, keyed by zone
+	anchorZones []string
+	insecure    []string // negative trust anchors (RFC 7646)
+
+	keys     *cache.Cache // validated zone keys, see chase
+	inflight *singleflight.Group
+	now      func() time.Time
+}
+
+// New returns a new Validate that uses the root zone trust anchors.
+func New() *Validate {
+	v := &Validate{
+		keys:     cache.New(defaultKeyCacheSize),
+		inflight: new(singleflight.Group),
+		now:      time.Now,
+	}
+	v.setAnchors(rootAnchors())
+	return v
+}
+
+// setAnchors sets the trust anchors to the DS records in ds.
+func (v *Validate) setAnchors(ds []*dns.DS) {
+	v.anchors = make(map[string][]*dns.DS)
+	v.anchorZones = nil
+	for _, d := range ds {
+		zone := dns.CanonicalName(d.Hdr.Name)
+		if _, ok := v.anchors[zone]; !ok {
+			v.anchorZones = append(v.anchorZones, zone)
+		}
+		v.anchors[zone] = append(v.anchors[zone], d)
+	}
+}
+
+// ServeDNS implements the plugin.Handler interface.
+func (v *Validate) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	state := request.Request{W: w, Req: r}
+
+	zone := plugin.Zones(v.Zones).Matches(state.Name())
+	if zone == "" || r.Opcode != dns.OpcodeQuery || r.CheckingDisabled {
+		return plugin.NextOrFailure(v.Name(), v.Next, ctx, w, r)
+	}
+
+	// Ask for the DNSSEC records, and for the data even when an upstream thinks it's bogus, we do
+	// our own validation.
+	req := r.Copy()
+	if o := req.IsEdns0(); o != nil {
+		o.SetDo()
+	} else {
+		req.SetEdns0(4096, true)
+	}
+	req.CheckingDisabled = true
+
+	nw := nonwriter.New(w)
+	rcode, err := plugin.NextOrFailure(v.Name(), v.Next, ctx, nw, req)
+	if nw.Msg == nil {
+		return rcode, err
+	}
+	m := nw.Msg
+
+	server, view := metrics.WithServer(ctx), metrics.WithView(ctx)
+
+	secure, err := v.verify(ctx, w, m, state.Name(), state.QType())
+	if err != nil {
+		ResponsesCount.WithLabelValues(server, zone, view, "bogus").Inc()
+		log.Debugf("Bogus response for %s/%s: %s", state.Name(), state.Type(), err)
+		w.WriteMsg(bogusReply(state, err))
+		return dns.RcodeSuccess, nil
+	}
+
+	if secure {
+		ResponsesCount.WithLabelValues(server, zone, view, "secure").Inc()
+	} else {
+		ResponsesCount.WithLabelValues(server, zone, view, "insecure").Inc()
+	}
+	m.AuthenticatedData = secure
+	m.CheckingDisabled = false
+
+	if !state.Do() {
+		m.Answer = strip(m.Answer, state.QType())
+		m.Ns = strip(m.Ns, state.QType())
+		m.Extra = strip(m.Extra, state.QType())
+	}
+	if !state.SizeAndDo(m) {
+		removeOPT(m)
+	}
+
+	w.WriteMsg(m)
+	return dns.RcodeSuccess, nil
+}
+
+// Name implements the plugin.Handler interface.
+func (v *Validate) Name() string { return pluginName }
+
+// bogusReply returns the SERVFAIL reply for a bogus response, if the client supports EDNS0 the reason is
+// added as an Extended DNS Error.
+func bogusReply(state request.Request, err error) *dns.Msg {
+	m := new(dns.Msg)
+	m.SetRcode(state.Req, dns.RcodeServerFailure)
+	m.RecursionAvailable = true
+	if state.Req.IsEdns0() == nil {
+		return m
+	}
+
+	m.SetEdns0(uint16(state.Size()), state.Do())
+	ede := &dns.EDNS0_EDE{InfoCode: dns.ExtendedErrorCodeDNSBogus, ExtraText: err.Error()}
+	if b, ok := err.(*bogusError); ok {
+		ede.InfoCode = b.code
+	}
+	m.IsEdns0().Option = append(m.IsEdns0().Option, ede)
+	return m
+}
+
+// strip removes the DNSSEC records, that the client didn't ask for, from rrs.
+func strip(rrs []dns.RR, qtype uint16) []dns.RR {
+	j := 0
+	for _, rr := range rrs {
+		switch t := rr.Header().Rrtype; t {
+		case dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3:
+			if t != qtype {
+				continue
+			}
+		}
+		rrs[j] = rr
+		j++
+	}
+	return rrs[:j]
+}
+
+// removeOPT removes the OPT record from m.
+func removeOPT(m *dns.Msg) {
+	j := 0
+	for _, rr := range m.Extra {
+		if rr.Header().Rrtype == dns.TypeOPT {
+			continue
+		}
+		m.Extra[j] = rr
+		j++
+	}
+	m.Extra = m.Extra[:j]
+}
+
+const defaultKeyCacheSize = 10000
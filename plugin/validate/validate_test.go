@@ -0,0 +1,220 @@
+package validate
+
+import (
+	"context"
+	"crypto"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/pkg/dnstest"
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// signer signs RRsets for a test zone.
+type signer struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newSigner(t *testing.T, zone string) *signer {
+	t.Helper()
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &signer{key: key, priv: priv.(crypto.Signer)}
+}
+
+// signPeriod returns rrs followed by their signature, valid from inception to expiration relative to now.
+func (s *signer) signPeriod(t *testing.T, inception, expiration time.Duration, rrs ...dns.RR) []dns.RR {
+	t.Helper()
+	now := time.Now()
+	h := rrs[0].Header()
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: h.Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: h.Ttl},
+		KeyTag:     s.key.KeyTag(),
+		SignerName: s.key.Hdr.Name,
+		Algorithm:  s.key.Algorithm,
+		Inception:  uint32(now.Add(inception).Unix()),
+		Expiration: uint32(now.Add(expiration).Unix()),
+	}
+	if err := sig.Sign(s.priv, rrs); err != nil {
+		t.Fatal(err)
+	}
+	return append(rrs, sig)
+}
+
+func (s *signer) sign(t *testing.T, rrs ...dns.RR) []dns.RR {
+	return s.signPeriod(t, -time.Hour, 24*time.Hour, rrs...)
+}
+
+// upstream answers queries from a fixed set of responses, keyed by name/type.
+type upstream map[string]*dns.Msg
+
+func (u upstream) ServeDNS(ctx context.Context, w dns.ResponseWriter, r *dns.Msg) (int, error) {
+	q := r.Question[0]
+	m, ok := u[dns.CanonicalName(q.Name)+"/"+dns.TypeToString[q.Qtype]]
+	if !ok {
+		return dns.RcodeServerFailure, nil
+	}
+	ret := m.Copy()
+	rcode := ret.Rcode
+	ret.SetReply(r)
+	ret.Rcode = rcode
+	ret.SetEdns0(4096, true)
+	w.WriteMsg(ret)
+	return dns.RcodeSuccess, nil
+}
+
+func (u upstream) Name() string { return "upstream" }
+
+func newTestValidate(t *testing.T) *Validate {
+	t.Helper()
+	org := newSigner(t, "example.org.")
+	sec := newSigner(t, "secure.example.org.")
+
+	soa := test.SOA("example.org. 3600 IN SOA ns.example.org. hostmaster.example.org. 1 7200 3600 1209600 3600")
+	nsecApex := &dns.NSEC{Hdr: dns.RR_Header{Name: "example.org.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+		NextDomain: "bogus.example.org.", TypeBitMap: []uint16{dns.TypeNS, dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeDNSKEY}}
+	nsecInsecure := &dns.NSEC{Hdr: dns.RR_Header{Name: "insecure.example.org.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+		NextDomain: "nosig.example.org.", TypeBitMap: []uint16{dns.TypeNS, dns.TypeRRSIG, dns.TypeNSEC}}
+	nsecNosig := &dns.NSEC{Hdr: dns.RR_Header{Name: "nosig.example.org.", Rrtype: dns.TypeNSEC, Class: dns.ClassINET, Ttl: 3600},
+		NextDomain: "secure.example.org.", TypeBitMap: []uint16{dns.TypeA, dns.TypeRRSIG, dns.TypeNSEC}}
+
+	bogusRRs := org.sign(t, test.A("bogus.example.org. 3600 IN A 127.0.0.1"))
+	bogusRRs[0].(*dns.A).A = []byte{127, 0, 0, 2}
+
+	u := upstream{
+		"example.org./DNSKEY":  {Answer: org.sign(t, org.key)},
+		"www.example.org./A":   {Answer: org.sign(t, test.A("www.example.org. 3600 IN A 127.0.0.1"))},
+		"bogus.example.org./A": {Answer: bogusRRs},
+		"expired.example.org./A": {Answer: org.signPeriod(t, -48*time.Hour, -24*time.Hour,
+			test.A("expired.example.org. 3600 IN A 127.0.0.1"))},
+		"nosig.example.org./A":  {Answer: []dns.RR{test.A("nosig.example.org. 3600 IN A 127.0.0.1")}},
+		"nosig.example.org./DS": {Ns: append(org.sign(t, soa), org.sign(t, nsecNosig)...)},
+		"nx.example.org./A": {MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError},
+			Ns: append(append(org.sign(t, soa), org.sign(t, nsecApex)...), org.sign(t, nsecNosig)...)},
+		"nxbogus.example.org./A": {MsgHdr: dns.MsgHdr{Rcode: dns.RcodeNameError}, Ns: org.sign(t, soa)},
+
+		"secure.example.org./DS":     {Answer: org.sign(t, sec.key.ToDS(dns.SHA256))},
+		"secure.example.org./DNSKEY": {Answer: sec.sign(t, sec.key)},
+		"a.secure.example.org./A":    {Answer: sec.sign(t, test.A("a.secure.example.org. 3600 IN A 127.0.0.1"))},
+
+		"insecure.example.org./DS":  {Ns: append(org.sign(t, soa), org.sign(t, nsecInsecure)...)},
+		"a.insecure.example.org./A": {Answer: []dns.RR{test.A("a.insecure.example.org. 3600 IN A 127.0.0.1")}},
+
+		"example.net./A": {Answer: []dns.RR{test.A("example.net. 3600 IN A 127.0.0.1")}},
+	}
+
+	v := New()
+	v.Zones = []string{"."}
+	v.Next = u
+	v.setAnchors([]*dns.DS{org.key.ToDS(dns.SHA256)})
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	v := newTestValidate(t)
+
+	tests := []struct {
+		qname string
+		do    bool
+		cd    bool
+		rcode int
+		ad    bool
+		ede   int // -1 for none
+	}{
+		{"www.example.org.", false, false, dns.RcodeSuccess, true, -1},
+		{"www.example.org.", true, false, dns.RcodeSuccess, true, -1},
+		{"a.secure.example.org.", true, false, dns.RcodeSuccess, true, -1},
+		{"a.insecure.example.org.", true, false, dns.RcodeSuccess, false, -1},
+		{"nx.example.org.", true, false, dns.RcodeNameError, true, -1},
+		{"example.net.", true, false, dns.RcodeSuccess, false, -1},
+		{"bogus.example.org.", true, false, dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeDNSBogus)},
+		{"expired.example.org.", true, false, dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeSignatureExpired)},
+		{"nosig.example.org.", true, false, dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeRRSIGsMissing)},
+		{"nxbogus.example.org.", true, false, dns.RcodeServerFailure, false, int(dns.ExtendedErrorCodeNSECMissing)},
+		// Checking disabled, the bogus answer is returned as is.
+		{"bogus.example.org.", true, true, dns.RcodeSuccess, false, -1},
+	}
+
+	for i, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, dns.TypeA)
+		m.SetEdns0(4096, tc.do)
+		m.CheckingDisabled = tc.cd
+
+		rec := dnstest.NewRecorder(&test.ResponseWriter{})
+		if _, err := v.ServeDNS(context.TODO(), rec, m); err != nil {
+			t.Fatalf("Test %d: expected no error, got %s", i, err)
+		}
+		if rec.Msg == nil {
+			t.Fatalf("Test %d: expected a response", i)
+		}
+		if rec.Msg.Rcode != tc.rcode {
+			t.Errorf("Test %d: expected rcode %s, got %s", i, dns.RcodeToString[tc.rcode], dns.RcodeToString[rec.Msg.Rcode])
+		}
+		if rec.Msg.AuthenticatedData != tc.ad {
+			t.Errorf("Test %d: expected AD bit %t, got %t", i, tc.ad, rec.Msg.AuthenticatedData)
+		}
+
+		ede := -1
+		if o := rec.Msg.IsEdns0(); o != nil {
+			for _, e := range o.Option {
+				if x, ok := e.(*dns.EDNS0_EDE); ok {
+					ede = int(x.InfoCode)
+				}
+			}
+		}
+		if ede != tc.ede {
+			t.Errorf("Test %d: expected extended error %d, got %d", i, tc.ede, ede)
+		}
+
+		sigs := 0
+		for _, rr := range append(rec.Msg.Answer, rec.Msg.Ns...) {
+			if rr.Header().Rrtype == dns.TypeRRSIG {
+				sigs++
+			}
+		}
+		if !tc.do && sigs > 0 {
+			t.Errorf("Test %d: expected signatures to be removed, got %d", i, sigs)
+		}
+		if tc.do && tc.ad && sigs == 0 {
+			t.Errorf("Test %d: expected signatures in the response", i)
+		}
+	}
+}
+
+func TestValidateNoEDNS(t *testing.T) {
+	v := newTestValidate(t)
+
+	m := new(dns.Msg)
+	m.SetQuestion("bogus.example.org.", dns.TypeA)
+	rec := dnstest.NewRecorder(&test.ResponseWriter{})
+	v.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Expected rcode SERVFAIL, got %s", dns.RcodeToString[rec.Msg.Rcode])
+	}
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record in the response")
+	}
+
+	m = new(dns.Msg)
+	m.SetQuestion("www.example.org.", dns.TypeA)
+	rec = dnstest.NewRecorder(&test.ResponseWriter{})
+	v.ServeDNS(context.TODO(), rec, m)
+	if rec.Msg.IsEdns0() != nil {
+		t.Errorf("Expected no OPT record in the response")
+	}
+	if len(rec.Msg.Answer) != 1 {
+		t.Errorf("Expected 1 RR in the answer, got %d", len(rec.Msg.Answer))
+	}
+}
@@ -0,0 +1,141 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// bogusError is returned when a response fails validation, code is the Extended DNS Error (RFC 8914)
+// returned to the client.
+type bogusError struct {
+	code   uint16
+	reason string
+}
+
+func (e *bogusError) Error() string { return e.reason }
+
+func bogus(code uint16, format string, a ...interface{}) error {
+	return &bogusError{code: code, reason: fmt.Sprintf(format, a...)}
+}
+
+// rrSet is an RRset together with the signatures that cover it.
+type rrSet struct {
+	name  string
+	qtype uint16
+	rrs   []dns.RR
+	sigs  []*dns.RRSIG
+}
+
+// rrSets groups the RRs in section into RRsets, in the order they appear. Signatures that don't cover
+// any RRset are dropped.
+func rrSets(section []dns.RR) []*rrSet {
+	var sets []*rrSet
+	find := func(name string, qtype uint16) *rrSet {
+		for _, s := range sets {
+			if s.qtype == qtype && s.name == name {
+				return s
+			}
+		}
+		return nil
+	}
+
+	for _, rr := range section {
+		h := rr.Header()
+		if h.Rrtype == dns.TypeRRSIG || h.Rrtype == dns.TypeOPT {
+			continue
+		}
+		name := strings.ToLower(h.Name)
+		s := find(name, h.Rrtype)
+		if s == nil {
+			s = &rrSet{name: name, qtype: h.Rrtype}
+			sets = append(sets, s)
+		}
+		s.rrs = append(s.rrs, rr)
+	}
+	for _, rr := range section {
+		sig, ok := rr.(*dns.RRSIG)
+		if !ok {
+			continue
+		}
+		if s := find(strings.ToLower(sig.Hdr.Name), sig.TypeCovered); s != nil {
+			s.sigs = append(s.sigs, sig)
+		}
+	}
+	return sets
+}
+
+// signer returns the signer name of the signatures in s, or the empty string if s is unsigned.
+func (s *rrSet) signer() string {
+	if len(s.sigs) == 0 {
+		return ""
+	}
+	return dns.CanonicalName(s.sigs[0].SignerName)
+}
+
+// verifyRRset verifies that s carries a valid signature made by one of the keys in zk. The signature that
+// validated is returned.
+func verifyRRset(s *rrSet, zk *zoneKeys, now time.Time) (*dns.RRSIG, error) {
+	if len(s.sigs) == 0 {
+		return nil, bogus(dns.ExtendedErrorCodeRRSIGsMissing, "no signatures for %s/%s", s.name, dns.TypeToString[s.qtype])
+	}
+
+	err := bogus(dns.ExtendedErrorCodeDNSKEYMissing, "no DNSKEY in %s for the signatures of %s/%s", zk.zone, s.name, dns.TypeToString[s.qtype])
+	labels := dns.CountLabel(s.name)
+	for _, sig := range s.sigs {
+		if dns.CanonicalName(sig.SignerName) != zk.zone || int(sig.Labels) > labels {
+			continue
+		}
+		for _, k := range zk.keys {
+			if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+				continue
+			}
+			if e := sig.Verify(k, s.rrs); e != nil {
+				err = bogus(dns.ExtendedErrorCodeDNSBogus, "signature for %s/%s failed to verify: %s", s.name, dns.TypeToString[s.qtype], e)
+				continue
+			}
+			if !sig.ValidityPeriod(now) {
+				if now.Unix() < int64(sig.Inception) {
+					err = bogus(dns.ExtendedErrorCodeSignatureNotYetValid, "signature for %s/%s is not yet valid", s.name, dns.TypeToString[s.qtype])
+				} else {
+					err = bogus(dns.ExtendedErrorCodeSignatureExpired, "signature for %s/%s has expired", s.name, dns.TypeToString[s.qtype])
+				}
+				continue
+			}
+			return sig, nil
+		}
+	}
+	return nil, err
+}
+
+// capTTL lowers the TTLs of the RRs in s and of its signatures, so they don't exceed the original TTL
+// from sig, nor outlive the signature (RFC 4035, section 5.3.3).
+func capTTL(s *rrSet, sig *dns.RRSIG, now time.Time) {
+	ttl := sig.OrigTtl
+	if left := int64(sig.Expiration) - now.Unix(); left < int64(ttl) {
+		ttl = uint32(left)
+	}
+	for _, rr := range s.rrs {
+		if rr.Header().Ttl > ttl {
+			rr.Header().Ttl = ttl
+		}
+	}
+	for _, rr := range s.sigs {
+		if rr.Hdr.Ttl > ttl {
+			rr.Hdr.Ttl = ttl
+		}
+	}
+}
+
+// minTTL returns the lowest TTL of the RRs in rrs.
+func minTTL(rrs []dns.RR) uint32 {
+	ttl := uint32(0)
+	for i, rr := range rrs {
+		if i == 0 || rr.Header().Ttl < ttl {
+			ttl = rr.Header().Ttl
+		}
+	}
+	return ttl
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+func TestFileUpdateWithCache(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(name, []byte(exampleOrg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	corefile := `example.org:0 {
+		cache
+		file ` + name + ` {
+			update 127.0.0.1 ::1
+		}
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// Get the SOA in the cache, an update has the same question.
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	if _, err := dns.Exchange(m, udp); err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeSuccess || resp.Opcode != dns.OpcodeUpdate {
+		t.Fatalf("Expected a NOERROR update response, got %s with opcode %d", dns.RcodeToString[resp.Rcode], resp.Opcode)
+	}
+
+	m = new(dns.Msg)
+	m.SetQuestion("new.example.org.", dns.TypeA)
+	resp, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if len(resp.Answer) != 1 {
+		t.Fatalf("Expected the updated record in the answer, got %v", resp.Answer)
+	}
+	if buf, _ := os.ReadFile(name); !strings.Contains(string(buf), "new.example.org.") {
+		t.Errorf("Expected the update to be written to %q", name)
+	}
+}
+
+func TestFileUpdateNotImplemented(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(name, []byte(exampleOrg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	corefile := `example.org:0 {
+		cache
+		file ` + name + `
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeNotImplemented {
+		t.Errorf("Expected NOTIMP for an update without an update policy, got %s", dns.RcodeToString[resp.Rcode])
+	}
+}
+
+func TestUpdateOtherZoneNotImplemented(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "db.example.org")
+	if err := os.WriteFile(name, []byte(exampleOrg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server accepts updates for example.org, whoami answers everything else.
+	corefile := `example.org:0 example.net:0 {
+		file ` + name + ` example.org {
+			update 127.0.0.1 ::1
+		}
+		whoami
+	}`
+
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	m := new(dns.Msg)
+	m.SetUpdate("example.net.")
+	m.Insert([]dns.RR{test.A("new.example.net. 300 IN A 127.0.0.2")})
+	resp, err := dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeNotImplemented {
+		t.Errorf("Expected NOTIMP for an update of a zone without an update policy, got %s", dns.RcodeToString[resp.Rcode])
+	}
+
+	m = new(dns.Msg)
+	m.SetUpdate("example.org.")
+	m.Insert([]dns.RR{test.A("new.example.org. 300 IN A 127.0.0.2")})
+	resp, err = dns.Exchange(m, udp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if resp.Rcode != dns.RcodeSuccess {
+		t.Errorf("Expected the update of example.org to succeed, got %s", dns.RcodeToString[resp.Rcode])
+	}
+}
@@ -215,3 +215,68 @@ www    IN AAAA ::1
 		}
 	}
 }
+
+func TestSecondaryZoneTransferTsig(t *testing.T) {
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	corefile := `example.org:0 {
+		tsig {
+			secret ` + tsigKey + ` ` + tsigSecret + `
+		}
+		file ` + name + `
+		transfer {
+			to * key ` + tsigKey + `
+		}
+	}`
+
+	i, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// An unsigned transfer is refused.
+	m := new(dns.Msg)
+	m.SetAxfr("example.org.")
+	c := &dns.Client{Net: "tcp"}
+	r, _, err := c.Exchange(m, tcp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if r.Rcode != dns.RcodeRefused {
+		t.Fatalf("Expected unsigned transfer to be refused, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	corefile = `example.org:0 {
+		tsig {
+			secret ` + tsigKey + ` ` + tsigSecret + `
+		}
+		secondary {
+			transfer from ` + tcp + ` key ` + tsigKey + `
+		}
+	}`
+
+	i1, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	// This is async; we need to wait for it to be transferred.
+	for i := 0; i < 50; i++ {
+		r, _ = dns.Exchange(m, udp)
+		if r != nil && len(r.Answer) != 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if r == nil || len(r.Answer) == 0 {
+		t.Fatalf("Expected answer section")
+	}
+}
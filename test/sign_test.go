@@ -0,0 +1,81 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// TestSignNSEC3Validate signs a zone with NSEC3, serves it with the file plugin and checks the answers and
+// denials of existence validate.
+func TestSignNSEC3Validate(t *testing.T) {
+	dir := t.TempDir()
+	corefile := `miek.nl:0 {
+		file ` + filepath.Join(dir, "db.miek.nl.signed") + ` {
+			reload 100ms
+		}
+		sign ../plugin/sign/testdata/db.miek.nl_nsec3 {
+			key file ../plugin/sign/testdata/Kmiek.nl.+013+59725
+			directory ` + dir + `
+			nsec3 salt AABBCCDD
+		}
+	}`
+	i, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	corefile = `miek.nl:0 {
+		validate {
+			trust_anchor ../plugin/sign/testdata/Kmiek.nl.+013+59725.key
+		}
+		forward . ` + udp + `
+	}`
+	i1, udp1, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	// Wait for the zone to be signed and loaded.
+	m := new(dns.Msg)
+	m.SetQuestion("miek.nl.", dns.TypeSOA)
+	for i := 0; i < 50; i++ {
+		r, err := dns.Exchange(m, udp)
+		if err == nil && len(r.Answer) > 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		rcode int
+	}{
+		{"a.miek.nl.", dns.TypeAAAA, dns.RcodeSuccess},
+		{"nope.miek.nl.", dns.TypeA, dns.RcodeNameError},
+		{"a.miek.nl.", dns.TypeA, dns.RcodeSuccess},
+		{"ent.miek.nl.", dns.TypeA, dns.RcodeSuccess},
+		{"x.wild.miek.nl.", dns.TypeTXT, dns.RcodeSuccess},
+		{"x.wild.miek.nl.", dns.TypeA, dns.RcodeSuccess},
+	}
+	for _, tc := range tests {
+		m := new(dns.Msg)
+		m.SetQuestion(tc.qname, tc.qtype)
+		m.SetEdns0(4096, true)
+		r, err := dns.Exchange(m, udp1)
+		if err != nil {
+			t.Fatalf("Expected to receive reply, but didn't: %s", err)
+		}
+		if r.Rcode != tc.rcode {
+			t.Errorf("Expected rcode %s for %s/%s, got %s", dns.RcodeToString[tc.rcode], tc.qname, dns.TypeToString[tc.qtype], dns.RcodeToString[r.Rcode])
+		}
+		if !r.AuthenticatedData {
+			t.Errorf("Expected a validated response for %s/%s, got %s", tc.qname, dns.TypeToString[tc.qtype], r)
+		}
+	}
+}
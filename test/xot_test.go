@@ -0,0 +1,136 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/coredns/coredns/plugin/test"
+
+	"github.com/miekg/dns"
+)
+
+// writeCert writes a certificate for 127.0.0.1 with usage, signed by parent (or self-signed if nil), and its key to
+// dir as name.pem and name-key.pem.
+func writeCert(t *testing.T, dir, name string, usage x509.ExtKeyUsage, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestSecondaryZoneTransferTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := writeCert(t, dir, "ca", x509.ExtKeyUsageAny, nil, nil)
+	writeCert(t, dir, "primary", x509.ExtKeyUsageServerAuth, ca, caKey)
+	writeCert(t, dir, "secondary", x509.ExtKeyUsageClientAuth, ca, caKey)
+
+	name, rm, err := test.TempFile(".", exampleOrg)
+	if err != nil {
+		t.Fatalf("Failed to create zone: %s", err)
+	}
+	defer rm()
+
+	corefile := `tls://example.org:0 {
+		tls ` + filepath.Join(dir, "primary.pem") + ` ` + filepath.Join(dir, "primary-key.pem") + ` ` + filepath.Join(dir, "ca.pem") + ` {
+			client_auth verify_if_given
+		}
+		file ` + name + `
+		transfer {
+			to tls://*
+		}
+	}`
+
+	i, _, tcp, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i.Stop()
+
+	// The certificate of the primary is for 127.0.0.1.
+	_, port, _ := net.SplitHostPort(tcp)
+	tcp = net.JoinHostPort("127.0.0.1", port)
+
+	// A transfer to a client without a certificate is refused.
+	m := new(dns.Msg)
+	m.SetAxfr("example.org.")
+	c := &dns.Client{Net: "tcp-tls", TLSConfig: &tls.Config{InsecureSkipVerify: true}}
+	r, _, err := c.Exchange(m, tcp)
+	if err != nil {
+		t.Fatalf("Expected to receive reply, but didn't: %s", err)
+	}
+	if r.Rcode != dns.RcodeRefused {
+		t.Fatalf("Expected transfer without client certificate to be refused, got %s", dns.RcodeToString[r.Rcode])
+	}
+
+	corefile = `example.org:0 {
+		secondary {
+			transfer from tls://` + tcp + `
+			tls ` + filepath.Join(dir, "secondary.pem") + ` ` + filepath.Join(dir, "secondary-key.pem") + ` ` + filepath.Join(dir, "ca.pem") + `
+		}
+	}`
+
+	i1, udp, _, err := CoreDNSServerAndPorts(corefile)
+	if err != nil {
+		t.Fatalf("Could not get CoreDNS serving instance: %s", err)
+	}
+	defer i1.Stop()
+
+	m = new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeSOA)
+	// This is async; we need to wait for it to be transferred.
+	for i := 0; i < 50; i++ {
+		r, _ = dns.Exchange(m, udp)
+		if r != nil && len(r.Answer) != 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if r == nil || len(r.Answer) == 0 {
+		t.Fatalf("Expected answer section")
+	}
+}